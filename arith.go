@@ -0,0 +1,183 @@
+package main
+
+import "math"
+
+// arithmetic applies the arithmetic operator to the non-NULL operands.
+// The integers yield integers, which are checked for overflow, and the
+// integers are widened to the decimals, and then to the floats, if the
+// other operand is so. The date and time values are computed by
+// temporalArithmetic.
+func arithmetic(op Operator, a, b any) (any, error) {
+	x, xok := a.(int)
+	y, yok := b.(int)
+	if xok && yok {
+		return intArithmetic(op, x, y)
+	}
+	if v, ok, err := temporalArithmetic(op, a, b); ok {
+		return v, err
+	}
+	_, af := a.(float64)
+	_, bf := b.(float64)
+	if !af && !bf {
+		if x, y, ok := decimalOperands(a, b); ok {
+			return decimalArithmetic(op, x, y)
+		}
+	} else if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return floatArithmetic(op, x, y)
+		}
+	}
+	return nil, newError(codeUndefinedFunction,
+		"operator does not exist: %s %s %s", valueType(a), op, valueType(b))
+}
+
+// toFloat converts the numeric value to the float.
+func toFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case float64:
+		return x, true
+	case Decimal:
+		return x.Float64(), true
+	}
+	return 0, false
+}
+
+// widen converts the number to the float or the decimal if the type is
+// so, e.g., the integer result of CASE whose other results are decimals,
+// and leaves the other values as they are.
+func widen(typ *Type, v any) any {
+	switch typ.id {
+	case floatID:
+		if f, ok := toFloat(v); ok {
+			return f
+		}
+	case decimalID:
+		if d, ok := toDecimal(v); ok {
+			return d
+		}
+	}
+	return v
+}
+
+var (
+	errIntegerOutOfRange = newError(codeNumericOutOfRange, "bigint out of range")
+	errDivisionByZero    = newError(codeDivisionByZero, "division by zero")
+)
+
+// checkInt checks if the integer result of the expression is in the range
+// of its type, e.g., smallint * smallint is a smallint, which is taken
+// from the schema of the table of the row. The results in the range of
+// smallint, which every integer type holds, are not looked into, and the
+// unknown types are taken as bigint, whose range is checked by the
+// arithmetic.
+func (env *evalEnv) checkInt(e Expr, v any) (any, error) {
+	x, ok := v.(int)
+	if !ok || (x >= math.MinInt16 && x <= math.MaxInt16) {
+		return v, nil
+	}
+	t := env.table
+	if t == nil {
+		t = &Table{}
+	}
+	typ := t.exprType(e)
+	if typ == nil || !typ.integral() {
+		return v, nil
+	}
+	if min, max := typ.intRange(); x < min || x > max {
+		return nil, newError(codeNumericOutOfRange, "%s out of range", typ)
+	}
+	return v, nil
+}
+
+func intArithmetic(op Operator, x, y int) (any, error) {
+	switch op {
+	case add:
+		z := x + y
+		if (z > x) != (y > 0) {
+			return nil, errIntegerOutOfRange
+		}
+		return z, nil
+	case sub:
+		z := x - y
+		if (z < x) != (y > 0) {
+			return nil, errIntegerOutOfRange
+		}
+		return z, nil
+	case mul:
+		z := x * y
+		if x != 0 && (z/x != y || (x == -1 && y == math.MinInt64)) {
+			return nil, errIntegerOutOfRange
+		}
+		return z, nil
+	case div:
+		if y == 0 {
+			return nil, errDivisionByZero
+		}
+		if x == math.MinInt64 && y == -1 {
+			return nil, errIntegerOutOfRange
+		}
+		return x / y, nil
+	default:
+		if y == 0 {
+			return nil, errDivisionByZero
+		}
+		if y == -1 {
+			return 0, nil
+		}
+		return x % y, nil
+	}
+}
+
+func floatArithmetic(op Operator, x, y float64) (any, error) {
+	switch op {
+	case add:
+		return x + y, nil
+	case sub:
+		return x - y, nil
+	case mul:
+		return x * y, nil
+	}
+	if y == 0 {
+		return nil, errDivisionByZero
+	}
+	if op == div {
+		return x / y, nil
+	}
+	return math.Mod(x, y), nil
+}
+
+func decimalArithmetic(op Operator, x, y Decimal) (any, error) {
+	switch op {
+	case add:
+		return x.Add(y), nil
+	case sub:
+		return x.Sub(y), nil
+	case mul:
+		return x.Mul(y), nil
+	case div:
+		return x.Quo(y)
+	default:
+		return x.Mod(y)
+	}
+}
+
+// negate returns the negation of the non-NULL numeric value.
+func negate(v any) (any, error) {
+	switch x := v.(type) {
+	case int:
+		if x == math.MinInt64 {
+			return nil, errIntegerOutOfRange
+		}
+		return -x, nil
+	case float64:
+		return -x, nil
+	case Decimal:
+		return x.Neg(), nil
+	case Interval:
+		return x.neg(), nil
+	}
+	return nil, newError(codeUndefinedFunction,
+		"operator does not exist: - %s", valueType(v))
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Array is the value of the one-dimensional array types, e.g., INTEGER[],
+// whose elements are of the element type or NULL. It is printed in the
+// text format of PostgreSQL, e.g., {1,NULL,3} or {a,"b c"}.
+type Array struct {
+	elem  *Type
+	elems []any
+}
+
+func (a Array) String() string {
+	return formatArray(a.elems, toText)
+}
+
+// MarshalJSON encodes the array as the JSON array of the elements.
+func (a Array) MarshalJSON() ([]byte, error) {
+	if a.elems == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(a.elems)
+}
+
+// formatArray formats the elements in the text format of the arrays with
+// the text of the non-NULL elements, which are quoted if they are empty,
+// NULL, or contain the spaces or the special characters.
+func formatArray(elems []any, text func(any) string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, e := range elems {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if e == nil {
+			b.WriteString("NULL")
+			continue
+		}
+		s := text(e)
+		if s != "" && !strings.EqualFold(s, "null") &&
+			!strings.ContainsAny(s, "{}\",\\ \t\n\r") {
+			b.WriteString(s)
+			continue
+		}
+		b.WriteByte('"')
+		for i := 0; i < len(s); i++ {
+			if s[i] == '"' || s[i] == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(s[i])
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// parseTextArray parses the text format of the one-dimensional arrays,
+// e.g., '{a,NULL,"b c"}', into the texts of the elements, which are nil
+// for the unquoted NULL.
+func parseTextArray(s string) ([]any, error) {
+	malformed := newError(codeInvalidTextRepresent,
+		"malformed array literal: %q", s)
+	t := strings.TrimSpace(s)
+	if !strings.HasPrefix(t, "{") || !strings.HasSuffix(t, "}") {
+		return nil, malformed
+	}
+	t = t[1 : len(t)-1]
+	elems := []any{}
+	if strings.TrimSpace(t) == "" {
+		return elems, nil
+	}
+	var (
+		cur strings.Builder
+		// quoted tells whether the element is quoted, and bare whether it
+		// has the unquoted characters
+		quoted, inQuotes, bare bool
+	)
+	flush := func() error {
+		switch {
+		case quoted:
+			elems = append(elems, cur.String())
+		case !bare:
+			return malformed
+		default:
+			v := strings.TrimRight(cur.String(), " \t\n\r")
+			if strings.EqualFold(v, "null") {
+				elems = append(elems, nil)
+			} else {
+				elems = append(elems, v)
+			}
+		}
+		cur.Reset()
+		quoted, bare = false, false
+		return nil
+	}
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		switch {
+		case inQuotes:
+			if c == '\\' && i+1 < len(t) {
+				i++
+				cur.WriteByte(t[i])
+			} else if c == '"' {
+				inQuotes = false
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '{':
+			return nil, newError(codeFeatureNotSupported,
+				"multidimensional arrays are not supported")
+		case c == '}':
+			return nil, malformed
+		case c == '"':
+			if quoted || bare {
+				return nil, malformed
+			}
+			quoted, inQuotes = true, true
+		case c == ',':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			// the spaces around the elements are insignificant
+			if bare {
+				cur.WriteByte(c)
+			}
+		default:
+			if quoted {
+				return nil, malformed
+			}
+			if c == '\\' && i+1 < len(t) {
+				i++
+				c = t[i]
+			}
+			bare = true
+			cur.WriteByte(c)
+		}
+	}
+	if inQuotes {
+		return nil, malformed
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return elems, nil
+}
+
+// newArray builds the array of the elements of ARRAY[...], whose element
+// type is the common type of the elements: the integers are widened to
+// the decimals, and then to the floats, and the strings are parsed as the
+// other elements. The elements are strings if none of them is typed.
+func newArray(elems []any) (Array, error) {
+	var typ *Type
+	for _, e := range elems {
+		if _, ok := e.(string); ok || e == nil {
+			continue
+		}
+		et := typeOf(e)
+		switch {
+		case et.id == arrayID:
+			return Array{}, newError(codeFeatureNotSupported,
+				"multidimensional arrays are not supported")
+		case typ == nil || typ.compatible(et):
+			if typ == nil {
+				typ = et
+			}
+		case typ.numeric() && et.numeric():
+			if typ.id != floatID && et.id != floatID {
+				typ = decimalType
+			} else {
+				typ = floatType
+			}
+		default:
+			return Array{}, newError(codeDatatypeMismatch,
+				"ARRAY types %s and %s cannot be matched", typ, et)
+		}
+	}
+	if typ == nil {
+		typ = stringType
+	}
+	a := Array{elem: typ, elems: make([]any, len(elems))}
+	for i, e := range elems {
+		switch x := e.(type) {
+		case nil:
+		case string:
+			v, err := typ.parse(x)
+			if err != nil {
+				return Array{}, err
+			}
+			a.elems[i] = v
+		default:
+			a.elems[i] = widen(typ, x)
+		}
+	}
+	return a, nil
+}
+
+// ArrayExpr is the array constructor ARRAY[elem, ...], which is folded
+// into the literal by the parser if all the elements are literals.
+type ArrayExpr struct {
+	elems []Expr
+}
+
+func (a *ArrayExpr) Eval(env *evalEnv) (any, error) {
+	elems := make([]any, len(a.elems))
+	for i, e := range a.elems {
+		v, err := e.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = v
+	}
+	return newArray(elems)
+}
+
+func (a *ArrayExpr) String() string {
+	elems := make([]string, len(a.elems))
+	for i, e := range a.elems {
+		elems[i] = e.String()
+	}
+	return "ARRAY[" + strings.Join(elems, ", ") + "]"
+}
+
+// Subscript is the element of the array a[i], whose index starts from 1.
+// It is NULL if the index is out of the bounds.
+type Subscript struct {
+	expr, index Expr
+}
+
+func (s *Subscript) Eval(env *evalEnv) (any, error) {
+	v, err := s.expr.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	i, err := s.index.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || i == nil {
+		return nil, nil
+	}
+	a, ok := v.(Array)
+	if !ok {
+		return nil, newError(codeDatatypeMismatch,
+			"cannot subscript type %s because it is not an array",
+			valueType(v))
+	}
+	n, ok := i.(int)
+	if !ok {
+		return nil, newError(codeDatatypeMismatch,
+			"array subscript must have type integer, not %s", valueType(i))
+	}
+	if n < 1 || n > len(a.elems) {
+		return nil, nil
+	}
+	return a.elems[n-1], nil
+}
+
+func (s *Subscript) String() string {
+	return s.expr.String() + "[" + s.index.String() + "]"
+}
+
+// QuantifiedExpr is the comparison of the value with the elements of the
+// array, "left op ANY (array)" or "left op ALL (array)", which is true if
+// the comparison is true for any or all of the elements. As the chain of
+// ORs or ANDs, it is NULL if no element decides it but some comparison
+// is NULL.
+type QuantifiedExpr struct {
+	op          Operator
+	all         bool
+	left, array Expr
+}
+
+func (q *QuantifiedExpr) Eval(env *evalEnv) (any, error) {
+	lv, err := q.left.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	av, err := q.array.Eval(env)
+	if err != nil || av == nil {
+		return nil, err
+	}
+	if s, ok := av.(string); ok {
+		// the string is taken as the array of the left operand
+		typ := textArrayType
+		if lv != nil {
+			typ = newArrayType(typeOf(lv))
+		}
+		if av, err = typ.parse(s); err != nil {
+			return nil, err
+		}
+	}
+	a, ok := av.(Array)
+	if !ok {
+		return nil, newError(codeWrongObjectType,
+			"op ANY/ALL (array) requires array on right side")
+	}
+	unknown := false
+	for _, e := range a.elems {
+		if lv == nil || e == nil {
+			unknown = true
+			continue
+		}
+		c, err := compareValues(lv, e)
+		if err != nil {
+			return nil, err
+		}
+		if compared(q.op, c) != q.all {
+			// the comparison decides it
+			return !q.all, nil
+		}
+	}
+	if unknown {
+		return nil, nil
+	}
+	return q.all, nil
+}
+
+func (q *QuantifiedExpr) String() string {
+	quantifier := "ANY"
+	if q.all {
+		quantifier = "ALL"
+	}
+	return "(" + q.left.String() + " " + q.op.String() + " " + quantifier +
+		" (" + q.array.String() + "))"
+}
+
+// compareArrays compares the arrays element by element, and then by the
+// lengths. NULL is greater than the other elements and equal to itself.
+func compareArrays(a, b Array) (int, error) {
+	for i := 0; i < len(a.elems) && i < len(b.elems); i++ {
+		x, y := a.elems[i], b.elems[i]
+		switch {
+		case x == nil && y == nil:
+			continue
+		case x == nil:
+			return 1, nil
+		case y == nil:
+			return -1, nil
+		}
+		c, err := compareValues(x, y)
+		if err != nil || c != 0 {
+			return c, err
+		}
+	}
+	return compareOrdered(len(a.elems), len(b.elems)), nil
+}
+
+// arrayOperands converts the string operand to the array of the type of
+// the other one, as the untyped literal compared with the array.
+func arrayOperands(a, b any) (any, any, error) {
+	if x, ok := a.(Array); ok {
+		if s, ok := b.(string); ok {
+			v, err := typeOf(x).parse(s)
+			return a, v, err
+		}
+	}
+	if x, ok := b.(Array); ok {
+		if s, ok := a.(string); ok {
+			v, err := typeOf(x).parse(s)
+			return v, b, err
+		}
+	}
+	return a, b, nil
+}
+
+// arrayOperator applies the array operator to the non-NULL operands, at
+// least one of which is an array: @> and <@ test if all the elements of
+// one array are in the other, && if the arrays have an element in common,
+// and || concatenates the arrays, or appends or prepends the element.
+func arrayOperator(op Operator, a, b any) (any, error) {
+	a, b, err := arrayOperands(a, b)
+	if err != nil {
+		return nil, err
+	}
+	x, xok := a.(Array)
+	y, yok := b.(Array)
+	switch {
+	case op == concat && xok && yok:
+		return newArray(append(append([]any(nil), x.elems...), y.elems...))
+	case op == concat && xok:
+		return newArray(append(append([]any(nil), x.elems...), b))
+	case op == concat:
+		return newArray(append([]any{a}, y.elems...))
+	case !xok || !yok:
+		return nil, newError(codeUndefinedFunction,
+			"operator does not exist: %s %s %s", valueType(a), op, valueType(b))
+	case op == containedBy:
+		x, y = y, x
+	}
+	for _, e := range y.elems {
+		found, err := arrayHas(x, e)
+		if err != nil {
+			return nil, err
+		}
+		if op == overlaps && found {
+			return true, nil
+		}
+		if op != overlaps && !found {
+			return false, nil
+		}
+	}
+	return op != overlaps, nil
+}
+
+// arrayHas tells whether the array has the element, NULL is in no array.
+func arrayHas(a Array, elem any) (bool, error) {
+	if elem == nil {
+		return false, nil
+	}
+	for _, e := range a.elems {
+		if e == nil {
+			continue
+		}
+		c, err := compareValues(e, elem)
+		if err != nil {
+			return false, err
+		}
+		if c == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// arrayLength returns the length of the dimension of the array, which is
+// NULL for the empty arrays and the dimensions other than 1.
+func arrayLength(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	a, ok := args[0].(Array)
+	dim, dok := args[1].(int)
+	if !ok || !dok {
+		return nil, newError(codeUndefinedFunction,
+			"function array_length(%s, %s) does not exist",
+			valueType(args[0]), valueType(args[1]))
+	}
+	if dim != 1 || len(a.elems) == 0 {
+		return nil, nil
+	}
+	return len(a.elems), nil
+}
+
+// unnest returns the set of the elements of the array.
+func unnest(env *evalEnv, args []any) ([]any, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	a, ok := args[0].(Array)
+	if !ok {
+		return nil, newError(codeUndefinedFunction,
+			"function unnest(%s) does not exist", valueType(args[0]))
+	}
+	return a.elems, nil
+}
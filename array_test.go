@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestArrayExpressions(t *testing.T) {
+	tts := []struct {
+		expr   string
+		code   string
+		expect string
+	}{
+		{"ARRAY[1, 2, 3]", "", "{1,2,3}"},
+		{"ARRAY[1, 2.5, decimal '3']", "", "{1,2.5,3}"},
+		{"ARRAY[1, 2.5::float]", "", "{1.0,2.5}"},
+		{"ARRAY[1, decimal '2.50']", "", "{1,2.50}"},
+		{"ARRAY['a', NULL, 'b c', '', 'null', 'x\"y']", "",
+			`{a,NULL,"b c","","null","x\"y"}`},
+		{"ARRAY[date '2024-01-02', '2024-01-01']", "", "{2024-01-02,2024-01-01}"},
+		{"ARRAY[1, 'a']", codeInvalidTextRepresent, ""},
+		{"ARRAY[1, true]", codeDatatypeMismatch, ""},
+		{"ARRAY[] is null", codeIndeterminateDatatype, ""},
+		{"ARRAY[]::integer[]", "", "{}"},
+		{"CAST(ARRAY[] AS text[]) || ARRAY['a']", "", "{a}"},
+		{"array_length(ARRAY[]::integer[] || 1, 1)", "", "1"},
+		{"ARRAY[]::integer[] = ARRAY[]", codeIndeterminateDatatype, ""},
+		{"ARRAY[]::integer", codeIndeterminateDatatype, ""},
+		{"ARRAY[ARRAY[1]] is null", codeFeatureNotSupported, ""},
+		{"ARRAY[10, 20, 30][2]", "", "20"},
+		{"ARRAY[10, 20, 30][4] is null", "", "true"},
+		{"ARRAY[10, 20, 30][0] is null", "", "true"},
+		{"(ARRAY[1] || 2)[2]", "", "2"},
+		{"1[1] is null", codeDatatypeMismatch, ""},
+		{"ARRAY[1, 2] = '{1,2}'", "", "true"},
+		{"ARRAY[1, 2] < ARRAY[1, 2, 0]", "", "true"},
+		{"ARRAY[1, 3] > ARRAY[1, 2, 0]", "", "true"},
+		{"ARRAY[1, NULL] > ARRAY[1, 2]", "", "true"},
+		{"ARRAY[1, 2] = ARRAY['a']", codeUndefinedFunction, ""},
+		{"ARRAY[1, 2] = '{a}'", codeInvalidTextRepresent, ""},
+		{"ARRAY['a', 'b'] = ARRAY[1]", codeUndefinedFunction, ""},
+		{"2 = ANY (ARRAY[1, 2])", "", "true"},
+		{"3 = SOME ('{1,2}')", "", "false"},
+		{"3 = ANY (ARRAY[1, NULL]) is null", "", "true"},
+		{"1 = ANY (ARRAY[1, NULL])", "", "true"},
+		{"3 > ALL (ARRAY[1, 2])", "", "true"},
+		{"2 > ALL (ARRAY[1, 2])", "", "false"},
+		{"NULL = ANY ('{}')", "", "false"},
+		{"1 = ANY (1)", codeWrongObjectType, ""},
+		{"ARRAY[1, 2, 3] @> ARRAY[3, 1]", "", "true"},
+		{"ARRAY[1, 2, 3] @> '{1,4}'", "", "false"},
+		{"ARRAY[1, NULL] @> ARRAY[1, NULL]", "", "false"},
+		{"'{1,2}' <@ ARRAY[2, 1, 3]", "", "true"},
+		{"ARRAY['a', 'b'] && ARRAY['c', 'b']", "", "true"},
+		{"ARRAY['a', 'b'] && '{c}'", "", "false"},
+		{"ARRAY[1, 2] || ARRAY[3]", "", "{1,2,3}"},
+		{"0 || ARRAY[1]", "", "{0,1}"},
+		{"ARRAY[1] || 1.5", "", "{1,1.5}"},
+		{"array_length(ARRAY[1, 2, 3], 1)", "", "3"},
+		{"array_length(ARRAY[1, 2, 3], 2) is null", "", "true"},
+		{"array_length('{}', 1) is null", codeUndefinedFunction, ""},
+		{"unnest(ARRAY[1]) = 1", codeFeatureNotSupported, ""},
+	}
+	env := &evalEnv{sess: NewSession(defaultSuperuser)}
+	for i, tt := range tts {
+		got, err := evalText(env, tt.expr)
+		if sqlState(err) != tt.code && (err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.expr, err, tt.code)
+		}
+		if got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.expr, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.expr)
+	}
+}
+
+func TestParseTextArray(t *testing.T) {
+	tts := []struct {
+		text   string
+		code   string
+		expect []any
+	}{
+		{"{}", "", []any{}},
+		{" { a , b c ,NULL, \"NULL\" , \"\" } ", "",
+			[]any{"a", "b c", nil, "NULL", ""}},
+		{`{"a,b","c\"d",e\,f}`, "", []any{"a,b", `c"d`, "e,f"}},
+		{"{a,,b}", codeInvalidTextRepresent, nil},
+		{`{"a"b}`, codeInvalidTextRepresent, nil},
+		{`{"a}`, codeInvalidTextRepresent, nil},
+		{"a,b", codeInvalidTextRepresent, nil},
+		{"{{1},{2}}", codeFeatureNotSupported, nil},
+	}
+	for i, tt := range tts {
+		got, err := parseTextArray(tt.text)
+		if sqlState(err) != tt.code && (err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.text, err, tt.code)
+		}
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Fatalf("case %d (%s) failed: got %#v, expect %#v",
+				i, tt.text, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.text)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	for i, s := range []string{
+		"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11",
+		"{a0eebc99-9c0b4ef8-bb6d6bb9-bd380a11}",
+		"a0eebc999c0b4ef8bb6d6bb9bd380a11",
+		"a0ee-bc99-9c0b-4ef8-bb6d-6bb9-bd38-0a11",
+	} {
+		u, err := parseUUID(s)
+		if err != nil || u.String() != "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11" {
+			t.Fatalf("case %d (%s) failed: got %v (%v)", i, s, u, err)
+		}
+	}
+	for i, s := range []string{
+		"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a1",
+		"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11-",
+		"-a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"a0eeb-c99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"a0eebc99--9c0b-4ef8-bb6d-6bb9bd380a11",
+		"{a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"g0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+	} {
+		if _, err := parseUUID(s); sqlState(err) != codeInvalidTextRepresent {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, s, err, codeInvalidTextRepresent)
+		}
+	}
+
+	v4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, _ := genRandomUUID(nil, nil)
+	b, _ := genRandomUUID(nil, nil)
+	if !v4.MatchString(a.(UUID).String()) || a == b {
+		t.Fatalf("got %v and %v, expect distinct UUIDs of version 4", a, b)
+	}
+}
+
+func TestUUIDAndArrayColumns(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table items (id uuid primary key default gen_random_uuid(), " +
+			"tags text[] default '{}', scores integer[], prices decimal(5, 2)[])",
+		"insert into items values ('00000000-0000-4000-8000-000000000002', " +
+			"'{red,\"dark blue\"}', ARRAY[3, 1, 2], '{1.5}')",
+		"insert into items values ('00000000-0000-4000-8000-000000000001', " +
+			"ARRAY['green'], '{}', NULL)",
+		"insert into items (tags, scores) values (ARRAY['red', 'green'], '{5,NULL}')",
+		"create table paths (path text[] primary key, n bigint[])",
+		"insert into paths values ('{a,b}', ARRAY[1])",
+		"insert into paths values ('{a}', '{2,3}')",
+		"insert into paths values ('{b}', NULL)",
+		"insert into paths values ('{a,NULL}', NULL)",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		expect []string
+	}{
+		{"Uuid key", "select tags from items where " +
+			"id = '00000000-0000-4000-8000-000000000001'", "", []string{"{green}"}},
+		{"Uuid order", "select tags from items where " +
+			"id <= uuid '00000000-0000-4000-8000-000000000002'", "",
+			[]string{"{green}", `{red,"dark blue"}`}},
+		{"Any", "select scores from items where 'red' = ANY (tags)", "",
+			[]string{"{3,1,2}", "{5,NULL}"}},
+		{"Contains", "select scores from items where tags @> '{red,green}'", "",
+			[]string{"{5,NULL}"}},
+		{"Subscript", "select tags from items where scores[1] > 2", "",
+			[]string{`{red,"dark blue"}`, "{red,green}"}},
+		{"Decimal elements", "select prices from items where prices[1] = 1.5", "",
+			[]string{"{1.50}"}},
+		{"Array length", "select tags from items where " +
+			"array_length(scores, 1) is null", "", []string{"{green}"}},
+		{"Append", "update items set tags = tags || ARRAY['blue'] where " +
+			"id = '00000000-0000-4000-8000-000000000001'", "", nil},
+		{"Appended", "select tags from items where tags && '{blue}'", "",
+			[]string{"{green,blue}"}},
+		{"Array key order", "select path from paths", "",
+			[]string{"{a}", "{a,b}", "{a,NULL}", "{b}"}},
+		{"Array key", "select n from paths where path = ARRAY['a', 'b']", "",
+			[]string{"{1}"}},
+		{"Array key range", "select path from paths where path > '{a,b}' and " +
+			"path < '{b}'", "", []string{"{a,NULL}"}},
+		{"Duplicate uuid", "insert into items (id) values " +
+			"('{00000000-0000-4000-8000-000000000001}')", codeUniqueViolation, nil},
+		{"Duplicate array", "insert into paths values (ARRAY['a'], NULL)",
+			codeUniqueViolation, nil},
+		{"Invalid uuid", "insert into items (id) values ('x')",
+			codeInvalidTextRepresent, nil},
+		{"Element type", "insert into items (scores) values (ARRAY[true])",
+			codeDatatypeMismatch, nil},
+		{"Element range", "insert into items (scores) values (ARRAY[3000000000])",
+			codeNumericOutOfRange, nil},
+		{"Json array key", "create table j (id json[] primary key)",
+			codeUndefinedObject, nil},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		var got []string
+		for _, row := range r.rows {
+			got = append(got, toText(row.fields[r.cols[0]]))
+		}
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.name, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	r := execSQL(db, defaultSuperuser, "select id, tags, scores from items "+
+		"where id = '00000000-0000-4000-8000-000000000002'")
+	if r.err != nil || len(r.rows) != 1 {
+		t.Fatalf("got %d rows (%v), expect 1", len(r.rows), r.err)
+	}
+	b, err := json.Marshal(r.rows[0].fields)
+	if expect := `{"id":"00000000-0000-4000-8000-000000000002",` +
+		`"scores":[3,1,2],"tags":["red","dark blue"]}`; err != nil ||
+		string(b) != expect {
+		t.Fatalf("got %s (%v), expect %s", b, err, expect)
+	}
+}
+
+func TestEncodeArray(t *testing.T) {
+	typ := newArrayType(integerType)
+	a := Array{elem: integerType, elems: []any{1, nil, -2}}
+	if got := string(encodeText(a, typ)); got != "{1,NULL,-2}" {
+		t.Fatalf("got %q, expect {1,NULL,-2}", got)
+	}
+	raw, err := encodeValue(a, typ, formatBinary)
+	if err != nil {
+		t.Fatalf("failed to encode %v: %v", a, err)
+	}
+	got, err := decodeParam(raw, typ, formatBinary)
+	if err != nil || !reflect.DeepEqual(got, a) {
+		t.Fatalf("got %#v (%v), expect %#v", got, err, a)
+	}
+	if oid, _ := typeToOID(typ); oidToType(oid).String() != "integer[]" {
+		t.Fatalf("got type %s of oid %d, expect integer[]", oidToType(oid), oid)
+	}
+	ts := Array{elem: timestampType, elems: []any{Timestamp{pgEpoch}}}
+	if got := string(encodeText(ts, newArrayType(timestampType))); got !=
+		`{"2000-01-01 00:00:00"}` {
+		t.Fatalf(`got %q, expect {"2000-01-01 00:00:00"}`, got)
+	}
+}
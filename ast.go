@@ -0,0 +1,291 @@
+package main
+
+import (
+	"strings"
+)
+
+// Statement is a parsed SQL statement.
+type Statement interface {
+	statementNode()
+}
+
+// TableRef refers to a table by its name.
+type TableRef struct {
+	name string
+}
+
+// SelectItem is an expression in the select list, e.g., "name AS n".
+type SelectItem struct {
+	expr Expr
+	// alias is empty if not given
+	alias string
+}
+
+// ColumnDef is the column definition of the CREATE TABLE statement.
+type ColumnDef struct {
+	name       string
+	typ        *Type
+	primaryKey bool
+	// defaultValue is nil if there is no DEFAULT
+	defaultValue Expr
+	// constraints are the constraints declared with the column
+	constraints []*Constraint
+	// serial is true for the SERIAL column, whose default is taken from
+	// the sequence owned by the column
+	serial bool
+	// identity is nil unless GENERATED ... AS IDENTITY
+	identity *Identity
+}
+
+// Identity is the "GENERATED {ALWAYS | BY DEFAULT} AS IDENTITY" of the
+// column, whose values are taken from the sequence owned by the column.
+type Identity struct {
+	// always rejects the values given by INSERT and UPDATE
+	always  bool
+	options SequenceOptions
+}
+
+// SequenceOptions are the options of the sequence, nil if not given.
+type SequenceOptions struct {
+	increment *int
+	minValue  *int
+	maxValue  *int
+	start     *int
+	cycle     *bool
+}
+
+// ConstraintKind is the kind of the table constraint.
+type ConstraintKind int
+
+const (
+	notNullConstraint ConstraintKind = iota
+	uniqueConstraint
+	checkConstraint
+	foreignKeyConstraint
+	primaryKeyConstraint
+)
+
+// RefAction is the action taken on the referencing rows when the
+// referenced row is deleted or its key is updated.
+type RefAction int
+
+const (
+	// noAction fails unless the key is still present at the end of the
+	// statement
+	noAction RefAction = iota
+	restrict
+	cascade
+	setNull
+)
+
+func (a RefAction) String() string {
+	switch a {
+	case restrict:
+		return "RESTRICT"
+	case cascade:
+		return "CASCADE"
+	case setNull:
+		return "SET NULL"
+	default:
+		return "NO ACTION"
+	}
+}
+
+// Constraint is a NOT NULL, UNIQUE, CHECK, FOREIGN KEY or PRIMARY KEY
+// constraint of the table.
+type Constraint struct {
+	// name is empty if not given, in which case the table generates one,
+	// e.g., t_name_key
+	name string
+	kind ConstraintKind
+	// columns are the constrained columns, which is the column itself
+	// for a column constraint and empty for a table CHECK constraint
+	columns []string
+	// check is the expression of the CHECK constraint
+	check Expr
+	// refTable and refColumns are referenced by the FOREIGN KEY
+	// constraint, refColumns is nil for the primary key of the refTable
+	refTable   string
+	refColumns []string
+	onDelete   RefAction
+	onUpdate   RefAction
+}
+
+// String returns the definition of the constraint without the name,
+// e.g., "UNIQUE (a, b)".
+func (c *Constraint) String() string {
+	switch c.kind {
+	case notNullConstraint:
+		return "NOT NULL " + strings.Join(c.columns, ", ")
+	case uniqueConstraint:
+		return "UNIQUE (" + strings.Join(c.columns, ", ") + ")"
+	case primaryKeyConstraint:
+		return "PRIMARY KEY (" + strings.Join(c.columns, ", ") + ")"
+	case foreignKeyConstraint:
+		s := "FOREIGN KEY (" + strings.Join(c.columns, ", ") + ") REFERENCES " +
+			c.refTable + "(" + strings.Join(c.refColumns, ", ") + ")"
+		if c.onDelete != noAction {
+			s += " ON DELETE " + c.onDelete.String()
+		}
+		if c.onUpdate != noAction {
+			s += " ON UPDATE " + c.onUpdate.String()
+		}
+		return s
+	default:
+		return "CHECK (" + c.check.String() + ")"
+	}
+}
+
+type CreateStatement struct {
+	table   *TableRef
+	columns []*ColumnDef
+	// constraints are the table constraints following the columns
+	constraints []*Constraint
+}
+
+// primaryKey returns the columns of the primary key, which is either
+// declared with the column or as the table constraint.
+func (cs *CreateStatement) primaryKey() []string {
+	for _, col := range cs.columns {
+		if col.primaryKey {
+			return []string{col.name}
+		}
+	}
+	for _, c := range cs.constraints {
+		if c.kind == primaryKeyConstraint {
+			return c.columns
+		}
+	}
+	return nil
+}
+
+type SelectStatement struct {
+	// items is nil for "SELECT *"
+	items []*SelectItem
+	table *TableRef
+	// where is nil if there is no WHERE clause
+	where Expr
+}
+
+type InsertStatement struct {
+	table *TableRef
+	// columns is nil if the values are given for all columns in order
+	columns []string
+	values  []Expr
+}
+
+type DeleteStatement struct {
+	table *TableRef
+	where Expr
+}
+
+type UpdateStatement struct {
+	table       *TableRef
+	assignments []*Assignment
+	where       Expr
+}
+
+// Assignment is the "column = value" of the UPDATE statement.
+type Assignment struct {
+	column string
+	value  Expr
+}
+
+type DropStatement struct {
+	table *TableRef
+	// cascade drops the foreign keys referencing the table as well
+	cascade bool
+}
+
+type SetStatement struct {
+	name  string
+	value string
+}
+
+type CreateUserStatement struct {
+	name string
+	// nil if the option is not given
+	password  *string
+	superuser *bool
+}
+
+type AlterUserStatement struct {
+	name      string
+	password  *string
+	superuser *bool
+}
+
+type DropUserStatement struct {
+	name string
+}
+
+type CreateSequenceStatement struct {
+	name    string
+	options SequenceOptions
+}
+
+type DropSequenceStatement struct {
+	name string
+}
+
+// CreateIndexStatement creates the index on the columns and the
+// expressions of the table, the name is generated if empty.
+type CreateIndexStatement struct {
+	name  string
+	table *TableRef
+	exprs []Expr
+}
+
+type DropIndexStatement struct {
+	name string
+}
+
+// CreatePolicyStatement creates the row-level security policy on the
+// table for the command, i.e., PrivAll, PrivSelect, PrivInsert,
+// PrivUpdate or PrivDelete.
+type CreatePolicyStatement struct {
+	name    string
+	table   *TableRef
+	command Privilege
+	using   Expr
+	check   Expr
+}
+
+type DropPolicyStatement struct {
+	name  string
+	table *TableRef
+}
+
+// AlterTableStatement enables or disables the row-level security.
+type AlterTableStatement struct {
+	table       *TableRef
+	rowSecurity bool
+}
+
+// GrantStatement grants or revokes the privileges on the table, which is
+// allTables for "ON ALL TABLES".
+type GrantStatement struct {
+	privileges Privilege
+	table      string
+	user       string
+	revoke     bool
+}
+
+func (*CreateStatement) statementNode()         {}
+func (*SelectStatement) statementNode()         {}
+func (*InsertStatement) statementNode()         {}
+func (*DeleteStatement) statementNode()         {}
+func (*UpdateStatement) statementNode()         {}
+func (*DropStatement) statementNode()           {}
+func (*SetStatement) statementNode()            {}
+func (*CreateUserStatement) statementNode()     {}
+func (*AlterUserStatement) statementNode()      {}
+func (*DropUserStatement) statementNode()       {}
+func (*CreatePolicyStatement) statementNode()   {}
+func (*DropPolicyStatement) statementNode()     {}
+func (*CreateSequenceStatement) statementNode() {}
+func (*DropSequenceStatement) statementNode()   {}
+func (*CreateIndexStatement) statementNode()    {}
+func (*DropIndexStatement) statementNode()      {}
+func (*AlterTableStatement) statementNode()     {}
+func (*GrantStatement) statementNode()          {}
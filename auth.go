@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"sort"
+	"strings"
+)
+
+const (
+	// defaultSuperuser is created along with the database, it has no
+	// password until one is set.
+	defaultSuperuser = "simpledb"
+	// allTables is the table name of the privileges granted on all tables.
+	allTables = "*"
+
+	saltSize   = 16
+	hashRounds = 4096
+)
+
+// Privilege is a set of the table-level privileges.
+type Privilege int
+
+const (
+	PrivSelect Privilege = 1 << iota
+	PrivInsert
+	PrivUpdate
+	PrivDelete
+	PrivCreate
+	PrivDrop
+	// PrivReferences allows the foreign keys referencing the table
+	PrivReferences
+
+	PrivAll = PrivSelect | PrivInsert | PrivUpdate |
+		PrivDelete | PrivCreate | PrivDrop | PrivReferences
+)
+
+var privilegeNames = []struct {
+	priv Privilege
+	name string
+}{
+	{PrivSelect, "SELECT"},
+	{PrivInsert, "INSERT"},
+	{PrivUpdate, "UPDATE"},
+	{PrivDelete, "DELETE"},
+	{PrivCreate, "CREATE"},
+	{PrivDrop, "DROP"},
+	{PrivReferences, "REFERENCES"},
+}
+
+func (p Privilege) String() string {
+	var names []string
+	for _, pn := range privilegeNames {
+		if p&pn.priv != 0 {
+			names = append(names, pn.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func stringToPrivilege(str string) (Privilege, bool) {
+	for _, pn := range privilegeNames {
+		if strings.EqualFold(pn.name, str) {
+			return pn.priv, true
+		}
+	}
+	return 0, false
+}
+
+type User struct {
+	name      string
+	superuser bool
+	// salt and hash are empty if the password is not set, such a user
+	// cannot be authenticated
+	salt []byte
+	hash []byte
+	// privileges are keyed by the table name, allTables for the
+	// privileges granted on all tables
+	privileges map[string]Privilege
+}
+
+func newUser(name string, superuser bool) *User {
+	return &User{
+		name:       name,
+		superuser:  superuser,
+		privileges: make(map[string]Privilege),
+	}
+}
+
+func (u *User) setPassword(password string) error {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	u.salt = salt
+	u.hash = hashPassword(password, salt)
+	return nil
+}
+
+func (u *User) checkPassword(password string) bool {
+	if len(u.hash) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare(
+		hashPassword(password, u.salt), u.hash) == 1
+}
+
+// hashPassword derives the hash from the password with PBKDF2-HMAC-SHA256.
+func hashPassword(password string, salt []byte) []byte {
+	prf := hmac.New(sha256.New, []byte(password))
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1})
+	u := prf.Sum(nil)
+	hash := append([]byte(nil), u...)
+	for i := 1; i < hashRounds; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range hash {
+			hash[j] ^= u[j]
+		}
+	}
+	return hash
+}
+
+// Authenticate checks the password of the user.
+func (db *Database) Authenticate(name, password string) error {
+	db.RLock()
+	defer db.RUnlock()
+	u, exist := db.users[name]
+	if !exist || !u.checkPassword(password) {
+		return newError(codeInvalidPassword,
+			"password authentication failed for user %q", name)
+	}
+	return nil
+}
+
+// SetPassword sets the password of the user.
+func (db *Database) SetPassword(name, password string) error {
+	db.Lock()
+	defer db.Unlock()
+	u, exist := db.users[name]
+	if !exist {
+		return newError(codeUndefinedObject, "role %q does not exist", name)
+	}
+	return u.setPassword(password)
+}
+
+// sessionUser returns the user of the session carried by the ctx.
+func (db *Database) sessionUser(ctx context.Context) (*User, error) {
+	sess := SessionFromContext(ctx)
+	if sess == nil {
+		return nil, newError(codeInvalidAuthorization, "no session user")
+	}
+	db.RLock()
+	defer db.RUnlock()
+	u, exist := db.users[sess.User()]
+	if !exist {
+		return nil, newError(codeInvalidAuthorization,
+			"role %q does not exist", sess.User())
+	}
+	return u, nil
+}
+
+// authorize checks if the user is allowed to execute the statement.
+func (db *Database) authorize(u *User, sts Statement) error {
+	db.RLock()
+	defer db.RUnlock()
+	if u.superuser {
+		return nil
+	}
+
+	var (
+		table string
+		priv  Privilege
+	)
+	switch s := sts.(type) {
+	case *SetStatement:
+		return nil
+	case *SelectStatement:
+		table, priv = s.table.name, PrivSelect
+	case *InsertStatement:
+		table, priv = s.table.name, PrivInsert
+	// the conditions and the values reading the columns reveal the rows
+	// by the affected counts, which requires SELECT as well
+	case *DeleteStatement:
+		table, priv = s.table.name, PrivDelete
+		if len(columnRefs(s.where)) != 0 {
+			priv |= PrivSelect
+		}
+	case *UpdateStatement:
+		table, priv = s.table.name, PrivUpdate
+		refs := columnRefs(s.where)
+		for _, a := range s.assignments {
+			refs = append(refs, columnRefs(a.value)...)
+		}
+		if len(refs) != 0 {
+			priv |= PrivSelect
+		}
+	case *CreateStatement:
+		if err := db.checkReferences(u, s); err != nil {
+			return err
+		}
+		table, priv = s.table.name, PrivCreate
+	case *DropStatement:
+		table, priv = s.table.name, PrivDrop
+	case *AlterUserStatement:
+		// users can change their own passwords
+		if s.name == u.name && s.superuser == nil {
+			return nil
+		}
+		return newError(codeInsufficientPrivilege,
+			"permission denied to alter role %q", s.name)
+	case *GrantStatement:
+		// the owner can grant the privileges on the table
+		if t, exist := db.tables[s.table]; exist && t.owner == u.name {
+			return nil
+		}
+		return newError(codeInsufficientPrivilege,
+			"permission denied for table %s", s.table)
+	// only the owner can manage the row-level security of the table
+	case *CreatePolicyStatement:
+		return db.checkOwner(u, s.table.name)
+	case *DropPolicyStatement:
+		return db.checkOwner(u, s.table.name)
+	case *AlterTableStatement:
+		return db.checkOwner(u, s.table.name)
+	// only the owner can index the table
+	case *CreateIndexStatement:
+		return db.checkOwner(u, s.table.name)
+	case *DropIndexStatement:
+		if _, table := db.lookupIndex(s.name); table != "" {
+			return db.checkOwner(u, table)
+		}
+		return nil
+	default:
+		return newError(codeInsufficientPrivilege,
+			"permission denied, superuser is required")
+	}
+
+	if t, exist := db.tables[table]; exist && t.owner == u.name {
+		return nil
+	}
+	if (u.privileges[table]|u.privileges[allTables])&priv != priv {
+		return newError(codeInsufficientPrivilege,
+			"permission denied for table %s", table)
+	}
+	return nil
+}
+
+// VisibleTables returns the names of the tables in order which the user
+// of the session owns or holds any privilege on, all the tables for the
+// superusers. CREATE alone reveals no table, since it only allows the
+// tables to be created.
+func (db *Database) VisibleTables(ctx context.Context) ([]string, error) {
+	u, err := db.sessionUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db.RLock()
+	defer db.RUnlock()
+	names := make([]string, 0, len(db.tables))
+	for name, t := range db.tables {
+		granted := u.privileges[name] | u.privileges[allTables]
+		if u.superuser || t.owner == u.name || granted&^PrivCreate != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// checkOwner checks if the user owns the table. The caller must hold the
+// lock.
+func (db *Database) checkOwner(u *User, table string) error {
+	if t, exist := db.tables[table]; exist && t.owner == u.name {
+		return nil
+	}
+	return newError(codeInsufficientPrivilege,
+		"must be owner of table %s", table)
+}
+
+// checkReferences checks if the user owns or has the REFERENCES privilege
+// on the tables referenced by the foreign keys of the new table, which
+// would otherwise reveal the keys of the tables and block their changes.
+// The caller must hold the lock.
+func (db *Database) checkReferences(u *User, cs *CreateStatement) error {
+	constraints := append([]*Constraint(nil), cs.constraints...)
+	for _, col := range cs.columns {
+		constraints = append(constraints, col.constraints...)
+	}
+	for _, c := range constraints {
+		if c.kind != foreignKeyConstraint || c.refTable == cs.table.name {
+			continue
+		}
+		t, exist := db.tables[c.refTable]
+		if !exist || t.owner == u.name ||
+			(u.privileges[c.refTable]|u.privileges[allTables])&PrivReferences != 0 {
+			continue
+		}
+		return newError(codeInsufficientPrivilege,
+			"permission denied for table %s", c.refTable)
+	}
+	return nil
+}
+
+func (db *Database) CreateUser(ctx context.Context, cs *CreateUserStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	if _, exist := db.users[cs.name]; exist {
+		return &Result{
+			err: newError(codeDuplicateObject,
+				"role %q already exists", cs.name),
+		}
+	}
+	u := newUser(cs.name, cs.superuser != nil && *cs.superuser)
+	if cs.password != nil {
+		if err := u.setPassword(*cs.password); err != nil {
+			return &Result{
+				err: err,
+			}
+		}
+	}
+	db.users[cs.name] = u
+	return &Result{
+		message: "USER CREATED",
+	}
+}
+
+func (db *Database) AlterUser(ctx context.Context, as *AlterUserStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	u, exist := db.users[as.name]
+	if !exist {
+		return &Result{
+			err: newError(codeUndefinedObject,
+				"role %q does not exist", as.name),
+		}
+	}
+	if as.password != nil {
+		if err := u.setPassword(*as.password); err != nil {
+			return &Result{
+				err: err,
+			}
+		}
+	}
+	if as.superuser != nil {
+		u.superuser = *as.superuser
+	}
+	return &Result{
+		message: "USER ALTERED",
+	}
+}
+
+func (db *Database) DropUser(ctx context.Context, ds *DropUserStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	if _, exist := db.users[ds.name]; !exist {
+		return &Result{
+			err: newError(codeUndefinedObject,
+				"role %q does not exist", ds.name),
+		}
+	}
+	if ds.name == SessionFromContext(ctx).User() {
+		return &Result{
+			err: newError(codeObjectInUse,
+				"current user cannot be dropped"),
+		}
+	}
+	var owned []string
+	for name, t := range db.tables {
+		if t.owner == ds.name {
+			owned = append(owned, name)
+		}
+	}
+	if len(owned) != 0 {
+		sort.Strings(owned)
+		return &Result{
+			err: newError(codeDependentObjects,
+				"role %q cannot be dropped because it owns tables: %s",
+				ds.name, strings.Join(owned, ", ")),
+		}
+	}
+	delete(db.users, ds.name)
+	return &Result{
+		message: "USER DROPPED",
+	}
+}
+
+func (db *Database) Grant(ctx context.Context, gs *GrantStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	u, exist := db.users[gs.user]
+	if !exist {
+		return &Result{
+			err: newError(codeUndefinedObject,
+				"role %q does not exist", gs.user),
+		}
+	}
+	if _, exist := db.tables[gs.table]; !exist &&
+		gs.table != allTables && gs.privileges != PrivCreate {
+		// CREATE can be granted on a table that does not exist yet
+		return &Result{
+			err: newError(codeUndefinedTable,
+				"relation %s does not exist", gs.table),
+		}
+	}
+
+	if gs.revoke {
+		u.privileges[gs.table] &^= gs.privileges
+		if u.privileges[gs.table] == 0 {
+			delete(u.privileges, gs.table)
+		}
+		return &Result{
+			message: "REVOKED",
+		}
+	}
+	u.privileges[gs.table] |= gs.privileges
+	return &Result{
+		message: "GRANTED",
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// execSQL executes the sql on behalf of the user.
+func execSQL(db *Database, user, sql string) *Result {
+	sts, err := parseSQL(sql)
+	if err != nil {
+		return &Result{err: err}
+	}
+	ctx := WithSession(context.Background(), NewSession(user))
+	return db.Interpret(ctx, sts)
+}
+
+func TestAuthenticate(t *testing.T) {
+	db := NewDatabase()
+	if err := db.Authenticate(defaultSuperuser, ""); err == nil {
+		t.Fatalf("expect the user without password to be rejected")
+	}
+	r := execSQL(db, defaultSuperuser,
+		"CREATE USER alice WITH PASSWORD 'pw'")
+	if r.err != nil {
+		t.Fatalf("failed to create the user: %v", r.err)
+	}
+	if err := db.Authenticate("alice", "pw"); err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if err := db.Authenticate("alice", "wrong"); err == nil {
+		t.Fatalf("expect the wrong password to be rejected")
+	}
+	if u := db.users["alice"]; string(u.hash) == "pw" || len(u.salt) == 0 {
+		t.Fatalf("expect the password to be stored with a salted hash")
+	}
+}
+
+func TestPrivileges(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create user alice with password 'pw'",
+		"create user bob with password 'pw'",
+		"create table t (id integer primary key)",
+		"insert into t (id) values (1)",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	tts := []struct {
+		name    string
+		user    string
+		sql     string
+		allowed bool
+	}{
+		{"Select without privilege", "alice", "select * from t", false},
+		{"Grant by non-owner", "alice", "grant select on t to alice", false},
+		{"Grant select", defaultSuperuser, "grant select, delete on table t to alice", true},
+		{"Select with privilege", "alice", "select * from t", true},
+		{"Insert without privilege", "alice", "insert into t (id) values (2)", false},
+		{"Drop without privilege", "alice", "drop table t", false},
+		{"Revoke select", defaultSuperuser, "revoke select on t from alice", true},
+		{"Select after revoke", "alice", "select * from t", false},
+		{"Delete by condition without select", "alice",
+			"delete from t where id = 1", false},
+		{"Delete is kept", "alice", "delete from t", true},
+		{"Grant update", defaultSuperuser, "grant update on t to alice", true},
+		{"Update by value", "alice", "update t set id = 2", true},
+		{"Update by column without select", "alice",
+			"update t set id = id + 1", false},
+		{"Update by condition without select", "alice",
+			"update t set id = 2 where id = 1", false},
+		{"Create without privilege", "bob", "create table b (id integer primary key)", false},
+		{"Grant create on all tables", defaultSuperuser, "grant create on all tables to bob", true},
+		{"Create with privilege", "bob", "create table b (id integer primary key)", true},
+		{"Owner inserts", "bob", "insert into b (id) values (1)", true},
+		{"References without privilege", "bob",
+			"create table r (id integer primary key references t)", false},
+		{"Foreign key without privilege", "bob", "create table r (id integer " +
+			"primary key, foreign key (id) references t (id))", false},
+		{"Self reference", "bob",
+			"create table s (id integer primary key, p integer references s)", true},
+		{"Grant references", defaultSuperuser, "grant references on t to bob", true},
+		{"References with privilege", "bob",
+			"create table r (id integer primary key references t)", true},
+		{"Owner grants", "bob", "grant all privileges on b to alice", true},
+		{"Insert with granted privilege", "alice", "insert into b (id) values (2)", true},
+		{"Change own password", "alice", "alter user alice password 'new'", true},
+		{"Become superuser", "alice", "alter user alice superuser", false},
+		{"Create user by non-superuser", "alice", "create user eve", false},
+		{"Drop user owning tables", defaultSuperuser, "drop user bob", false},
+		{"Drop table by owner", "bob", "drop table b", true},
+		{"Drop referencing table", "bob", "drop table r", true},
+		{"Drop self-referencing table", "bob", "drop table s", true},
+		{"Drop user", defaultSuperuser, "drop user bob", true},
+		{"Dropped user", "bob", "select * from t", false},
+	}
+
+	for i, tt := range tts {
+		r := execSQL(db, tt.user, tt.sql)
+		if (r.err == nil) != tt.allowed {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect allowed(%v)",
+				i, tt.name, r.err, tt.allowed)
+		}
+		if r.err != nil && sqlState(r.err) != codeInsufficientPrivilege &&
+			sqlState(r.err) != codeDependentObjects &&
+			sqlState(r.err) != codeInvalidAuthorization {
+			t.Fatalf("case %d (%s) failed: unexpected error %v",
+				i, tt.name, r.err)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
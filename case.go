@@ -0,0 +1,90 @@
+package main
+
+import "strings"
+
+// CaseExpr is the conditional expression
+// "CASE [operand] WHEN cond THEN result [...] [ELSE result] END". The
+// operand, if any, is compared with the conditions for equality, or else
+// the conditions are evaluated as booleans. The result of the first
+// matched condition is taken, or the ELSE result, NULL if there is none.
+type CaseExpr struct {
+	// operand is nil for the searched CASE
+	operand Expr
+	whens   []*CaseWhen
+	// els is nil if there is no ELSE
+	els Expr
+}
+
+// CaseWhen is the "WHEN cond THEN result" of CASE.
+type CaseWhen struct {
+	cond   Expr
+	result Expr
+}
+
+func (c *CaseExpr) Eval(env *evalEnv) (any, error) {
+	var operand Expr
+	if c.operand != nil {
+		// the operand is evaluated only once
+		v, err := c.operand.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		operand = &Literal{val: v}
+	}
+	for _, w := range c.whens {
+		cond := w.cond
+		if operand != nil {
+			cond = &BinaryExpr{op: equal, left: operand, right: w.cond}
+		}
+		v, err := cond.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		// NULL is not matched
+		b, err := toNullableBool(v)
+		if err != nil {
+			return nil, err
+		}
+		if b != nil && *b {
+			return c.result(env, w.result)
+		}
+	}
+	if c.els == nil {
+		return nil, nil
+	}
+	return c.result(env, c.els)
+}
+
+// result evaluates the result of CASE, whose number is widened to the type
+// of all the results, see Table.commonType, e.g., the integer to the
+// decimal if the other results are decimals.
+func (c *CaseExpr) result(env *evalEnv, e Expr) (any, error) {
+	v, err := e.Eval(env)
+	if err != nil || v == nil {
+		return v, err
+	}
+	t := env.table
+	if t == nil {
+		t = &Table{}
+	}
+	if typ := t.exprType(c); typ != nil {
+		return widen(typ, v), nil
+	}
+	return v, nil
+}
+
+func (c *CaseExpr) String() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	if c.operand != nil {
+		b.WriteString(" " + c.operand.String())
+	}
+	for _, w := range c.whens {
+		b.WriteString(" WHEN " + w.cond.String() + " THEN " + w.result.String())
+	}
+	if c.els != nil {
+		b.WriteString(" ELSE " + c.els.String())
+	}
+	b.WriteString(" END")
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"unicode/utf8"
+)
+
+// CastExpr is the explicit conversion "CAST(expr AS type)", or
+// "expr::type", see castValue.
+type CastExpr struct {
+	expr Expr
+	typ  *Type
+}
+
+func (c *CastExpr) Eval(env *evalEnv) (any, error) {
+	v, err := c.expr.Eval(env)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return castValue(v, c.typ)
+}
+
+func (c *CastExpr) String() string {
+	return "CAST(" + c.expr.String() + " AS " + c.typ.String() + ")"
+}
+
+// castValue converts the non-NULL value to the type explicitly, which
+// allows the conversions the implicit ones, see Type.assign, do not: any
+// value converts to the strings, which are truncated to the length of
+// VARCHAR, the strings are parsed as the type, the floats and the decimals
+// are rounded to the integers, the integers and the booleans convert to
+// each other, and the arrays are converted element by element.
+func castValue(v any, typ *Type) (any, error) {
+	switch {
+	case typ.id == stringID || typ.id == varcharID:
+		s := toText(v)
+		if typ.length != 0 && utf8.RuneCountInString(s) > typ.length {
+			s = string([]rune(s)[:typ.length])
+		}
+		return s, nil
+	case typ.id == arrayID:
+		a, ok := v.(Array)
+		if !ok {
+			break
+		}
+		elems := make([]any, len(a.elems))
+		for i, e := range a.elems {
+			if e == nil {
+				continue
+			}
+			x, err := castValue(e, typ.elem)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = x
+		}
+		return Array{elem: typ.elem, elems: elems}, nil
+	case typ.integral():
+		switch x := v.(type) {
+		case float64:
+			// rounded half to even as PostgreSQL does for the floats
+			f := math.RoundToEven(x)
+			// the bounds are checked as floats not to overflow the
+			// conversion
+			if min, max := typ.intRange(); math.IsNaN(f) || f < float64(min) ||
+				f >= -float64(min) || int(f) > max {
+				return nil, newError(codeNumericOutOfRange, "%s out of range", typ)
+			}
+			return int(f), nil
+		case Decimal:
+			coef := x.rescale(0).coef
+			if !coef.IsInt64() {
+				return nil, newError(codeNumericOutOfRange, "%s out of range", typ)
+			}
+			i, _, err := typ.assign(int(coef.Int64()))
+			return i, err
+		case bool:
+			if x {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case typ.id == booleanID:
+		if i, ok := v.(int); ok {
+			return i != 0, nil
+		}
+	}
+	if s, ok := v.(string); ok {
+		return typ.parse(s)
+	}
+	x, ok, err := typ.assign(v)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, newError(codeCannotCoerce, "cannot cast type %s to %s",
+			valueType(v), typ)
+	}
+	return x, nil
+}
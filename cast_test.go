@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCastExpressions(t *testing.T) {
+	tts := []struct {
+		expr   string
+		code   string
+		expect string
+	}{
+		{"CAST('12' AS integer)", "", "12"},
+		{"'12'::integer + 1", "", "13"},
+		{"' 1.5 '::float", "", "1.5"},
+		{"cast(1 as float) / 2", "", "0.5"},
+		{"2.5::integer", "", "3"},
+		{"2.5::float::integer", "", "2"},
+		{"3.5::integer", "", "4"},
+		{"decimal '2.5'::integer", "", "3"},
+		{"-1.5::integer", "", "-2"},
+		{"1.25::decimal(3, 1)", "", "1.3"},
+		{"'abc'::integer", codeInvalidTextRepresent, ""},
+		{"'1.5'::integer", codeInvalidTextRepresent, ""},
+		{"3000000000::integer", codeNumericOutOfRange, ""},
+		{"40000.4::smallint", codeNumericOutOfRange, ""},
+		{"1e300::bigint", codeNumericOutOfRange, ""},
+		{"decimal '1e30'::bigint", codeNumericOutOfRange, ""},
+		{"'abcdef'::varchar(3)", "", "abc"},
+		{"12::text || 'x'", "", "12x"},
+		{"1.5::string", "", "1.5"},
+		{"true::integer", "", "1"},
+		{"0::boolean", "", "false"},
+		{"'yes'::boolean", "", "true"},
+		{"'{1,2}'::integer[]", "", "{1,2}"},
+		{"ARRAY[1.6, 2.4]::integer[]", "", "{2,2}"},
+		{"(ARRAY[1, 2]::text[])[1] || 'x'", "", "1x"},
+		{"'2024-01-31'::date", "", "2024-01-31"},
+		{"timestamp '2024-01-31 10:00:00'::date", "", "2024-01-31"},
+		{"jsonb '{\"b\": 1, \"a\": 2}'::json", "", `{"a": 2, "b": 1}`},
+		{"date '2024-01-31'::integer", codeCannotCoerce, ""},
+		{"1::uuid", codeCannotCoerce, ""},
+		{"'1'::money", codeUndefinedObject, ""},
+		{"NULL::integer is null", "", "true"},
+		{"('{a,b}'::text[])[2]", "", "b"},
+		{"CAST(1 integer)", codeSyntaxError, ""},
+	}
+	env := &evalEnv{sess: NewSession(defaultSuperuser)}
+	for i, tt := range tts {
+		got, err := evalText(env, tt.expr)
+		if sqlState(err) != tt.code && (err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.expr, err, tt.code)
+		}
+		if got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.expr, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.expr)
+	}
+}
+
+func TestImplicitCoercion(t *testing.T) {
+	db := NewDatabase()
+	if r := execSQL(db, defaultSuperuser, "create table m (id integer "+
+		"primary key, f float, d decimal(5, 1), s varchar(2))"); r.err != nil {
+		t.Fatalf("failed to create the table: %v", r.err)
+	}
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		expect []any
+	}{
+		{"Integer to float", "insert into m values (1, 1, 2, 'a')", "", nil},
+		{"Decimal to float", "insert into m values (2, decimal '2.5', 2.25, 'b')",
+			"", nil},
+		{"Float to integer", "insert into m values (1.5, 1, 1, 'c')",
+			codeDatatypeMismatch, nil},
+		{"Decimal to integer", "insert into m (id) values (decimal '3')",
+			codeDatatypeMismatch, nil},
+		{"String to integer", "insert into m (id) values ('5')", "", nil},
+		{"Invalid string to integer", "insert into m (id) values ('x')",
+			codeInvalidTextRepresent, nil},
+		{"Cast", "insert into m values (CAST('3' AS integer), '1.5'::float, " +
+			"'7'::decimal, 12345::varchar(2))", "", nil},
+		{"Cast float", "insert into m (id) values (4.4::integer)", "", nil},
+		{"Invalid cast", "insert into m (id) values ('x'::integer)",
+			codeInvalidTextRepresent, nil},
+		{"Update", "update m set f = f + 1 where id = 1", "", nil},
+		{"Select", "select * from m where f > 1", "", []any{1, 2, 3}},
+		{"Cast key", "select * from m where id = '2'::integer", "", []any{2}},
+		{"Cast key range", "select * from m where id < 3.5::integer and " +
+			"id > cast('1' as bigint)", "", []any{2, 3}},
+		{"Cast column", "select * from m where f::integer = 2", "",
+			[]any{1, 2, 3}},
+		{"Cast string", "select * from m where id = 3 and s::integer = 12", "",
+			[]any{3}},
+		{"Invalid cast string", "select * from m where s::integer = 12",
+			codeInvalidTextRepresent, nil},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		var got []any
+		for _, row := range r.rows {
+			got = append(got, row.fields["id"])
+		}
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Fatalf("case %d (%s) failed: got %v, expect %v",
+				i, tt.name, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	ss, err := parseSQL("select * from m where f = $1::integer and id = $2")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	types, err := db.ParamTypes(ss)
+	if expect := []*Type{integerType, integerType}; err != nil ||
+		!reflect.DeepEqual(types, expect) {
+		t.Fatalf("got param types %v (%v), expect %v", types, err, expect)
+	}
+}
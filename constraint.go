@@ -0,0 +1,284 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// addConstraints validates the defaults and the constraints of the
+// CREATE TABLE statement and adds them to the table. The unnamed
+// constraints are named as PostgreSQL does, e.g., t_name_key.
+func (t *Table) addConstraints(cs *CreateStatement) error {
+	table := cs.table.name
+	var all []*Constraint
+	for _, col := range cs.columns {
+		if col.defaultValue != nil {
+			if refs := columnRefs(col.defaultValue); len(refs) != 0 {
+				return newError(codeFeatureNotSupported,
+					"cannot use column reference %s in DEFAULT expression",
+					refs[0])
+			}
+			t.defaults[col.name] = col.defaultValue
+		}
+		all = append(all, col.constraints...)
+		// the SERIAL and the identity columns are implicitly NOT NULL
+		if (col.serial || col.identity != nil) && !col.primaryKey &&
+			!hasNotNull(col.constraints) {
+			all = append(all, &Constraint{
+				kind:    notNullConstraint,
+				columns: []string{col.name},
+			})
+		}
+	}
+	all = append(all, cs.constraints...)
+
+	// the given names are taken before any name is generated
+	t.pkeyName = table + "_pkey"
+	for _, c := range cs.constraints {
+		if c.kind == primaryKeyConstraint && c.name != "" {
+			t.pkeyName = c.name
+		}
+	}
+	names := map[string]bool{t.pkeyName: true}
+	for _, c := range all {
+		if c.name == "" || c.kind == primaryKeyConstraint {
+			continue
+		}
+		if names[c.name] {
+			return newError(codeDuplicateObject,
+				"constraint %s for relation %s already exists",
+				c.name, table)
+		}
+		names[c.name] = true
+	}
+	for _, c := range all {
+		if c.kind == primaryKeyConstraint {
+			continue
+		}
+		for _, col := range append(columnRefs(c.check), c.columns...) {
+			if _, exist := t.schema[col]; !exist {
+				return newError(codeUndefinedColumn,
+					"column %s named in constraint does not exist", col)
+			}
+		}
+		name := c.name
+		if name == "" {
+			name = constraintName(table, c, names)
+			names[name] = true
+		}
+		added := *c
+		added.name = name
+		t.constraints = append(t.constraints, &added)
+		if added.kind == uniqueConstraint {
+			t.uniques[&added] = make(map[string][]string)
+		}
+	}
+	return nil
+}
+
+// hasNotNull checks if any of the constraints is NOT NULL.
+func hasNotNull(cs []*Constraint) bool {
+	for _, c := range cs {
+		if c.kind == notNullConstraint {
+			return true
+		}
+	}
+	return false
+}
+
+// columnRefs returns the names of the columns referenced by the
+// expression.
+func columnRefs(e Expr) []string {
+	var refs []string
+	walkExpr(e, func(e Expr) {
+		if c, ok := e.(*ColumnRef); ok {
+			refs = append(refs, c.name)
+		}
+	})
+	return refs
+}
+
+// constraintName generates the name of the constraint from the table,
+// the columns and the kind, a number is appended if the name is taken.
+func constraintName(table string, c *Constraint, taken map[string]bool) string {
+	parts := append([]string{table}, c.columns...)
+	switch c.kind {
+	case notNullConstraint:
+		parts = append(parts, "not", "null")
+	case uniqueConstraint:
+		parts = append(parts, "key")
+	case checkConstraint:
+		parts = append(parts, "check")
+	case foreignKeyConstraint:
+		parts = append(parts, "fkey")
+	}
+	name := strings.Join(parts, "_")
+	for i := 1; taken[name]; i++ {
+		name = strings.Join(parts, "_") + strconv.Itoa(i)
+	}
+	return name
+}
+
+// defaultValue returns the value of the column if it is not given, i.e.,
+// its DEFAULT or NULL.
+func (t *Table) defaultValue(env *evalEnv, col string) (any, error) {
+	e, exist := t.defaults[col]
+	if !exist {
+		return nil, nil
+	}
+	val, err := e.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return t.coerce(col, val)
+}
+
+// checkRow checks if the new row of the env satisfies the NOT NULL and
+// the CHECK constraints, the CHECK constraint is satisfied by NULL, and
+// if it can be indexed.
+func (t *Table) checkRow(env *evalEnv, table string) error {
+	for _, c := range t.constraints {
+		switch c.kind {
+		case notNullConstraint:
+			if env.row.fields[c.columns[0]] == nil {
+				return newError(codeNotNullViolation,
+					"null value in column %s of relation %s violates "+
+						"not-null constraint %s", c.columns[0], table, c.name)
+			}
+		case checkConstraint:
+			v, err := c.check.Eval(env)
+			if err != nil {
+				return err
+			}
+			ok, err := toNullableBool(v)
+			if err != nil {
+				return err
+			}
+			if ok != nil && !*ok {
+				return newError(codeCheckViolation,
+					"new row for relation %s violates check constraint %s",
+					table, c.name)
+			}
+		}
+	}
+	// the expressions of the indexes must be computable on the row
+	for _, idx := range t.indexes {
+		for _, e := range idx.exprs {
+			if _, err := e.Eval(env); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkUnique checks if the new rows violate the UNIQUE constraints,
+// among themselves or with the existing rows except the replaced ones,
+// which are keyed by the primary keys. NULLs are never duplicates.
+func (t *Table) checkUnique(rows []*Row, replaced map[string]bool) error {
+	for _, c := range t.constraints {
+		if c.kind != uniqueConstraint {
+			continue
+		}
+		seen := make(map[string]bool, len(rows))
+		for _, r := range rows {
+			key, ok := uniqueKey(r, c.columns)
+			if !ok {
+				continue
+			}
+			dup := seen[key]
+			for _, pk := range t.uniques[c][key] {
+				dup = dup || !replaced[pk]
+			}
+			if dup {
+				return newError(codeUniqueViolation,
+					"duplicate key value violates unique constraint %s: "+
+						"(%s)=(%s) already exists", c.name,
+					strings.Join(c.columns, ", "), key)
+			}
+			seen[key] = true
+		}
+	}
+	return nil
+}
+
+// addUnique adds the keys of the row of the primary key on the UNIQUE
+// constraints.
+func (t *Table) addUnique(pk string, r *Row) {
+	for c, keys := range t.uniques {
+		if key, ok := uniqueKey(r, c.columns); ok {
+			keys[key] = append(keys[key], pk)
+		}
+	}
+}
+
+// deleteUnique removes the keys of the row of the primary key on the
+// UNIQUE constraints.
+func (t *Table) deleteUnique(pk string, r *Row) {
+	for c, keys := range t.uniques {
+		key, ok := uniqueKey(r, c.columns)
+		if !ok {
+			continue
+		}
+		pks := keys[key]
+		for i, p := range pks {
+			if p == pk {
+				pks = append(pks[:i:i], pks[i+1:]...)
+				break
+			}
+		}
+		if len(pks) == 0 {
+			delete(keys, key)
+		} else {
+			keys[key] = pks
+		}
+	}
+}
+
+// uniqueKey returns the values of the columns of the row as a key, which
+// is false if any of them is NULL. The equal decimals and intervals have
+// the same key, e.g., 1.5 and 1.50, 1 month and 30 days, or the JSON
+// documents {"a": 1.0} and {"a": 1}, and so do the arrays of them.
+func uniqueKey(r *Row, columns []string) (string, bool) {
+	vals := make([]string, len(columns))
+	for i, col := range columns {
+		v := r.fields[col]
+		if v == nil {
+			return "", false
+		}
+		vals[i] = uniqueText(v)
+	}
+	return strings.Join(vals, ", "), true
+}
+
+// uniqueText returns the text of the value in the key of uniqueKey.
+func uniqueText(val any) string {
+	switch v := val.(type) {
+	case Decimal:
+		return v.normalize().String()
+	case Interval:
+		return formatLiteral(Interval{micros: int64(v.total())})
+	case JSON:
+		return valueType(v) + " " + formatLiteral(v.key())
+	case Array:
+		elems := make([]string, len(v.elems))
+		for i, e := range v.elems {
+			elems[i] = "NULL"
+			if e != nil {
+				elems[i] = uniqueText(e)
+			}
+		}
+		return "ARRAY[" + strings.Join(elems, ", ") + "]"
+	}
+	return formatLiteral(val)
+}
+
+// dropConstraint removes the constraint from the table.
+func (t *Table) dropConstraint(c *Constraint) {
+	for i, tc := range t.constraints {
+		if tc == c {
+			t.constraints = append(t.constraints[:i:i], t.constraints[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstraints(t *testing.T) {
+	db := NewDatabase()
+	if r := execSQL(db, defaultSuperuser, "create table t ("+
+		"id integer primary key, "+
+		"email string not null unique, "+
+		"score float default 1.5 constraint positive check (score > 0), "+
+		"team string, "+
+		"seat integer, "+
+		"unique (team, seat), "+
+		"check (seat < 10))"); r.err != nil {
+		t.Fatalf("failed to create the table: %v", r.err)
+	}
+
+	tts := []struct {
+		name       string
+		sql        string
+		code       string
+		constraint string
+	}{
+		{"Insert", "insert into t (id, email, team, seat) values (1, 'a', 'x', 1)",
+			"", ""},
+		{"Default", "select * from t where score = 1.5", "", ""},
+		{"Missing not null", "insert into t (id) values (2)",
+			codeNotNullViolation, "t_email_not_null"},
+		{"Explicit null", "insert into t (id, email) values (2, null)",
+			codeNotNullViolation, "t_email_not_null"},
+		{"Duplicate unique", "insert into t (id, email) values (2, 'a')",
+			codeUniqueViolation, "t_email_key"},
+		{"Duplicate primary key", "insert into t (id, email) values (1, 'b')",
+			codeUniqueViolation, "t_pkey"},
+		{"Named check", "insert into t (id, email, score) values (2, 'b', -1.0)",
+			codeCheckViolation, "positive"},
+		{"Table check", "insert into t (id, email, seat) values (2, 'b', 10)",
+			codeCheckViolation, "t_check"},
+		{"Null satisfies check", "insert into t (id, email, score) values (2, 'b', null)",
+			"", ""},
+		{"Null is not duplicate", "insert into t (id, email, team) values (3, 'c', 'x')",
+			"", ""},
+		{"Duplicate composite unique",
+			"insert into t (id, email, team, seat) values (4, 'd', 'x', 1)",
+			codeUniqueViolation, "t_team_seat_key"},
+		{"Update to duplicate", "update t set email = 'a' where id = 2",
+			codeUniqueViolation, "t_email_key"},
+		{"Update violates check", "update t set score = 0.0 where id = 1",
+			codeCheckViolation, "positive"},
+		{"Update to null", "update t set email = null",
+			codeNotNullViolation, "t_email_not_null"},
+		{"Swap unique values", "update t set email = email || '!'", "", ""},
+		{"Old value after update", "insert into t (id, email) values (4, 'a')",
+			"", ""},
+		{"New value after update", "insert into t (id, email) values (5, 'a!')",
+			codeUniqueViolation, "t_email_key"},
+		{"Delete", "delete from t where id = 1", "", ""},
+		{"Value after delete", "insert into t (id, email) values (5, 'a!')",
+			"", ""},
+		{"Duplicate name", "create table u (id integer primary key, " +
+			"a integer constraint c unique, b integer constraint c unique)",
+			codeDuplicateObject, ""},
+		{"Missing column", "create table u (id integer primary key, " +
+			"unique (a))", codeUndefinedColumn, ""},
+		{"Column in default", "create table u (id integer primary key, " +
+			"a integer default id)", codeFeatureNotSupported, ""},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		if r.err != nil && !strings.Contains(r.err.Error(), tt.constraint) {
+			t.Fatalf("case %d (%s) failed: expect %q in %q",
+				i, tt.name, tt.constraint, r.err)
+		}
+		if r.cols != nil && len(r.rows) != 1 {
+			t.Fatalf("case %d (%s) failed: got %d rows, expect 1",
+				i, tt.name, len(r.rows))
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
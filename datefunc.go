@@ -0,0 +1,530 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// now returns the time of the statement, which is the same for all the
+// calls in the statement as in PostgreSQL.
+func now(env *evalEnv, args []any) (any, error) {
+	if env.now.IsZero() {
+		env.now = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	return TimestampTZ{env.now}, nil
+}
+
+// fieldArg returns the field name of date_trunc, date_part and extract,
+// e.g., "year", the plurals are taken as well.
+func fieldArg(fn string, v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", newError(codeDatatypeMismatch,
+			"the first argument of %s must be a string", fn)
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "s"), nil
+}
+
+func unitNotRecognized(field string, v any) error {
+	return newError(codeInvalidParameterValue,
+		"unit %q not recognized for type %s", field, valueType(v))
+}
+
+// dateTrunc truncates the timestamp to the precision of the field, e.g.,
+// date_trunc('month', ts) is the first day of the month of ts. The date
+// is truncated as the timestamp at its midnight.
+func dateTrunc(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	field, err := fieldArg("date_trunc", args[0])
+	if err != nil {
+		return nil, err
+	}
+	t, ok := instant(args[1])
+	if !ok {
+		return nil, newError(codeUndefinedFunction,
+			"function date_trunc(%s) does not exist", valueType(args[1]))
+	}
+	y, m, d := t.Date()
+	switch field {
+	case "microsecond":
+	case "millisecond":
+		t = t.Truncate(time.Millisecond)
+	case "second":
+		t = t.Truncate(time.Second)
+	case "minute":
+		t = t.Truncate(time.Minute)
+	case "hour":
+		t = t.Truncate(time.Hour)
+	case "day":
+		t = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case "week":
+		// the ISO week starts on Monday
+		t = time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case "month":
+		t = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case "quarter":
+		t = time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+	case "year":
+		t = time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	case "decade":
+		t = time.Date(y-y%10, 1, 1, 0, 0, 0, 0, time.UTC)
+	case "century":
+		// the 21st century starts with 2001
+		t = time.Date((y-1)/100*100+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	case "millennium":
+		t = time.Date((y-1)/1000*1000+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return nil, unitNotRecognized(field, args[1])
+	}
+	if ts, ok := args[1].(TimestampTZ); ok {
+		ts.Time = t
+		return ts, nil
+	}
+	return Timestamp{t}, nil
+}
+
+// extract returns the field of the date and time value as the decimal,
+// e.g., extract(year from ts), which is written as
+// "extract(field FROM source)".
+func extract(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	field, err := fieldArg("extract", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return extractField(field, args[1])
+}
+
+// datePart is extract returning the float.
+func datePart(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	field, err := fieldArg("date_part", args[0])
+	if err != nil {
+		return nil, err
+	}
+	d, err := extractField(field, args[1])
+	if err != nil {
+		return nil, err
+	}
+	return d.Float64(), nil
+}
+
+// extractField returns the field of the value, the seconds are of the
+// scale 6, and the milliseconds of the scale 3.
+func extractField(field string, v any) (Decimal, error) {
+	var micros int64
+	switch x := v.(type) {
+	case TimeOfDay:
+		micros = int64(x)
+	case Interval:
+		return extractInterval(field, x)
+	default:
+		t, ok := instant(v)
+		if !ok {
+			return Decimal{}, newError(codeUndefinedFunction,
+				"function extract(%s) does not exist", valueType(v))
+		}
+		if d, ok := extractDate(field, t); ok {
+			return d, nil
+		}
+		if field == "epoch" {
+			return Decimal{coef: big.NewInt(t.UnixMicro()), scale: 6}, nil
+		}
+		micros = t.UnixMicro() - t.Truncate(24*time.Hour).UnixMicro()
+	}
+	switch field {
+	case "hour":
+		return decimalFromInt(int(micros / (3600 * microsPerSecond))), nil
+	case "minute":
+		return decimalFromInt(int(micros / (60 * microsPerSecond) % 60)), nil
+	case "second":
+		return Decimal{coef: big.NewInt(micros % (60 * microsPerSecond)), scale: 6}, nil
+	case "millisecond":
+		return Decimal{coef: big.NewInt(micros % (60 * microsPerSecond)), scale: 3}, nil
+	case "microsecond":
+		return decimalFromInt(int(micros % (60 * microsPerSecond))), nil
+	case "epoch":
+		if _, ok := v.(TimeOfDay); ok {
+			return Decimal{coef: big.NewInt(micros), scale: 6}, nil
+		}
+	}
+	return Decimal{}, unitNotRecognized(field, v)
+}
+
+// extractDate returns the fields of the date part of the time, which is
+// false for the other fields.
+func extractDate(field string, t time.Time) (Decimal, bool) {
+	y, m, d := t.Date()
+	var n int
+	switch field {
+	case "year":
+		n = y
+	case "month":
+		n = int(m)
+	case "day":
+		n = d
+	case "quarter":
+		n = (int(m)-1)/3 + 1
+	case "dow":
+		// Sunday is 0
+		n = int(t.Weekday())
+	case "isodow":
+		// Sunday is 7
+		n = (int(t.Weekday())+6)%7 + 1
+	case "doy":
+		n = t.YearDay()
+	case "week":
+		_, n = t.ISOWeek()
+	case "isoyear":
+		n, _ = t.ISOWeek()
+	case "decade":
+		n = y / 10
+	case "century":
+		n = (y + 99) / 100
+	case "millennium":
+		n = (y + 999) / 1000
+	default:
+		return Decimal{}, false
+	}
+	return decimalFromInt(n), true
+}
+
+// extractInterval returns the field of the interval, the epoch takes a
+// year as 365.25 days and a month as 30 days as PostgreSQL does.
+func extractInterval(field string, iv Interval) (Decimal, error) {
+	switch field {
+	case "millennium":
+		return decimalFromInt(iv.months / 12000), nil
+	case "century":
+		return decimalFromInt(iv.months / 1200), nil
+	case "decade":
+		return decimalFromInt(iv.months / 120), nil
+	case "year":
+		return decimalFromInt(iv.months / 12), nil
+	case "quarter":
+		return decimalFromInt(iv.months%12/3 + 1), nil
+	case "month":
+		return decimalFromInt(iv.months % 12), nil
+	case "day":
+		return decimalFromInt(iv.days), nil
+	case "epoch":
+		days := float64(iv.months/12)*365.25 + float64(iv.months%12*30) +
+			float64(iv.days)
+		micros := int64(days*float64(microsPerDay)) + iv.micros
+		return Decimal{coef: big.NewInt(micros), scale: 6}, nil
+	}
+	return extractField(field, TimeOfDay(iv.micros))
+}
+
+// toChar formats the date and time value by the template of PostgreSQL,
+// e.g., to_char(ts, 'YYYY-MM-DD HH24:MI:SS').
+func toChar(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	format, ok := args[1].(string)
+	if !ok {
+		return nil, newError(codeDatatypeMismatch,
+			"the format of to_char must be a string")
+	}
+	t, ok := instant(args[0])
+	if tod, isTime := args[0].(TimeOfDay); isTime {
+		t, ok = time.UnixMicro(int64(tod)).UTC(), true
+	}
+	if !ok {
+		return nil, newError(codeUndefinedFunction,
+			"function to_char(%s, string) does not exist", valueType(args[0]))
+	}
+	var b strings.Builder
+	for _, tk := range scanTemplate(format) {
+		if tk.pattern == "" {
+			b.WriteString(tk.text)
+			continue
+		}
+		b.WriteString(formatPattern(t, tk))
+	}
+	return b.String(), nil
+}
+
+// templateToken is a pattern of the template, e.g., YYYY, or the text
+// copied as it is if the pattern is empty.
+type templateToken struct {
+	pattern string
+	// text is the pattern as written, e.g., "Month" for MONTH
+	text string
+	// fill is true for the FM prefix, which suppresses the padding
+	fill bool
+}
+
+// templatePatterns are the patterns of the templates, the longer ones
+// come first.
+var templatePatterns = []string{"HH24", "HH12", "HH", "MI", "SS", "MS",
+	"US", "AM", "PM", "YYYY", "YY", "MONTH", "MON", "MM", "DAY", "DDD",
+	"DD", "DY", "D", "Q", "TZ"}
+
+// scanTemplate splits the template into the patterns and the texts, the
+// text in double quotes is copied as it is.
+func scanTemplate(format string) []templateToken {
+	var tks []templateToken
+	for i := 0; i < len(format); {
+		if format[i] == '"' {
+			j := strings.IndexByte(format[i+1:], '"')
+			if j < 0 {
+				j = len(format) - i - 1
+			}
+			tks = append(tks, templateToken{text: format[i+1 : i+1+j]})
+			i += j + 2
+			continue
+		}
+		fill := false
+		if strings.HasPrefix(strings.ToUpper(format[i:]), "FM") {
+			fill = true
+			i += 2
+		}
+		matched := false
+		for _, p := range templatePatterns {
+			if strings.HasPrefix(strings.ToUpper(format[i:]), p) {
+				tks = append(tks, templateToken{
+					pattern: p,
+					text:    format[i : i+len(p)],
+					fill:    fill,
+				})
+				i += len(p)
+				matched = true
+				break
+			}
+		}
+		if !matched && i < len(format) {
+			tks = append(tks, templateToken{text: format[i : i+1]})
+			i++
+		}
+	}
+	return tks
+}
+
+// formatPattern formats the time by the pattern, the names are in the
+// case of the pattern as written, e.g., "Month", "MONTH" or "month".
+func formatPattern(t time.Time, tk templateToken) string {
+	num := func(n, width int) string {
+		s := strconv.Itoa(n)
+		if !tk.fill && len(s) < width {
+			s = strings.Repeat("0", width-len(s)) + s
+		}
+		return s
+	}
+	name := func(s string, width int) string {
+		switch {
+		case tk.text == strings.ToUpper(tk.text):
+			s = strings.ToUpper(s)
+		case tk.text == strings.ToLower(tk.text):
+			s = strings.ToLower(s)
+		}
+		if !tk.fill && len(s) < width {
+			s += strings.Repeat(" ", width-len(s))
+		}
+		return s
+	}
+	hour12 := (t.Hour()+11)%12 + 1
+	switch tk.pattern {
+	case "HH24":
+		return num(t.Hour(), 2)
+	case "HH12", "HH":
+		return num(hour12, 2)
+	case "MI":
+		return num(t.Minute(), 2)
+	case "SS":
+		return num(t.Second(), 2)
+	case "MS":
+		return num(t.Nanosecond()/1e6, 3)
+	case "US":
+		return num(t.Nanosecond()/1e3, 6)
+	case "AM", "PM":
+		meridiem := "AM"
+		if t.Hour() >= 12 {
+			meridiem = "PM"
+		}
+		if tk.text == strings.ToLower(tk.text) {
+			meridiem = strings.ToLower(meridiem)
+		}
+		return meridiem
+	case "YYYY":
+		return num(t.Year(), 4)
+	case "YY":
+		return num(t.Year()%100, 2)
+	case "MONTH":
+		return name(t.Month().String(), 9)
+	case "MON":
+		return name(t.Month().String()[:3], 0)
+	case "MM":
+		return num(int(t.Month()), 2)
+	case "DAY":
+		return name(t.Weekday().String(), 9)
+	case "DY":
+		return name(t.Weekday().String()[:3], 0)
+	case "DDD":
+		return num(t.YearDay(), 3)
+	case "DD":
+		return num(t.Day(), 2)
+	case "D":
+		return strconv.Itoa(int(t.Weekday()) + 1)
+	case "Q":
+		return strconv.Itoa((int(t.Month())-1)/3 + 1)
+	default:
+		return name("UTC", 0)
+	}
+}
+
+// toTimestamp parses the string by the template, see to_char, or converts
+// the seconds since the Unix epoch, to the timestamp with time zone.
+func toTimestamp(env *evalEnv, args []any) (any, error) {
+	if len(args) == 1 {
+		if args[0] == nil {
+			return nil, nil
+		}
+		sec, ok := toFloat(args[0])
+		if !ok || math.IsNaN(sec) || math.IsInf(sec, 0) {
+			return nil, newError(codeDatatypeMismatch,
+				"the seconds of to_timestamp must be a number")
+		}
+		micros := int64(math.Round(sec * float64(microsPerSecond)))
+		return TimestampTZ{time.UnixMicro(micros).UTC()}, nil
+	}
+	t, err := parseByTemplate("to_timestamp", args)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	return TimestampTZ{*t}, nil
+}
+
+// toDate parses the string by the template to the date.
+func toDate(env *evalEnv, args []any) (any, error) {
+	t, err := parseByTemplate("to_date", args)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	return Date{t.Truncate(24 * time.Hour)}, nil
+}
+
+// parseByTemplate parses the first argument by the template of the second,
+// the separators of the template match any non-alphanumeric characters.
+// It returns nil if either argument is NULL.
+func parseByTemplate(fn string, args []any) (*time.Time, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	s, ok1 := args[0].(string)
+	format, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		return nil, newError(codeDatatypeMismatch,
+			"the arguments of %s must be strings", fn)
+	}
+	invalid := newError(codeInvalidDatetimeFormat,
+		"invalid value %q for the template %q", s, format)
+	year, month, day, hour, minute, micros, pm := 1, 1, 1, 0, 0, int64(0), -1
+	i := 0
+	// number reads at most width digits
+	number := func(width int) (int, bool) {
+		j := i
+		for j < len(s) && j-i < width && unicode.IsDigit(rune(s[j])) {
+			j++
+		}
+		n, err := strconv.Atoi(s[i:j])
+		i = j
+		return n, err == nil
+	}
+	// names matches one of the names case-insensitively, and returns its
+	// index
+	names := func(list []string) (int, bool) {
+		for k, n := range list {
+			if len(s)-i >= len(n) && strings.EqualFold(s[i:i+len(n)], n) {
+				i += len(n)
+				return k, true
+			}
+		}
+		return 0, false
+	}
+	var months, shortMonths []string
+	for m := time.January; m <= time.December; m++ {
+		months = append(months, m.String())
+		shortMonths = append(shortMonths, m.String()[:3])
+	}
+	for _, tk := range scanTemplate(format) {
+		ok := true
+		var n int
+		switch tk.pattern {
+		case "":
+			for range tk.text {
+				if i < len(s) && !unicode.IsLetter(rune(s[i])) &&
+					!unicode.IsDigit(rune(s[i])) {
+					i++
+				}
+			}
+		case "YYYY":
+			year, ok = number(4)
+		case "YY":
+			n, ok = number(2)
+			year = 2000 + n
+		case "MM":
+			month, ok = number(2)
+		case "MONTH":
+			n, ok = names(months)
+			month = n + 1
+		case "MON":
+			n, ok = names(shortMonths)
+			month = n + 1
+		case "DD":
+			day, ok = number(2)
+		case "HH24":
+			hour, ok = number(2)
+		case "HH12", "HH":
+			hour, ok = number(2)
+			if pm < 0 {
+				pm = 0
+			}
+		case "MI":
+			minute, ok = number(2)
+		case "SS":
+			n, ok = number(2)
+			micros += int64(n) * microsPerSecond
+		case "MS":
+			n, ok = number(3)
+			micros += int64(n) * 1000
+		case "US":
+			n, ok = number(6)
+			micros += int64(n)
+		case "AM", "PM":
+			n, ok = names([]string{"AM", "PM"})
+			pm = n
+		default:
+			return nil, newError(codeFeatureNotSupported,
+				"the template pattern %s is not supported by %s", tk.text, fn)
+		}
+		if !ok {
+			return nil, invalid
+		}
+	}
+	if pm >= 0 {
+		if hour < 1 || hour > 12 {
+			return nil, invalid
+		}
+		hour = hour%12 + pm*12
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, 0, 0, time.UTC)
+	if i != len(strings.TrimSpace(s)) || t.Month() != time.Month(month) ||
+		t.Day() != day || hour > 23 || minute > 59 ||
+		micros >= 60*microsPerSecond {
+		return nil, invalid
+	}
+	t = t.Add(time.Duration(micros) * time.Microsecond)
+	return &t, nil
+}
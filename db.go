@@ -1,223 +1,754 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
-
-	"github.com/pkg/errors"
 )
 
 type Database struct {
 	sync.RWMutex
-	tables map[string]*Table
+	tables    map[string]*Table
+	users     map[string]*User
+	sequences map[string]*Sequence
 }
 
 func NewDatabase() *Database {
 	return &Database{
-		tables: make(map[string]*Table),
+		tables:    make(map[string]*Table),
+		sequences: make(map[string]*Sequence),
+		users: map[string]*User{
+			defaultSuperuser: newUser(defaultSuperuser, true),
+		},
 	}
 }
 
 type Result struct {
-	err     error
-	cols    []string
-	rows    []*Row
-	message string
+	err   error
+	cols  []string
+	types []*Type
+	rows  []*Row
+	// affected is the number of rows inserted, updated or deleted
+	affected int
+	message  string
+}
+
+// rowSink receives the result of a SELECT as the rows are scanned instead
+// of the Result, which holds no rows then. The columns are given before
+// the rows, and an error of the sink fails the statement. The sink is
+// called with the lock held, so a slow sink delays the writers.
+type rowSink interface {
+	columns(cols []string, types []*Type) error
+	row(values []any) error
+}
+
+type rowSinkKey struct{}
+
+// withRowSink returns a copy of the ctx that carries the sink of the
+// rows.
+func withRowSink(ctx context.Context, sink rowSink) context.Context {
+	return context.WithValue(ctx, rowSinkKey{}, sink)
+}
+
+// rowSinkFromContext returns the sink carried by the ctx, or nil if there
+// is none.
+func rowSinkFromContext(ctx context.Context) rowSink {
+	sink, _ := ctx.Value(rowSinkKey{}).(rowSink)
+	return sink
+}
+
+// TableInfo describes the schema of a table.
+type TableInfo struct {
+	Name       string
+	Columns    []string
+	Types      []*Type
+	PrimaryKey []string
+	// PrimaryKeyName is the name of the primary key constraint
+	PrimaryKeyName string
+	Owner          string
+	// NotNull tells whether the columns are NOT NULL
+	NotNull []bool
+	// Defaults are the DEFAULT expressions of the columns, empty if none
+	Defaults []string
+	// Constraints are the names and the definitions of the constraints,
+	// e.g., "t_name_key UNIQUE (name)"
+	Constraints []string
+	// Indexes are the names and the columns of the indexes besides the
+	// primary key, e.g., "t_expr_idx ((payload ->> 'kind'))"
+	Indexes []string
 }
 
-func (db *Database) Interpret(sts any) *Result {
+// TableNames returns the names of all tables in alphabetical order.
+func (db *Database) TableNames() []string {
+	db.RLock()
+	defer db.RUnlock()
+	names := make([]string, 0, len(db.tables))
+	for name := range db.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DescribeTable returns the schema of the table.
+func (db *Database) DescribeTable(name string) (*TableInfo, error) {
+	db.RLock()
+	defer db.RUnlock()
+	t, exist := db.tables[name]
+	if !exist {
+		return nil, newError(codeUndefinedTable,
+			"relation %s does not exist", name)
+	}
+	cols, types, err := t.selectColumns(nil)
+	if err != nil {
+		return nil, err
+	}
+	info := &TableInfo{
+		Name:           name,
+		Columns:        cols,
+		Types:          types,
+		PrimaryKey:     t.primaryKey,
+		PrimaryKeyName: t.pkeyName,
+		Owner:          t.owner,
+		NotNull:        make([]bool, len(cols)),
+		Defaults:       make([]string, len(cols)),
+	}
+	notNull := make(map[string]bool)
+	for _, col := range t.primaryKey {
+		notNull[col] = true
+	}
+	for _, c := range t.constraints {
+		if c.kind == notNullConstraint {
+			notNull[c.columns[0]] = true
+		}
+		info.Constraints = append(info.Constraints, c.name+" "+c.String())
+	}
+	for _, idx := range t.indexes {
+		info.Indexes = append(info.Indexes,
+			idx.name+" ("+strings.Join(idx.columns(), ", ")+")")
+	}
+	for i, col := range cols {
+		info.NotNull[i] = notNull[col]
+		if always, exist := t.identities[col]; exist {
+			info.Defaults[i] = "generated by default as identity"
+			if always {
+				info.Defaults[i] = "generated always as identity"
+			}
+		} else if e, exist := t.defaults[col]; exist {
+			info.Defaults[i] = e.String()
+		}
+	}
+	return info, nil
+}
+
+// withTimeout bounds the context of the statement by the
+// statement_timeout, the tests replace it to expire the context at a
+// known point.
+var withTimeout = context.WithTimeout
+
+// Interpret executes the statement on behalf of the user of the session
+// attached to the ctx, which also supplies the run-time parameters, e.g.,
+// statement_timeout.
+func (db *Database) Interpret(ctx context.Context, sts Statement) *Result {
+	u, err := db.sessionUser(ctx)
+	if err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+	if err := db.authorize(u, sts); err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+
+	sess := SessionFromContext(ctx)
+	if s, ok := sts.(*SetStatement); ok {
+		return sess.Set(s)
+	}
+
+	if timeout := sess.StatementTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = withTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return &Result{
+			err: ctxError(err),
+		}
+	}
+
 	switch s := sts.(type) {
 	case *CreateStatement:
-		return db.CreateTable(s)
+		return db.CreateTable(ctx, s)
 	case *SelectStatement:
-		return db.SelectFrom(s)
+		return db.SelectFrom(ctx, s)
 	case *InsertStatement:
-		return db.InsertInto(s)
+		return db.InsertInto(ctx, s)
 	case *DeleteStatement:
-		return db.DeleteFrom(s)
+		return db.DeleteFrom(ctx, s)
+	case *UpdateStatement:
+		return db.UpdateTable(ctx, s)
+	case *DropStatement:
+		return db.DropTable(ctx, s)
+	case *CreateUserStatement:
+		return db.CreateUser(ctx, s)
+	case *AlterUserStatement:
+		return db.AlterUser(ctx, s)
+	case *DropUserStatement:
+		return db.DropUser(ctx, s)
+	case *GrantStatement:
+		return db.Grant(ctx, s)
+	case *CreatePolicyStatement:
+		return db.CreatePolicy(ctx, s)
+	case *DropPolicyStatement:
+		return db.DropPolicy(ctx, s)
+	case *AlterTableStatement:
+		return db.AlterTable(ctx, s)
+	case *CreateSequenceStatement:
+		return db.CreateSequence(ctx, s)
+	case *DropSequenceStatement:
+		return db.DropSequence(ctx, s)
+	case *CreateIndexStatement:
+		return db.CreateIndex(ctx, s)
+	case *DropIndexStatement:
+		return db.DropIndex(ctx, s)
 	default:
 		return &Result{
-			err: errors.Errorf("unsupported statement %v",
-				reflect.TypeOf(sts).Kind()),
+			err: newError(codeFeatureNotSupported,
+				"unsupported statement %v", reflect.TypeOf(sts)),
 		}
 	}
 }
 
-func (db *Database) CreateTable(cs *CreateStatement) *Result {
-	if _, exist := cs.schema[cs.primaryKey]; !exist {
+func (db *Database) CreateTable(ctx context.Context, cs *CreateStatement) *Result {
+	schema := make(map[string]*Type, len(cs.columns))
+	columns := make([]string, len(cs.columns))
+	for i, col := range cs.columns {
+		schema[col.name] = col.typ
+		columns[i] = col.name
+	}
+	pk := cs.primaryKey()
+	if len(pk) == 0 {
 		return &Result{
-			err: errors.New("primary key not defined"),
+			err: newError(codeUndefinedColumn, "primary key not defined"),
+		}
+	}
+	for i, col := range pk {
+		typ, exist := schema[col]
+		if !exist {
+			return &Result{
+				err: newError(codeUndefinedColumn,
+					"column %s named in key does not exist", col),
+			}
+		}
+		if typ.base().id == jsonID {
+			return &Result{
+				err: newError(codeUndefinedObject,
+					"data type %s has no default operator class for "+
+						"access method btree, use jsonb instead", typ),
+			}
+		}
+		for _, prev := range pk[:i] {
+			if prev == col {
+				return &Result{
+					err: newError(codeDuplicateColumn,
+						"column %s appears twice in primary key constraint",
+						col),
+				}
+			}
 		}
 	}
 	db.Lock()
 	defer db.Unlock()
-	if _, exist := db.tables[cs.table]; exist {
+	if db.relationExists(cs.table.name) {
+		return &Result{
+			err: newError(codeDuplicateTable,
+				"table %s already exists", cs.table.name),
+		}
+	}
+	t := NewTable(pk, schema, columns)
+	t.owner = SessionFromContext(ctx).User()
+	if err := t.addConstraints(cs); err != nil {
 		return &Result{
-			err: errors.Errorf("table %s already exists"),
+			err: err,
+		}
+	}
+	if err := db.resolveForeignKeys(cs.table.name, t); err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+	// the SERIAL and the identity columns default to the next values of
+	// the sequences owned by them
+	seqs := make(map[string]*Sequence)
+	for _, col := range cs.columns {
+		if !col.serial && col.identity == nil {
+			continue
+		}
+		opts := &SequenceOptions{}
+		if col.identity != nil {
+			opts = &col.identity.options
+			t.identities[col.name] = col.identity.always
+		}
+		seq, err := newSequence(opts)
+		if err != nil {
+			return &Result{
+				err: err,
+			}
+		}
+		seq.table, seq.column = cs.table.name, col.name
+		name := db.ownedSequenceName(cs.table.name, col.name)
+		seqs[name] = seq
+		t.defaults[col.name] = &FuncCall{
+			name: "nextval",
+			args: []Expr{&Literal{val: quoteIdent(name)}},
 		}
 	}
-	db.tables[cs.table] = NewTable(cs.primaryKey, cs.schema)
+	for name, seq := range seqs {
+		db.sequences[name] = seq
+	}
+	db.tables[cs.table.name] = t
 	return &Result{
 		message: "TABLE CREATED",
 	}
 }
 
-func (db *Database) DeleteTable(ds *DeleteStatement) *Result {
+func (db *Database) DropTable(ctx context.Context, ds *DropStatement) *Result {
 	db.Lock()
 	defer db.Unlock()
-	if _, exist := db.tables[ds.table]; !exist {
+	if _, exist := db.tables[ds.table.name]; !exist {
 		return &Result{
-			err: errors.Errorf("delete non exist table %s", ds.table),
+			err: newError(codeUndefinedTable,
+				"delete non exist table %s", ds.table.name),
 		}
 	}
-	delete(db.tables, ds.table)
-	return nil
+	// the foreign keys of other tables referencing the table are dropped
+	// on cascade
+	for _, ref := range db.references(ds.table.name) {
+		if ref.table == ds.table.name {
+			continue
+		}
+		if !ds.cascade {
+			return &Result{
+				err: newError(codeDependentObjects,
+					"cannot drop table %s because constraint %s on table "+
+						"%s depends on it, use DROP ... CASCADE to drop "+
+						"the constraint too", ds.table.name, ref.fk.name,
+					ref.table),
+			}
+		}
+		ref.t.dropConstraint(ref.fk)
+	}
+	delete(db.tables, ds.table.name)
+	for name, seq := range db.sequences {
+		if seq.table == ds.table.name {
+			delete(db.sequences, name)
+		}
+	}
+	// the privileges on the dropped table are gone with it
+	for _, u := range db.users {
+		delete(u.privileges, ds.table.name)
+	}
+	return &Result{
+		message: "TABLE DROPPED",
+	}
 }
 
-func (db *Database) InsertInto(is *InsertStatement) *Result {
+func (db *Database) InsertInto(ctx context.Context, is *InsertStatement) *Result {
 	db.Lock()
 	defer db.Unlock()
-	t, exist := db.tables[is.table]
+	t, exist := db.tables[is.table.name]
 	if !exist {
 		return &Result{
-			err: errors.Errorf("insert into non exist table %s",
-				is.table),
+			err: newError(codeUndefinedTable,
+				"insert into non exist table %s", is.table.name),
 		}
 	}
 
-	r := &Row{
-		fields: make(map[string]any),
-	}
-
-	for cn := range t.schema {
-		r.fields[cn] = nil
+	// the values are given for all columns in order if the columns are
+	// omitted
+	columns := is.columns
+	if columns == nil {
+		columns = t.columns
 	}
-
-	// primary key cannot be empty
-	pk, exist := is.values[t.primaryKey]
-	if !exist {
+	if len(is.values) > len(columns) {
 		return &Result{
-			err: errors.Errorf("primary key is not given"),
+			err: newError(codeSyntaxError,
+				"INSERT has more expressions than target columns"),
+		}
+	}
+	given := make(map[string]bool, len(is.values))
+	for _, cn := range columns[:len(is.values)] {
+		if t.identities[cn] {
+			return &Result{
+				err: newError(codeGeneratedAlways,
+					"cannot insert a non-DEFAULT value into column %s, "+
+						"which is GENERATED ALWAYS", cn),
+			}
+		}
+		given[cn] = true
+	}
+	for _, v := range is.values {
+		if err := t.checkExpr(v, false); err != nil {
+			return &Result{
+				err: err,
+			}
 		}
 	}
 
-	for cn, v := range is.values {
-		kind, exist := t.schema[cn]
-		// column is not defined in the schema
-		if !exist {
+	// the defaults are only evaluated for the columns not given, so that
+	// no sequence is advanced in vain
+	env := &evalEnv{table: t, sess: SessionFromContext(ctx), db: db}
+	r := &Row{
+		fields: make(map[string]any),
+	}
+	for _, cn := range t.columns {
+		if given[cn] {
+			continue
+		}
+		val, err := t.defaultValue(env, cn)
+		if err != nil {
 			return &Result{
-				err: errors.Errorf("column(%s) not exist", cn),
+				err: err,
 			}
 		}
-		// the given value kind is not as defined
-		tk := reflect.TypeOf(v).Kind()
-		if tk != kind {
+		r.fields[cn] = val
+	}
+	for i, v := range is.values {
+		cn := columns[i]
+		val, err := v.Eval(env)
+		if err != nil {
 			return &Result{
-				err: errors.Errorf("invalid column(%s) type: "+
-					"given(%s), expect(%s)", cn, tk, kind),
+				err: err,
 			}
 		}
+		if val, err = t.coerce(cn, val); err != nil {
+			return &Result{
+				err: err,
+			}
+		}
+		r.fields[cn] = val
+	}
+	// primary key cannot be empty
+	key, err := t.rowKey(is.table.name, r)
+	if err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+	if _, exist := t.rows[key]; exist {
+		return &Result{
+			err: t.duplicateKey(is.table.name, r),
+		}
+	}
 
-		r.fields[cn] = v
+	env.row = r
+	if err := t.checkRow(env, is.table.name); err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+	if err := t.checkUnique([]*Row{r}, nil); err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+	if err := db.rowSecurity(ctx, t, PrivInsert).check(env, is.table.name); err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+	// insert the row to the table, which may be referenced by the row
+	// itself
+	t.put(key, r)
+	if err := db.checkForeignKeys(is.table.name, t, []*Row{r}); err != nil {
+		t.remove(key)
+		return &Result{
+			err: err,
+		}
 	}
-	// insert the row to the table
-	t.rows[pk] = r
 	return &Result{
-		message: "1 ROW INSERTED",
+		affected: 1,
+		message:  "1 ROW INSERTED",
+	}
+}
+
+// Columns returns the names and types of the columns selected by the
+// statement.
+func (db *Database) Columns(ss *SelectStatement) (
+	[]string, []*Type, error) {
+	db.RLock()
+	defer db.RUnlock()
+	table, exist := db.tables[ss.table.name]
+	if !exist {
+		return nil, nil, newError(codeUndefinedTable,
+			"select from non-exist table %s", ss.table.name)
 	}
+	return table.selectList(ss.items)
 }
 
-func (db *Database) SelectFrom(ss *SelectStatement) *Result {
+// matchRows returns the keys of the rows that satisfy the where clause
+// and are visible under the row-level security in the order of the keys.
+// Only the keys narrowed by the where clause are scanned, see scanKeys.
+// The caller must hold the lock.
+func (db *Database) matchRows(ctx context.Context, t *Table,
+	where Expr, rs *rowSecurity) ([]string, error) {
+	var pks []string
+	err := db.scanRows(ctx, t, where, rs, func(pk string) error {
+		pks = append(pks, pk)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pks, nil
+}
+
+// scanRows calls the fn with the keys of the rows matched as matchRows
+// does one by one as they are scanned, the scan stops at the first error
+// of the fn. The caller must hold the lock.
+func (db *Database) scanRows(ctx context.Context, t *Table, where Expr,
+	rs *rowSecurity, fn func(pk string) error) error {
+	if err := t.checkExpr(where, false); err != nil {
+		return err
+	}
+	env := &evalEnv{table: t, sess: SessionFromContext(ctx), db: db}
+	for _, pk := range t.scanKeys(where) {
+		if err := ctx.Err(); err != nil {
+			return ctxError(err)
+		}
+		env.row = t.rows[pk]
+		visible, err := rs.visible(env)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			continue
+		}
+		match, err := evalBool(where, env)
+		if err != nil {
+			return err
+		}
+		if match {
+			if err := fn(pk); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (db *Database) SelectFrom(ctx context.Context, ss *SelectStatement) *Result {
 	db.RLock()
 	defer db.RUnlock()
-	table, exist := db.tables[ss.table]
+	table, exist := db.tables[ss.table.name]
 	if !exist {
 		return &Result{
-			err: errors.Errorf("select from non-exist table %s",
-				ss.table),
+			err: newError(codeUndefinedTable,
+				"select from non-exist table %s", ss.table.name),
 		}
 	}
 
+	cols, types, err := table.selectList(ss.items)
+	if err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+
+	sink := rowSinkFromContext(ctx)
+	if sink != nil {
+		if err := sink.columns(cols, types); err != nil {
+			return &Result{
+				err: err,
+			}
+		}
+	}
+	// the select list is evaluated as the where clause, the rows are
+	// collected unless they are sent to the sink as they are scanned
+	env := &evalEnv{table: table, sess: SessionFromContext(ctx), db: db}
 	var rs []*Row
-	var cols []string
-	selectAll := len(cols) == 0
-	if !selectAll && len(cols) == 0 {
-		cols = ss.fields
-	}
-
-	if ss.where == nil {
-		for _, r := range table.rows {
-			if selectAll && len(cols) == 0 {
-				// get all the column name
-				for col := range r.fields {
-					cols = append(cols, col)
-				}
+	err = db.scanRows(ctx, table, ss.where,
+		db.rowSecurity(ctx, table, PrivSelect), func(pk string) error {
+			env.row = table.rows[pk]
+			rows, err := project(env, ss.items, cols)
+			if err != nil {
+				return err
 			}
-			row := &Row{
-				fields: make(map[string]any),
-			}
-			if selectAll {
-				// fetch all fields
-				row.fields = r.fields
-			} else {
-				// only fetch the desired fields
-				cols = ss.fields
-				for _, f := range ss.fields {
-					val, exist := r.fields[f]
-					if !exist {
-						return &Result{
-							err: errors.Errorf("column %s not exist", f),
-						}
-					}
-					row.fields[f] = val
+			if sink == nil {
+				rs = append(rs, rows...)
+				return nil
+			}
+			for _, r := range rows {
+				if err := sink.row(r.values); err != nil {
+					return err
 				}
 			}
-			rs = append(rs, row)
+			return nil
+		})
+	if err != nil {
+		return &Result{
+			err: err,
 		}
 	}
 
-	// TODO (charleszheng44): filter by where clause
 	return &Result{
-		rows: rs,
-		cols: cols,
+		rows:  rs,
+		cols:  cols,
+		types: types,
 	}
 }
 
-func (db *Database) DeleteFrom(ds *DeleteStatement) *Result {
+func (db *Database) DeleteFrom(ctx context.Context, ds *DeleteStatement) *Result {
 	db.Lock()
 	defer db.Unlock()
-	table, exist := db.tables[ds.table]
+	table, exist := db.tables[ds.table.name]
 	if !exist {
 		return &Result{
-			err: errors.Errorf("delete from non-exist table %s",
-				ds.table),
+			err: newError(codeUndefinedTable,
+				"delete from non-exist table %s", ds.table.name),
 		}
 	}
 
-	// check type
-	given := reflect.TypeOf(ds.where.value).Kind()
-	expect := table.schema[ds.where.field]
-	if given != expect {
+	// the rows are deleted only if the where clause is evaluated on all
+	// rows without error
+	pks, err := db.matchRows(ctx, table, ds.where,
+		db.rowSecurity(ctx, table, PrivDelete))
+	if err != nil {
 		return &Result{
-			err: errors.Errorf("given value type is invalid: "+
-				"expect(%s) got(%s)", expect, given),
+			err: err,
 		}
 	}
-
-	var count int
-	for pk, r := range table.rows {
-		// TODO(charleszheng44): support more operator type
-		if reflect.DeepEqual(
-			r.fields[ds.where.field],
-			ds.where.value) {
-			delete(table.rows, pk)
-			count++
+	var undo undoLog
+	undo.record(table, pks...)
+	old := make([]*Row, len(pks))
+	for i, pk := range pks {
+		old[i] = table.rows[pk]
+		table.remove(pk)
+	}
+	env := &evalEnv{table: table, sess: SessionFromContext(ctx), db: db}
+	if err := db.propagate(env, &undo, ds.table.name, old, nil); err != nil {
+		undo.rollback()
+		return &Result{
+			err: err,
 		}
 	}
 
 	return &Result{
-		message: fmt.Sprintf("%d ROWS DELETED", count),
+		affected: len(pks),
+		message:  fmt.Sprintf("%d ROWS DELETED", len(pks)),
+	}
+}
+
+func (db *Database) UpdateTable(ctx context.Context, us *UpdateStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	table, exist := db.tables[us.table.name]
+	if !exist {
+		return &Result{
+			err: newError(codeUndefinedTable,
+				"update non-exist table %s", us.table.name),
+		}
+	}
+	for _, a := range us.assignments {
+		if _, exist := table.schema[a.column]; !exist {
+			return &Result{
+				err: newError(codeUndefinedColumn,
+					"column(%s) not exist", a.column),
+			}
+		}
+		if table.identities[a.column] {
+			return &Result{
+				err: newError(codeGeneratedAlways,
+					"column %s can only be updated to DEFAULT", a.column),
+			}
+		}
+		if err := table.checkExpr(a.value, false); err != nil {
+			return &Result{
+				err: err,
+			}
+		}
+	}
+
+	rs := db.rowSecurity(ctx, table, PrivUpdate)
+	pks, err := db.matchRows(ctx, table, us.where, rs)
+	if err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+
+	// compute the new rows before changing the table, so that the table
+	// is untouched if any of them is invalid
+	env := &evalEnv{table: table, sess: SessionFromContext(ctx), db: db}
+	updated := make([]*Row, len(pks))
+	for i, pk := range pks {
+		old := table.rows[pk]
+		row := &Row{
+			fields: make(map[string]any, len(old.fields)),
+		}
+		for cn, v := range old.fields {
+			row.fields[cn] = v
+		}
+		// the values are evaluated against the old row
+		env.row = old
+		for _, a := range us.assignments {
+			v, err := a.value.Eval(env)
+			if err != nil {
+				return &Result{
+					err: err,
+				}
+			}
+			if v, err = table.coerce(a.column, v); err != nil {
+				return &Result{
+					err: err,
+				}
+			}
+			row.fields[a.column] = v
+		}
+		env.row = row
+		if err := table.checkRow(env, us.table.name); err != nil {
+			return &Result{
+				err: err,
+			}
+		}
+		if err := rs.check(env, us.table.name); err != nil {
+			return &Result{
+				err: err,
+			}
+		}
+		updated[i] = row
 	}
 
+	var undo undoLog
+	old := make([]*Row, len(pks))
+	for i, pk := range pks {
+		old[i] = table.rows[pk]
+	}
+	if err := table.replaceRows(us.table.name, pks, updated, &undo); err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+	if err := db.checkForeignKeys(us.table.name, table, updated); err != nil {
+		undo.rollback()
+		return &Result{
+			err: err,
+		}
+	}
+	if err := db.propagate(env, &undo, us.table.name, old, updated); err != nil {
+		undo.rollback()
+		return &Result{
+			err: err,
+		}
+	}
+
+	return &Result{
+		affected: len(pks),
+		message:  fmt.Sprintf("%d ROWS UPDATED", len(pks)),
+	}
 }
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Decimal is the exact decimal number coef × 10^-scale, e.g., 1.50 is
+// 150 with the scale 2. It is immutable.
+type Decimal struct {
+	coef  *big.Int
+	scale int
+}
+
+// divScale is the minimal scale of the quotients, which keeps at least as
+// many fractional digits as PostgreSQL does for most operands.
+const divScale = 16
+
+var bigTen = big.NewInt(10)
+
+// pow10 returns 10^n.
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+func decimalFromInt(i int) Decimal {
+	return Decimal{coef: big.NewInt(int64(i))}
+}
+
+// decimalFromFloat converts the float by its shortest decimal
+// representation, e.g., 0.1 is exactly 0.1.
+func decimalFromFloat(f float64) (Decimal, error) {
+	return parseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// maxDigits is the most digits of the decimals before and after the
+// point, as many as DECIMAL(p, s) holds.
+const maxDigits = 1000
+
+var errDecimalOverflow = newError(codeNumericOutOfRange,
+	"value overflows numeric format")
+
+// parseDecimal parses the decimal, e.g., "-1.50" or "1.5e3", which is out
+// of range if it has more than maxDigits digits before or after the point.
+func parseDecimal(s string) (Decimal, error) {
+	invalid := newError(codeInvalidTextRepresent,
+		"invalid input syntax for type decimal: %q", s)
+	mantissa, exp := strings.TrimSpace(s), 0
+	if i := strings.IndexAny(mantissa, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(mantissa[i+1:], 10, 32)
+		if errors.Is(err, strconv.ErrRange) {
+			return Decimal{}, errDecimalOverflow
+		}
+		if err != nil {
+			return Decimal{}, invalid
+		}
+		mantissa, exp = mantissa[:i], int(e)
+	}
+	neg := strings.HasPrefix(mantissa, "-")
+	signs := len(mantissa)
+	mantissa = strings.TrimLeft(mantissa, "+-")
+	intPart, frac, _ := strings.Cut(mantissa, ".")
+	digits := intPart + frac
+	if digits == "" || strings.Trim(digits, "0123456789") != "" ||
+		signs-len(mantissa) > 1 {
+		return Decimal{}, invalid
+	}
+	scale := len(frac) - exp
+	significant := len(strings.TrimLeft(digits, "0"))
+	if significant == 0 && scale < 0 {
+		// zero whatever the exponent
+		scale = 0
+	}
+	if scale > maxDigits || significant-scale > maxDigits {
+		return Decimal{}, errDecimalOverflow
+	}
+	coef, _ := new(big.Int).SetString(digits, 10)
+	if neg {
+		coef.Neg(coef)
+	}
+	d := Decimal{coef: coef, scale: scale}
+	if d.scale < 0 {
+		d = Decimal{coef: coef.Mul(coef, pow10(-d.scale))}
+	}
+	return d, nil
+}
+
+// String returns the decimal with all the digits of its scale.
+func (d Decimal) String() string {
+	s := new(big.Int).Abs(d.coef).String()
+	if d.scale > 0 {
+		if len(s) <= d.scale {
+			s = strings.Repeat("0", d.scale-len(s)+1) + s
+		}
+		s = s[:len(s)-d.scale] + "." + s[len(s)-d.scale:]
+	}
+	if d.coef.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Float64 returns the nearest float of the decimal.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// rescale returns the decimal of the scale, which is rounded half away
+// from zero if the scale is reduced.
+func (d Decimal) rescale(scale int) Decimal {
+	switch {
+	case scale == d.scale:
+		return d
+	case scale > d.scale:
+		return Decimal{
+			coef:  new(big.Int).Mul(d.coef, pow10(scale-d.scale)),
+			scale: scale,
+		}
+	}
+	return Decimal{coef: roundQuo(d.coef, pow10(d.scale-scale)), scale: scale}
+}
+
+// roundQuo returns a / b rounded half away from zero.
+func roundQuo(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	// |r| * 2 >= |b| rounds away from zero
+	r.Abs(r).Lsh(r, 1)
+	if r.CmpAbs(b) >= 0 {
+		if a.Sign()*b.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// normalize removes the trailing zeros of the fraction, so that the equal
+// decimals are identical, e.g., 1.50 is 1.5.
+func (d Decimal) normalize() Decimal {
+	coef, scale := new(big.Int).Set(d.coef), d.scale
+	r := new(big.Int)
+	for scale > 0 {
+		q, _ := new(big.Int).QuoRem(coef, bigTen, r)
+		if r.Sign() != 0 {
+			break
+		}
+		coef, scale = q, scale-1
+	}
+	return Decimal{coef: coef, scale: scale}
+}
+
+// integerDigits returns the number of digits before the decimal point,
+// zero if the decimal is less than 1 in magnitude.
+func (d Decimal) integerDigits() int {
+	if d.coef.Sign() == 0 {
+		return 0
+	}
+	n := len(new(big.Int).Abs(d.coef).String()) - d.scale
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// align returns the coefficients of the decimals at their common scale.
+func align(a, b Decimal) (*big.Int, *big.Int, int) {
+	scale := a.scale
+	if b.scale > scale {
+		scale = b.scale
+	}
+	return a.rescale(scale).coef, b.rescale(scale).coef, scale
+}
+
+func (d Decimal) Cmp(o Decimal) int {
+	x, y, _ := align(d, o)
+	return x.Cmp(y)
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	x, y, scale := align(d, o)
+	return Decimal{coef: new(big.Int).Add(x, y), scale: scale}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	x, y, scale := align(d, o)
+	return Decimal{coef: new(big.Int).Sub(x, y), scale: scale}
+}
+
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{
+		coef:  new(big.Int).Mul(d.coef, o.coef),
+		scale: d.scale + o.scale,
+	}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.coef), scale: d.scale}
+}
+
+// Quo returns the quotient rounded to the scale of the operands, but at
+// least divScale.
+func (d Decimal) Quo(o Decimal) (Decimal, error) {
+	if o.coef.Sign() == 0 {
+		return Decimal{}, newError(codeDivisionByZero, "division by zero")
+	}
+	scale := divScale
+	if d.scale > scale {
+		scale = d.scale
+	}
+	if o.scale > scale {
+		scale = o.scale
+	}
+	// d / o = (d.coef × 10^(scale + o.scale - d.scale)) / o.coef × 10^-scale
+	num := new(big.Int).Mul(d.coef, pow10(scale+o.scale-d.scale))
+	return Decimal{coef: roundQuo(num, o.coef), scale: scale}, nil
+}
+
+// Mod returns the remainder of the truncated division, which has the sign
+// of the dividend.
+func (d Decimal) Mod(o Decimal) (Decimal, error) {
+	if o.coef.Sign() == 0 {
+		return Decimal{}, newError(codeDivisionByZero, "division by zero")
+	}
+	x, y, scale := align(d, o)
+	return Decimal{coef: new(big.Int).Rem(x, y), scale: scale}, nil
+}
+
+// MarshalJSON encodes the decimal as the exact JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// toDecimal converts the integer or the finite float to the decimal.
+func toDecimal(v any) (Decimal, bool) {
+	switch x := v.(type) {
+	case Decimal:
+		return x, true
+	case int:
+		return decimalFromInt(x), true
+	case float64:
+		d, err := decimalFromFloat(x)
+		return d, err == nil
+	}
+	return Decimal{}, false
+}
+
+// decimalOperands converts the numeric operands to decimals if either of
+// them is a decimal, which is false otherwise.
+func decimalOperands(a, b any) (Decimal, Decimal, bool) {
+	_, ad := a.(Decimal)
+	_, bd := b.(Decimal)
+	if !ad && !bd {
+		return Decimal{}, Decimal{}, false
+	}
+	x, ok := toDecimal(a)
+	if !ok {
+		return Decimal{}, Decimal{}, false
+	}
+	y, ok := toDecimal(b)
+	return x, y, ok
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/peterh/liner"
+)
+
+// errAborted is returned by ReadLine on Ctrl-C.
+var errAborted = errors.New("aborted")
+
+// historyFile is the name of the history file in the home directory.
+const historyFile = ".simple_db_history"
+
+// lineReader reads the input line by line.
+type lineReader interface {
+	// ReadLine returns io.EOF at the end of the input.
+	ReadLine(prompt string) (string, error)
+	// AppendHistory records the complete statement or meta-command,
+	// which may span multiple lines.
+	AppendHistory(entry string)
+}
+
+// scanReader reads the lines of a script, there is neither prompt nor
+// history.
+type scanReader struct {
+	scanner *bufio.Scanner
+}
+
+func newScanReader(in io.Reader) *scanReader {
+	return &scanReader{
+		scanner: bufio.NewScanner(in),
+	}
+}
+
+func (sr *scanReader) ReadLine(string) (string, error) {
+	if !sr.scanner.Scan() {
+		if err := sr.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return sr.scanner.Text(), nil
+}
+
+func (sr *scanReader) AppendHistory(string) {}
+
+// lineEditor is the readline-style editor of the interactive prompt,
+// which supports the cursor movement, the history, the reverse search
+// (Ctrl-R) and the tab completion.
+type lineEditor struct {
+	state *liner.State
+	// history is the path of the history file, empty if there is no home
+	// directory
+	history string
+}
+
+func newLineEditor(db *Database) *lineEditor {
+	le := &lineEditor{
+		state: liner.NewLiner(),
+	}
+	// the interrupt cancels the running statement instead, see interrupter
+	le.state.SetCtrlCAborts(true)
+	le.state.SetMultiLineMode(true)
+	le.state.SetTabCompletionStyle(liner.TabPrints)
+	le.state.SetWordCompleter(func(line string, pos int) (
+		string, []string, string) {
+		return complete(db, line, pos)
+	})
+	if home, err := os.UserHomeDir(); err == nil {
+		le.history = filepath.Join(home, historyFile)
+		if f, err := os.Open(le.history); err == nil {
+			le.state.ReadHistory(f)
+			f.Close()
+		}
+	}
+	return le
+}
+
+func (le *lineEditor) ReadLine(prompt string) (string, error) {
+	line, err := le.state.Prompt(prompt)
+	if err == liner.ErrPromptAborted {
+		return "", errAborted
+	}
+	return line, err
+}
+
+func (le *lineEditor) AppendHistory(entry string) {
+	le.state.AppendHistory(entry)
+}
+
+// Close saves the history and restores the terminal.
+func (le *lineEditor) Close() error {
+	if le.history != "" {
+		// the history records the passwords of CREATE USER and ALTER USER
+		f, err := os.OpenFile(le.history,
+			os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err == nil {
+			// the file may be created by the earlier versions
+			f.Chmod(0600)
+			le.state.WriteHistory(f)
+			f.Close()
+		}
+	}
+	return le.state.Close()
+}
+
+// completionWords are the non-reserved words completed besides the
+// keywords, the functions and the types.
+var completionWords = []string{"any", "array", "as", "case", "cast",
+	"disable", "else", "enable", "end", "index", "level", "password",
+	"policy", "privileges", "row", "security", "superuser", "tables", "then",
+	"trim", "when"}
+
+// tableKeyWords are the keywords followed by a table name.
+var tableKeyWords = map[string]bool{"from": true, "into": true,
+	"update": true, "table": true, "on": true, `\d`: true}
+
+var metaCommands = []string{`\?`, `\d`, `\di`, `\dt`, `\format`, `\i`, `\o`,
+	`\pset`, `\q`, `\timing`, `\x`}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// complete completes the word before the cursor. A table name is
+// expected after FROM, INTO, UPDATE, TABLE and ON, otherwise the
+// keywords and the columns of the tables mentioned in the line are
+// completed.
+func complete(db *Database, line string, pos int) (
+	head string, completions []string, tail string) {
+	rs := []rune(line)
+	if pos > len(rs) {
+		pos = len(rs)
+	}
+	start := pos
+	for start > 0 && isWordRune(rs[start-1]) {
+		start--
+	}
+	// the meta-commands
+	if start == 1 && rs[0] == '\\' {
+		start = 0
+	}
+	head, prefix, tail := string(rs[:start]), string(rs[start:pos]),
+		string(rs[pos:])
+
+	fields := strings.Fields(strings.ToLower(head))
+	// the names are completed as is, while the words follow the case of
+	// the prefix
+	var words, names []string
+	switch {
+	case strings.HasPrefix(prefix, `\`):
+		names = metaCommands
+	case len(fields) != 0 && tableKeyWords[fields[len(fields)-1]]:
+		names = db.TableNames()
+	default:
+		for kw := range keyWords {
+			if isWordRune([]rune(kw)[0]) {
+				words = append(words, kw)
+			}
+		}
+		words = append(words, completionWords...)
+		for name := range functions {
+			words = append(words, name)
+		}
+		for name := range typeNames {
+			words = append(words, name)
+		}
+		for _, name := range db.TableNames() {
+			if !containsWord(fields, strings.ToLower(name)) {
+				continue
+			}
+			if info, err := db.DescribeTable(name); err == nil {
+				names = append(names, info.Columns...)
+			}
+		}
+	}
+
+	upper := prefix != strings.ToLower(prefix)
+	seen := make(map[string]bool)
+	add := func(c string) {
+		if !strings.HasPrefix(strings.ToLower(c), strings.ToLower(prefix)) ||
+			seen[c] {
+			return
+		}
+		seen[c] = true
+		completions = append(completions, c)
+	}
+	for _, w := range words {
+		if upper {
+			w = strings.ToUpper(w)
+		}
+		add(w)
+	}
+	for _, name := range names {
+		add(name)
+	}
+	sort.Strings(completions)
+	return head, completions, tail
+}
+
+// containsWord checks if the table is mentioned by any of the words,
+// e.g., "t" in "t(id," or "t,".
+func containsWord(words []string, table string) bool {
+	for _, w := range words {
+		for _, f := range strings.FieldsFunc(w, func(r rune) bool {
+			return !isWordRune(r)
+		}) {
+			if f == table {
+				return true
+			}
+		}
+	}
+	return false
+}
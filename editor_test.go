@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table users (id integer primary key, name string)",
+		"create table orders (oid integer primary key, uid integer)",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	tts := []struct {
+		name     string
+		line     string
+		pos      int
+		head     string
+		expected []string
+	}{
+		{"Keyword", "sel", 3, "", []string{"select"}},
+		{"Upper case keyword", "SEL", 3, "", []string{"SELECT"}},
+		{"Table", "select * from u", 15, "select * from ", []string{"users"}},
+		{"All tables", "insert into ", 12, "insert into ", []string{"orders", "users"}},
+		{"Column", "select * from users where na", 28,
+			"select * from users where ", []string{"name"}},
+		{"Keyword and column", "update orders set u", 19,
+			"update orders set ",
+			[]string{"uid", "unique", "unnest", "update", "upper", "user", "using", "uuid"}},
+		{"Function", "select coal", 11, "select ", []string{"coalesce"}},
+		{"Functions", "select json_a", 13, "select ",
+			[]string{"json_array_length"}},
+		{"Type", "select 1::int", 13, "select 1::",
+			[]string{"int", "int2", "int4", "int8", "integer", "interval",
+				"into"}},
+		{"Cursor in the middle", "select * from o where", 15,
+			"select * from ", []string{"orders"}},
+		{"Meta-command", `\t`, 2, "", []string{`\timing`}},
+		{"Describe", `\d o`, 4, `\d `, []string{"orders"}},
+	}
+	for i, tt := range tts {
+		head, completions, _ := complete(db, tt.line, tt.pos)
+		if head != tt.head || !reflect.DeepEqual(completions, tt.expected) {
+			t.Fatalf("case %d (%s) failed: got (%q, %v), expect (%q, %v)",
+				i, tt.name, head, completions, tt.head, tt.expected)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
+
+// fakeReader reads the lines and records the history.
+type fakeReader struct {
+	lines   []string
+	history []string
+}
+
+func (fr *fakeReader) ReadLine(string) (string, error) {
+	if len(fr.lines) == 0 {
+		return "", io.EOF
+	}
+	line := fr.lines[0]
+	fr.lines = fr.lines[1:]
+	if line == "^C" {
+		return "", errAborted
+	}
+	return line, nil
+}
+
+func (fr *fakeReader) AppendHistory(entry string) {
+	fr.history = append(fr.history, entry)
+}
+
+func TestREPLHistory(t *testing.T) {
+	r, _ := newTestREPL()
+	fr := &fakeReader{
+		lines: []string{
+			"create table t (",
+			"  id integer primary key",
+			");",
+			`\dt`,
+			"select *",
+			"^C",
+			"select * from t; select",
+			"* from t;",
+			"-- the comments are dropped",
+			"select id -- the key",
+			"from t where 'a--b' <> 'c'; -- done",
+		},
+	}
+	if err := r.run(fr); err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+	expected := []string{
+		"create table t (   id integer primary key );",
+		`\dt`,
+		"select * from t; select * from t;",
+		"select id from t where 'a--b' <> 'c';",
+	}
+	if !reflect.DeepEqual(fr.history, expected) {
+		t.Fatalf("got history %q, expect %q", fr.history, expected)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// SQLSTATE codes reported to the clients, see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	codeInternalError                = "XX000"
+	codeSyntaxError                  = "42601"
+	codeUndefinedTable               = "42P01"
+	codeDuplicateTable               = "42P07"
+	codeDuplicateColumn              = "42701"
+	codeUndefinedColumn              = "42703"
+	codeDatatypeMismatch             = "42804"
+	codeNotNullViolation             = "23502"
+	codeQueryCanceled                = "57014"
+	codeInvalidParameterValue        = "22023"
+	codeFeatureNotSupported          = "0A000"
+	codeProtocolViolation            = "08P01"
+	codeInvalidStatementName         = "26000"
+	codeInvalidCursorName            = "34000"
+	codeInvalidTextRepresent         = "22P02"
+	codeUndefinedParameter           = "42P02"
+	codeInvalidPassword              = "28P01"
+	codeInvalidAuthorization         = "28000"
+	codeInsufficientPrivilege        = "42501"
+	codeDuplicateObject              = "42710"
+	codeUndefinedObject              = "42704"
+	codeDependentObjects             = "2BP01"
+	codeObjectInUse                  = "55006"
+	codeUndefinedFunction            = "42883"
+	codeUniqueViolation              = "23505"
+	codeCheckViolation               = "23514"
+	codeForeignKeyViolation          = "23503"
+	codeInvalidForeignKey            = "42830"
+	codeGeneratedAlways              = "428C9"
+	codeSequenceLimitExceeded        = "2200H"
+	codeNumericOutOfRange            = "22003"
+	codeDivisionByZero               = "22012"
+	codeStringDataRightTruncation    = "22001"
+	codeInvalidDatetimeFormat        = "22007"
+	codeDatetimeFieldOverflow        = "22008"
+	codeObjectNotInPrerequisiteState = "55000"
+	codeInvalidObjectDefinition      = "42P17"
+	codeWrongObjectType              = "42809"
+	codeIndeterminateDatatype        = "42P18"
+	codeCannotCoerce                 = "42846"
+	codeSubstringError               = "22011"
+	codeInvalidArgumentForPower      = "2201F"
+)
+
+// Error is an error carrying the SQLSTATE code.
+type Error struct {
+	Code string
+	Msg  string
+	// Pos is the position of the error in the statement, nil if unknown
+	Pos *Position
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// SQLState returns the SQLSTATE code of the error.
+func (e *Error) SQLState() string {
+	return e.Code
+}
+
+func newError(code, format string, args ...any) error {
+	return &Error{
+		Code: code,
+		Msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// newSyntaxError returns the syntax error at the position.
+func newSyntaxError(pos Position, format string, args ...any) error {
+	return &Error{
+		Code: codeSyntaxError,
+		Msg:  fmt.Sprintf(format, args...),
+		Pos:  &pos,
+	}
+}
+
+// atPosition returns the err at the position in the statement, which is
+// taken as a syntax error unless it is coded.
+func atPosition(err error, pos Position) error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return newSyntaxError(pos, "%v", err)
+	}
+	return &Error{Code: e.Code, Msg: e.Msg, Pos: &pos}
+}
+
+// errorPosition returns the position of the err in the statement, nil if
+// unknown.
+func errorPosition(err error) *Position {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Pos
+	}
+	return nil
+}
+
+// sqlState returns the SQLSTATE code of the err, errors that are not
+// coded are reported as internal errors.
+func sqlState(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return codeInternalError
+}
@@ -0,0 +1,813 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Expr is a scalar expression evaluated against a row.
+type Expr interface {
+	Eval(env *evalEnv) (any, error)
+	String() string
+}
+
+// evalEnv is the environment an expression is evaluated in.
+type evalEnv struct {
+	row *Row
+	// table is the table of the row, whose schema gives the types of the
+	// integer results to check their ranges, nil if none
+	table *Table
+	sess  *Session
+	// db is the database whose lock is held by the evaluation, nil if
+	// none, e.g., for the sequence functions
+	db *Database
+	// now is the time of the statement, which is taken by the first call
+	// of now()
+	now time.Time
+}
+
+type Operator int
+
+const (
+	equal Operator = iota
+	notEqual
+	less
+	lessEqual
+	greater
+	greaterEqual
+	and
+	or
+	not
+	concat
+	// isNull and isNotNull are the postfix IS [NOT] NULL
+	isNull
+	isNotNull
+	add
+	sub
+	mul
+	div
+	mod
+	// neg is the prefix minus
+	neg
+	// jsonGet and jsonGetText are -> and ->>, jsonPath and jsonPathText
+	// are #> and #>>, contains and containedBy are @> and <@ of JSONB and
+	// the arrays, and overlaps is && of the arrays
+	jsonGet
+	jsonGetText
+	jsonPath
+	jsonPathText
+	contains
+	containedBy
+	overlaps
+)
+
+func (op Operator) String() string {
+	switch op {
+	case equal:
+		return "="
+	case notEqual:
+		return "<>"
+	case less:
+		return "<"
+	case lessEqual:
+		return "<="
+	case greater:
+		return ">"
+	case greaterEqual:
+		return ">="
+	case and:
+		return "AND"
+	case or:
+		return "OR"
+	case not:
+		return "NOT"
+	case concat:
+		return "||"
+	case isNull:
+		return "IS NULL"
+	case isNotNull:
+		return "IS NOT NULL"
+	case add:
+		return "+"
+	case sub, neg:
+		return "-"
+	case mul:
+		return "*"
+	case div:
+		return "/"
+	case mod:
+		return "%"
+	case jsonGet:
+		return "->"
+	case jsonGetText:
+		return "->>"
+	case jsonPath:
+		return "#>"
+	case jsonPathText:
+		return "#>>"
+	case contains:
+		return "@>"
+	case containedBy:
+		return "<@"
+	case overlaps:
+		return "&&"
+	}
+	return "invalid"
+}
+
+// binaryOperators maps the keywords to the binary operators.
+var binaryOperators = map[KeyWord]Operator{
+	Equal:           equal,
+	NotEqual:        notEqual,
+	Less:            less,
+	LessEqual:       lessEqual,
+	Greater:         greater,
+	GreaterEqual:    greaterEqual,
+	And:             and,
+	Or:              or,
+	Concat:          concat,
+	Plus:            add,
+	Minus:           sub,
+	Star:            mul,
+	Slash:           div,
+	Percent:         mod,
+	Arrow:           jsonGet,
+	DoubleArrow:     jsonGetText,
+	HashArrow:       jsonPath,
+	HashDoubleArrow: jsonPathText,
+	AtGreater:       contains,
+	LessAt:          containedBy,
+	DoubleAmpersand: overlaps,
+}
+
+// precedence returns the binding power of the operator, the higher binds
+// tighter: OR < AND < NOT < IS < comparison < || and the JSON and the
+// array operators < + - < * / % < prefix -.
+func (op Operator) precedence() int {
+	switch op {
+	case or:
+		return 1
+	case and:
+		return 2
+	case not:
+		return 3
+	case isNull, isNotNull:
+		return 4
+	case concat, jsonGet, jsonGetText, jsonPath, jsonPathText, contains,
+		containedBy, overlaps:
+		return 6
+	case add, sub:
+		return 7
+	case mul, div, mod:
+		return 8
+	case neg:
+		return 9
+	default:
+		return 5
+	}
+}
+
+// comparison tells whether the operator is one of the comparisons.
+func (op Operator) comparison() bool {
+	return op >= equal && op <= greaterEqual
+}
+
+// postfix tells whether the unary operator follows its operand.
+func (op Operator) postfix() bool {
+	return op == isNull || op == isNotNull
+}
+
+type Literal struct {
+	val any
+}
+
+func (l *Literal) Eval(env *evalEnv) (any, error) {
+	return l.val, nil
+}
+
+func (l *Literal) String() string {
+	return formatLiteral(l.val)
+}
+
+func formatLiteral(val any) string {
+	switch v := val.(type) {
+	case Decimal:
+		if s, ok := decimalLiteral(v); ok {
+			return s
+		}
+		return "decimal " + formatLiteral(v.String())
+	case Bytes, Date, TimeOfDay, Timestamp, TimestampTZ, Interval,
+		JSON, UUID:
+		// the typed literal, e.g., decimal '1.50'
+		return valueType(v) + " " + formatLiteral(toText(v))
+	case Array:
+		if len(v.elems) == 0 {
+			return formatLiteral(v.String())
+		}
+		elems := make([]string, len(v.elems))
+		for i, e := range v.elems {
+			elems[i] = formatLiteral(e)
+		}
+		return "ARRAY[" + strings.Join(elems, ", ") + "]"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		// keep the float a float, e.g., 2.0 rather than 2
+		if !strings.ContainsAny(s, ".eIN") {
+			s += ".0"
+		}
+		return s
+	case nil:
+		return "NULL"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// decimalLiteral returns the numeric literal of the decimal, e.g., 1.50,
+// which is false if it would be read as an integer, e.g., 1.
+func decimalLiteral(d Decimal) (string, bool) {
+	s := d.String()
+	if _, err := strconv.Atoi(s); err == nil {
+		return "", false
+	}
+	return s, true
+}
+
+// toText returns the text representation of the non-NULL value.
+func toText(val any) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	return formatLiteral(val)
+}
+
+type ColumnRef struct {
+	name string
+}
+
+func (c *ColumnRef) Eval(env *evalEnv) (any, error) {
+	if env.row == nil {
+		return nil, newError(codeUndefinedColumn,
+			"column %s does not exist", c.name)
+	}
+	val, exist := env.row.fields[c.name]
+	if !exist {
+		return nil, newError(codeUndefinedColumn,
+			"column %s does not exist", c.name)
+	}
+	return val, nil
+}
+
+func (c *ColumnRef) String() string {
+	return c.name
+}
+
+func (p Param) Eval(env *evalEnv) (any, error) {
+	return nil, newError(codeUndefinedParameter,
+		"there is no parameter %s", p)
+}
+
+type BinaryExpr struct {
+	op          Operator
+	left, right Expr
+}
+
+func (b *BinaryExpr) Eval(env *evalEnv) (any, error) {
+	lv, err := b.left.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if b.op == and || b.op == or {
+		// the three-valued logic, in which NULL is unknown: FALSE AND
+		// NULL is FALSE while TRUE AND NULL is NULL, and vice versa for OR
+		dominant := b.op == or
+		lb, err := toNullableBool(lv)
+		if err != nil {
+			return nil, err
+		}
+		// short circuit
+		if lb != nil && *lb == dominant {
+			return dominant, nil
+		}
+		rv, err := b.right.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		rb, err := toNullableBool(rv)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case rb != nil && *rb == dominant:
+			return dominant, nil
+		case lb == nil || rb == nil:
+			return nil, nil
+		}
+		return !dominant, nil
+	}
+
+	rv, err := b.right.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if lv == nil || rv == nil {
+		// the operators on NULL yield NULL
+		return nil, nil
+	}
+	_, la := lv.(Array)
+	_, ra := rv.(Array)
+	switch b.op {
+	case concat:
+		if la || ra {
+			return arrayOperator(b.op, lv, rv)
+		}
+		return toText(lv) + toText(rv), nil
+	case add, sub, mul, div, mod:
+		v, err := arithmetic(b.op, lv, rv)
+		if err != nil {
+			return nil, err
+		}
+		return env.checkInt(b, v)
+	case contains, containedBy, overlaps:
+		if la || ra || b.op == overlaps {
+			return arrayOperator(b.op, lv, rv)
+		}
+		return jsonOperator(b.op, lv, rv)
+	case jsonGet, jsonGetText, jsonPath, jsonPathText:
+		return jsonOperator(b.op, lv, rv)
+	}
+	c, err := compareValues(lv, rv)
+	if err != nil {
+		return nil, errors.Wrapf(err, "operator %s", b.op)
+	}
+	if !b.op.comparison() {
+		return nil, newError(codeFeatureNotSupported,
+			"unsupported operator %s", b.op)
+	}
+	return compared(b.op, c), nil
+}
+
+// compared returns the result of the comparison operator from the result
+// of compareValues.
+func compared(op Operator, c int) bool {
+	switch op {
+	case equal:
+		return c == 0
+	case notEqual:
+		return c != 0
+	case less:
+		return c < 0
+	case lessEqual:
+		return c <= 0
+	case greater:
+		return c > 0
+	}
+	return c >= 0
+}
+
+func (b *BinaryExpr) String() string {
+	return "(" + b.left.String() + " " + b.op.String() + " " +
+		b.right.String() + ")"
+}
+
+type UnaryExpr struct {
+	op   Operator
+	expr Expr
+}
+
+func (u *UnaryExpr) Eval(env *evalEnv) (any, error) {
+	v, err := u.expr.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch u.op {
+	case isNull:
+		return v == nil, nil
+	case isNotNull:
+		return v != nil, nil
+	case neg:
+		if v == nil {
+			return nil, nil
+		}
+		if v, err = negate(v); err != nil {
+			return nil, err
+		}
+		return env.checkInt(u, v)
+	}
+	b, err := toNullableBool(v)
+	if err != nil || b == nil {
+		// NOT NULL is NULL
+		return nil, err
+	}
+	return !*b, nil
+}
+
+func (u *UnaryExpr) String() string {
+	if u.op.postfix() {
+		return "(" + u.expr.String() + " " + u.op.String() + ")"
+	}
+	return "(" + u.op.String() + " " + u.expr.String() + ")"
+}
+
+type FuncCall struct {
+	name string
+	args []Expr
+}
+
+func (f *FuncCall) Eval(env *evalEnv) (any, error) {
+	fn, args, err := f.call(env)
+	if err != nil {
+		return nil, err
+	}
+	if fn.set != nil {
+		return nil, newError(codeFeatureNotSupported,
+			"set-returning function %s is not allowed here", f.name)
+	}
+	return fn.eval(env, args)
+}
+
+// call evaluates the arguments of the call and checks them against the
+// signature of the function, see checkCall.
+func (f *FuncCall) call(env *evalEnv) (*Function, []any, error) {
+	args := make([]any, len(f.args))
+	types := make([]*Type, len(f.args))
+	for i, arg := range f.args {
+		v, err := arg.Eval(env)
+		if err != nil {
+			return nil, nil, err
+		}
+		args[i] = v
+		if v != nil {
+			types[i] = typeOf(v)
+		}
+	}
+	fn, err := checkCall(f.name, types)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fn, args, nil
+}
+
+func (f *FuncCall) String() string {
+	if field, ok := f.extractField(); ok {
+		return "extract(" + field + " FROM " + f.args[1].String() + ")"
+	}
+	if f.name == "position" && len(f.args) == 2 {
+		return "position(" + f.args[0].String() + " IN " + f.args[1].String() + ")"
+	}
+	args := make([]string, len(f.args))
+	for i, arg := range f.args {
+		args[i] = arg.String()
+	}
+	return f.name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// extractField returns the field of "extract(field FROM source)" as it is
+// written, which is false for the other calls.
+func (f *FuncCall) extractField() (string, bool) {
+	if f.name != "extract" || len(f.args) != 2 {
+		return "", false
+	}
+	l, ok := f.args[0].(*Literal)
+	if !ok {
+		return "", false
+	}
+	field, ok := l.val.(string)
+	if !ok {
+		return "", false
+	}
+	if quoteIdent(field) != field {
+		return formatLiteral(field), true
+	}
+	return field, true
+}
+
+// coalesce returns the first non-NULL argument, or NULL if all of them
+// are NULL.
+func coalesce(env *evalEnv, args []any) (any, error) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
+
+// nullIf returns NULL if the two arguments are equal, otherwise the first
+// one.
+func nullIf(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || args[1] == nil {
+		return args[0], nil
+	}
+	c, err := compareValues(args[0], args[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "nullif")
+	}
+	if c == 0 {
+		return nil, nil
+	}
+	return args[0], nil
+}
+
+// currentSetting returns the run-time parameter of the session, the
+// optional second argument tells whether a missing parameter yields NULL
+// instead of an error.
+func currentSetting(env *evalEnv, args []any) (any, error) {
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, newError(codeDatatypeMismatch,
+			"the name of the setting must be a string")
+	}
+	missingOK := false
+	if len(args) == 2 {
+		b, ok := args[1].(bool)
+		if !ok {
+			return nil, newError(codeDatatypeMismatch,
+				"missing_ok of current_setting must be a boolean")
+		}
+		missingOK = b
+	}
+	val, exist := env.sess.Get(name)
+	if !exist {
+		if missingOK {
+			return nil, nil
+		}
+		return nil, newError(codeUndefinedObject,
+			"unrecognized configuration parameter %q", name)
+	}
+	return val, nil
+}
+
+func currentUser(env *evalEnv, args []any) (any, error) {
+	return env.sess.User(), nil
+}
+
+// toNullableBool converts the value to a boolean, which is nil for NULL.
+func toNullableBool(v any) (*bool, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := toBool(v)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// toBool converts the value to a boolean, NULL is taken as false.
+func toBool(v any) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case nil:
+		return false, nil
+	default:
+		return false, newError(codeDatatypeMismatch,
+			"argument must be type boolean, not %s", valueType(v))
+	}
+}
+
+// compareValues compares two non-NULL values, integers, floats and
+// decimals are comparable with each other.
+func compareValues(a, b any) (int, error) {
+	if ad, bd, ok := decimalOperands(a, b); ok {
+		return ad.Cmp(bd), nil
+	}
+	if _, ok := a.(JSON); ok {
+		return compareJSONValues(a, b)
+	}
+	if _, ok := b.(JSON); ok {
+		return compareJSONValues(a, b)
+	}
+	_, aa := a.(Array)
+	_, ba := b.(Array)
+	if aa || ba {
+		a, b, err := arrayOperands(a, b)
+		if err != nil {
+			return 0, err
+		}
+		x, xok := a.(Array)
+		y, yok := b.(Array)
+		if xok && yok {
+			return compareArrays(x, y)
+		}
+	}
+	a, b, err := uuidOperands(a, b)
+	if err != nil {
+		return 0, err
+	}
+	if isTemporal(a) || isTemporal(b) {
+		a, b, err := temporalOperands(a, b)
+		if err != nil {
+			return 0, err
+		}
+		if c, ok := compareTemporal(a, b); ok {
+			return c, nil
+		}
+	}
+	switch av := a.(type) {
+	case int:
+		switch bv := b.(type) {
+		case int:
+			return compareOrdered(av, bv), nil
+		case float64:
+			return compareOrdered(float64(av), bv), nil
+		}
+	case float64:
+		switch bv := b.(type) {
+		case int:
+			return compareOrdered(av, float64(bv)), nil
+		case float64:
+			return compareOrdered(av, bv), nil
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), nil
+		}
+	case Bytes:
+		if bv, ok := b.(Bytes); ok {
+			return bytes.Compare(av, bv), nil
+		}
+	case UUID:
+		if bv, ok := b.(UUID); ok {
+			return bytes.Compare(av[:], bv[:]), nil
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			if av == bv {
+				return 0, nil
+			}
+			if !av {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, newError(codeUndefinedFunction,
+		"cannot compare %s with %s", valueType(a), valueType(b))
+}
+
+func compareOrdered[T int | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// evalBool evaluates the predicate, NULL is taken as false.
+func evalBool(e Expr, env *evalEnv) (bool, error) {
+	if e == nil {
+		return true, nil
+	}
+	v, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	return toBool(v)
+}
+
+// walkExpr calls the fn on the expression and its sub-expressions.
+func walkExpr(e Expr, fn func(Expr)) {
+	if e == nil {
+		return
+	}
+	fn(e)
+	switch v := e.(type) {
+	case *BinaryExpr:
+		walkExpr(v.left, fn)
+		walkExpr(v.right, fn)
+	case *UnaryExpr:
+		walkExpr(v.expr, fn)
+	case *FuncCall:
+		for _, arg := range v.args {
+			walkExpr(arg, fn)
+		}
+	case *ArrayExpr:
+		for _, elem := range v.elems {
+			walkExpr(elem, fn)
+		}
+	case *Subscript:
+		walkExpr(v.expr, fn)
+		walkExpr(v.index, fn)
+	case *QuantifiedExpr:
+		walkExpr(v.left, fn)
+		walkExpr(v.array, fn)
+	case *CastExpr:
+		walkExpr(v.expr, fn)
+	case *CaseExpr:
+		walkExpr(v.operand, fn)
+		for _, w := range v.whens {
+			walkExpr(w.cond, fn)
+			walkExpr(w.result, fn)
+		}
+		walkExpr(v.els, fn)
+	}
+}
+
+// rewriteExpr returns a copy of the expression with each sub-expression
+// replaced by the result of the fn.
+func rewriteExpr(e Expr, fn func(Expr) (Expr, error)) (Expr, error) {
+	if e == nil {
+		return nil, nil
+	}
+	switch v := e.(type) {
+	case *BinaryExpr:
+		left, err := rewriteExpr(v.left, fn)
+		if err != nil {
+			return nil, err
+		}
+		right, err := rewriteExpr(v.right, fn)
+		if err != nil {
+			return nil, err
+		}
+		e = &BinaryExpr{op: v.op, left: left, right: right}
+	case *UnaryExpr:
+		sub, err := rewriteExpr(v.expr, fn)
+		if err != nil {
+			return nil, err
+		}
+		e = &UnaryExpr{op: v.op, expr: sub}
+	case *FuncCall:
+		args := make([]Expr, len(v.args))
+		for i, arg := range v.args {
+			a, err := rewriteExpr(arg, fn)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = a
+		}
+		e = &FuncCall{name: v.name, args: args}
+	case *ArrayExpr:
+		elems := make([]Expr, len(v.elems))
+		for i, elem := range v.elems {
+			x, err := rewriteExpr(elem, fn)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = x
+		}
+		e = &ArrayExpr{elems: elems}
+	case *Subscript:
+		sub, err := rewriteExpr(v.expr, fn)
+		if err != nil {
+			return nil, err
+		}
+		index, err := rewriteExpr(v.index, fn)
+		if err != nil {
+			return nil, err
+		}
+		e = &Subscript{expr: sub, index: index}
+	case *QuantifiedExpr:
+		left, err := rewriteExpr(v.left, fn)
+		if err != nil {
+			return nil, err
+		}
+		array, err := rewriteExpr(v.array, fn)
+		if err != nil {
+			return nil, err
+		}
+		e = &QuantifiedExpr{op: v.op, all: v.all, left: left, array: array}
+	case *CastExpr:
+		sub, err := rewriteExpr(v.expr, fn)
+		if err != nil {
+			return nil, err
+		}
+		e = &CastExpr{expr: sub, typ: v.typ}
+	case *CaseExpr:
+		operand, err := rewriteExpr(v.operand, fn)
+		if err != nil {
+			return nil, err
+		}
+		c := &CaseExpr{operand: operand, whens: make([]*CaseWhen, len(v.whens))}
+		for i, w := range v.whens {
+			cond, err := rewriteExpr(w.cond, fn)
+			if err != nil {
+				return nil, err
+			}
+			result, err := rewriteExpr(w.result, fn)
+			if err != nil {
+				return nil, err
+			}
+			c.whens[i] = &CaseWhen{cond: cond, result: result}
+		}
+		if c.els, err = rewriteExpr(v.els, fn); err != nil {
+			return nil, err
+		}
+		e = c
+	}
+	return fn(e)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+)
+
+// evalSQL evaluates the expression without any row.
+func evalSQL(t *testing.T, expr string) any {
+	t.Helper()
+	ss := mustParse(t, "select * from t where "+expr).(*SelectStatement)
+	v, err := ss.where.Eval(&evalEnv{sess: NewSession(defaultSuperuser)})
+	if err != nil {
+		t.Fatalf("failed to evaluate %q: %v", expr, err)
+	}
+	return v
+}
+
+func TestThreeValuedLogic(t *testing.T) {
+	tts := []struct {
+		expr   string
+		expect any
+	}{
+		{"null and false", false},
+		{"false and null", false},
+		{"null and true", nil},
+		{"true and null", nil},
+		{"null or true", true},
+		{"true or null", true},
+		{"null or false", nil},
+		{"null and null", nil},
+		{"not null", nil},
+		{"not (1 = null)", nil},
+		{"null = null", nil},
+		{"null is null", true},
+		{"1 is null", false},
+		{"null is not null", false},
+		{"not 1 = 2 is not null", false},
+		{"(1 = null) is null", true},
+		{"null || 'a' is null", true},
+		{"coalesce(null, null, 2, 3) = 2", true},
+		{"coalesce(null, null) is null", true},
+		{"nullif(1, 1) is null", true},
+		{"nullif(1, 2) = 1", true},
+		{"nullif(null, 1) is null", true},
+	}
+	for i, tt := range tts {
+		if got := evalSQL(t, tt.expr); got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %v, expect %v",
+				i, tt.expr, got, tt.expect)
+		}
+	}
+}
+
+func TestNullValues(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table t (id integer primary key, name string, score float)",
+		"insert into t values (1, 'a', 1.5)",
+		"insert into t (id, score) values (2, 2.5)",
+		"insert into t values (3, null, null)",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	tts := []struct {
+		name     string
+		sql      string
+		code     string
+		affected int
+	}{
+		{"IS NULL", "select * from t where name is null", "", 2},
+		{"IS NOT NULL", "select * from t where name is not null", "", 1},
+		{"Equal NULL", "select * from t where name = null", "", 0},
+		{"NOT skips NULL", "select * from t where not name = 'a'", "", 0},
+		{"OR with NULL", "select * from t where name = 'a' or score > 2", "", 2},
+		{"COALESCE", "select * from t where coalesce(name, 'x') = 'x'", "", 2},
+		{"NULLIF", "select * from t where nullif(score, 1.5) is null", "", 2},
+		{"Wrong NULLIF", "select * from t where nullif(name, 1) is null",
+			codeUndefinedFunction, 0},
+		{"NULL primary key", "insert into t values (null, 'b', 1.0)",
+			codeNotNullViolation, 0},
+		{"Update to NULL", "update t set score = null where id = 1", "", 1},
+		{"Delete NULL", "delete from t where score is null", "", 2},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		n := r.affected
+		if r.cols != nil {
+			n = len(r.rows)
+		}
+		if r.err == nil && n != tt.affected {
+			t.Fatalf("case %d (%s) failed: got %d rows, expect %d",
+				i, tt.name, n, tt.affected)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// reference is a foreign key referencing a table.
+type reference struct {
+	// table is the name of the referencing table
+	table string
+	t     *Table
+	fk    *Constraint
+}
+
+// resolveForeignKeys checks the foreign keys of the new table, which may
+// reference the table itself, and resolves the referenced columns
+// omitted for the primary keys. The caller must hold the lock.
+func (db *Database) resolveForeignKeys(name string, t *Table) error {
+	for _, c := range t.constraints {
+		if c.kind != foreignKeyConstraint {
+			continue
+		}
+		parent, exist := db.tables[c.refTable]
+		if c.refTable == name {
+			parent, exist = t, true
+		}
+		if !exist {
+			return newError(codeUndefinedTable,
+				"relation %s does not exist", c.refTable)
+		}
+		if c.refColumns == nil {
+			c.refColumns = append([]string(nil), parent.primaryKey...)
+		}
+		if len(c.refColumns) != len(c.columns) {
+			return newError(codeInvalidForeignKey,
+				"number of referencing and referenced columns for "+
+					"foreign key %s disagree", c.name)
+		}
+		for i, col := range c.refColumns {
+			typ, exist := parent.schema[col]
+			if !exist {
+				return newError(codeUndefinedColumn,
+					"column %s referenced in foreign key constraint "+
+						"does not exist", col)
+			}
+			if !typ.compatible(t.schema[c.columns[i]]) {
+				return newError(codeDatatypeMismatch,
+					"foreign key constraint %s cannot be implemented: key "+
+						"columns %s and %s are of incompatible types: %s "+
+						"and %s", c.name, c.columns[i], col,
+					t.schema[c.columns[i]], typ)
+			}
+		}
+		if !parent.isUniqueKey(c.refColumns) {
+			return newError(codeInvalidForeignKey,
+				"there is no unique constraint matching given keys for "+
+					"referenced table %s", c.refTable)
+		}
+	}
+	return nil
+}
+
+// isUniqueKey checks if the columns are the columns of the primary key or
+// a UNIQUE constraint in any order.
+func (t *Table) isUniqueKey(cols []string) bool {
+	sorted := func(ss []string) string {
+		ss = append([]string(nil), ss...)
+		sort.Strings(ss)
+		return strings.Join(ss, ",")
+	}
+	if sorted(t.primaryKey) == sorted(cols) {
+		return true
+	}
+	for _, c := range t.constraints {
+		if c.kind == uniqueConstraint && sorted(c.columns) == sorted(cols) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyFinder returns the function telling if any row of the table has the
+// values of the columns of a row on the key columns, which are the primary
+// key or a UNIQUE constraint. The primary key is looked up in the rows
+// directly, while the other keys in the keys of the UNIQUE constraint.
+func (t *Table) keyFinder(keyCols []string) func(r *Row, cols []string) bool {
+	pos := make(map[string]int, len(keyCols))
+	for i, col := range keyCols {
+		pos[col] = i
+	}
+	primary := len(keyCols) == len(t.primaryKey)
+	for _, col := range t.primaryKey {
+		_, exist := pos[col]
+		primary = primary && exist
+	}
+	if primary {
+		return func(r *Row, cols []string) bool {
+			var b []byte
+			for _, col := range t.primaryKey {
+				v := r.fields[cols[pos[col]]]
+				if v == nil {
+					return false
+				}
+				b = appendKey(b, v)
+			}
+			_, exist := t.rows[string(b)]
+			return exist
+		}
+	}
+	var unique *Constraint
+	for c := range t.uniques {
+		match := len(c.columns) == len(keyCols)
+		for _, col := range c.columns {
+			_, exist := pos[col]
+			match = match && exist
+		}
+		if match {
+			unique = c
+			break
+		}
+	}
+	return func(r *Row, cols []string) bool {
+		if unique == nil {
+			return false
+		}
+		// the columns of the row in the order of the UNIQUE constraint
+		ordered := make([]string, len(unique.columns))
+		for i, col := range unique.columns {
+			ordered[i] = cols[pos[col]]
+		}
+		k, ok := uniqueKey(r, ordered)
+		return ok && len(t.uniques[unique][k]) != 0
+	}
+}
+
+// keysBy returns the keys of the rows in order grouped by their values of
+// the columns, the rows with any NULL column are left out.
+func (t *Table) keysBy(cols []string) map[string][]string {
+	groups := make(map[string][]string)
+	for _, pk := range t.keys {
+		if k, ok := uniqueKey(t.rows[pk], cols); ok {
+			groups[k] = append(groups[k], pk)
+		}
+	}
+	return groups
+}
+
+// references returns the foreign keys referencing the table, ordered by
+// the referencing tables and the names. The caller must hold the lock.
+func (db *Database) references(name string) []*reference {
+	var refs []*reference
+	for child, t := range db.tables {
+		for _, c := range t.constraints {
+			if c.kind == foreignKeyConstraint && c.refTable == name {
+				refs = append(refs, &reference{table: child, t: t, fk: c})
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].table != refs[j].table {
+			return refs[i].table < refs[j].table
+		}
+		return refs[i].fk.name < refs[j].fk.name
+	})
+	return refs
+}
+
+// checkForeignKeys checks if the referenced keys of the new rows are
+// present, a key with any NULL column is not checked. The caller must
+// hold the lock.
+func (db *Database) checkForeignKeys(name string, t *Table, rows []*Row) error {
+	for _, c := range t.constraints {
+		if c.kind != foreignKeyConstraint {
+			continue
+		}
+		hasKey := db.tables[c.refTable].keyFinder(c.refColumns)
+		for _, r := range rows {
+			key, ok := uniqueKey(r, c.columns)
+			if !ok || hasKey(r, c.columns) {
+				continue
+			}
+			return newError(codeForeignKeyViolation,
+				"insert or update on table %s violates foreign key "+
+					"constraint %s: key (%s)=(%s) is not present in table %s",
+				name, c.name, strings.Join(c.columns, ", "), key, c.refTable)
+		}
+	}
+	return nil
+}
+
+// propagate applies the ON DELETE or the ON UPDATE actions of the foreign
+// keys referencing the table, whose old rows are deleted, or replaced by
+// the new rows if the new is not nil. The actions propagate to the tables
+// referencing the changed rows in turn, and the replaced or removed rows
+// are recorded in the undo log. The caller must hold the lock and roll
+// back the undo log on error.
+func (db *Database) propagate(env *evalEnv, undo *undoLog, name string,
+	old, new []*Row) error {
+	t := db.tables[name]
+	for _, ref := range db.references(name) {
+		fk := ref.fk
+		action := fk.onDelete
+		if new != nil {
+			action = fk.onUpdate
+		}
+		hasKey := t.keyFinder(fk.refColumns)
+		var children map[string][]string
+		// the changes of the referencing rows keyed by the primary keys,
+		// the new row is nil if the row is deleted
+		var pks []string
+		var olds, news []*Row
+		for i, r := range old {
+			key, ok := uniqueKey(r, fk.refColumns)
+			if !ok {
+				continue
+			}
+			if new != nil {
+				if k, ok := uniqueKey(new[i], fk.refColumns); ok && k == key {
+					continue
+				}
+			}
+			if action == noAction && hasKey(r, fk.refColumns) {
+				continue
+			}
+			if children == nil {
+				children = ref.t.keysBy(fk.columns)
+			}
+			for _, pk := range children[key] {
+				child := ref.t.rows[pk]
+				if action == noAction || action == restrict {
+					return newError(codeForeignKeyViolation,
+						"update or delete on table %s violates foreign key "+
+							"constraint %s on table %s: key (%s)=(%s) is "+
+							"still referenced from table %s", name, fk.name,
+						ref.table, strings.Join(fk.refColumns, ", "), key,
+						ref.table)
+				}
+				var row *Row
+				if action == setNull || new != nil {
+					row = &Row{fields: make(map[string]any, len(child.fields))}
+					for cn, v := range child.fields {
+						row.fields[cn] = v
+					}
+					for j, col := range fk.columns {
+						row.fields[col] = nil
+						if action == cascade {
+							row.fields[col] = new[i].fields[fk.refColumns[j]]
+						}
+					}
+				}
+				pks = append(pks, pk)
+				olds = append(olds, child)
+				news = append(news, row)
+			}
+		}
+		if len(pks) == 0 {
+			continue
+		}
+
+		if action == cascade && new == nil {
+			undo.record(ref.t, pks...)
+			for _, pk := range pks {
+				ref.t.remove(pk)
+			}
+			if err := db.propagate(env, undo, ref.table, olds, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		env.table = ref.t
+		for _, row := range news {
+			env.row = row
+			if err := ref.t.checkRow(env, ref.table); err != nil {
+				return err
+			}
+		}
+		if err := ref.t.replaceRows(ref.table, pks, news, undo); err != nil {
+			return err
+		}
+		if err := db.propagate(env, undo, ref.table, olds, news); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undoLog records the rows of the tables before they are replaced or
+// removed, so that a statement failing halfway, e.g., by the actions of
+// the foreign keys, puts them back.
+type undoLog []undoEntry
+
+// undoEntry is the row of the key before the change, which is nil if
+// there is no row of the key.
+type undoEntry struct {
+	t   *Table
+	key string
+	row *Row
+}
+
+// record records the current rows of the keys, it does nothing on the
+// nil log.
+func (u *undoLog) record(t *Table, keys ...string) {
+	if u == nil {
+		return
+	}
+	for _, key := range keys {
+		*u = append(*u, undoEntry{t: t, key: key, row: t.rows[key]})
+	}
+}
+
+// rollback puts back the recorded rows in the reverse order.
+func (u *undoLog) rollback() {
+	for i := len(*u) - 1; i >= 0; i-- {
+		e := (*u)[i]
+		if e.row == nil {
+			e.t.remove(e.key)
+		} else {
+			e.t.put(e.key, e.row)
+		}
+	}
+	*u = nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestForeignKeys(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table orders (id integer primary key, code string unique)",
+		"create table items (id integer primary key, " +
+			"order_id integer references orders on delete cascade " +
+			"on update cascade, " +
+			"code string, " +
+			"constraint items_code_fkey foreign key (code) " +
+			"references orders (code) on delete set null on update set null)",
+		"create table notes (id integer primary key, " +
+			"item_id integer references items on delete restrict)",
+		"create table tree (id integer primary key, " +
+			"parent integer references tree on delete cascade)",
+		"insert into orders values (1, 'a')",
+		"insert into orders values (2, 'b')",
+		"insert into items values (10, 1, 'a')",
+		"insert into items values (11, 1, 'a')",
+		"insert into items values (20, 2, 'b')",
+		"insert into notes values (100, 20)",
+		"insert into tree values (1, null)",
+		"insert into tree values (2, 1)",
+		"insert into tree values (3, 2)",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	count := func(sql string) int {
+		r := execSQL(db, defaultSuperuser, sql)
+		if r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+		return len(r.rows)
+	}
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		check  string
+		expect int
+	}{
+		{"Orphan", "insert into items values (12, 3, null)",
+			codeForeignKeyViolation, "", 0},
+		{"Null is not checked", "insert into items values (12, null, null)",
+			"", "select * from items where id = 12", 1},
+		{"Update to orphan", "update items set code = 'x' where id = 12",
+			codeForeignKeyViolation, "", 0},
+		{"Cascade update", "update orders set id = 3 where id = 1",
+			"", "select * from items where order_id = 3", 2},
+		{"Set null", "update orders set code = 'c' where id = 3", "",
+			"select * from items where code is null", 3},
+		{"Cascade delete", "delete from orders where id = 3", "",
+			"select * from items", 2},
+		{"Restrict", "delete from orders where id = 2",
+			codeForeignKeyViolation, "select * from items where id = 20", 1},
+		{"Restrict rolls back", "delete from orders",
+			codeForeignKeyViolation, "select * from orders", 1},
+		{"No action", "delete from items where id = 20",
+			codeForeignKeyViolation, "", 0},
+		{"Self reference", "insert into tree values (4, 4)", "",
+			"select * from tree", 4},
+		{"Recursive cascade", "delete from tree where id = 1", "",
+			"select * from tree", 1},
+		{"Drop referenced", "drop table items",
+			codeDependentObjects, "", 0},
+		{"Missing parent", "create table x (id integer primary key " +
+			"references missing)", codeUndefinedTable, "", 0},
+		{"Not unique", "create table x (id integer primary key, " +
+			"code string references items (code))", codeInvalidForeignKey, "", 0},
+		{"Mismatched types", "create table x (id string primary key " +
+			"references orders)", codeDatatypeMismatch, "", 0},
+		{"Drop cascade", "drop table items cascade", "",
+			"select * from notes", 1},
+		{"Constraint dropped", "insert into notes values (101, 99)", "",
+			"select * from notes", 2},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		if tt.check != "" {
+			if n := count(tt.check); n != tt.expect {
+				t.Fatalf("case %d (%s) failed: got %d rows, expect %d",
+					i, tt.name, n, tt.expect)
+			}
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	r := execSQL(db, defaultSuperuser, "drop table orders")
+	if r.err != nil {
+		t.Fatalf("failed to drop the table: %v", r.err)
+	}
+	info, err := db.DescribeTable("tree")
+	if err != nil || len(info.Constraints) != 1 || !strings.Contains(
+		info.Constraints[0], "tree_parent_fkey FOREIGN KEY (parent) "+
+			"REFERENCES tree(id) ON DELETE CASCADE") {
+		t.Fatalf("unexpected constraints %v (%v)", info, err)
+	}
+}
+
+func TestForeignKeyRollback(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table p (a integer, b string, primary key (a, b))",
+		"create table c (id integer primary key, x string, y integer, " +
+			"unique (x, y), " +
+			"foreign key (y, x) references p (a, b) on update cascade)",
+		"create index c_x on c (x)",
+		"create table d (id integer primary key, dx string, dy integer, " +
+			"foreign key (dx, dy) references c (x, y) on update restrict)",
+		"insert into p values (1, 'a')",
+		"insert into p values (2, 'b')",
+		"insert into c values (10, 'a', 1)",
+		"insert into c values (11, 'b', 2)",
+		"insert into d values (100, 'b', 2)",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		check  string
+		expect int
+	}{
+		{"Key in other order", "insert into c values (12, 'a', 2)",
+			codeForeignKeyViolation, "", 0},
+		{"Unique key", "insert into d values (101, 'a', 1)", "",
+			"select * from d", 2},
+		{"Missing unique key", "insert into d values (102, 'a', 2)",
+			codeForeignKeyViolation, "", 0},
+		{"Cascade restricted", "update p set b = 'z' where a = 1",
+			codeForeignKeyViolation, "select * from c where x = 'a'", 1},
+		{"No action rolls back", "delete from c", codeForeignKeyViolation,
+			"select * from c", 2},
+		{"Delete", "delete from d where id = 101", "",
+			"select * from d", 1},
+		{"Cascade", "update p set b = 'z' where a = 1", "",
+			"select * from c where x = 'z' and y = 1", 1},
+		{"Cascade rolls back", "update p set b = b || '!'",
+			codeForeignKeyViolation, "select * from p where b = 'b'", 1},
+		{"Index rolled back", "select * from c where x = 'b!'", "",
+			"select * from c where x = 'b'", 1},
+		{"Rows rolled back", "select * from c where x = 'z'", "",
+			"select * from c where x = 'z!'", 0},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		if tt.check != "" {
+			r := execSQL(db, defaultSuperuser, tt.check)
+			if r.err != nil || len(r.rows) != tt.expect {
+				t.Fatalf("case %d (%s) failed: got %d rows (%v), expect %d",
+					i, tt.name, len(r.rows), r.err, tt.expect)
+			}
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
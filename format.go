@@ -0,0 +1,574 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// formatOptions are the options of the SQL formatter.
+type formatOptions struct {
+	// upper tells whether the keywords are in upper case
+	upper bool
+	// indent is the indentation of the continuation lines
+	indent int
+	// width is the line width beyond which the lists and the predicates
+	// are wrapped, 0 means no wrapping
+	width int
+}
+
+func defaultFormatOptions() *formatOptions {
+	return &formatOptions{
+		upper:  true,
+		indent: 4,
+		width:  80,
+	}
+}
+
+// formatter turns the statements back into the normalized SQL text, the
+// output is parsed into the same statements, while the comments and the
+// original layout are not kept.
+type formatter struct {
+	opts *formatOptions
+	b    strings.Builder
+	// col is the width of the current line
+	col int
+}
+
+// formatScript formats the statements, each of which ends with a
+// semicolon and a newline.
+func formatScript(stmts []Statement, opts *formatOptions) string {
+	f := &formatter{opts: opts}
+	for i, stmt := range stmts {
+		if i != 0 {
+			f.write("\n")
+		}
+		f.statement(stmt)
+		f.write(";\n")
+	}
+	return f.b.String()
+}
+
+// formatSQL parses and formats the statements of the sql, all the syntax
+// errors are returned if any.
+func formatSQL(sql string, opts *formatOptions) (string, []error) {
+	tks, err := Tokenize([]rune(sql))
+	if err != nil {
+		return "", []error{err}
+	}
+	stmts, errs := parseScript(tks)
+	if len(errs) != 0 {
+		return "", errs
+	}
+	return formatScript(stmts, opts), nil
+}
+
+func (f *formatter) write(s string) {
+	f.b.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		f.col = width(s[i+1:])
+		return
+	}
+	f.col += width(s)
+}
+
+// kw returns the keywords in the configured case.
+func (f *formatter) kw(s string) string {
+	if f.opts.upper {
+		return strings.ToUpper(s)
+	}
+	return strings.ToLower(s)
+}
+
+// newline starts a new line with the indentation.
+func (f *formatter) newline(indent bool) {
+	f.write("\n")
+	if indent {
+		f.write(strings.Repeat(" ", f.opts.indent))
+	}
+}
+
+// fits checks if the text fits in the current line.
+func (f *formatter) fits(s string) bool {
+	return f.opts.width <= 0 || f.col+width(s) <= f.opts.width
+}
+
+// list writes the items separated by commas, or one item per indented
+// line if they do not fit in the line or wrap is forced. The inline items
+// are led by the lead, e.g., the space following SELECT, which is left
+// out of the wrapped list so that no line ends with a space, and the
+// items of the wrapped list are followed by the close, e.g., ")".
+func (f *formatter) list(lead string, items []string, force bool, close string) {
+	inline := lead + strings.Join(items, ", ")
+	if !force && f.fits(inline+close) {
+		f.write(inline + close)
+		return
+	}
+	for i, item := range items {
+		f.newline(true)
+		f.write(item)
+		if i != len(items)-1 {
+			f.write(",")
+		}
+	}
+	if close != "" {
+		f.newline(false)
+		f.write(strings.TrimSpace(close))
+	}
+}
+
+func (f *formatter) statement(stmt Statement) {
+	switch s := stmt.(type) {
+	case *SelectStatement:
+		f.write(f.kw("select"))
+		if s.items == nil {
+			f.write(" *")
+		} else {
+			items := make([]string, len(s.items))
+			for i, item := range s.items {
+				items[i] = f.expr(item.expr)
+				if item.alias != "" {
+					items[i] += " " + f.kw("as") + " " + quoteIdent(item.alias)
+				}
+			}
+			f.list(" ", items, false, "")
+		}
+		f.newline(false)
+		f.write(f.kw("from") + " " + quoteIdent(s.table.name))
+		f.where(s.where)
+	case *InsertStatement:
+		f.write(f.kw("insert into") + " " + quoteIdent(s.table.name))
+		if s.columns != nil {
+			cols := make([]string, len(s.columns))
+			for i, col := range s.columns {
+				cols[i] = quoteIdent(col)
+			}
+			f.write(" (")
+			f.list("", cols, false, ")")
+		}
+		f.newline(false)
+		f.write(f.kw("values") + " (")
+		f.list("", f.exprs(s.values), false, ")")
+	case *UpdateStatement:
+		f.write(f.kw("update") + " " + quoteIdent(s.table.name))
+		f.newline(false)
+		f.write(f.kw("set"))
+		items := make([]string, len(s.assignments))
+		for i, a := range s.assignments {
+			items[i] = quoteIdent(a.column) + " = " + f.expr(a.value)
+		}
+		f.list(" ", items, false, "")
+		f.where(s.where)
+	case *DeleteStatement:
+		f.write(f.kw("delete from") + " " + quoteIdent(s.table.name))
+		f.where(s.where)
+	case *CreateStatement:
+		f.write(f.kw("create table") + " " + quoteIdent(s.table.name) + " (")
+		items := make([]string, 0, len(s.columns)+len(s.constraints))
+		for _, col := range s.columns {
+			typ := col.typ.String()
+			if col.serial {
+				typ = "serial"
+			}
+			item := quoteIdent(col.name) + " " + f.kw(typ)
+			if col.primaryKey {
+				item += " " + f.kw("primary key")
+			}
+			if col.defaultValue != nil {
+				item += " " + f.kw("default") + " " + f.expr(col.defaultValue)
+			}
+			if id := col.identity; id != nil {
+				generated := "generated by default as identity"
+				if id.always {
+					generated = "generated always as identity"
+				}
+				item += " " + f.kw(generated)
+				if opts := f.sequenceOptions(&id.options); opts != "" {
+					item += " (" + opts + ")"
+				}
+			}
+			for _, c := range col.constraints {
+				item += " " + f.constraint(c, true)
+			}
+			items = append(items, item)
+		}
+		for _, c := range s.constraints {
+			items = append(items, f.constraint(c, false))
+		}
+		f.list("", items, true, ")")
+	case *DropStatement:
+		f.write(f.kw("drop table") + " " + quoteIdent(s.table.name))
+		if s.cascade {
+			f.write(" " + f.kw("cascade"))
+		}
+	case *SetStatement:
+		parts := strings.Split(s.name, ".")
+		for i, part := range parts {
+			parts[i] = quoteIdent(part)
+		}
+		f.write(f.kw("set") + " " + strings.Join(parts, ".") + " = " +
+			formatLiteral(s.value))
+	case *CreateUserStatement:
+		f.write(f.kw("create user") + " " + quoteIdent(s.name))
+		f.userOptions(s.password, s.superuser)
+	case *AlterUserStatement:
+		f.write(f.kw("alter user") + " " + quoteIdent(s.name))
+		f.userOptions(s.password, s.superuser)
+	case *DropUserStatement:
+		f.write(f.kw("drop user") + " " + quoteIdent(s.name))
+	case *GrantStatement:
+		f.grant(s)
+	case *CreatePolicyStatement:
+		f.write(f.kw("create policy") + " " + quoteIdent(s.name) + " " +
+			f.kw("on") + " " + quoteIdent(s.table.name))
+		if s.command != PrivAll {
+			f.newline(true)
+			f.write(f.kw("for") + " " + f.kw(s.command.String()))
+		}
+		if s.using != nil {
+			f.newline(true)
+			f.write(f.kw("using") + " (" + f.expr(s.using) + ")")
+		}
+		if s.check != nil {
+			f.newline(true)
+			f.write(f.kw("with check") + " (" + f.expr(s.check) + ")")
+		}
+	case *DropPolicyStatement:
+		f.write(f.kw("drop policy") + " " + quoteIdent(s.name) + " " +
+			f.kw("on") + " " + quoteIdent(s.table.name))
+	case *CreateSequenceStatement:
+		f.write(f.kw("create sequence") + " " + quoteIdent(s.name))
+		if opts := f.sequenceOptions(&s.options); opts != "" {
+			f.write(" " + opts)
+		}
+	case *DropSequenceStatement:
+		f.write(f.kw("drop sequence") + " " + quoteIdent(s.name))
+	case *CreateIndexStatement:
+		f.write(f.kw("create index") + " ")
+		if s.name != "" {
+			f.write(quoteIdent(s.name) + " ")
+		}
+		f.write(f.kw("on") + " " + quoteIdent(s.table.name) + " (")
+		items := make([]string, len(s.exprs))
+		for i, e := range s.exprs {
+			items[i] = f.indexItem(e)
+		}
+		f.list("", items, false, ")")
+	case *DropIndexStatement:
+		f.write(f.kw("drop index") + " " + quoteIdent(s.name))
+	case *AlterTableStatement:
+		action := "disable"
+		if s.rowSecurity {
+			action = "enable"
+		}
+		f.write(f.kw("alter table") + " " + quoteIdent(s.table.name) + " " +
+			f.kw(action+" row level security"))
+	}
+}
+
+// indexItem returns the text of the indexed column or expression, the
+// expressions other than the function calls are parenthesized.
+func (f *formatter) indexItem(e Expr) string {
+	switch e.(type) {
+	case *ColumnRef, *FuncCall:
+		return f.expr(e)
+	}
+	return "(" + f.expr(e) + ")"
+}
+
+// where writes the WHERE clause, the top-level ANDs or ORs are wrapped
+// if the predicate does not fit in the line.
+func (f *formatter) where(where Expr) {
+	if where == nil {
+		return
+	}
+	f.newline(false)
+	f.write(f.kw("where") + " ")
+	if e := f.expr(where); f.fits(e) {
+		f.write(e)
+		return
+	}
+	b, ok := where.(*BinaryExpr)
+	if !ok || (b.op != and && b.op != or) {
+		f.write(f.expr(where))
+		return
+	}
+	// the chain of the same operator, which is left-associative
+	var operands []Expr
+	e := where
+	for {
+		be, ok := e.(*BinaryExpr)
+		if !ok || be.op != b.op {
+			break
+		}
+		operands = append([]Expr{be.right}, operands...)
+		e = be.left
+	}
+	operands = append([]Expr{e}, operands...)
+	for i, operand := range operands {
+		if i != 0 {
+			f.newline(true)
+			f.write(f.kw(b.op.String()) + " ")
+		}
+		f.write(f.operand(operand, b.op, i != 0))
+	}
+}
+
+// constraint returns the text of the column or the table constraint.
+func (f *formatter) constraint(c *Constraint, column bool) string {
+	s := ""
+	if c.name != "" {
+		s = f.kw("constraint") + " " + quoteIdent(c.name) + " "
+	}
+	switch c.kind {
+	case notNullConstraint:
+		return s + f.kw("not null")
+	case uniqueConstraint:
+		s += f.kw("unique")
+		if column {
+			return s
+		}
+		return s + " " + columnList(c.columns)
+	case primaryKeyConstraint:
+		return s + f.kw("primary key") + " " + columnList(c.columns)
+	case foreignKeyConstraint:
+		if !column {
+			s += f.kw("foreign key") + " " + columnList(c.columns) + " "
+		}
+		s += f.kw("references") + " " + quoteIdent(c.refTable)
+		if c.refColumns != nil {
+			s += " " + columnList(c.refColumns)
+		}
+		if c.onDelete != noAction {
+			s += " " + f.kw("on delete "+c.onDelete.String())
+		}
+		if c.onUpdate != noAction {
+			s += " " + f.kw("on update "+c.onUpdate.String())
+		}
+		return s
+	default:
+		return s + f.kw("check") + " (" + f.expr(c.check) + ")"
+	}
+}
+
+// sequenceOptions returns the given options of the sequence, empty if
+// there is none.
+func (f *formatter) sequenceOptions(opts *SequenceOptions) string {
+	var items []string
+	for _, o := range []struct {
+		name  string
+		value *int
+	}{
+		{"increment by", opts.increment},
+		{"minvalue", opts.minValue},
+		{"maxvalue", opts.maxValue},
+		{"start with", opts.start},
+	} {
+		if o.value != nil {
+			items = append(items, f.kw(o.name)+" "+strconv.Itoa(*o.value))
+		}
+	}
+	if opts.cycle != nil {
+		cycle := "no cycle"
+		if *opts.cycle {
+			cycle = "cycle"
+		}
+		items = append(items, f.kw(cycle))
+	}
+	return strings.Join(items, " ")
+}
+
+// columnList returns the parenthesized column names.
+func columnList(cols []string) string {
+	quoted := make([]string, len(cols))
+	for i, col := range cols {
+		quoted[i] = quoteIdent(col)
+	}
+	return "(" + strings.Join(quoted, ", ") + ")"
+}
+
+func (f *formatter) userOptions(password *string, superuser *bool) {
+	if password == nil && superuser == nil {
+		return
+	}
+	f.write(" " + f.kw("with"))
+	if password != nil {
+		f.write(" " + f.kw("password") + " " + formatLiteral(*password))
+	}
+	if superuser != nil {
+		if *superuser {
+			f.write(" " + f.kw("superuser"))
+		} else {
+			f.write(" " + f.kw("nosuperuser"))
+		}
+	}
+}
+
+func (f *formatter) grant(gs *GrantStatement) {
+	verb, prep := "grant", "to"
+	if gs.revoke {
+		verb, prep = "revoke", "from"
+	}
+	privs := f.kw("all privileges")
+	if gs.privileges != PrivAll {
+		privs = f.kw(gs.privileges.String())
+	}
+	table := f.kw("all tables")
+	if gs.table != allTables {
+		table = f.kw("table") + " " + quoteIdent(gs.table)
+	}
+	f.write(f.kw(verb) + " " + privs + " " + f.kw("on") + " " + table +
+		" " + f.kw(prep) + " " + quoteIdent(gs.user))
+}
+
+func (f *formatter) exprs(es []Expr) []string {
+	ss := make([]string, len(es))
+	for i, e := range es {
+		ss[i] = f.expr(e)
+	}
+	return ss
+}
+
+// expr returns the text of the expression with the parentheses only
+// where the precedence requires.
+func (f *formatter) expr(e Expr) string {
+	switch v := e.(type) {
+	case *Literal:
+		switch val := v.val.(type) {
+		case bool:
+			return f.kw(strconv.FormatBool(val))
+		case nil:
+			return f.kw("null")
+		case Decimal:
+			if s, ok := decimalLiteral(val); ok {
+				return s
+			}
+			return f.kw("decimal") + " " + formatLiteral(val.String())
+		case Bytes, Date, TimeOfDay, Timestamp, TimestampTZ, Interval,
+			JSON, UUID:
+			return f.kw(valueType(val)) + " " + formatLiteral(toText(val))
+		case Array:
+			if len(val.elems) == 0 {
+				return formatLiteral(val.String())
+			}
+			elems := make([]Expr, len(val.elems))
+			for i, e := range val.elems {
+				elems[i] = &Literal{val: e}
+			}
+			return f.expr(&ArrayExpr{elems: elems})
+		}
+		return formatLiteral(v.val)
+	case Param:
+		return v.String()
+	case *ColumnRef:
+		return quoteIdent(v.name)
+	case *FuncCall:
+		if v.name == "current_user" && len(v.args) == 0 {
+			return f.kw(v.name)
+		}
+		if field, ok := v.extractField(); ok {
+			return "extract(" + field + " " + f.kw("from") + " " +
+				f.expr(v.args[1]) + ")"
+		}
+		if v.name == "position" && len(v.args) == 2 {
+			return "position(" + f.expr(v.args[0]) + " " + f.kw("in") + " " +
+				f.expr(v.args[1]) + ")"
+		}
+		return quoteIdent(v.name) + "(" + strings.Join(f.exprs(v.args), ", ") + ")"
+	case *UnaryExpr:
+		if v.op.postfix() {
+			return f.operand(v.expr, v.op, false) + " " + f.kw(v.op.String())
+		}
+		if v.op == neg {
+			s := f.operand(v.expr, v.op, false)
+			// "--" starts a comment
+			if strings.HasPrefix(s, "-") {
+				s = "(" + s + ")"
+			}
+			return "-" + s
+		}
+		return f.kw(v.op.String()) + " " + f.operand(v.expr, v.op, false)
+	case *BinaryExpr:
+		return f.operand(v.left, v.op, false) + " " + f.kw(v.op.String()) +
+			" " + f.operand(v.right, v.op, true)
+	case *ArrayExpr:
+		return f.kw("array") + "[" + strings.Join(f.exprs(v.elems), ", ") + "]"
+	case *Subscript:
+		s := f.expr(v.expr)
+		switch v.expr.(type) {
+		case *BinaryExpr, *UnaryExpr, *QuantifiedExpr:
+			s = "(" + s + ")"
+		}
+		return s + "[" + f.expr(v.index) + "]"
+	case *CaseExpr:
+		s := f.kw("case")
+		if v.operand != nil {
+			s += " " + f.expr(v.operand)
+		}
+		for _, w := range v.whens {
+			s += " " + f.kw("when") + " " + f.expr(w.cond) + " " + f.kw("then") +
+				" " + f.expr(w.result)
+		}
+		if v.els != nil {
+			s += " " + f.kw("else") + " " + f.expr(v.els)
+		}
+		return s + " " + f.kw("end")
+	case *CastExpr:
+		return f.kw("cast") + "(" + f.expr(v.expr) + " " + f.kw("as") + " " +
+			f.kw(v.typ.String()) + ")"
+	case *QuantifiedExpr:
+		quantifier := "any"
+		if v.all {
+			quantifier = "all"
+		}
+		return f.operand(v.left, v.op, false) + " " + v.op.String() + " " +
+			f.kw(quantifier) + " (" + f.expr(v.array) + ")"
+	}
+	return e.String()
+}
+
+// operand returns the text of the operand of the operator, which is
+// parenthesized if it binds looser than the operator. The operators are
+// left-associative, so the right operand of the same precedence is
+// parenthesized as well.
+func (f *formatter) operand(e Expr, op Operator, right bool) string {
+	s := f.expr(e)
+	prec := op.precedence()
+	switch v := e.(type) {
+	case *BinaryExpr:
+		if p := v.op.precedence(); p < prec || (right && p == prec) {
+			return "(" + s + ")"
+		}
+	case *QuantifiedExpr:
+		if p := v.op.precedence(); p < prec || (right && p == prec) {
+			return "(" + s + ")"
+		}
+	case *UnaryExpr:
+		// NOT takes the comparisons following it as its operand, and so
+		// does IS NULL preceding it
+		if prec > v.op.precedence() {
+			return "(" + s + ")"
+		}
+	}
+	return s
+}
+
+// reservedWords are the words taken as the parts of the expressions
+// rather than the identifiers.
+var reservedWords = map[string]bool{"case": true, "current_user": true,
+	"else": true, "end": true, "then": true, "when": true}
+
+// quoteIdent quotes the identifier unless it reads the same unquoted,
+// i.e., it is in lower case and is neither a keyword, a reserved word nor
+// a boolean.
+func quoteIdent(name string) string {
+	plain := name != "" && name == strings.ToLower(name) && !reservedWords[name]
+	for i, r := range name {
+		if (i == 0 && !isIdentStart(r)) || !isIdentRune(r) {
+			plain = false
+		}
+	}
+	if plain && tokenize(name).Type == UnquoteStringToken {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
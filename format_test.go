@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var roundTripSQL = []string{
+	"create table t (id integer primary key, name string, score float, ok boolean)",
+	`create table "Order" ("Id" integer primary key, "select" string, "a""b" integer)`,
+	"create table t (id integer primary key default 1 check (id > 0), " +
+		"name string not null constraint u unique default 'x' || 'y' null, " +
+		"constraint c check (name <> 'a' or id is null), unique (id, name))",
+	"create table c (id integer primary key references p on delete cascade, " +
+		"a integer constraint fk references \"P\" (x) on update set null on delete restrict, " +
+		"foreign key (id, a) references p (x, y) on update no action on update cascade)",
+	"create table m (a integer, b string, primary key (a, b))",
+	"create table s (id serial primary key, " +
+		"n integer generated always as identity (increment by -1 start with -10 no cycle), " +
+		"m integer generated by default as identity)",
+	"create table n (id bigint primary key, a smallint, b decimal(10, 2), " +
+		"c numeric, d varchar(20), e bytea, f int4)",
+	"create table e (id timestamp primary key, d date, t time, " +
+		"z timestamp with time zone, w timestamp without time zone, i interval)",
+	"select * from t where d = date '2024-01-31' and ts + interval '1 day' > " +
+		"timestamptz '2024-01-01 00:00:00+08' and t - time '12:00' < interval 'PT1H'",
+	"select * from t where extract(year from d) = 2024 and extract('ISODOW' from now()) > 5 " +
+		"and date_trunc('month', ts) = timestamp '2024-02-01'",
+	"select * from t where payload -> 'a' ->> 0 = 'x' and payload #> '{a,b}' @> " +
+		"jsonb '{\"c\": 1}' and '[1]' <@ payload #>> '{a}'",
+	"create table a (id uuid primary key default gen_random_uuid(), tags text[], " +
+		"n integer[3][], v varchar(5)[])",
+	"select * from t where tags[1] = 'a' and 'x' = any (tags) and n > all ('{1,2}') " +
+		"and tags @> array['a', null] and tags && array[lower(x), 'y'] and (a || b)[2] = 1 " +
+		"and id = uuid 'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11' and (a = some (b)) = c " +
+		"and array[1.5, 2] <@ n and tags <> array[]::text[] and cast(array[] as integer[]) = n",
+	"select * from t where cast(a as integer) = '1'::bigint + b::decimal(5, 2) " +
+		"and (a || b)::text[] @> tags[1::integer]::varchar(3)[] and -x::float > 0",
+	"create index i on t ((payload ->> 'kind'), lower(name), id)",
+	"create index on t (id)",
+	"drop index i",
+	"create sequence s increment by 2 minvalue -5 maxvalue 100 start with 3 cycle",
+	"create sequence \"S\"",
+	"drop sequence s",
+	"create table m (a integer, b string, constraint m_key primary key (b, a))",
+	"select * from t",
+	"select position('-' in sku), trim(leading 'x' from name), trim(both from a), " +
+		"substr(b, 1, 2), greatest(a, 1.5) from t where split_part(c, ',', -1) = upper(d)",
+	"select id, name as n, lower(name) l, current_user, current_setting('x', true) from t",
+	"select price * qty as total, case when a then 'x' when b is null then 'y' else z end, " +
+		"case id + 1 when 1 then 2 end \"end\", unnest(tags), \"case\" from t " +
+		"where case when a then b end",
+	"select * from t where a = 1 and (b = 2 or c = 3) and not d and e",
+	"select * from t where not (a and b) or not a = b or (not a) = b",
+	"select * from t where a or (b or c) and (d and e)",
+	"select * from t where (a = b) = c and a = (b = c)",
+	"select * from t where name || '!' || 'it''s' = 'x' || (y || z)",
+	"select * from t where x <> -5 and y >= 2.0 and z < 1e21 and w <= .5 and v != $1",
+	"select * from t where a is null and not b is not null or (not a) is null",
+	"select * from t where a + b * c - -d = (a - b) * -c % 2 and x / (y / z) > -(-1)",
+	"select * from t where a - (b + c) || d = -(a + b) and - a is null",
+	`insert into t values (decimal '1.50', bytea '\x0aff', numeric '-0.5e-3')`,
+	"select * from t where (a is null) = b and a = b is null and coalesce(a, null) is null",
+	"insert into t values (1, 'a', 1.5, true)",
+	"insert into t (id, name) values ($1, null)",
+	"update t set name = 'b', score = score where id = 1",
+	"delete from t where \"current_user\" = current_user",
+	"delete from t",
+	"drop table t",
+	"drop table t cascade",
+	"set statement_timeout = '5s'",
+	"set app.tenant to a",
+	`set "user" = 1`,
+	"create user alice with password 'pw' nosuperuser",
+	"create user bob",
+	"alter user alice superuser",
+	"drop user alice",
+	"grant all on all tables to alice",
+	"grant select, insert, update, delete, create, drop, references on t to alice",
+	"revoke select, update on table t from alice",
+	"create policy p on t for select using (tenant = current_setting('app.tenant'))",
+	"create policy p on t with check (a) ",
+	"create policy p on t for update using (a) with check (b)",
+	"drop policy p on t",
+	"alter table t enable row level security",
+	"alter table t disable row level security",
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, opts := range []*formatOptions{
+		defaultFormatOptions(),
+		{upper: false, indent: 2, width: 20},
+		{upper: true, indent: 0, width: 0},
+	} {
+		for i, sql := range roundTripSQL {
+			sts := mustParse(t, sql)
+			formatted := formatScript([]Statement{sts}, opts)
+			got, err := parseSQL(formatted)
+			if err != nil {
+				t.Fatalf("case %d (%q): failed to parse the formatted %q: %v",
+					i, sql, formatted, err)
+			}
+			if !reflect.DeepEqual(got, sts) {
+				t.Fatalf("case %d (%q): got %#v from %q, expect %#v",
+					i, sql, got, formatted, sts)
+			}
+			for _, line := range strings.Split(formatted, "\n") {
+				if strings.HasSuffix(line, " ") {
+					t.Fatalf("case %d (%q): got the trailing space in %q",
+						i, sql, formatted)
+				}
+			}
+			// formatting the formatted statement changes nothing
+			if again := formatScript([]Statement{got}, opts); again != formatted {
+				t.Fatalf("case %d (%q): got %q, expect %q",
+					i, sql, again, formatted)
+			}
+		}
+	}
+}
+
+func TestFormatSQL(t *testing.T) {
+	got, errs := formatSQL("select id,name from t where id=1 and "+
+		"name='some long name' or id = 2 and name = 'another long name';"+
+		"create table t (id integer primary key, name string)",
+		defaultFormatOptions())
+	if len(errs) != 0 {
+		t.Fatalf("failed to format: %v", errs)
+	}
+	expect := "SELECT id, name\n" +
+		"FROM t\n" +
+		"WHERE id = 1 AND name = 'some long name'\n" +
+		"    OR id = 2 AND name = 'another long name';\n" +
+		"\n" +
+		"CREATE TABLE t (\n" +
+		"    id INTEGER PRIMARY KEY,\n" +
+		"    name STRING\n" +
+		");\n"
+	if got != expect {
+		t.Fatalf("got\n%s\nexpect\n%s", got, expect)
+	}
+
+	// the numeric literals are kept exact
+	got, errs = formatSQL("select 100000000000000000000, 1.50, 1e3 from t",
+		defaultFormatOptions())
+	expect = "SELECT 100000000000000000000, 1.50, DECIMAL '1000'\nFROM t;\n"
+	if len(errs) != 0 || got != expect {
+		t.Fatalf("got %q (%v), expect %q", got, errs, expect)
+	}
+
+	_, errs = formatSQL("select * form t; select 1 from t; update t", nil)
+	if len(errs) != 2 {
+		t.Fatalf("got errors %v, expect 2 errors", errs)
+	}
+}
+
+func TestRunFmt(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.sql")
+	bad := filepath.Join(dir, "bad.sql")
+	os.WriteFile(good, []byte("-- dropped\nselect * from t where a=1"), 0644)
+	os.WriteFile(bad, []byte("select * from t;\nselect * form t;"), 0644)
+
+	var stdout, stderr bytes.Buffer
+	if code := runFmt([]string{"-w", "-lower", good, bad}, nil,
+		&stdout, &stderr); code != 1 {
+		t.Fatalf("got exit code %d, expect 1", code)
+	}
+	if !strings.Contains(stderr.String(), bad+":2:10: ") {
+		t.Fatalf("unexpected errors %q", stderr.String())
+	}
+	b, _ := os.ReadFile(good)
+	if string(b) != "select *\nfrom t\nwhere a = 1;\n" {
+		t.Fatalf("unexpected formatted file %q", b)
+	}
+
+	stdout.Reset()
+	if code := runFmt(nil, strings.NewReader("drop table t"), &stdout,
+		&stderr); code != 0 || stdout.String() != "DROP TABLE t;\n" {
+		t.Fatalf("got exit code %d and output %q", code, stdout.String())
+	}
+}
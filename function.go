@@ -0,0 +1,389 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// paramKind is the kind of the values a parameter of a function takes.
+type paramKind int
+
+const (
+	anyKind paramKind = iota
+	textKind
+	integerKind
+	numberKind
+	booleanKind
+	// temporalKind, jsonKind, jsonbKind and arrayKind take the
+	// strings as well, which are the untyped literals of the types
+	temporalKind
+	jsonKind
+	jsonbKind
+	arrayKind
+)
+
+func (k paramKind) String() string {
+	switch k {
+	case textKind:
+		return "text"
+	case integerKind:
+		return "integer"
+	case numberKind:
+		return "numeric"
+	case booleanKind:
+		return "boolean"
+	case temporalKind:
+		return "date/time"
+	case jsonKind:
+		return "json"
+	case jsonbKind:
+		return "jsonb"
+	case arrayKind:
+		return "array"
+	}
+	return "any"
+}
+
+// accepts tells whether the parameter takes the values of the type.
+func (k paramKind) accepts(t *Type) bool {
+	textual := t.id == stringID || t.id == varcharID
+	switch k {
+	case textKind:
+		return textual
+	case integerKind:
+		return t.integral()
+	case numberKind:
+		return t.numeric()
+	case booleanKind:
+		return t.id == booleanID
+	case temporalKind:
+		return textual || t.temporal()
+	case jsonKind:
+		return textual || t.id == jsonID
+	case jsonbKind:
+		return textual || t.id == jsonbID
+	case arrayKind:
+		return textual || t.id == arrayID
+	}
+	return true
+}
+
+// Function is a built-in function of the registry, whose calls are
+// checked against its signature, see checkCall, both before the statement
+// is executed, with the types of the expressions of the arguments, and as
+// they are evaluated, with the types of the values.
+type Function struct {
+	params []paramKind
+	// minArgs is the number of the leading parameters which must be given
+	minArgs int
+	// variadic tells whether the last parameter is repeated
+	variadic bool
+	// result returns the type of the result from the types of the
+	// arguments, some of which are nil if unknown, nil if unknown itself
+	result func(args []*Type) *Type
+	// volatile tells whether the results may change for the same
+	// arguments, which cannot be indexed
+	volatile bool
+	// eval evaluates the call of the scalar function, while set evaluates
+	// that of the set-returning one, e.g., unnest, which returns the set
+	// of the values
+	eval func(env *evalEnv, args []any) (any, error)
+	set  func(env *evalEnv, args []any) ([]any, error)
+}
+
+// arity describes the number of the arguments of the function, e.g., "2
+// or 3 arguments".
+func (f *Function) arity() string {
+	n := strconv.Itoa(f.minArgs)
+	switch {
+	case f.variadic:
+		n = "at least " + n
+	case f.minArgs == len(f.params) && f.minArgs == 0:
+		return "no argument"
+	case f.minArgs+1 == len(f.params):
+		n += " or " + strconv.Itoa(len(f.params))
+	case f.minArgs < len(f.params):
+		n += " to " + strconv.Itoa(len(f.params))
+	}
+	if f.minArgs == 1 && (len(f.params) == 1 || f.variadic) {
+		return n + " argument"
+	}
+	return n + " arguments"
+}
+
+// param returns the kind of the i-th parameter.
+func (f *Function) param(i int) paramKind {
+	if i >= len(f.params) {
+		return f.params[len(f.params)-1]
+	}
+	return f.params[i]
+}
+
+// checkCall checks the number and the types of the arguments of the call
+// to the function, the types of which are nil if unknown, e.g., of NULL.
+func checkCall(name string, args []*Type) (*Function, error) {
+	f, exist := functions[name]
+	if !exist {
+		return nil, newError(codeUndefinedFunction,
+			"function %s does not exist", name)
+	}
+	if len(args) < f.minArgs || (len(args) > len(f.params) && !f.variadic) {
+		return nil, newError(codeUndefinedFunction,
+			"function %s takes %s, got %d", name, f.arity(), len(args))
+	}
+	for i, typ := range args {
+		if typ == nil || f.param(i).accepts(typ) {
+			continue
+		}
+		names := make([]string, len(args))
+		for j, t := range args {
+			names[j] = "unknown"
+			if t != nil {
+				names[j] = t.String()
+			}
+		}
+		return nil, newError(codeUndefinedFunction,
+			"function %s(%s) does not exist, argument %d must be %s",
+			name, strings.Join(names, ", "), i+1, f.param(i))
+	}
+	if name == "greatest" || name == "least" {
+		// the arguments are compared with each other
+		for i, a := range args {
+			for _, b := range args[i+1:] {
+				if a != nil && b != nil && !comparableTypes(a, b) {
+					return nil, newError(codeDatatypeMismatch,
+						"%s types %s and %s cannot be matched",
+						strings.ToUpper(name), a, b)
+				}
+			}
+		}
+	}
+	return f, nil
+}
+
+// comparableTypes tells whether the values of the types are compared
+// with each other, see compareValues, e.g., the numbers, or the strings
+// with the values taking the untyped literals.
+func comparableTypes(a, b *Type) bool {
+	literal := func(t *Type) bool {
+		return t.temporal() || t.id == jsonID || t.id == jsonbID ||
+			t.id == uuidID || t.id == arrayID || t.id == byteaID
+	}
+	textual := func(t *Type) bool { return t.id == stringID || t.id == varcharID }
+	return a.compatible(b) || (a.numeric() && b.numeric()) ||
+		(textual(a) && literal(b)) || (literal(a) && textual(b))
+}
+
+// checkExpr checks the calls of the functions in the expression of the
+// rows of the table before it is evaluated on any of them, see checkCall.
+// The set-returning functions are only allowed as the items of the select
+// list, see project.
+func (t *Table) checkExpr(e Expr, allowSet bool) error {
+	var err error
+	walkExpr(e, func(x Expr) {
+		fc, ok := x.(*FuncCall)
+		if !ok || err != nil {
+			return
+		}
+		types := make([]*Type, len(fc.args))
+		for i, arg := range fc.args {
+			types[i] = t.exprType(arg)
+		}
+		var f *Function
+		if f, err = checkCall(fc.name, types); err == nil &&
+			f.set != nil && (!allowSet || x != e) {
+			err = newError(codeFeatureNotSupported,
+				"set-returning function %s is not allowed here", fc.name)
+		}
+	})
+	return err
+}
+
+// returns returns the result of the function of the fixed type.
+func returns(typ *Type) func(args []*Type) *Type {
+	return func(args []*Type) *Type {
+		return typ
+	}
+}
+
+// returnsArg returns the result of the function of the type of its i-th
+// argument.
+func returnsArg(i int) func(args []*Type) *Type {
+	return func(args []*Type) *Type {
+		if i < len(args) {
+			return args[i]
+		}
+		return nil
+	}
+}
+
+// returnsNumber returns the type of the result of the math function on
+// the number, which is the type of the number without the modifiers.
+func returnsNumber(args []*Type) *Type {
+	if len(args) == 0 || args[0] == nil || !args[0].numeric() {
+		return nil
+	}
+	if args[0].id == decimalID {
+		return decimalType
+	}
+	return args[0]
+}
+
+// returnsWidest returns the type of the result of greatest and least,
+// which is the widest of the numbers, or the common type of the others.
+func returnsWidest(args []*Type) *Type {
+	var widest *Type
+	for _, typ := range args {
+		switch {
+		case typ == nil:
+			return nil
+		case widest == nil:
+			widest = typ
+		case widest.numeric() && typ.numeric():
+			widest = arithmeticType(widest, typ)
+		case !widest.compatible(typ):
+			return nil
+		}
+	}
+	return widest
+}
+
+// functions are the built-in functions keyed by the name.
+var functions = map[string]*Function{
+	// the conditional functions, the result of coalesce is of the common
+	// type of its arguments, see Table.commonType
+	"coalesce": {params: []paramKind{anyKind}, minArgs: 1, variadic: true,
+		eval: coalesce},
+	"nullif": {params: []paramKind{anyKind, anyKind}, minArgs: 2,
+		result: returnsArg(0), eval: nullIf},
+	"greatest": {params: []paramKind{anyKind}, minArgs: 1, variadic: true,
+		result: returnsWidest, eval: extremum(1)},
+	"least": {params: []paramKind{anyKind}, minArgs: 1, variadic: true,
+		result: returnsWidest, eval: extremum(-1)},
+
+	// the string functions
+	"lower": {params: []paramKind{textKind}, minArgs: 1,
+		result: returns(stringType), eval: lower},
+	"upper": {params: []paramKind{textKind}, minArgs: 1,
+		result: returns(stringType), eval: upper},
+	"length": {params: []paramKind{textKind}, minArgs: 1,
+		result: returns(integerType), eval: length},
+	"substr": {params: []paramKind{textKind, integerKind, integerKind},
+		minArgs: 2, result: returns(stringType), eval: substr},
+	"btrim": {params: []paramKind{textKind, textKind}, minArgs: 1,
+		result: returns(stringType), eval: trim("btrim")},
+	"ltrim": {params: []paramKind{textKind, textKind}, minArgs: 1,
+		result: returns(stringType), eval: trim("ltrim")},
+	"rtrim": {params: []paramKind{textKind, textKind}, minArgs: 1,
+		result: returns(stringType), eval: trim("rtrim")},
+	"replace": {params: []paramKind{textKind, textKind, textKind},
+		minArgs: 3, result: returns(stringType), eval: replace},
+	"concat": {params: []paramKind{anyKind}, minArgs: 1, variadic: true,
+		result: returns(stringType), eval: concatValues},
+	"position": {params: []paramKind{textKind, textKind}, minArgs: 2,
+		result: returns(integerType), eval: position},
+	"split_part": {params: []paramKind{textKind, textKind, integerKind},
+		minArgs: 3, result: returns(stringType), eval: splitPart},
+
+	// the math functions
+	"abs": {params: []paramKind{numberKind}, minArgs: 1,
+		result: returnsNumber, eval: abs},
+	"round": {params: []paramKind{numberKind, integerKind}, minArgs: 1,
+		result: func(args []*Type) *Type {
+			if len(args) == 2 && args[0] != nil && args[0].integral() {
+				return decimalType
+			}
+			return returnsNumber(args)
+		}, eval: round},
+	"floor": {params: []paramKind{numberKind}, minArgs: 1,
+		result: returnsNumber, eval: floor},
+	"ceil": {params: []paramKind{numberKind}, minArgs: 1,
+		result: returnsNumber, eval: ceil},
+	"ceiling": {params: []paramKind{numberKind}, minArgs: 1,
+		result: returnsNumber, eval: ceil},
+	"power": {params: []paramKind{numberKind, numberKind}, minArgs: 2,
+		result: returns(floatType), eval: power},
+	"pow": {params: []paramKind{numberKind, numberKind}, minArgs: 2,
+		result: returns(floatType), eval: power},
+	"sqrt": {params: []paramKind{numberKind}, minArgs: 1,
+		result: returns(floatType), eval: sqrt},
+	"mod": {params: []paramKind{numberKind, numberKind}, minArgs: 2,
+		result: func(args []*Type) *Type {
+			return arithmeticType(args[0], args[1])
+		}, eval: modulo},
+
+	// the session functions
+	"current_setting": {params: []paramKind{textKind, booleanKind},
+		minArgs: 1, result: returns(stringType), volatile: true,
+		eval: currentSetting},
+	"current_user": {result: returns(stringType), volatile: true,
+		eval: currentUser},
+
+	// the sequence functions
+	"nextval": {params: []paramKind{textKind}, minArgs: 1,
+		result: returns(bigintType), volatile: true, eval: nextVal},
+	"currval": {params: []paramKind{textKind}, minArgs: 1,
+		result: returns(bigintType), volatile: true, eval: currVal},
+	"setval": {params: []paramKind{textKind, integerKind, booleanKind},
+		minArgs: 2, result: returns(bigintType), volatile: true, eval: setVal},
+
+	// the date and time functions
+	"now": {result: returns(timestamptzType), volatile: true, eval: now},
+	"date_trunc": {params: []paramKind{textKind, temporalKind}, minArgs: 2,
+		result: func(args []*Type) *Type {
+			if args[1] != nil && args[1].id == timestamptzID {
+				return timestamptzType
+			}
+			return timestampType
+		}, eval: dateTrunc},
+	"extract": {params: []paramKind{textKind, temporalKind}, minArgs: 2,
+		result: returns(decimalType), eval: extract},
+	"date_part": {params: []paramKind{textKind, temporalKind}, minArgs: 2,
+		result: returns(floatType), eval: datePart},
+	"to_char": {params: []paramKind{temporalKind, textKind}, minArgs: 2,
+		result: returns(stringType), eval: toChar},
+	"to_date": {params: []paramKind{textKind, textKind}, minArgs: 2,
+		result: returns(dateType), eval: toDate},
+	// to_timestamp takes either the seconds or the string and the template
+	"to_timestamp": {params: []paramKind{anyKind, textKind}, minArgs: 1,
+		result: returns(timestamptzType), eval: toTimestamp},
+
+	// the JSON functions
+	"json_array_length": {params: []paramKind{jsonKind}, minArgs: 1,
+		result: returns(integerType), eval: jsonArrayLength("json_array_length")},
+	"jsonb_array_length": {params: []paramKind{jsonbKind}, minArgs: 1,
+		result: returns(integerType), eval: jsonArrayLength("jsonb_array_length")},
+	"json_extract_path": {params: []paramKind{jsonKind, textKind},
+		minArgs: 1, variadic: true, result: returns(jsonType),
+		eval: jsonExtractPath("json_extract_path")},
+	"json_extract_path_text": {params: []paramKind{jsonKind, textKind},
+		minArgs: 1, variadic: true, result: returns(stringType),
+		eval: jsonExtractPath("json_extract_path_text")},
+	"jsonb_extract_path": {params: []paramKind{jsonbKind, textKind},
+		minArgs: 1, variadic: true, result: returns(jsonbType),
+		eval: jsonExtractPath("jsonb_extract_path")},
+	"jsonb_extract_path_text": {params: []paramKind{jsonbKind, textKind},
+		minArgs: 1, variadic: true, result: returns(stringType),
+		eval: jsonExtractPath("jsonb_extract_path_text")},
+	"json_typeof": {params: []paramKind{jsonKind}, minArgs: 1,
+		result: returns(stringType), eval: jsonTypeOf("json_typeof")},
+	"jsonb_typeof": {params: []paramKind{jsonbKind}, minArgs: 1,
+		result: returns(stringType), eval: jsonTypeOf("jsonb_typeof")},
+	"json_object_keys": {params: []paramKind{jsonKind}, minArgs: 1,
+		result: returns(stringType), set: jsonObjectKeys("json_object_keys")},
+	"jsonb_object_keys": {params: []paramKind{jsonbKind}, minArgs: 1,
+		result: returns(stringType), set: jsonObjectKeys("jsonb_object_keys")},
+
+	// the array and UUID functions
+	"array_length": {params: []paramKind{arrayKind, integerKind},
+		minArgs: 2, result: returns(integerType), eval: arrayLength},
+	"unnest": {params: []paramKind{arrayKind}, minArgs: 1,
+		result: func(args []*Type) *Type {
+			if args[0] != nil && args[0].id == arrayID {
+				return args[0].elem
+			}
+			return nil
+		}, set: unnest},
+	"gen_random_uuid": {result: returns(uuidType), volatile: true,
+		eval: genRandomUUID},
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFunctions(t *testing.T) {
+	tts := []struct {
+		expr   string
+		code   string
+		expect string
+	}{
+		{"lower('AbC')", "", "abc"},
+		{"upper('héllo')", "", "HÉLLO"},
+		{"length('héllo')", "", "5"},
+		{"length(NULL) is null", "", "true"},
+		{"substr('hello', 2, 3)", "", "ell"},
+		{"substr('hello', 2)", "", "ello"},
+		{"substr('hello', 0, 2)", "", "h"},
+		{"substr('hello', -5, 3)", "", ""},
+		{"substr('hello', 4, 100)", "", "lo"},
+		{"substr('hello', 1, -1)", codeSubstringError, ""},
+		{"trim('  a b  ') || '|'", "", "a b|"},
+		{"trim(LEADING 'x' FROM 'xxaxx')", "", "axx"},
+		{"trim(trailing 'xy' from 'xaxyx')", "", "xa"},
+		{"trim(both from '  a  ') || '|'", "", "a|"},
+		{"trim(from ' a ') || '|'", "", "a|"},
+		{"trim('xax', 'x')", "", "a"},
+		{"rtrim('a  ') || '|'", "", "a|"},
+		{"replace('abcabc', 'b', 'XY')", "", "aXYcaXYc"},
+		{"replace('abc', '', 'x')", "", "abc"},
+		{"concat('a', NULL, 1, true, 1.5)", "", "a1true1.5"},
+		{"position('lo' IN 'hello')", "", "4"},
+		{"position('x' in 'hello')", "", "0"},
+		{"position('' IN 'hello')", "", "1"},
+		{"position('é' IN 'aéb')", "", "2"},
+		{"split_part('a,b,,c', ',', 2)", "", "b"},
+		{"split_part('a,b,,c', ',', 3)", "", ""},
+		{"split_part('a,b,,c', ',', -1)", "", "c"},
+		{"split_part('a,b', ',', 5)", "", ""},
+		{"split_part('a,b', '', 1)", "", "a,b"},
+		{"split_part('a,b', ',', 0)", codeInvalidParameterValue, ""},
+		{"abs(-3)", "", "3"},
+		{"abs(-1.5)", "", "1.5"},
+		{"abs(decimal '-2.50')", "", "2.50"},
+		{"abs(-9223372036854775807 - 1)", codeNumericOutOfRange, ""},
+		{"round(2.5)", "", "3"},
+		{"round(2.5::float)", "", "2.0"},
+		{"round(decimal '2.5')", "", "3"},
+		{"round(decimal '-2.5')", "", "-3"},
+		{"round(decimal '1.2345', 2)", "", "1.23"},
+		{"round(1.255, 1)", "", "1.3"},
+		{"round(1234, -2)", "", "1200"},
+		{"round(decimal '1250', -2)", "", "1300"},
+		{"round(7, 1)", "", "7.0"},
+		{"floor(-1.5::float)", "", "-2.0"},
+		{"floor(decimal '-1.50')", "", "-2"},
+		{"ceil(decimal '1.01')", "", "2"},
+		{"ceiling(-1.5::float)", "", "-1.0"},
+		{"floor(3)", "", "3"},
+		{"power(2, 10)", "", "1024.0"},
+		{"pow(4, 0.5)", "", "2.0"},
+		{"power(0, -1)", codeInvalidArgumentForPower, ""},
+		{"power(-8, 1.0 / 3)", codeInvalidArgumentForPower, ""},
+		{"power(10, 400)", codeNumericOutOfRange, ""},
+		{"sqrt(16)", "", "4.0"},
+		{"sqrt(-1)", codeInvalidArgumentForPower, ""},
+		{"mod(7, 3)", "", "1"},
+		{"mod(-7, 3)", "", "-1"},
+		{"mod(decimal '7.5', 2)", "", "1.5"},
+		{"mod(1, 0)", codeDivisionByZero, ""},
+		{"greatest(1, 3, 2)", "", "3"},
+		{"least(1, NULL, -2)", "", "-2"},
+		{"greatest(NULL, NULL) is null", "", "true"},
+		{"greatest(1, 0.5::float)", "", "1.0"},
+		{"least(2, decimal '2.50')", "", "2"},
+		{"greatest('b', 'a', 'c')", "", "c"},
+		{"least(date '2024-01-02', '2024-01-01')", "", "2024-01-01"},
+		{"lower(1)", codeUndefinedFunction, ""},
+		{"lower('a', 'b')", codeUndefinedFunction, ""},
+		{"abs('1')", codeUndefinedFunction, ""},
+		{"substr('a', 1.5)", codeUndefinedFunction, ""},
+		{"greatest()", codeUndefinedFunction, ""},
+		{"no_such_function(1)", codeUndefinedFunction, ""},
+		{"position('a', 'b')", codeSyntaxError, ""},
+	}
+	env := &evalEnv{sess: NewSession(defaultSuperuser)}
+	for i, tt := range tts {
+		got, err := evalText(env, tt.expr)
+		if sqlState(err) != tt.code && (err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.expr, err, tt.code)
+		}
+		if got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.expr, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.expr)
+	}
+}
+
+func TestFunctionSignatures(t *testing.T) {
+	tts := []struct {
+		name   string
+		args   []*Type
+		code   string
+		expect string
+	}{
+		{"lower", []*Type{stringType}, "", ""},
+		{"lower", []*Type{nil}, "", ""},
+		{"lower", []*Type{integerType}, codeUndefinedFunction,
+			"function lower(integer) does not exist, argument 1 must be text"},
+		{"lower", nil, codeUndefinedFunction,
+			"function lower takes 1 argument, got 0"},
+		{"substr", []*Type{stringType}, codeUndefinedFunction,
+			"function substr takes 2 or 3 arguments, got 1"},
+		{"replace", []*Type{stringType, stringType}, codeUndefinedFunction,
+			"function replace takes 3 arguments, got 2"},
+		{"concat", nil, codeUndefinedFunction,
+			"function concat takes at least 1 argument, got 0"},
+		{"now", []*Type{stringType}, codeUndefinedFunction,
+			"function now takes no argument, got 1"},
+		{"round", []*Type{decimalType, floatType}, codeUndefinedFunction,
+			"function round(decimal, float) does not exist, argument 2 must be integer"},
+		{"split_part", []*Type{nil, stringType, stringType}, codeUndefinedFunction,
+			"function split_part(unknown, string, string) does not exist, " +
+				"argument 3 must be integer"},
+		{"greatest", []*Type{integerType, decimalType, floatType}, "", ""},
+		{"greatest", []*Type{integerType, stringType}, codeDatatypeMismatch,
+			"GREATEST types integer and string cannot be matched"},
+		{"least", []*Type{dateType, stringType}, "", ""},
+		{"foo", nil, codeUndefinedFunction, "function foo does not exist"},
+	}
+	for i, tt := range tts {
+		_, err := checkCall(tt.name, tt.args)
+		if sqlState(err) != tt.code && (err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, err, tt.code)
+		}
+		if err != nil && err.(*Error).Msg != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.name, err.(*Error).Msg, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
+
+func TestFunctionsInStatements(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table f (id integer primary key, name varchar(10), " +
+			"price decimal(6, 2), qty smallint, f float)",
+		"insert into f values (1, 'Apple', 1.25, 4, 2.5)",
+		"insert into f values (2, 'pear', -2.5, NULL, -1)",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		types  []*Type
+		expect [][]any
+	}{
+		{"Select", "select upper(name), length(name), abs(price), " +
+			"round(price, 1), greatest(qty, 1, f), mod(id, 2) from f", "",
+			[]*Type{stringType, integerType, decimalType, decimalType,
+				floatType, integerType},
+			[][]any{{"APPLE", 5, dec(t, "1.25"), dec(t, "1.3"),
+				4.0, 1}, {"PEAR", 4, dec(t, "2.50"),
+				dec(t, "-2.5"), 1.0, 0}}},
+		{"Where", "select id from f where lower(name) = 'apple' and " +
+			"position('p' IN name) = 2", "", []*Type{integerType},
+			[][]any{{1}}},
+		{"Types", "select floor(f), sqrt(qty), round(qty), concat(id, name), " +
+			"least(price, qty) from f where id = 1", "",
+			[]*Type{floatType, floatType, smallintType, stringType, decimalType},
+			[][]any{{2.0, 2.0, 4, "1Apple", dec(t, "1.25")}}},
+		{"Plan time", "select lower(id) from f where id = 3",
+			codeUndefinedFunction, nil, nil},
+		{"Plan time where", "select id from f where id = 3 and abs(name) = 1",
+			codeUndefinedFunction, nil, nil},
+		{"Plan time arity", "select id from f where id = 3 and substr(name) = ''",
+			codeUndefinedFunction, nil, nil},
+		{"Plan time mismatch", "select greatest(id, name) from f where id = 3",
+			codeDatatypeMismatch, nil, nil},
+		{"Update", "update f set name = upper(name) where id = 2", "", nil, nil},
+		{"Update plan time", "update f set name = lower(qty) where id = 3",
+			codeUndefinedFunction, nil, nil},
+		{"Delete plan time", "delete from f where length(id) = 1",
+			codeUndefinedFunction, nil, nil},
+		{"Insert", "insert into f (id, name) values (3, trim('  x  '))", "",
+			nil, nil},
+		{"Insert plan time", "insert into f (id, name) values (4, lower(1))",
+			codeUndefinedFunction, nil, nil},
+		{"Index plan time", "create index on f ((lower(qty)))",
+			codeUndefinedFunction, nil, nil},
+		{"Index", "create index on f ((lower(name)))", "", nil, nil},
+		{"Check", "select name from f where lower(name) = 'pear' or id = 3", "",
+			[]*Type{newVarchar(t, 10)}, [][]any{{"PEAR"}, {"x"}}},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		if tt.types != nil && !reflect.DeepEqual(r.types, tt.types) {
+			t.Fatalf("case %d (%s) failed: got types %v, expect %v",
+				i, tt.name, r.types, tt.types)
+		}
+		if tt.expect != nil {
+			var got [][]any
+			for _, row := range r.rows {
+				got = append(got, row.values)
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Fatalf("case %d (%s) failed: got %v, expect %v",
+					i, tt.name, got, tt.expect)
+			}
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
@@ -2,4 +2,8 @@ module github.com/charleszheng44/simple-db-go
 
 go 1.18
 
-require github.com/pkg/errors v0.9.1
+require (
+	github.com/mattn/go-runewidth v0.0.3
+	github.com/peterh/liner v1.1.0
+	github.com/pkg/errors v0.9.1
+)
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxRequestBytes = 10 << 20
+	contentTypeJSON = "application/json"
+	contentTypeNDJ  = "application/x-ndjson"
+)
+
+// HTTPServer serves the database over HTTP with JSON bodies, the clients
+// are authenticated with the basic authentication.
+//
+//	POST /query         executes {"sql": "...", "params": [...]}
+//	GET  /tables        lists the tables visible to the user
+//	GET  /tables/{name} describes the schema of the table
+//
+// The result of the query is streamed as NDJSON if the request accepts
+// application/x-ndjson, i.e., a line of the columns, a line per row and a
+// line of the message. The rows of SELECT are written and flushed as the
+// table is scanned, so they are never held in memory all together.
+type HTTPServer struct {
+	db *Database
+}
+
+func NewHTTPServer(db *Database) *HTTPServer {
+	return &HTTPServer{
+		db: db,
+	}
+}
+
+func (s *HTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query", s.query)
+	mux.HandleFunc("/tables", s.tables)
+	mux.HandleFunc("/tables/", s.table)
+	return s.authenticate(mux)
+}
+
+// authenticate checks the credentials of the request and attaches the
+// session of the user to the request context.
+func (s *HTTPServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="simple-db"`)
+			writeJSON(w, http.StatusUnauthorized, &queryResponse{
+				Error: &jsonError{
+					Code:    codeInvalidAuthorization,
+					Message: "authentication is required",
+				},
+			})
+			return
+		}
+		if err := s.db.Authenticate(user, password); err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="simple-db"`)
+			writeJSON(w, http.StatusUnauthorized, &queryResponse{
+				Error: &jsonError{
+					Code:    sqlState(err),
+					Message: err.Error(),
+				},
+			})
+			return
+		}
+		ctx := WithSession(r.Context(), NewSession(user))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type queryRequest struct {
+	SQL    string `json:"sql"`
+	Params []any  `json:"params"`
+}
+
+type jsonColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type jsonError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Line and Column are the position of the syntax error in the SQL
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+type queryResponse struct {
+	Columns  []jsonColumn `json:"columns,omitempty"`
+	Rows     *[][]any     `json:"rows,omitempty"`
+	Affected int          `json:"affected,omitempty"`
+	Message  string       `json:"message,omitempty"`
+	Error    *jsonError   `json:"error,omitempty"`
+}
+
+type tableResponse struct {
+	Name       string       `json:"name"`
+	Columns    []jsonColumn `json:"columns"`
+	PrimaryKey []string     `json:"primary_key"`
+}
+
+func jsonColumns(cols []string, types []*Type) []jsonColumn {
+	jcs := make([]jsonColumn, len(cols))
+	for i, col := range cols {
+		jcs[i] = jsonColumn{
+			Name: col,
+			Type: types[i].String(),
+		}
+	}
+	return jcs
+}
+
+// writeJSON writes the v as the response of the status. The v is encoded
+// before the status is written, so that a value JSON cannot encode, e.g.,
+// the float NaN, is reported as 500 with the structured error.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		b, _ = json.Marshal(&queryResponse{Error: encodeError(err)})
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	_, _ = w.Write(append(b, '\n'))
+}
+
+// encodeError returns the structured error of the failed JSON encoding.
+func encodeError(err error) *jsonError {
+	return &jsonError{
+		Code:    codeInternalError,
+		Message: "failed to encode the response: " + err.Error(),
+	}
+}
+
+// writeError writes the err as the structured error, errors caused by
+// the client are reported as 400 and the others as 500.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if code := sqlState(err); code == codeInternalError {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, &queryResponse{
+		Error: &jsonError{
+			Code:    sqlState(err),
+			Message: err.Error(),
+		},
+	})
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeJSON(w, http.StatusMethodNotAllowed, &queryResponse{
+		Error: &jsonError{
+			Code:    codeFeatureNotSupported,
+			Message: "method " + r.Method + " is not allowed",
+		},
+	})
+	return false
+}
+
+func (s *HTTPServer) query(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req queryRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, newError(codeProtocolViolation,
+			"invalid request body: %v", err))
+		return
+	}
+
+	stmts, err := parseScriptSQL(req.SQL)
+	if err != nil {
+		resp := &queryResponse{
+			Error: &jsonError{
+				Code:    sqlState(err),
+				Message: err.Error(),
+			},
+		}
+		if pos := errorPosition(err); pos != nil {
+			resp.Error.Line, resp.Error.Column = pos.Line, pos.Column
+		}
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	if len(stmts) != 1 {
+		writeError(w, newError(codeSyntaxError,
+			"expect a single statement, got %d", len(stmts)))
+		return
+	}
+	sts := stmts[0]
+	if sts, err = s.bind(sts, req.Params); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// the statement is canceled once the client goes away
+	ctx := r.Context()
+	var nw *ndjsonWriter
+	if strings.Contains(r.Header.Get("Accept"), contentTypeNDJ) {
+		nw = &ndjsonWriter{w: w}
+		ctx = withRowSink(ctx, nw)
+	}
+	result := s.db.Interpret(ctx, sts)
+	if nw != nil {
+		nw.finish(result)
+		return
+	}
+	if result.err != nil {
+		writeError(w, result.err)
+		return
+	}
+
+	resp := &queryResponse{
+		Affected: result.affected,
+		Message:  result.message,
+	}
+	if len(result.cols) != 0 {
+		rows := make([][]any, 0, len(result.rows))
+		for _, row := range result.rows {
+			rows = append(rows, row.values)
+		}
+		resp.Columns = jsonColumns(result.cols, result.types)
+		resp.Rows = &rows
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// bind binds the JSON values to the parameters of the statement.
+func (s *HTTPServer) bind(sts Statement, params []any) (Statement, error) {
+	types, err := s.db.ParamTypes(sts)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) < len(types) {
+		return nil, newError(codeUndefinedParameter,
+			"the statement requires %d parameters, got %d",
+			len(types), len(params))
+	}
+	vals := make([]any, len(params))
+	for i, p := range params {
+		typ := stringType
+		if i < len(types) {
+			typ = types[i]
+		}
+		val, err := jsonParam(p, typ)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	return bindParams(sts, vals)
+}
+
+// jsonParam converts the JSON value to the type of the parameter, null
+// is NULL.
+func jsonParam(p any, typ *Type) (any, error) {
+	switch v := p.(type) {
+	case nil:
+		return nil, nil
+	case json.Number:
+		return decodeParam([]byte(v.String()), typ, formatText)
+	case string:
+		return decodeParam([]byte(v), typ, formatText)
+	case bool:
+		return decodeParam([]byte(strconv.FormatBool(v)), typ, formatText)
+	case map[string]any, []any:
+		if a, ok := v.([]any); ok && typ.id == arrayID {
+			return jsonArrayParam(a, typ)
+		}
+		// the objects and the other arrays are the JSON documents
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return decodeParam(raw, typ, formatText)
+	default:
+		return nil, newError(codeFeatureNotSupported,
+			"unsupported parameter %v", p)
+	}
+}
+
+// jsonArrayParam converts the JSON array to the array of the type, whose
+// elements are converted to the type of the elements.
+func jsonArrayParam(v []any, typ *Type) (any, error) {
+	a := Array{elem: typ.elem, elems: make([]any, len(v))}
+	for i, e := range v {
+		elem, err := jsonParam(e, typ.elem)
+		if err != nil {
+			return nil, err
+		}
+		a.elems[i] = elem
+	}
+	return a, nil
+}
+
+// ndjsonWriter streams the result as NDJSON, see rowSink. The response
+// starts with the first row, so that the statement failing before it is
+// reported with the status as the JSON response is, while a failure after
+// it ends the stream with the error line instead of the final one.
+type ndjsonWriter struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	cols    []jsonColumn
+	started bool
+}
+
+// start writes the status and the line of the columns, if any.
+func (nw *ndjsonWriter) start() {
+	nw.started = true
+	nw.w.Header().Set("Content-Type", contentTypeNDJ)
+	nw.w.WriteHeader(http.StatusOK)
+	nw.enc = json.NewEncoder(nw.w)
+	if len(nw.cols) != 0 {
+		_ = nw.enc.Encode(&queryResponse{Columns: nw.cols})
+	}
+}
+
+func (nw *ndjsonWriter) columns(cols []string, types []*Type) error {
+	nw.cols = jsonColumns(cols, types)
+	return nil
+}
+
+// row writes and flushes the row, so that the client can process it
+// while the rest is scanned. Failing to encode or write the row stops the
+// scan.
+func (nw *ndjsonWriter) row(values []any) error {
+	if !nw.started {
+		nw.start()
+	}
+	if err := nw.enc.Encode(values); err != nil {
+		return newError(codeInternalError,
+			"failed to encode the response: %v", err)
+	}
+	if flusher, ok := nw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// finish writes the final line of the result, or the error.
+func (nw *ndjsonWriter) finish(result *Result) {
+	if result.err != nil && !nw.started {
+		writeError(nw.w, result.err)
+		return
+	}
+	if !nw.started {
+		nw.start()
+	}
+	if result.err != nil {
+		// the status has been sent, so the error ends the stream
+		_ = nw.enc.Encode(&queryResponse{
+			Error: &jsonError{
+				Code:    sqlState(result.err),
+				Message: result.err.Error(),
+			},
+		})
+		return
+	}
+	_ = nw.enc.Encode(&queryResponse{
+		Affected: result.affected,
+		Message:  result.message,
+	})
+}
+
+func (s *HTTPServer) tables(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	names, err := s.db.VisibleTables(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{
+		"tables": names,
+	})
+}
+
+func (s *HTTPServer) table(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/tables/")
+	names, err := s.db.VisibleTables(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var info *TableInfo
+	if i := sort.SearchStrings(names, name); i < len(names) && names[i] == name {
+		info, err = s.db.DescribeTable(name)
+	} else {
+		// the invisible tables are reported as missing not to reveal them
+		err = newError(codeUndefinedTable, "relation %s does not exist", name)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if sqlState(err) == codeUndefinedTable {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, &queryResponse{
+			Error: &jsonError{
+				Code:    sqlState(err),
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, &tableResponse{
+		Name:       info.Name,
+		Columns:    jsonColumns(info.Columns, info.Types),
+		PrimaryKey: info.PrimaryKey,
+	})
+}
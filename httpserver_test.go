@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func postQuery(t *testing.T, srv *httptest.Server,
+	body string, accept string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/query",
+		strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create the request: %v", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	req.SetBasicAuth(defaultSuperuser, testPassword)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to post the query: %v", err)
+	}
+	return resp
+}
+
+func newHTTPTestServer(t *testing.T, db *Database) *httptest.Server {
+	if err := db.SetPassword(defaultSuperuser, testPassword); err != nil {
+		t.Fatalf("failed to set the password: %v", err)
+	}
+	return httptest.NewServer(NewHTTPServer(db).Handler())
+}
+
+func TestHTTPQuery(t *testing.T) {
+	srv := newHTTPTestServer(t, NewDatabase())
+	defer srv.Close()
+
+	tts := []struct {
+		name   string
+		body   string
+		status int
+		expect string
+	}{
+		{
+			"Create table",
+			`{"sql": "create table t (id integer primary key, name string)"}`,
+			http.StatusOK,
+			`{"message":"TABLE CREATED"}`,
+		},
+		{
+			"Insert with parameters",
+			`{"sql": "insert into t (id, name) values ($1, $2)", ` +
+				`"params": [1, "a"]}`,
+			http.StatusOK,
+			`{"affected":1,"message":"1 ROW INSERTED"}`,
+		},
+		{
+			"Select typed rows",
+			`{"sql": "select * from t"}`,
+			http.StatusOK,
+			`{"columns":[{"name":"id","type":"integer"},` +
+				`{"name":"name","type":"string"}],"rows":[[1,"a"]]}`,
+		},
+		{
+			"NULL parameter",
+			`{"sql": "select id from t where id = $1 and $2 is null", ` +
+				`"params": [1, null]}`,
+			http.StatusOK,
+			`{"columns":[{"name":"id","type":"integer"}],"rows":[[1]]}`,
+		},
+		{
+			"Structured error",
+			`{"sql": "select * from missing"}`,
+			http.StatusBadRequest,
+			`{"error":{"code":"42P01",` +
+				`"message":"select from non-exist table missing"}}`,
+		},
+		{
+			"Unencodable value",
+			`{"sql": "select id, 'NaN'::float from t"}`,
+			http.StatusInternalServerError,
+			`{"error":{"code":"XX000","message":"failed to encode the ` +
+				`response: json: unsupported value: NaN"}}`,
+		},
+		{
+			"Invalid parameter",
+			`{"sql": "insert into t (id, name) values ($1, 'b')", ` +
+				`"params": ["x"]}`,
+			http.StatusBadRequest,
+			`{"error":{"code":"22P02",` +
+				`"message":"invalid input syntax for type integer: \"x\""}}`,
+		},
+	}
+
+	for i, tt := range tts {
+		resp := postQuery(t, srv, tt.body, "")
+		var got, expect any
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+		}
+		resp.Body.Close()
+		if err := json.Unmarshal([]byte(tt.expect), &expect); err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+		}
+		if resp.StatusCode != tt.status || !reflect.DeepEqual(got, expect) {
+			t.Fatalf("case %d (%s) failed: got(%d %v), expect(%d %v)", i,
+				tt.name, resp.StatusCode, got, tt.status, expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	resp := postQuery(t, srv, `{"sql": "select name from t"}`, contentTypeNDJ)
+	defer resp.Body.Close()
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	expect := []string{
+		`{"columns":[{"name":"name","type":"string"}]}`,
+		`["a"]`,
+		`{}`,
+	}
+	if !reflect.DeepEqual(lines, expect) {
+		t.Fatalf("got NDJSON(%q), expect(%q)", lines, expect)
+	}
+
+	// the stream ends with the error of the row failing to encode
+	resp = postQuery(t, srv, `{"sql": "select 'Infinity'::float from t"}`,
+		contentTypeNDJ)
+	defer resp.Body.Close()
+	lines = nil
+	scanner = bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	expect = []string{
+		`{"columns":[{"name":"float","type":"float"}]}`,
+		`{"error":{"code":"XX000","message":"failed to encode the ` +
+			`response: json: unsupported value: +Inf"}}`,
+	}
+	if !reflect.DeepEqual(lines, expect) {
+		t.Fatalf("got NDJSON(%q), expect(%q)", lines, expect)
+	}
+}
+
+func TestHTTPTables(t *testing.T) {
+	db := NewDatabase()
+	db.CreateTable(context.Background(), &CreateStatement{
+		table: &TableRef{name: "t"},
+		columns: []*ColumnDef{
+			{name: "id", typ: integerType, primaryKey: true},
+		},
+	})
+	srv := newHTTPTestServer(t, db)
+	defer srv.Close()
+	for _, sql := range []string{
+		"create user alice with password 'pw'",
+		"create user bob with password 'pw'",
+		"create table u (id integer primary key)",
+		"grant select on u to alice",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	tts := []struct {
+		name   string
+		path   string
+		user   string
+		status int
+		expect string
+	}{
+		{
+			"Unauthenticated",
+			"/tables",
+			"",
+			http.StatusUnauthorized,
+			`{"error":{"code":"28000",` +
+				`"message":"authentication is required"}}`,
+		},
+		{
+			"List tables",
+			"/tables",
+			defaultSuperuser,
+			http.StatusOK,
+			`{"tables":["t","u"]}`,
+		},
+		{
+			"Describe table",
+			"/tables/t",
+			defaultSuperuser,
+			http.StatusOK,
+			`{"name":"t","columns":[{"name":"id","type":"integer"}],` +
+				`"primary_key":["id"]}`,
+		},
+		{
+			"Describe missing table",
+			"/tables/missing",
+			defaultSuperuser,
+			http.StatusNotFound,
+			`{"error":{"code":"42P01",` +
+				`"message":"relation missing does not exist"}}`,
+		},
+		{
+			"List granted tables",
+			"/tables",
+			"alice",
+			http.StatusOK,
+			`{"tables":["u"]}`,
+		},
+		{
+			"List no tables",
+			"/tables",
+			"bob",
+			http.StatusOK,
+			`{"tables":[]}`,
+		},
+		{
+			"Describe table without privilege",
+			"/tables/t",
+			"alice",
+			http.StatusNotFound,
+			`{"error":{"code":"42P01",` +
+				`"message":"relation t does not exist"}}`,
+		},
+	}
+
+	for i, tt := range tts {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+		if err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+		}
+		if tt.user != "" {
+			password := "pw"
+			if tt.user == defaultSuperuser {
+				password = testPassword
+			}
+			req.SetBasicAuth(tt.user, password)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+		}
+		var got, expect any
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+		}
+		resp.Body.Close()
+		if err := json.Unmarshal([]byte(tt.expect), &expect); err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+		}
+		if resp.StatusCode != tt.status || !reflect.DeepEqual(got, expect) {
+			t.Fatalf("case %d (%s) failed: got(%d %v), expect(%d %v)", i,
+				tt.name, resp.StatusCode, got, tt.status, expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
+
+// flushRecorder records the body written by each flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+	last    int
+}
+
+func (fr *flushRecorder) Flush() {
+	body := fr.Body.String()
+	fr.flushed = append(fr.flushed, body[fr.last:])
+	fr.last = len(body)
+}
+
+func TestHTTPStream(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table t (id integer primary key, name string)",
+		"insert into t values (1, 'a')",
+		"insert into t values (2, 'b')",
+		"insert into t values (3, 'c')",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+	if err := db.SetPassword(defaultSuperuser, testPassword); err != nil {
+		t.Fatalf("failed to set the password: %v", err)
+	}
+	handler := NewHTTPServer(db).Handler()
+
+	tts := []struct {
+		name    string
+		sql     string
+		status  int
+		flushed []string
+		body    string
+	}{
+		{
+			"Rows flushed one by one",
+			"select id from t where id > 1",
+			http.StatusOK,
+			[]string{`{"columns":[{"name":"id","type":"integer"}]}` + "\n" +
+				"[2]\n", "[3]\n"},
+			`{"columns":[{"name":"id","type":"integer"}]}` + "\n" +
+				"[2]\n[3]\n{}\n",
+		},
+		{
+			"No rows",
+			"select id from t where id > 3",
+			http.StatusOK,
+			nil,
+			`{"columns":[{"name":"id","type":"integer"}]}` + "\n{}\n",
+		},
+		{
+			"Error before the rows",
+			"select * from missing",
+			http.StatusBadRequest,
+			nil,
+			`{"error":{"code":"42P01",` +
+				`"message":"select from non-exist table missing"}}` + "\n",
+		},
+		{
+			"Error after the rows",
+			"select 10 / (id - 2) from t",
+			http.StatusOK,
+			[]string{`{"columns":[{"name":"?column?","type":"integer"}]}` +
+				"\n[-10]\n"},
+			`{"columns":[{"name":"?column?","type":"integer"}]}` + "\n" +
+				"[-10]\n" + `{"error":{"code":"22012",` +
+				`"message":"division by zero"}}` + "\n",
+		},
+	}
+	for i, tt := range tts {
+		body, _ := json.Marshal(map[string]string{"sql": tt.sql})
+		req := httptest.NewRequest(http.MethodPost, "/query",
+			strings.NewReader(string(body)))
+		req.Header.Set("Accept", contentTypeNDJ)
+		req.SetBasicAuth(defaultSuperuser, testPassword)
+		rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status || rec.Body.String() != tt.body ||
+			!reflect.DeepEqual(rec.flushed, tt.flushed) {
+			t.Fatalf("case %d (%s) failed: got(%d %q, flushed %q), "+
+				"expect(%d %q, flushed %q)", i, tt.name, rec.Code,
+				rec.Body.String(), rec.flushed, tt.status, tt.body, tt.flushed)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
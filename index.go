@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Index is the secondary index on the columns and the expressions of a
+// table, e.g., (payload ->> 'kind'). Its entries pair the keys encoded
+// from the values of the expressions, see appendKey, with the primary
+// keys of the rows, which are kept in order for the range scans. The rows
+// with NULL in any of the expressions are not indexed.
+type Index struct {
+	name    string
+	exprs   []Expr
+	entries []indexEntry
+}
+
+type indexEntry struct {
+	key string
+	pk  string
+}
+
+// key returns the key of the row in the index, which is false if any of
+// the expressions is NULL or fails, the latter of which is rejected by
+// Table.checkRow before the row is stored.
+func (idx *Index) key(r *Row) (string, bool) {
+	env := &evalEnv{row: r}
+	var b []byte
+	for _, e := range idx.exprs {
+		v, err := e.Eval(env)
+		if err != nil || v == nil {
+			return "", false
+		}
+		b = appendKey(b, v)
+	}
+	return string(b), true
+}
+
+// search returns the position of the entry, or where it is to be
+// inserted.
+func (idx *Index) search(e indexEntry) int {
+	return sort.Search(len(idx.entries), func(i int) bool {
+		x := idx.entries[i]
+		return x.key > e.key || (x.key == e.key && x.pk >= e.pk)
+	})
+}
+
+// add indexes the row of the primary key.
+func (idx *Index) add(pk string, r *Row) {
+	key, ok := idx.key(r)
+	if !ok {
+		return
+	}
+	e := indexEntry{key: key, pk: pk}
+	i := idx.search(e)
+	idx.entries = append(idx.entries, indexEntry{})
+	copy(idx.entries[i+1:], idx.entries[i:])
+	idx.entries[i] = e
+}
+
+// delete removes the row of the primary key from the index.
+func (idx *Index) delete(pk string, r *Row) {
+	key, ok := idx.key(r)
+	if !ok {
+		return
+	}
+	e := indexEntry{key: key, pk: pk}
+	if i := idx.search(e); i < len(idx.entries) && idx.entries[i] == e {
+		idx.entries = append(idx.entries[:i], idx.entries[i+1:]...)
+	}
+}
+
+// columns returns the texts of the indexed columns and expressions.
+func (idx *Index) columns() []string {
+	cols := make([]string, len(idx.exprs))
+	for i, e := range idx.exprs {
+		cols[i] = e.String()
+	}
+	return cols
+}
+
+// indexScan returns the keys of the rows which may satisfy the conditions
+// in order by the index narrowing the range most, see scanRange, which is
+// false if no index narrows it.
+func (t *Table) indexScan(conds []Expr) ([]string, bool) {
+	var (
+		best       *Index
+		narrowed   int
+		start, end string
+	)
+	for _, idx := range t.indexes {
+		types := make([]*Type, len(idx.exprs))
+		for i, e := range idx.exprs {
+			types[i] = t.exprType(e)
+		}
+		s, e, n := scanRange(conds, idx.exprs, types)
+		if n > narrowed {
+			best, narrowed, start, end = idx, n, s, e
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	var pks []string
+	i := sort.Search(len(best.entries), func(i int) bool {
+		return best.entries[i].key >= start
+	})
+	for ; i < len(best.entries); i++ {
+		if end != "" && best.entries[i].key >= end {
+			break
+		}
+		pks = append(pks, best.entries[i].pk)
+	}
+	sort.Strings(pks)
+	return pks, true
+}
+
+// exprType returns the type of the values of the expression in the table,
+// nil if unknown.
+func (t *Table) exprType(e Expr) *Type {
+	switch v := e.(type) {
+	case *ColumnRef:
+		return t.schema[v.name]
+	case *Literal:
+		if x, ok := v.val.(int); ok && (x < math.MinInt32 || x > math.MaxInt32) {
+			// the integer literals out of the range of integer are bigint
+			return bigintType
+		}
+		if v.val != nil {
+			return typeOf(v.val)
+		}
+	case *BinaryExpr:
+		switch v.op {
+		case add, sub, mul, div, mod:
+			l, r := t.exprType(v.left), t.exprType(v.right)
+			if typ := temporalType(v.op, l, r); typ != nil {
+				return typ
+			}
+			return arithmeticType(l, r)
+		case and, or, equal, notEqual, less, lessEqual, greater, greaterEqual,
+			contains, containedBy, overlaps:
+			return booleanType
+		case concat:
+			for _, operand := range []Expr{v.left, v.right} {
+				if typ := t.exprType(operand); typ != nil && typ.id == arrayID {
+					return typ
+				}
+			}
+			return stringType
+		case jsonGetText, jsonPathText:
+			return stringType
+		case jsonGet, jsonPath:
+			if typ := t.exprType(v.left); typ != nil &&
+				(typ.id == jsonID || typ.id == jsonbID) {
+				return typ
+			}
+		}
+	case *ArrayExpr:
+		if typ := t.commonType(v.elems); typ != nil {
+			return newArrayType(typ)
+		}
+	case *Subscript:
+		if typ := t.exprType(v.expr); typ != nil && typ.id == arrayID {
+			return typ.elem
+		}
+	case *UnaryExpr:
+		if v.op == neg {
+			return t.exprType(v.expr)
+		}
+		return booleanType
+	case *QuantifiedExpr:
+		return booleanType
+	case *CastExpr:
+		return v.typ
+	case *CaseExpr:
+		results := []Expr{v.els}
+		for _, w := range v.whens {
+			results = append(results, w.result)
+		}
+		return t.commonType(results)
+	case *FuncCall:
+		if v.name == "coalesce" {
+			return t.commonType(v.args)
+		}
+		types := make([]*Type, len(v.args))
+		for i, arg := range v.args {
+			types[i] = t.exprType(arg)
+		}
+		if f, err := checkCall(v.name, types); err == nil && f.result != nil {
+			return f.result(types)
+		}
+	}
+	return nil
+}
+
+// commonType returns the type of the values of all the expressions, the
+// NULLs and the missing ones aside, which is nil if unknown or if they are
+// not of the same type. The numbers are widened to the widest of them,
+// see arithmeticType, e.g., the integers and the decimals are decimals.
+func (t *Table) commonType(es []Expr) *Type {
+	var common *Type
+	for _, e := range es {
+		if l, ok := e.(*Literal); e == nil || (ok && l.val == nil) {
+			continue
+		}
+		typ := t.exprType(e)
+		switch {
+		case typ == nil:
+			return nil
+		case common == nil:
+			common = typ
+		case common.compatible(typ):
+			if typ.integral() && typ.id > common.id {
+				common = typ
+			}
+		case common.numeric() && typ.numeric():
+			common = arithmeticType(common, typ)
+		default:
+			return nil
+		}
+	}
+	return common
+}
+
+// arithmeticType returns the type of the results of the arithmetic on the
+// numbers of the types, see arithmetic, which is nil for the others.
+func arithmeticType(l, r *Type) *Type {
+	switch {
+	case l == nil || r == nil || !l.numeric() || !r.numeric():
+		return nil
+	case l.id == floatID || r.id == floatID:
+		return floatType
+	case l.id == decimalID || r.id == decimalID:
+		return decimalType
+	case l.id > r.id:
+		return l
+	}
+	return r
+}
+
+// indexName generates the name of the index from the table and the
+// indexed columns as PostgreSQL does, e.g., t_name_idx, or t_expr_idx for
+// the expressions. A number is appended if the name is taken. The caller
+// must hold the lock.
+func (db *Database) indexName(table string, exprs []Expr) string {
+	parts := []string{table}
+	for _, e := range exprs {
+		switch v := e.(type) {
+		case *ColumnRef:
+			parts = append(parts, v.name)
+		case *FuncCall:
+			parts = append(parts, v.name)
+		default:
+			parts = append(parts, "expr")
+		}
+	}
+	base := strings.Join(append(parts, "idx"), "_")
+	name := base
+	for i := 1; db.relationExists(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	return name
+}
+
+// lookupIndex returns the index of the name and the name of its table,
+// which is nil if there is no such index. The caller must hold the lock.
+func (db *Database) lookupIndex(name string) (*Index, string) {
+	for table, t := range db.tables {
+		for _, idx := range t.indexes {
+			if idx.name == name {
+				return idx, table
+			}
+		}
+	}
+	return nil, ""
+}
+
+// checkIndexExpr checks if the expression can be indexed, i.e., its
+// columns exist, its functions are not volatile, and its values are
+// comparable.
+func (t *Table) checkIndexExpr(e Expr) error {
+	var err error
+	walkExpr(e, func(e Expr) {
+		switch v := e.(type) {
+		case *ColumnRef:
+			if _, exist := t.schema[v.name]; !exist && err == nil {
+				err = newError(codeUndefinedColumn,
+					"column %s does not exist", v.name)
+			}
+		case *FuncCall:
+			if f := functions[v.name]; f != nil && f.volatile && err == nil {
+				err = newError(codeInvalidObjectDefinition,
+					"functions in index expression must be marked IMMUTABLE")
+			}
+		case Param:
+			if err == nil {
+				err = newError(codeFeatureNotSupported,
+					"there is no parameter %s in index expression", v)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := t.checkExpr(e, false); err != nil {
+		return err
+	}
+	if typ := t.exprType(e); typ != nil && typ.base().id == jsonID {
+		return newError(codeUndefinedObject,
+			"data type %s has no default operator class for access "+
+				"method btree, use jsonb instead", typ)
+	}
+	return nil
+}
+
+func (db *Database) CreateIndex(ctx context.Context, ci *CreateIndexStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	t, exist := db.tables[ci.table.name]
+	if !exist {
+		return &Result{
+			err: newError(codeUndefinedTable,
+				"relation %s does not exist", ci.table.name),
+		}
+	}
+	for _, e := range ci.exprs {
+		if err := t.checkIndexExpr(e); err != nil {
+			return &Result{
+				err: err,
+			}
+		}
+	}
+	name := ci.name
+	if name == "" {
+		name = db.indexName(ci.table.name, ci.exprs)
+	} else if db.relationExists(name) {
+		return &Result{
+			err: newError(codeDuplicateTable,
+				"relation %s already exists", name),
+		}
+	}
+	idx := &Index{name: name, exprs: ci.exprs}
+	// the expressions must be computable on all rows
+	env := &evalEnv{table: t, sess: SessionFromContext(ctx), db: db}
+	for _, pk := range t.keys {
+		env.row = t.rows[pk]
+		for _, e := range idx.exprs {
+			if _, err := e.Eval(env); err != nil {
+				return &Result{
+					err: err,
+				}
+			}
+		}
+		idx.add(pk, env.row)
+	}
+	t.indexes = append(t.indexes, idx)
+	return &Result{
+		message: "INDEX CREATED",
+	}
+}
+
+func (db *Database) DropIndex(ctx context.Context, ds *DropIndexStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	idx, table := db.lookupIndex(ds.name)
+	for name, t := range db.tables {
+		if t.pkeyName == ds.name {
+			return &Result{
+				err: newError(codeDependentObjects,
+					"cannot drop index %s because constraint %s on table "+
+						"%s requires it", ds.name, ds.name, name),
+			}
+		}
+	}
+	if idx == nil {
+		return &Result{
+			err: newError(codeUndefinedObject,
+				"index %s does not exist", ds.name),
+		}
+	}
+	t := db.tables[table]
+	for i, ti := range t.indexes {
+		if ti == idx {
+			t.indexes = append(t.indexes[:i:i], t.indexes[i+1:]...)
+			break
+		}
+	}
+	return &Result{
+		message: "INDEX DROPPED",
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Interval is the value of INTERVAL, whose months, days and microseconds
+// are kept apart as in PostgreSQL, since neither a month nor a day has a
+// fixed length, e.g., across the daylight saving time.
+type Interval struct {
+	months int
+	days   int
+	micros int64
+}
+
+// total returns the microseconds of the interval, in which a month is 30
+// days, to compare the intervals as PostgreSQL does.
+func (iv Interval) total() int {
+	return int((int64(iv.months)*30+int64(iv.days))*microsPerDay + iv.micros)
+}
+
+func (iv Interval) add(o Interval) Interval {
+	return Interval{iv.months + o.months, iv.days + o.days, iv.micros + o.micros}
+}
+
+func (iv Interval) neg() Interval {
+	return Interval{-iv.months, -iv.days, -iv.micros}
+}
+
+// scale multiplies the interval by the factor, the fractions of the months
+// and the days spill over into the days and the microseconds.
+func (iv Interval) scale(f float64) Interval {
+	return spillInterval(float64(iv.months)*f, float64(iv.days)*f,
+		float64(iv.micros)*f)
+}
+
+// spillInterval returns the interval of the fractional months, days and
+// microseconds, in which a month is 30 days.
+func spillInterval(months, days, micros float64) Interval {
+	m := math.Trunc(months)
+	days += (months - m) * 30
+	d := math.Trunc(days)
+	micros += (days - d) * float64(microsPerDay)
+	return Interval{int(m), int(d), int64(math.Round(micros))}
+}
+
+// String returns the interval in the ISO 8601 format, e.g., P1Y2M3DT4H5M6S.
+func (iv Interval) String() string {
+	if iv == (Interval{}) {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString("P")
+	part := func(n int64, unit string) {
+		if n != 0 {
+			b.WriteString(strconv.FormatInt(n, 10) + unit)
+		}
+	}
+	part(int64(iv.months/12), "Y")
+	part(int64(iv.months%12), "M")
+	part(int64(iv.days), "D")
+	if iv.micros != 0 {
+		b.WriteString("T")
+		sec := iv.micros / microsPerSecond
+		part(sec/3600, "H")
+		part(sec/60%60, "M")
+		if frac := iv.micros % (60 * microsPerSecond); frac != 0 {
+			b.WriteString(formatSeconds(frac) + "S")
+		}
+	}
+	return b.String()
+}
+
+func (iv Interval) MarshalJSON() ([]byte, error) {
+	return json.Marshal(iv.String())
+}
+
+// formatSeconds formats the microseconds as the seconds without the
+// trailing zeros of the fraction, e.g., -1.5.
+func formatSeconds(micros int64) string {
+	s := strconv.FormatFloat(float64(micros)/float64(microsPerSecond), 'f', 6, 64)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+// pgString returns the interval in the PostgreSQL output format, e.g.,
+// "1 year 2 mons 3 days 04:05:06".
+func (iv Interval) pgString() string {
+	var parts []string
+	part := func(n int, unit string) {
+		if n == 1 || n == -1 {
+			parts = append(parts, strconv.Itoa(n)+" "+unit)
+		} else if n != 0 {
+			parts = append(parts, strconv.Itoa(n)+" "+unit+"s")
+		}
+	}
+	part(iv.months/12, "year")
+	part(iv.months%12, "mon")
+	part(iv.days, "day")
+	if iv.micros != 0 || len(parts) == 0 {
+		clock := formatClock(iv.micros)
+		if iv.micros < 0 {
+			clock = "-" + formatClock(-iv.micros)
+		}
+		parts = append(parts, clock)
+	}
+	return strings.Join(parts, " ")
+}
+
+// intervalUnits maps the units of the intervals to the months, the days
+// and the microseconds of one unit.
+var intervalUnits = map[string]Interval{
+	"microsecond": {micros: 1},
+	"us":          {micros: 1},
+	"usec":        {micros: 1},
+	"millisecond": {micros: 1000},
+	"ms":          {micros: 1000},
+	"msec":        {micros: 1000},
+	"second":      {micros: microsPerSecond},
+	"sec":         {micros: microsPerSecond},
+	"s":           {micros: microsPerSecond},
+	"minute":      {micros: 60 * microsPerSecond},
+	"min":         {micros: 60 * microsPerSecond},
+	"m":           {micros: 60 * microsPerSecond},
+	"hour":        {micros: 3600 * microsPerSecond},
+	"hr":          {micros: 3600 * microsPerSecond},
+	"h":           {micros: 3600 * microsPerSecond},
+	"day":         {days: 1},
+	"d":           {days: 1},
+	"week":        {days: 7},
+	"w":           {days: 7},
+	"month":       {months: 1},
+	"mon":         {months: 1},
+	"year":        {months: 12},
+	"yr":          {months: 12},
+	"y":           {months: 12},
+	"decade":      {months: 120},
+	"century":     {months: 1200},
+	"millennium":  {months: 12000},
+}
+
+// lookupIntervalUnit returns the unit of the interval, which may be plural,
+// e.g., "days", "centuries".
+func lookupIntervalUnit(unit string) (Interval, bool) {
+	unit = strings.ToLower(unit)
+	if iv, ok := intervalUnits[unit]; ok {
+		return iv, true
+	}
+	switch {
+	case strings.HasSuffix(unit, "ies"):
+		unit = strings.TrimSuffix(unit, "ies") + "y"
+	case unit == "millennia":
+		unit = "millennium"
+	default:
+		unit = strings.TrimSuffix(unit, "s")
+	}
+	iv, ok := intervalUnits[unit]
+	return iv, ok
+}
+
+// parseInterval parses the interval in the PostgreSQL format, e.g.,
+// "1 day 2 hours", "-1 year 2 mons 03:04:05" or "1 week ago", or in the
+// ISO 8601 format, e.g., "P1DT2H".
+func parseInterval(s string) (Interval, error) {
+	invalid := invalidInput("interval", s)
+	fields := splitIntervalFields(strings.TrimPrefix(strings.TrimSpace(s), "@"))
+	if len(fields) == 1 && strings.HasPrefix(strings.ToUpper(fields[0]), "P") {
+		iv, ok := parseISOInterval(strings.ToUpper(fields[0]))
+		if !ok {
+			return Interval{}, invalid
+		}
+		return iv, nil
+	}
+	ago := len(fields) > 0 && strings.EqualFold(fields[len(fields)-1], "ago")
+	if ago {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) == 0 {
+		return Interval{}, invalid
+	}
+	var iv Interval
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.Contains(f, ":") {
+			micros, ok := parseClock(strings.TrimLeft(f, "+-"))
+			if !ok {
+				return Interval{}, invalid
+			}
+			if strings.HasPrefix(f, "-") {
+				micros = -micros
+			}
+			iv.micros += micros
+			continue
+		}
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return Interval{}, invalid
+		}
+		// a number without unit is in seconds, or in days if the time
+		// follows it, e.g., "1 02:00"
+		unit := intervalUnits["second"]
+		if i+1 < len(fields) && strings.Contains(fields[i+1], ":") {
+			unit = intervalUnits["day"]
+		} else if i+1 < len(fields) {
+			u, ok := lookupIntervalUnit(fields[i+1])
+			if !ok {
+				return Interval{}, invalid
+			}
+			unit = u
+			i++
+		}
+		iv = iv.add(unit.scale(n))
+	}
+	if ago {
+		iv = iv.neg()
+	}
+	return iv, nil
+}
+
+// splitIntervalFields splits the interval into the numbers, the units and
+// the times, e.g., "1day" into "1" and "day".
+func splitIntervalFields(s string) []string {
+	var fields []string
+	for _, f := range strings.Fields(s) {
+		if strings.HasPrefix(strings.ToUpper(f), "P") {
+			fields = append(fields, f)
+			continue
+		}
+		i := strings.IndexFunc(f, unicode.IsLetter)
+		if i > 0 {
+			fields = append(fields, f[:i], f[i:])
+		} else {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// parseISOInterval parses the interval in the ISO 8601 format, e.g.,
+// P1Y2M3W4DT5H6M7.5S, whose numbers may be negative or fractional.
+func parseISOInterval(s string) (Interval, bool) {
+	var iv Interval
+	clock := false
+	num := ""
+	for _, r := range s[1:] {
+		switch {
+		case r == 'T' && !clock && num == "":
+			clock = true
+			continue
+		case unicode.IsDigit(r) || r == '.' || r == '-' || r == '+':
+			num += string(r)
+			continue
+		}
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return Interval{}, false
+		}
+		num = ""
+		unit := strings.ToLower(string(r))
+		if !clock && unit == "m" {
+			unit = "month"
+		}
+		u, ok := intervalUnits[unit]
+		// the weeks and the larger units are in the date part
+		if !ok || clock != (u.days == 0 && u.months == 0) {
+			return Interval{}, false
+		}
+		iv = iv.add(u.scale(n))
+	}
+	return iv, num == "" && len(s) > 1
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSON is the value of JSON and JSONB, whose document is decoded into nil,
+// bool, Decimal, string, []any and map[string]any. JSON keeps the text as
+// it is given, while JSONB is printed in the normalized form, e.g.,
+// {"a": 1, "b": [true, null]}, in which the keys are ordered as in
+// PostgreSQL, i.e., by their lengths and then by their bytes.
+type JSON struct {
+	doc any
+	// text is the text of JSON, empty for JSONB
+	text   string
+	binary bool
+}
+
+// newJSON returns the JSON or the JSONB of the document, the text of JSON
+// is the normalized one.
+func newJSON(doc any, binary bool) JSON {
+	j := JSON{doc: doc, binary: binary}
+	if !binary {
+		j.text = j.normalized(false)
+	}
+	return j
+}
+
+// parseJSON parses the JSON text, the numbers are exact.
+func parseJSON(s string, binary bool) (JSON, error) {
+	typ := "json"
+	if binary {
+		typ = "jsonb"
+	}
+	invalid := newError(codeInvalidTextRepresent,
+		"invalid input syntax for type %s: %q", typ, s)
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return JSON{}, invalid
+	}
+	// nothing but the spaces may follow the document
+	if _, err := dec.Token(); err != io.EOF {
+		return JSON{}, invalid
+	}
+	doc, err := decodeNumbers(doc)
+	if err != nil {
+		return JSON{}, invalid
+	}
+	j := JSON{doc: doc, binary: binary}
+	if !binary {
+		j.text = s
+	}
+	return j, nil
+}
+
+// decodeNumbers converts the json.Numbers of the document to Decimals.
+func decodeNumbers(doc any) (any, error) {
+	switch v := doc.(type) {
+	case json.Number:
+		return parseDecimal(v.String())
+	case []any:
+		for i, e := range v {
+			d, err := decodeNumbers(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = d
+		}
+	case map[string]any:
+		for k, e := range v {
+			d, err := decodeNumbers(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = d
+		}
+	}
+	return doc, nil
+}
+
+func (j JSON) String() string {
+	if !j.binary {
+		return j.text
+	}
+	return j.normalized(false)
+}
+
+// MarshalJSON embeds the document in the JSON output.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return []byte(j.String()), nil
+}
+
+// key returns the normalized text in which the equal numbers are alike,
+// e.g., 1.0 and 1, which is the key of the JSONB in the indexes.
+func (j JSON) key() string {
+	return j.normalized(true)
+}
+
+// normalized returns the text of the document in the normalized form,
+// the trailing zeros of the numbers are removed if exact is true.
+func (j JSON) normalized(exact bool) string {
+	var b strings.Builder
+	writeDocument(&b, j.doc, exact)
+	return b.String()
+}
+
+// writeDocument writes the document in the normalized form, see JSON.
+func writeDocument(b *strings.Builder, doc any, exact bool) {
+	switch v := doc.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case Decimal:
+		if exact {
+			v = v.normalize()
+		}
+		b.WriteString(v.String())
+	case string:
+		b.WriteString(quoteJSON(v))
+	case []any:
+		b.WriteString("[")
+		for i, e := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeDocument(b, e, exact)
+		}
+		b.WriteString("]")
+	case map[string]any:
+		b.WriteString("{")
+		for i, k := range jsonKeys(v) {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(quoteJSON(k) + ": ")
+			writeDocument(b, v[k], exact)
+		}
+		b.WriteString("}")
+	}
+}
+
+// quoteJSON returns the JSON string of the s, in which only the quotes,
+// the backslashes and the control characters are escaped.
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsonKeys returns the keys of the object in the order of JSONB.
+func jsonKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// jsonRank orders the kinds of the documents as JSONB does, i.e.,
+// null < string < number < boolean < array < object.
+func jsonRank(doc any) int {
+	switch doc.(type) {
+	case string:
+		return 1
+	case Decimal:
+		return 2
+	case bool:
+		return 3
+	case []any:
+		return 4
+	case map[string]any:
+		return 5
+	}
+	return 0
+}
+
+// compareJSON compares the documents as JSONB does, the arrays and the
+// objects with more elements are greater, and the ones of the same size
+// are compared element by element, or key and value by key and value.
+func compareJSON(a, b any) int {
+	if ra, rb := jsonRank(a), jsonRank(b); ra != rb {
+		return compareOrdered(ra, rb)
+	}
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case Decimal:
+		return av.Cmp(b.(Decimal))
+	case bool:
+		c, _ := compareValues(av, b)
+		return c
+	case []any:
+		bv := b.([]any)
+		if len(av) != len(bv) {
+			return compareOrdered(len(av), len(bv))
+		}
+		for i := range av {
+			if c := compareJSON(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+	case map[string]any:
+		bv := b.(map[string]any)
+		if len(av) != len(bv) {
+			return compareOrdered(len(av), len(bv))
+		}
+		ak, bk := jsonKeys(av), jsonKeys(bv)
+		for i := range ak {
+			if c := compareJSON(ak[i], bk[i]); c != 0 {
+				return c
+			}
+			if c := compareJSON(av[ak[i]], bv[bk[i]]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// jsonContains tells whether the document a contains the b as the JSONB
+// operator @> does: the objects contain the objects whose pairs they
+// contain, the arrays contain the arrays whose elements are contained by
+// any of their elements, and the scalars contain the equal ones. At the
+// top level, an array also contains the scalar in it.
+func jsonContains(a, b any) bool {
+	if _, ok := a.([]any); ok && jsonRank(b) < 4 {
+		b = []any{b}
+	}
+	return jsonContainsValue(a, b)
+}
+
+func jsonContainsValue(a, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, e := range bv {
+			ae, exist := av[k]
+			if !exist || !jsonContainsValue(ae, e) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			return false
+		}
+		for _, e := range bv {
+			found := false
+			for _, ae := range av {
+				// the elements only contain the ones of the same kind
+				if jsonRank(ae) == jsonRank(e) && jsonContainsValue(ae, e) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return jsonRank(a) == jsonRank(b) && compareJSON(a, b) == 0
+}
+
+func isJSONArray(doc any) bool {
+	_, ok := doc.([]any)
+	return ok
+}
+
+// jsonField returns the element of the array at the index, which counts
+// from the end if negative, or the field of the object, which is false if
+// there is no such element or field.
+func jsonField(doc any, step any) (any, bool) {
+	switch v := doc.(type) {
+	case []any:
+		i, ok := step.(int)
+		if !ok {
+			return nil, false
+		}
+		if i < 0 {
+			i += len(v)
+		}
+		if i < 0 || i >= len(v) {
+			return nil, false
+		}
+		return v[i], true
+	case map[string]any:
+		k, ok := step.(string)
+		if !ok {
+			return nil, false
+		}
+		e, exist := v[k]
+		return e, exist
+	}
+	return nil, false
+}
+
+// jsonPathField follows the path of the keys and the array indexes, e.g.,
+// {a,0,b}, which is false if any of them is missing. It also returns the
+// steps taken, see JSON.at.
+func jsonPathField(doc any, path []string) (any, []any, bool) {
+	steps := make([]any, len(path))
+	for i, p := range path {
+		var step any = p
+		if _, ok := doc.([]any); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil {
+				return nil, nil, false
+			}
+			step = n
+		}
+		var ok bool
+		if doc, ok = jsonField(doc, step); !ok {
+			return nil, nil, false
+		}
+		steps[i] = step
+	}
+	return doc, steps, true
+}
+
+// at returns the document found in the JSON by the steps, see jsonField.
+// The document of JSON keeps its text in the JSON, e.g., [1,2] rather
+// than the normalized [1, 2].
+func (j JSON) at(doc any, steps []any) JSON {
+	if j.binary {
+		return newJSON(doc, true)
+	}
+	// the text has been validated, and the steps found the document
+	raw := json.RawMessage(j.text)
+	for _, step := range steps {
+		if i, ok := step.(int); ok {
+			var elems []json.RawMessage
+			_ = json.Unmarshal(raw, &elems)
+			if i < 0 {
+				i += len(elems)
+			}
+			raw = elems[i]
+			continue
+		}
+		// the last of the duplicate keys is taken as by jsonField
+		var fields map[string]json.RawMessage
+		_ = json.Unmarshal(raw, &fields)
+		raw = fields[step.(string)]
+	}
+	return JSON{doc: doc, text: strings.TrimSpace(string(raw))}
+}
+
+// jsonText returns the text of the document for the operators ->> and
+// #>>, i.e., the strings unquoted and NULL for null.
+func jsonText(j JSON) any {
+	switch v := j.doc.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	}
+	return j.String()
+}
+
+// jsonOperands converts the string operand to the JSON of the other one,
+// as the untyped literal compared with the JSON.
+func jsonOperands(a, b any) (any, any, error) {
+	if j, ok := a.(JSON); ok {
+		if s, ok := b.(string); ok {
+			v, err := parseJSON(s, j.binary)
+			return a, v, err
+		}
+	}
+	if j, ok := b.(JSON); ok {
+		if s, ok := a.(string); ok {
+			v, err := parseJSON(s, j.binary)
+			return v, b, err
+		}
+	}
+	return a, b, nil
+}
+
+// jsonOperator evaluates the JSON operators on the non-NULL operands.
+func jsonOperator(op Operator, a, b any) (any, error) {
+	undefined := newError(codeUndefinedFunction,
+		"operator does not exist: %s %s %s", valueType(a), op, valueType(b))
+	if op == contains || op == containedBy {
+		a, b, err := jsonOperands(a, b)
+		if err != nil {
+			return nil, err
+		}
+		x, ok := a.(JSON)
+		y, ok2 := b.(JSON)
+		if !ok || !ok2 || !x.binary || !y.binary {
+			return nil, undefined
+		}
+		if op == containedBy {
+			x, y = y, x
+		}
+		return jsonContains(x.doc, y.doc), nil
+	}
+	j, ok := a.(JSON)
+	if !ok {
+		return nil, undefined
+	}
+	var (
+		doc   any
+		steps []any
+		found bool
+	)
+	switch op {
+	case jsonGet, jsonGetText:
+		switch b.(type) {
+		case int, string:
+		default:
+			return nil, undefined
+		}
+		doc, found = jsonField(j.doc, b)
+		steps = []any{b}
+	default:
+		// the path is the text array, e.g., '{a,0}'
+		v, ok, err := textArrayType.assign(b)
+		if !ok {
+			return nil, undefined
+		}
+		if err != nil {
+			return nil, err
+		}
+		path := make([]string, len(v.(Array).elems))
+		for i, step := range v.(Array).elems {
+			if step == nil {
+				return nil, nil
+			}
+			path[i] = step.(string)
+		}
+		doc, steps, found = jsonPathField(j.doc, path)
+	}
+	if !found {
+		return nil, nil
+	}
+	if op == jsonGetText || op == jsonPathText {
+		return jsonText(j.at(doc, steps)), nil
+	}
+	return j.at(doc, steps), nil
+}
+
+// jsonArg converts the argument of the json_ or the jsonb_ function to the
+// JSON, the strings are parsed.
+func jsonArg(fn string, v any) (JSON, error) {
+	binary := strings.HasPrefix(fn, "jsonb_")
+	switch x := v.(type) {
+	case string:
+		return parseJSON(x, binary)
+	case JSON:
+		if x.binary == binary {
+			return x, nil
+		}
+	}
+	return JSON{}, newError(codeUndefinedFunction,
+		"function %s(%s) does not exist", fn, valueType(v))
+}
+
+// jsonExtractPath returns the document at the path given by the rest of
+// the arguments, see jsonPathField. The _text variants return the text,
+// see jsonText.
+func jsonExtractPath(fn string) func(env *evalEnv, args []any) (any, error) {
+	return func(env *evalEnv, args []any) (any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		j, err := jsonArg(fn, args[0])
+		if err != nil {
+			return nil, err
+		}
+		path := make([]string, len(args)-1)
+		for i, arg := range args[1:] {
+			if arg == nil {
+				return nil, nil
+			}
+			s, ok := arg.(string)
+			if !ok {
+				return nil, newError(codeUndefinedFunction,
+					"function %s does not accept %s path", fn, valueType(arg))
+			}
+			path[i] = s
+		}
+		doc, steps, found := jsonPathField(j.doc, path)
+		if !found {
+			return nil, nil
+		}
+		if strings.HasSuffix(fn, "_text") {
+			return jsonText(j.at(doc, steps)), nil
+		}
+		return j.at(doc, steps), nil
+	}
+}
+
+// jsonArrayLength returns the number of the elements of the array.
+func jsonArrayLength(fn string) func(env *evalEnv, args []any) (any, error) {
+	return func(env *evalEnv, args []any) (any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		j, err := jsonArg(fn, args[0])
+		if err != nil {
+			return nil, err
+		}
+		arr, ok := j.doc.([]any)
+		if !ok {
+			return nil, newError(codeInvalidParameterValue,
+				"cannot get array length of a non-array")
+		}
+		return len(arr), nil
+	}
+}
+
+// jsonTypeOf returns the kind of the document, e.g., "object".
+func jsonTypeOf(fn string) func(env *evalEnv, args []any) (any, error) {
+	return func(env *evalEnv, args []any) (any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		j, err := jsonArg(fn, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return [...]string{"null", "string", "number", "boolean", "array",
+			"object"}[jsonRank(j.doc)], nil
+	}
+}
+
+// jsonObjectKeys returns the set of the keys of the object, in the order
+// of JSONB.
+func jsonObjectKeys(fn string) func(env *evalEnv, args []any) ([]any, error) {
+	return func(env *evalEnv, args []any) ([]any, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		j, err := jsonArg(fn, args[0])
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := j.doc.(map[string]any)
+		if !ok {
+			kind := "a scalar"
+			if isJSONArray(j.doc) {
+				kind = "an array"
+			}
+			return nil, newError(codeInvalidParameterValue,
+				"cannot call %s on %s", fn, kind)
+		}
+		keys := jsonKeys(obj)
+		set := make([]any, len(keys))
+		for i, k := range keys {
+			set[i] = k
+		}
+		return set, nil
+	}
+}
+
+// compareJSONValues compares the JSONB with the JSONB or the string, while
+// JSON is not comparable as in PostgreSQL.
+func compareJSONValues(a, b any) (int, error) {
+	a, b, err := jsonOperands(a, b)
+	if err != nil {
+		return 0, err
+	}
+	x, ok := a.(JSON)
+	y, ok2 := b.(JSON)
+	if !ok || !ok2 || !x.binary || !y.binary {
+		return 0, newError(codeUndefinedFunction,
+			"cannot compare %s with %s", valueType(a), valueType(b))
+	}
+	return compareJSON(x.doc, y.doc), nil
+}
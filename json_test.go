@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestJSONExpressions(t *testing.T) {
+	tts := []struct {
+		expr   string
+		code   string
+		expect string
+	}{
+		{`json '{"bb": 1, "a": [1, 2]}'`, "", `{"bb": 1, "a": [1, 2]}`},
+		{`jsonb '{"bb":1,"a":[1,2], "a": 3}'`, "", `{"a": 3, "bb": 1}`},
+		{`'[1,2,3]'::json`, "", `[1,2,3]`},
+		{`json '{"a": {"b":[1,2],"a" :1}}' -> 'a'`, "", `{"b":[1,2],"a" :1}`},
+		{`json '{"a": 1, "a": [1, 2 ]}' -> 'a'`, "", `[1, 2 ]`},
+		{`json ' [1, {"x" :2}] ' #>> '{-1}'`, "", `{"x" :2}`},
+		{`json '[1, {"x" : [3,4]}]' -> -1 #> '{x, 1}'`, "", `4`},
+		{`json_extract_path('{"a": [ 1 ]}', 'a')`, "", `[ 1 ]`},
+		{`jsonb '{"a": [1,2]}' -> 'a'`, "", `[1, 2]`},
+		{`'[1,2]'::jsonb::json`, "", `[1, 2]`},
+		{`jsonb '{"a": 1.50}' = '{"a":1.5}'`, "", "true"},
+		{`jsonb '{"a": {"b": ["x", "y"]}}' -> 'a' -> 'b' -> -1`, "", `"y"`},
+		{`jsonb '{"a": {"b": ["x", "y"]}}' -> 'a' -> 'b' ->> 0`, "", "x"},
+		{`json '{"a": {"b": ["x", "y"]}}' #> '{a,b,1}'`, "", `"y"`},
+		{`json '{"a": {"b": ["x", "y"]}}' #>> '{a,b,1}'`, "", "y"},
+		{`jsonb '{"a": 1}' -> 'b' is null`, "", "true"},
+		{`jsonb '[1, 2]' -> 'a' is null`, "", "true"},
+		{`jsonb '{"a": 1, "b": [1, 2, 3]}' @> '{"b": [3, 1]}'`, "", "true"},
+		{`jsonb '{"a": 1, "b": [1, 2, 3]}' @> '{"b": 1}'`, "", "false"},
+		{`jsonb '["a", 1]' @> '"a"'`, "", "true"},
+		{`'{"a": 1}' <@ jsonb '{"a": 1, "b": 2}'`, "", "true"},
+		{`json '{"a": 1}' @> '{"a": 1}'`, codeUndefinedFunction, ""},
+		{`json_array_length('[1, [2, 3], {}]')`, "", "3"},
+		{`jsonb_array_length('{}')`, codeInvalidParameterValue, ""},
+		{`json_typeof(json '{"a": null}' -> 'a')`, "", "null"},
+		{`jsonb_typeof('1.5')`, "", "number"},
+		{`json_extract_path('{"a": {"b": 1}}', 'a', 'b')`, "", "1"},
+		{`jsonb_extract_path_text('{"a": {"b": "x"}}', 'a', 'b')`, "", "x"},
+		{`json_extract_path(jsonb '{}', 'a') is null`, codeUndefinedFunction, ""},
+		{`jsonb '{"a": }' is null`, codeInvalidTextRepresent, ""},
+		{`jsonb_object_keys('{"a": 1}') = 'a'`, codeFeatureNotSupported, ""},
+		{`jsonb '{"a": 1}' -> 'a' = 1`, codeUndefinedFunction, ""},
+	}
+	env := &evalEnv{sess: NewSession(defaultSuperuser)}
+	for i, tt := range tts {
+		got, err := evalText(env, tt.expr)
+		if sqlState(err) != tt.code && (err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.expr, err, tt.code)
+		}
+		if got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.expr, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.expr)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	db := NewDatabase()
+	if r := execSQL(db, defaultSuperuser,
+		"create table j (id integer primary key, v json, b jsonb)"); r.err != nil {
+		t.Fatalf("failed to create the table: %v", r.err)
+	}
+	tts := []struct {
+		text  string
+		jsonb string
+	}{
+		{`{"b":1,  "a":[1,2]}`, `{"a": [1, 2], "b": 1}`},
+		{`{"a": 1, "a": 2}`, `{"a": 2}`},
+		{` [1,2,3] `, `[1, 2, 3]`},
+	}
+	for i, tt := range tts {
+		sql := fmt.Sprintf("insert into j values (%d, '%s', '%s')",
+			i, tt.text, tt.text)
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+	r := execSQL(db, defaultSuperuser, "select v, b from j")
+	if r.err != nil || len(r.rows) != len(tts) {
+		t.Fatalf("got %d rows (%v), expect %d", len(r.rows), r.err, len(tts))
+	}
+	for i, tt := range tts {
+		if got := toText(r.rows[i].values[0]); got != tt.text {
+			t.Fatalf("case %d failed: got json %q, expect %q", i, got, tt.text)
+		}
+		if got := toText(r.rows[i].values[1]); got != tt.jsonb {
+			t.Fatalf("case %d failed: got jsonb %q, expect %q", i, got, tt.jsonb)
+		}
+		t.Logf("case %d succeed", i)
+	}
+}
+
+func TestJSONObjectKeys(t *testing.T) {
+	keys := functions["jsonb_object_keys"].set
+	got, err := keys(nil, []any{`{"bb": 1, "a": 2, "c": 3}`})
+	if expect := []any{"a", "c", "bb"}; err != nil || !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %v (%v), expect %v", got, err, expect)
+	}
+	if _, err := keys(nil, []any{`[1]`}); sqlState(err) != codeInvalidParameterValue {
+		t.Fatalf("got error(%v), expect(%s)", err, codeInvalidParameterValue)
+	}
+}
+
+func TestExpressionIndex(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table ev (id integer primary key, payload jsonb, doc json)",
+		`insert into ev values (1, '{"kind": "click", "n": 1}', '{}')`,
+		`insert into ev values (2, '{"kind": "view"}', '{}')`,
+		`insert into ev values (3, '{"kind": "click", "n": 2}', '{}')`,
+		`insert into ev values (4, '{"n": 3}', '{}')`,
+		"create index ev_kind on ev ((payload ->> 'kind'))",
+		"create index on ev ((payload -> 'n'))",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		expect []any
+	}{
+		{"Equality", "select id from ev where payload ->> 'kind' = 'click'", "",
+			[]any{1, 3}},
+		{"Swapped", "select id from ev where 'view' = payload ->> 'kind'", "",
+			[]any{2}},
+		{"Containment", `select id from ev where payload @> '{"kind": "click"}'`, "",
+			[]any{1, 3}},
+		{"Jsonb equality", "select id from ev where payload -> 'n' = '3'", "",
+			[]any{4}},
+		{"Update", "update ev set payload = '{\"kind\": \"view\"}' where id = 1", "",
+			nil},
+		{"Updated", "select id from ev where payload ->> 'kind' = 'view'", "",
+			[]any{1, 2}},
+		{"Delete", "delete from ev where payload ->> 'kind' = 'view'", "", nil},
+		{"Deleted", "select id from ev where payload ->> 'kind' >= 'a'", "",
+			[]any{3}},
+		{"Json index", "create index on ev (doc)", codeUndefinedObject, nil},
+		{"Volatile", "create index on ev ((now() - interval '1 day'))",
+			codeInvalidObjectDefinition, nil},
+		{"Unknown column", "create index on ev (x)", codeUndefinedColumn, nil},
+		{"Duplicate", "create index ev_kind on ev (id)", codeDuplicateTable, nil},
+		{"Drop pk", "drop index ev_pkey", codeDependentObjects, nil},
+		{"Drop", "drop index ev_kind", "", nil},
+		{"Dropped", "drop index ev_kind", codeUndefinedObject, nil},
+		{"Scan", "select id from ev where payload ->> 'kind' = 'click'", "",
+			[]any{3}},
+		{"Json pk", "create table j (id json primary key)", codeUndefinedObject,
+			nil},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		var got []any
+		for _, row := range r.rows {
+			got = append(got, row.fields["id"])
+		}
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Fatalf("case %d (%s) failed: got %v, expect %v",
+				i, tt.name, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	info, err := db.DescribeTable("ev")
+	if expect := []string{"ev_expr_idx ((payload -> 'n'))"}; err != nil ||
+		!reflect.DeepEqual(info.Indexes, expect) {
+		t.Fatalf("got indexes %q (%v), expect %q", info.Indexes, err, expect)
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// appendKey appends the order-preserving encoding of the non-NULL value,
+// i.e., the encoded keys compare byte-wise as the values do column by
+// column. The integers and the floats take 8 bytes, while the strings and
+// the bytes are terminated by 0x00 0x01 with 0x00 escaped as 0x00 0xff, so
+// that no encoded value is a prefix of another. The decimals are encoded
+// by appendDecimalKey, the date and time values as the microseconds, the
+// JSON documents as their normalized texts, see JSON.key, and the UUIDs as
+// their 16 bytes. Each element of the arrays is preceded by 0x02, or is
+// 0x03 for NULL, which is greater than the other elements, and the arrays
+// are terminated by 0x01 so that the shorter arrays come first.
+func appendKey(b []byte, v any) []byte {
+	var buf [8]byte
+	switch v := v.(type) {
+	case int:
+		binary.BigEndian.PutUint64(buf[:], uint64(v)^(1<<63))
+		return append(b, buf[:]...)
+	case float64:
+		if v == 0 {
+			// -0 equals 0
+			v = 0
+		}
+		bits := math.Float64bits(v)
+		if bits&(1<<63) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		binary.BigEndian.PutUint64(buf[:], bits)
+		return append(b, buf[:]...)
+	case bool:
+		if v {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case Decimal:
+		return appendDecimalKey(b, v)
+	case Date:
+		return appendKey(b, int(v.UnixMicro()))
+	case Timestamp:
+		return appendKey(b, int(v.UnixMicro()))
+	case TimestampTZ:
+		return appendKey(b, int(v.UnixMicro()))
+	case TimeOfDay:
+		return appendKey(b, int(v))
+	case Interval:
+		return appendKey(b, v.total())
+	case Bytes:
+		return appendKey(b, string(v))
+	case JSON:
+		return appendKey(b, v.key())
+	case UUID:
+		return append(b, v[:]...)
+	case Array:
+		for _, e := range v.elems {
+			if e == nil {
+				b = append(b, 3)
+				continue
+			}
+			b = appendKey(append(b, 2), e)
+		}
+		return append(b, 1)
+	case string:
+		for i := 0; i < len(v); i++ {
+			b = append(b, v[i])
+			if v[i] == 0 {
+				b = append(b, 0xff)
+			}
+		}
+		return append(b, 0, 1)
+	}
+	return b
+}
+
+// appendDecimalKey encodes the decimal as 0.digits × 10^exponent, where the
+// digits have no trailing zeros so that the equal decimals are encoded
+// alike, e.g., 1.5 and 1.50. The zero is 0x01, the positive decimals are
+// 0x02, the exponent and the digits terminated by 0x00, and the negative
+// ones are 0x00 followed by the complement of the rest.
+func appendDecimalKey(b []byte, d Decimal) []byte {
+	sign := d.coef.Sign()
+	if sign == 0 {
+		return append(b, 1)
+	}
+	var buf [4]byte
+	d = d.normalize()
+	digits := new(big.Int).Abs(d.coef).String()
+	exp := uint32(len(digits)-d.scale) ^ (1 << 31)
+	digits = strings.TrimRight(digits, "0")
+	if sign > 0 {
+		b = append(b, 2)
+		binary.BigEndian.PutUint32(buf[:], exp)
+		b = append(b, buf[:]...)
+		return append(append(b, digits...), 0)
+	}
+	b = append(b, 0)
+	binary.BigEndian.PutUint32(buf[:], ^exp)
+	b = append(b, buf[:]...)
+	for i := 0; i < len(digits); i++ {
+		b = append(b, ^digits[i])
+	}
+	return append(b, 0xff)
+}
+
+// prefixEnd returns the smallest key greater than all the keys with the
+// prefix, which is empty if there is no such key.
+func prefixEnd(prefix string) string {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			return prefix[:i] + string([]byte{prefix[i] + 1})
+		}
+	}
+	return ""
+}
+
+// rowKey returns the encoded primary key of the row.
+func (t *Table) rowKey(table string, r *Row) (string, error) {
+	var b []byte
+	for _, col := range t.primaryKey {
+		v := r.fields[col]
+		if v == nil {
+			return "", newError(codeNotNullViolation,
+				"null value in column %s of relation %s violates "+
+					"not-null constraint %s", col, table, t.pkeyName)
+		}
+		b = appendKey(b, v)
+	}
+	return string(b), nil
+}
+
+// put adds or replaces the row of the key.
+func (t *Table) put(key string, r *Row) {
+	if old, exist := t.rows[key]; exist {
+		for _, idx := range t.indexes {
+			idx.delete(key, old)
+		}
+		t.deleteUnique(key, old)
+	} else {
+		i := sort.SearchStrings(t.keys, key)
+		t.keys = append(t.keys, "")
+		copy(t.keys[i+1:], t.keys[i:])
+		t.keys[i] = key
+	}
+	t.rows[key] = r
+	for _, idx := range t.indexes {
+		idx.add(key, r)
+	}
+	t.addUnique(key, r)
+}
+
+// remove deletes the row of the key.
+func (t *Table) remove(key string) {
+	r, exist := t.rows[key]
+	if !exist {
+		return
+	}
+	for _, idx := range t.indexes {
+		idx.delete(key, r)
+	}
+	t.deleteUnique(key, r)
+	delete(t.rows, key)
+	i := sort.SearchStrings(t.keys, key)
+	t.keys = append(t.keys[:i], t.keys[i+1:]...)
+}
+
+// keysIn returns the keys in the range [start, end) in order, the end is
+// empty for no upper bound.
+func (t *Table) keysIn(start, end string) []string {
+	i := sort.SearchStrings(t.keys, start)
+	j := len(t.keys)
+	if end != "" {
+		j = sort.SearchStrings(t.keys, end)
+	}
+	if i >= j {
+		return nil
+	}
+	return append([]string(nil), t.keys[i:j]...)
+}
+
+// scanKeys returns the keys of the rows which may satisfy the where
+// clause in order. Only the range of the primary key narrowed by the
+// where clause is scanned, or the range of an index if the primary key
+// does not narrow it, see scanRange.
+func (t *Table) scanKeys(where Expr) []string {
+	conds := conjuncts(where, nil)
+	targets := make([]Expr, len(t.primaryKey))
+	types := make([]*Type, len(t.primaryKey))
+	for i, col := range t.primaryKey {
+		targets[i], types[i] = &ColumnRef{name: col}, t.schema[col]
+	}
+	start, end, n := scanRange(conds, targets, types)
+	if n == 0 {
+		if keys, ok := t.indexScan(conds); ok {
+			return keys
+		}
+	}
+	return t.keysIn(start, end)
+}
+
+// scanRange returns the range [start, end) of the keys encoded from the
+// values of the targets, i.e., the columns of the primary key or the
+// expressions of an index, which may satisfy the conditions, as well as
+// the number of the targets narrowing the range. The range is narrowed by
+// the equalities on a prefix of the targets, and then by the comparisons
+// on the target following the prefix. The targets of unknown types, and
+// the ones following them, never narrow the range.
+func scanRange(conds []Expr, targets []Expr, types []*Type) (string, string, int) {
+	var prefix []byte
+	for i, target := range targets {
+		typ := types[i]
+		if typ == nil {
+			return string(prefix), prefixEnd(string(prefix)), i
+		}
+		if v, ok := keyCond(conds, target, typ, equal); ok {
+			prefix = appendKey(prefix, v)
+			continue
+		}
+		// the keys of JSONB are not ordered as the documents
+		if typ.base().id == jsonbID {
+			return string(prefix), prefixEnd(string(prefix)), i
+		}
+		start, end, n := string(prefix), prefixEnd(string(prefix)), i
+		for _, op := range []Operator{greater, greaterEqual} {
+			if v, ok := keyCond(conds, target, typ, op); ok {
+				k := string(appendKey(append([]byte(nil), prefix...), v))
+				if op == greater {
+					k = prefixEnd(k)
+				}
+				if k > start {
+					start = k
+				}
+				n = i + 1
+			}
+		}
+		for _, op := range []Operator{less, lessEqual} {
+			if v, ok := keyCond(conds, target, typ, op); ok {
+				k := string(appendKey(append([]byte(nil), prefix...), v))
+				if op == lessEqual {
+					k = prefixEnd(k)
+				}
+				if k != "" && (end == "" || k < end) {
+					end = k
+				}
+				n = i + 1
+			}
+		}
+		return start, end, n
+	}
+	return string(prefix), prefixEnd(string(prefix)), len(targets)
+}
+
+// conjuncts appends the operands of the top-level ANDs of the expression.
+func conjuncts(e Expr, conds []Expr) []Expr {
+	if b, ok := e.(*BinaryExpr); ok && b.op == and {
+		return conjuncts(b.right, conjuncts(b.left, conds))
+	}
+	if e != nil {
+		conds = append(conds, e)
+	}
+	return conds
+}
+
+// keyCond finds the comparison "target op literal", or the flipped one,
+// of the target among the conditions, and returns the literal, or the
+// constant cast, see constValue, if it is of the type of the target, which
+// is converted as stored in the column. The target is matched by its text,
+// e.g., (payload ->> 'kind').
+func keyCond(conds []Expr, target Expr, typ *Type,
+	op Operator) (any, bool) {
+	flipped := map[Operator]Operator{
+		equal: equal, less: greater, lessEqual: greaterEqual,
+		greater: less, greaterEqual: lessEqual,
+	}
+	for _, cond := range conds {
+		b, ok := cond.(*BinaryExpr)
+		if !ok {
+			continue
+		}
+		c, lit := b.left, b.right
+		if b.op != op {
+			if b.op != flipped[op] {
+				continue
+			}
+			c, lit = b.right, b.left
+		} else if _, ok := constValue(lit); !ok && b.op == equal {
+			c, lit = b.right, b.left
+		}
+		if c.String() != target.String() {
+			continue
+		}
+		val, ok := constValue(lit)
+		if !ok {
+			continue
+		}
+		if v, ok := keyValue(typ, val); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// constValue returns the value of the literal, or of the cast of the
+// constant, e.g., '2024-01-31'::date, which is false for the other
+// expressions.
+func constValue(e Expr) (any, bool) {
+	switch v := e.(type) {
+	case *Literal:
+		return v.val, true
+	case *CastExpr:
+		if _, ok := constValue(v.expr); ok {
+			val, err := v.Eval(&evalEnv{})
+			return val, err == nil
+		}
+	}
+	return nil, false
+}
+
+// keyValue converts the literal to the key of the column type, which is
+// false if the literal is not comparable with the column as it is stored.
+func keyValue(typ *Type, v any) (any, bool) {
+	if typ.id == arrayID {
+		if s, ok := v.(string); ok {
+			v, err := typ.parse(s)
+			return v, err == nil
+		}
+		a, ok := v.(Array)
+		if !ok {
+			return nil, false
+		}
+		// the elements are converted as the element type
+		elems := make([]any, len(a.elems))
+		for i, e := range a.elems {
+			if e == nil {
+				continue
+			}
+			if elems[i], ok = keyValue(typ.elem, e); !ok {
+				return nil, false
+			}
+		}
+		return Array{elem: typ.elem, elems: elems}, true
+	}
+	if typ.temporal() || typ.id == jsonbID || typ.id == uuidID {
+		// the strings are taken as the untyped literals
+		if s, ok := v.(string); ok {
+			v, err := typ.parse(s)
+			return v, err == nil
+		}
+		return v, typeOf(v).id == typ.id
+	}
+	switch x := v.(type) {
+	case int:
+		switch {
+		case typ.integral():
+			return x, true
+		case typ.id == floatID:
+			return float64(x), true
+		case typ.id == decimalID:
+			return decimalFromInt(x), true
+		}
+	case float64:
+		switch typ.id {
+		case floatID:
+			return x, true
+		case decimalID:
+			d, err := decimalFromFloat(x)
+			return d, err == nil
+		}
+	case Decimal:
+		return x, typ.id == decimalID
+	case string:
+		return x, typ.id == stringID || typ.id == varcharID
+	case Bytes:
+		return x, typ.id == byteaID
+	case bool:
+		return x, typ.id == booleanID
+	}
+	return nil, false
+}
+
+// pkeyString returns the description of the primary key of the row,
+// e.g., "(a, b)=(1, 'x')".
+func (t *Table) pkeyString(r *Row) string {
+	key, _ := uniqueKey(r, t.primaryKey)
+	return "(" + strings.Join(t.primaryKey, ", ") + ")=(" + key + ")"
+}
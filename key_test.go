@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAppendKey(t *testing.T) {
+	// the values of each group are in ascending order
+	groups := [][]any{
+		{math.MinInt64, -1000, -1, 0, 1, 255, 256, math.MaxInt64},
+		{math.Inf(-1), -1e10, -1.5, -0.0, 0.5, 1.0, 1e10, math.Inf(1)},
+		{false, true},
+		{"", "\x00", "\x00\x00", "\x00a", "a", "a\x00", "a\x00b", "ab", "b"},
+		{dec(t, "-100"), dec(t, "-10.5"), dec(t, "-10"), dec(t, "-1.5"),
+			dec(t, "-1"), dec(t, "-0.01"), dec(t, "0"), dec(t, "0.001"),
+			dec(t, "0.01"), dec(t, "0.5"), dec(t, "1"), dec(t, "1.05"),
+			dec(t, "1.5"), dec(t, "10"), dec(t, "100")},
+	}
+	for i, vals := range groups {
+		for j := 1; j < len(vals); j++ {
+			a := string(appendKey(nil, vals[j-1]))
+			b := string(appendKey(nil, vals[j]))
+			if a >= b {
+				t.Fatalf("case %d failed: key of %#v is not less than %#v",
+					i, vals[j-1], vals[j])
+			}
+		}
+		t.Logf("case %d succeed", i)
+	}
+
+	// a composite key orders by the first column, then the second
+	if string(appendKey(appendKey(nil, "a"), "z")) >=
+		string(appendKey(appendKey(nil, "ab"), "a")) {
+		t.Fatal("key of (a, z) is not less than (ab, a)")
+	}
+	if string(appendKey(nil, 0.0)) != string(appendKey(nil, math.Copysign(0, -1))) {
+		t.Fatal("key of -0 differs from 0")
+	}
+	if string(appendKey(nil, dec(t, "1.5"))) != string(appendKey(nil, dec(t, "1.500"))) {
+		t.Fatal("key of 1.500 differs from 1.5")
+	}
+}
+
+func TestCompositePrimaryKey(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table members (user_id integer, group_id integer, " +
+			"role string, constraint members_pk primary key (user_id, group_id))",
+		"insert into members values (2, 1, 'a')",
+		"insert into members values (1, 3, 'b')",
+		"insert into members values (1, 1, 'c')",
+		"insert into members values (-1, 5, 'd')",
+		"insert into members values (1, 2, 'e')",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		expect []any
+	}{
+		{"Ordered scan", "select role from members", "",
+			[]any{"d", "c", "e", "b", "a"}},
+		{"Prefix", "select role from members where user_id = 1", "",
+			[]any{"c", "e", "b"}},
+		{"Range", "select role from members where user_id = 1 and group_id > 1",
+			"", []any{"e", "b"}},
+		{"Flipped range",
+			"select role from members where 3 > group_id and 1 = user_id", "",
+			[]any{"c", "e"}},
+		{"Not a prefix", "select role from members where group_id = 1", "",
+			[]any{"c", "a"}},
+		{"Other conditions", "select role from members " +
+			"where user_id = 1 and group_id <= 2 and role <> 'c'", "",
+			[]any{"e"}},
+		{"Duplicate", "insert into members values (1, 2, 'x')",
+			codeUniqueViolation, nil},
+		{"Null key", "insert into members (user_id) values (3)",
+			codeNotNullViolation, nil},
+		{"Update key", "update members set group_id = 10 " +
+			"where user_id = 1 and group_id = 1", "", nil},
+		{"Updated", "select role from members where user_id = 1 and " +
+			"group_id >= 3", "", []any{"b", "c"}},
+		{"Update in place", "update members set user_id = 1 " +
+			"where user_id = 1 and group_id = 10", "", nil},
+		{"Update collides", "update members set user_id = 2, group_id = 1 " +
+			"where role = 'c'", codeUniqueViolation, nil},
+		{"Delete prefix", "delete from members where user_id = 1", "", nil},
+		{"Deleted", "select role from members", "", []any{"d", "a"}},
+		{"Missing key column", "create table x (a integer, primary key (b))",
+			codeUndefinedColumn, nil},
+		{"Repeated key column",
+			"create table x (a integer, primary key (a, a))",
+			codeDuplicateColumn, nil},
+		{"Multiple primary keys",
+			"create table x (a integer primary key, primary key (a))",
+			codeSyntaxError, nil},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		if tt.expect != nil {
+			var got []any
+			for _, row := range r.rows {
+				got = append(got, row.fields["role"])
+			}
+			if len(got) != len(tt.expect) {
+				t.Fatalf("case %d (%s) failed: got %v, expect %v",
+					i, tt.name, got, tt.expect)
+			}
+			for j := range got {
+				if got[j] != tt.expect[j] {
+					t.Fatalf("case %d (%s) failed: got %v, expect %v",
+						i, tt.name, got, tt.expect)
+				}
+			}
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	info, err := db.DescribeTable("members")
+	if err != nil || info.PrimaryKeyName != "members_pk" ||
+		len(info.PrimaryKey) != 2 || !info.NotNull[1] {
+		t.Fatalf("unexpected table info %+v (%v)", info, err)
+	}
+}
@@ -1,90 +1,236 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
 )
 
-// TODO (charleszheng44): dynamic column width
-func printRows(rs []*Row, cols []string) {
-	for _, col := range cols {
-		fmt.Printf("|%s\t", col)
-	}
-	fmt.Println()
-	for _, r := range rs {
-		for _, col := range cols {
-			fmt.Printf("|%v\t", r.fields[col])
+// passwordEnv is the environment variable of the superuser password.
+const passwordEnv = "SIMPLE_DB_PASSWORD"
+
+// interrupter cancels the running statement on Ctrl-C, the interrupt is
+// ignored if no statement is running.
+type interrupter struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
+
+func (it *interrupter) watch(sigCh <-chan os.Signal) {
+	for range sigCh {
+		it.Lock()
+		if it.cancel != nil {
+			it.cancel()
 		}
-		fmt.Println()
+		it.Unlock()
 	}
 }
 
-func main() {
-	var (
-		isStr  bool
-		stsTks [][]rune
-		curSts []rune
-	)
-	db := NewDatabase()
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("@simple-db=> ")
-	for {
-		r, _, err := reader.ReadRune()
+// run executes the statement with a context that will be canceled by
+// the interrupt.
+func (it *interrupter) run(ctx context.Context,
+	db *Database, sts Statement) *Result {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	it.Lock()
+	it.cancel = cancel
+	it.Unlock()
+	defer func() {
+		it.Lock()
+		it.cancel = nil
+		it.Unlock()
+	}()
+	return db.Interpret(ctx, sts)
+}
+
+// serve serves the database on the given addresses until any of the
+// servers fails. The password of the superuser must be given by the
+// environment variable.
+func serve(db *Database, pgAddr, httpAddr string) {
+	password := os.Getenv(passwordEnv)
+	if password == "" {
+		log.Fatalf("[ERROR] the password of the superuser %s "+
+			"must be set by %s", defaultSuperuser, passwordEnv)
+	}
+	if err := db.SetPassword(defaultSuperuser, password); err != nil {
+		log.Fatalf("[ERROR] failed to set the password: %v", err)
+	}
+
+	errCh := make(chan error, 2)
+	if pgAddr != "" {
+		ln, err := Listen(pgAddr)
 		if err != nil {
-			fmt.Printf("[ERROR] failed to read from stdin: %v\n", err)
+			log.Fatalf("[ERROR] failed to listen on %s: %v", pgAddr, err)
 		}
-		if r == '\'' {
-			isStr = !isStr
+		log.Printf("serving the PostgreSQL protocol on %s", ln.Addr())
+		go func() { errCh <- NewPGServer(db).Serve(ln) }()
+	}
+	if httpAddr != "" {
+		log.Printf("serving the HTTP API on %s", httpAddr)
+		go func() {
+			errCh <- http.ListenAndServe(httpAddr,
+				NewHTTPServer(db).Handler())
+		}()
+	}
+	log.Fatal(<-errCh)
+}
+
+// isTerminal checks if the file is a terminal, e.g., stdin is not if the
+// input is piped.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// runREPL runs the statements given by -c, the file given by -f, or
+// stdin, which is interactive if it is a terminal. The exit code is 1 if
+// any statement fails in the non-interactive mode.
+func runREPL(db *Database, command, file, onError string) int {
+	if onError != onErrorStop && onError != onErrorContinue {
+		fmt.Fprintf(os.Stderr, "[ERROR] invalid -on-error %s, "+
+			"expect %s or %s\n", onError, onErrorStop, onErrorContinue)
+		return 2
+	}
+
+	ctx := WithSession(context.Background(), NewSession(defaultSuperuser))
+	it := &interrupter{}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go it.watch(sigCh)
+	r := newREPL(ctx, db, it, os.Stdout, os.Stderr)
+	r.stopOnError = onError == onErrorStop
+	defer r.close()
+
+	var err error
+	switch {
+	case command != "":
+		r.feed(command)
+		r.flush()
+	case file != "" && file != "-":
+		var f *os.File
+		if f, err = os.Open(file); err == nil {
+			err = r.run(newScanReader(f))
+			f.Close()
+			r.flush()
+		}
+	case file == "-" || !isTerminal(os.Stdin):
+		err = r.run(newScanReader(os.Stdin))
+		r.flush()
+	default:
+		le := newLineEditor(db)
+		err = r.run(le)
+		le.Close()
+		if err == nil && !r.quit {
+			// end the prompt line on Ctrl-D
+			fmt.Println()
 		}
+		// the failed statements do not matter in the interactive mode
+		r.failed = false
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] failed to read the input: %v\n", err)
+		return 1
+	}
+	if r.failed {
+		return 1
+	}
+	return 0
+}
+
+// runFmt formats the SQL files, or stdin if no file is given, and prints
+// the results or rewrites the files with -w. The syntax errors of all the
+// statements are reported, and the exit code is 1 if there is any.
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	lower := fs.Bool("lower", false, "print the keywords in lower case")
+	indent := fs.Int("indent", 4, "the indentation of the continuation lines")
+	lineWidth := fs.Int("width", 80, "wrap the lines wider than the width, "+
+		"0 means no wrapping")
+	write := fs.Bool("w", false, "write the results to the files "+
+		"instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	opts := &formatOptions{
+		upper:  !*lower,
+		indent: *indent,
+		width:  *lineWidth,
+	}
+	if fs.NArg() == 0 && *write {
+		fmt.Fprintln(stderr, "[ERROR] -w requires the files to format")
+		return 2
+	}
 
-		if r == ';' && !isStr {
-			stsTks = append(stsTks, curSts)
-			curSts = []rune{}
+	code := 0
+	format := func(name string, src []byte) {
+		out, errs := formatSQL(string(src), opts)
+		for _, err := range errs {
+			pos := errorPosition(err)
+			fmt.Fprintf(stderr, "%s:%d:%d: %v (SQLSTATE %s)\n",
+				name, pos.Line, pos.Column, err, sqlState(err))
+		}
+		if len(errs) != 0 {
+			code = 1
+			return
+		}
+		if !*write {
+			fmt.Fprint(stdout, out)
+			return
+		}
+		if err := os.WriteFile(name, []byte(out), 0644); err != nil {
+			fmt.Fprintf(stderr, "[ERROR] %v\n", err)
+			code = 1
+		}
+	}
+	if fs.NArg() == 0 {
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "[ERROR] failed to read stdin: %v\n", err)
+			return 1
+		}
+		format("<stdin>", src)
+		return code
+	}
+	for _, name := range fs.Args() {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "[ERROR] %v\n", err)
+			code = 1
 			continue
 		}
+		format(name, src)
+	}
+	return code
+}
 
-		if r == '\n' {
-			for _, stk := range stsTks {
-				tks, err := Tokenize(stk)
-				if err != nil {
-					fmt.Printf("[ERROR] failed to tokenize the input string: %v\n", err)
-					continue
-				}
-
-				sts, err := parse(tks)
-				if err != nil {
-					fmt.Printf("[ERROR] failed to parse the input tokens: %v\n", err)
-					continue
-				}
-
-				result := db.Interpret(sts)
-				if result.err != nil {
-					fmt.Printf("[ERROR] failed to interpret "+
-						"the statement: %v\n", err)
-					continue
-				}
-				if len(result.message) != 0 {
-					fmt.Println(result.message)
-				}
-				if len(result.rows) != 0 {
-					printRows(result.rows, result.cols)
-				}
-			}
-			stsTks = nil
-
-			if len(curSts) == 0 {
-				fmt.Printf("@simple-db=> ")
-				continue
-			}
-
-			if isStr {
-				fmt.Printf("@simple-db'> ")
-				continue
-			}
-
-			fmt.Printf("@simple-db-> ")
-		}
-		curSts = append(curSts, r)
+func main() {
+	// the fmt subcommand has its own flags
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(runFmt(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	pgAddr := flag.String("pg", "", "serve the PostgreSQL wire protocol "+
+		"on the TCP address or the Unix socket path")
+	httpAddr := flag.String("http", "", "serve the HTTP/JSON API "+
+		"on the TCP address")
+	command := flag.String("c", "", "execute the statements and exit")
+	file := flag.String("f", "", "execute the statements from the file, "+
+		"or stdin if it is -, and exit")
+	onError := flag.String("on-error", onErrorContinue, "whether to "+
+		"\"stop\" or \"continue\" after a statement fails")
+	flag.Parse()
+
+	db := NewDatabase()
+	if *pgAddr != "" || *httpAddr != "" {
+		serve(db, *pgAddr, *httpAddr)
+		return
 	}
+	os.Exit(runREPL(db, *command, *file, *onError))
 }
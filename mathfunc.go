@@ -0,0 +1,196 @@
+package main
+
+import (
+	"math"
+	"math/big"
+)
+
+// abs returns the absolute value of the number.
+func abs(env *evalEnv, args []any) (any, error) {
+	switch x := args[0].(type) {
+	case int:
+		if x < 0 {
+			return negate(x)
+		}
+	case float64:
+		return math.Abs(x), nil
+	case Decimal:
+		if x.coef.Sign() < 0 {
+			return x.Neg(), nil
+		}
+	}
+	return args[0], nil
+}
+
+// maxRoundScale bounds the number of the digits round rounds to.
+const maxRoundScale = 1000
+
+// round rounds the number to the integer, or to the number of the
+// fractional digits given by the second argument, which rounds to the
+// tens, hundreds, etc. if negative. The floats are rounded half to even,
+// the decimals half away from zero, as in PostgreSQL. The integers rounded
+// to the digits are the decimals.
+func round(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || (len(args) == 2 && args[1] == nil) {
+		return nil, nil
+	}
+	if len(args) == 1 {
+		switch x := args[0].(type) {
+		case float64:
+			return math.RoundToEven(x), nil
+		case Decimal:
+			return roundDecimal(x, 0), nil
+		}
+		return args[0], nil
+	}
+	n := args[1].(int)
+	if n < -maxRoundScale || n > maxRoundScale {
+		return nil, newError(codeNumericOutOfRange,
+			"the digits of round must be between %d and %d",
+			-maxRoundScale, maxRoundScale)
+	}
+	d, ok := toDecimal(args[0])
+	if !ok {
+		// the infinities and NaN are kept
+		return args[0], nil
+	}
+	d = roundDecimal(d, n)
+	if _, ok := args[0].(float64); ok {
+		return d.Float64(), nil
+	}
+	return d, nil
+}
+
+// roundDecimal rounds the decimal half away from zero to n fractional
+// digits, or to the multiple of 10^-n if n is negative.
+func roundDecimal(d Decimal, n int) Decimal {
+	if n >= 0 {
+		return d.rescale(n)
+	}
+	unit := pow10(-n)
+	q := roundQuo(d.coef, new(big.Int).Mul(pow10(d.scale), unit))
+	return Decimal{coef: q.Mul(q, unit)}
+}
+
+// floor returns the largest integer not greater than the number.
+func floor(env *evalEnv, args []any) (any, error) {
+	switch x := args[0].(type) {
+	case float64:
+		return math.Floor(x), nil
+	case Decimal:
+		return floorDecimal(x), nil
+	}
+	return args[0], nil
+}
+
+// ceil returns the smallest integer not less than the number.
+func ceil(env *evalEnv, args []any) (any, error) {
+	switch x := args[0].(type) {
+	case float64:
+		return math.Ceil(x), nil
+	case Decimal:
+		return floorDecimal(x.Neg()).Neg(), nil
+	}
+	return args[0], nil
+}
+
+// floorDecimal returns the largest integer not greater than the decimal
+// as the decimal of the scale 0.
+func floorDecimal(d Decimal) Decimal {
+	// the Euclidean division rounds down by the positive divisor
+	return Decimal{coef: new(big.Int).Div(d.coef, pow10(d.scale))}
+}
+
+// power returns the first argument raised to the power of the second as
+// the float.
+func power(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	x, _ := toFloat(args[0])
+	y, _ := toFloat(args[1])
+	switch {
+	case x == 0 && y < 0:
+		return nil, newError(codeInvalidArgumentForPower,
+			"zero raised to a negative power is undefined")
+	case x < 0 && y != math.Trunc(y):
+		return nil, newError(codeInvalidArgumentForPower,
+			"a negative number raised to a non-integer power yields a "+
+				"complex result")
+	}
+	z := math.Pow(x, y)
+	if math.IsInf(z, 0) && !math.IsInf(x, 0) && !math.IsInf(y, 0) {
+		return nil, newError(codeNumericOutOfRange,
+			"value out of range: overflow")
+	}
+	return z, nil
+}
+
+// sqrt returns the square root of the number as the float.
+func sqrt(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	x, _ := toFloat(args[0])
+	if x < 0 {
+		return nil, newError(codeInvalidArgumentForPower,
+			"cannot take square root of a negative number")
+	}
+	return math.Sqrt(x), nil
+}
+
+// modulo returns the remainder of the division as the operator %.
+func modulo(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	return arithmetic(mod, args[0], args[1])
+}
+
+// extremum returns greatest, for the sign 1, or least, for -1, which
+// returns the largest or the smallest of the arguments respectively, the
+// NULLs aside. The numbers are widened to the widest type of them, e.g.,
+// greatest(1, 0.5) is the float 1.
+func extremum(sign int) func(env *evalEnv, args []any) (any, error) {
+	return func(env *evalEnv, args []any) (any, error) {
+		var (
+			result           any
+			floats, decimals bool
+		)
+		for _, arg := range args {
+			switch arg.(type) {
+			case nil:
+				continue
+			case float64:
+				floats = true
+			case Decimal:
+				decimals = true
+			}
+			if result == nil {
+				result = arg
+				continue
+			}
+			c, err := compareValues(arg, result)
+			if err != nil {
+				return nil, err
+			}
+			if c*sign > 0 {
+				result = arg
+			}
+		}
+		switch x := result.(type) {
+		case int:
+			if floats {
+				return float64(x), nil
+			}
+			if decimals {
+				return decimalFromInt(x), nil
+			}
+		case Decimal:
+			if floats {
+				return x.Float64(), nil
+			}
+		}
+		return result, nil
+	}
+}
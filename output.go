@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// the output formats of the query results, see \pset format
+const (
+	formatAligned  = "aligned"
+	formatCSV      = "csv"
+	formatTSV      = "tsv"
+	formatJSON     = "json"
+	formatNDJSON   = "ndjson"
+	formatMarkdown = "markdown"
+)
+
+var outputFormats = []string{formatAligned, formatCSV, formatTSV,
+	formatJSON, formatNDJSON, formatMarkdown}
+
+func isOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// printOptions controls how the query results are printed.
+type printOptions struct {
+	format string
+	// expanded prints each row vertically, i.e., a line per column, it
+	// only applies to the aligned format
+	expanded bool
+	// null is printed for the NULL values, JSON uses null instead
+	null string
+}
+
+func defaultPrintOptions() *printOptions {
+	return &printOptions{
+		format: formatAligned,
+	}
+}
+
+// printResult prints the rows, i.e., the values in the order of the
+// columns, in the format.
+func printResult(w io.Writer, opts *printOptions,
+	cols []string, rows [][]any) {
+	switch opts.format {
+	case formatCSV:
+		printCSV(w, opts, cols, rows)
+	case formatTSV:
+		printTSV(w, opts, cols, rows)
+	case formatJSON:
+		printJSON(w, cols, rows)
+	case formatNDJSON:
+		printNDJSON(w, cols, rows)
+	case formatMarkdown:
+		printMarkdown(w, opts, cols, rows)
+	default:
+		if opts.expanded {
+			printExpanded(w, opts, cols, rows)
+			return
+		}
+		printAligned(w, opts, cols, rows)
+	}
+}
+
+// formatValue formats the value for the text formats.
+func formatValue(opts *printOptions, v any) string {
+	if v == nil {
+		return opts.null
+	}
+	return fmt.Sprint(v)
+}
+
+// isNumeric tells if the value is right aligned.
+func isNumeric(v any) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, float32, float64, Decimal:
+		return true
+	}
+	return false
+}
+
+// width returns the display width of the string in the terminal, the
+// East Asian wide characters take two columns.
+func width(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// lines splits the cell into its lines, which are printed one below the
+// other in the aligned format.
+func lines(s string) []string {
+	return strings.Split(s, "\n")
+}
+
+// pad pads the string to the width, on the left if right is true.
+func pad(s string, n int, right bool) string {
+	fill := strings.Repeat(" ", n-width(s))
+	if right {
+		return fill + s
+	}
+	return s + fill
+}
+
+func rowCount(n int) string {
+	if n == 1 {
+		return "(1 row)"
+	}
+	return fmt.Sprintf("(%d rows)", n)
+}
+
+// printAligned prints the rows in a box sized to the content, e.g.,
+//
+//	+----+-------+
+//	| id | name  |
+//	+----+-------+
+//	|  1 | a    +|
+//	|    | b     |
+//	+----+-------+
+//	(1 row)
+//
+// where the multi-line values are printed on the lines below, each but
+// the last of which is marked by a + as psql does.
+func printAligned(w io.Writer, opts *printOptions,
+	cols []string, rows [][]any) {
+	widths := make([]int, len(cols))
+	header := make([][]string, len(cols))
+	for i, col := range cols {
+		header[i] = lines(col)
+		for _, l := range header[i] {
+			if n := width(l); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	cells := make([][][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([][]string, len(cols))
+		for j, v := range row {
+			cells[i][j] = lines(formatValue(opts, v))
+			for _, l := range cells[i][j] {
+				if n := width(l); n > widths[j] {
+					widths[j] = n
+				}
+			}
+		}
+	}
+
+	var sep strings.Builder
+	sep.WriteString("+")
+	for _, n := range widths {
+		sep.WriteString(strings.Repeat("-", n+2) + "+")
+	}
+	line := func(vals [][]string, right func(int) bool) {
+		height := 1
+		for _, v := range vals {
+			if len(v) > height {
+				height = len(v)
+			}
+		}
+		for k := 0; k < height; k++ {
+			var b strings.Builder
+			b.WriteString("|")
+			for j, v := range vals {
+				s, marker := "", " "
+				if k < len(v) {
+					s = v[k]
+				}
+				if k < len(v)-1 {
+					marker = "+"
+				}
+				b.WriteString(" " + pad(s, widths[j], right(j)) + marker + "|")
+			}
+			fmt.Fprintln(w, b.String())
+		}
+	}
+
+	fmt.Fprintln(w, sep.String())
+	line(header, func(int) bool { return false })
+	fmt.Fprintln(w, sep.String())
+	for i, row := range rows {
+		line(cells[i], func(j int) bool { return isNumeric(row[j]) })
+	}
+	if len(rows) != 0 {
+		fmt.Fprintln(w, sep.String())
+	}
+	fmt.Fprintln(w, rowCount(len(rows)))
+}
+
+// printExpanded prints each row as a record, e.g.,
+//
+//	-[ RECORD 1 ]
+//	id   | 1
+//	name | a+
+//	     | b
+//
+// where the lines of the multi-line values but the last are marked by a +.
+func printExpanded(w io.Writer, opts *printOptions,
+	cols []string, rows [][]any) {
+	n := 0
+	for _, col := range cols {
+		if width(col) > n {
+			n = width(col)
+		}
+	}
+	for i, row := range rows {
+		fmt.Fprintf(w, "-[ RECORD %d ]\n", i+1)
+		for j, col := range cols {
+			ls := lines(formatValue(opts, row[j]))
+			for k, l := range ls {
+				name := ""
+				if k == 0 {
+					name = col
+				}
+				if k < len(ls)-1 {
+					l += "+"
+				}
+				fmt.Fprintf(w, "%s | %s\n", pad(name, n, false), l)
+			}
+		}
+	}
+	fmt.Fprintln(w, rowCount(len(rows)))
+}
+
+func printCSV(w io.Writer, opts *printOptions,
+	cols []string, rows [][]any) {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(cols)
+	for _, row := range rows {
+		vals := make([]string, len(row))
+		for i, v := range row {
+			vals[i] = formatValue(opts, v)
+		}
+		_ = cw.Write(vals)
+	}
+	cw.Flush()
+}
+
+// tsvEscaper escapes the characters that break the lines and the fields
+// of TSV.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`,
+	"\n", `\n`, "\r", `\r`)
+
+func printTSV(w io.Writer, opts *printOptions, cols []string, rows [][]any) {
+	fmt.Fprintln(w, strings.Join(cols, "\t"))
+	for _, row := range rows {
+		vals := make([]string, len(row))
+		for i, v := range row {
+			// the NULL marker, e.g., \N, is not escaped
+			vals[i] = opts.null
+			if v != nil {
+				vals[i] = tsvEscaper.Replace(formatValue(opts, v))
+			}
+		}
+		fmt.Fprintln(w, strings.Join(vals, "\t"))
+	}
+}
+
+// jsonObject encodes the row as a JSON object whose keys are in the
+// order of the columns.
+func jsonObject(cols []string, row []any) string {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, col := range cols {
+		if i != 0 {
+			b.WriteString(",")
+		}
+		k, _ := json.Marshal(col)
+		v, err := json.Marshal(row[i])
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprint(row[i]))
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(v)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func printJSON(w io.Writer, cols []string, rows [][]any) {
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "[]")
+		return
+	}
+	fmt.Fprintln(w, "[")
+	for i, row := range rows {
+		sep := ","
+		if i == len(rows)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(w, "  %s%s\n", jsonObject(cols, row), sep)
+	}
+	fmt.Fprintln(w, "]")
+}
+
+func printNDJSON(w io.Writer, cols []string, rows [][]any) {
+	for _, row := range rows {
+		fmt.Fprintln(w, jsonObject(cols, row))
+	}
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", "<br>")
+
+func printMarkdown(w io.Writer, opts *printOptions,
+	cols []string, rows [][]any) {
+	line := func(vals []string) {
+		fmt.Fprintf(w, "| %s |\n", strings.Join(vals, " | "))
+	}
+	vals := make([]string, len(cols))
+	for i, col := range cols {
+		vals[i] = markdownEscaper.Replace(col)
+	}
+	line(vals)
+	for i := range vals {
+		vals[i] = "---"
+	}
+	line(vals)
+	for _, row := range rows {
+		for i, v := range row {
+			vals[i] = markdownEscaper.Replace(formatValue(opts, v))
+		}
+		line(vals)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintResult(t *testing.T) {
+	cols := []string{"id", "name"}
+	rows := [][]any{{1, "alice"}, {22, nil}}
+	tts := []struct {
+		name     string
+		opts     *printOptions
+		expected string
+	}{
+		{"Aligned", &printOptions{format: formatAligned, null: "NULL"},
+			"+----+-------+\n" +
+				"| id | name  |\n" +
+				"+----+-------+\n" +
+				"|  1 | alice |\n" +
+				"| 22 | NULL  |\n" +
+				"+----+-------+\n" +
+				"(2 rows)\n"},
+		{"Expanded", &printOptions{format: formatAligned, expanded: true},
+			"-[ RECORD 1 ]\nid   | 1\nname | alice\n" +
+				"-[ RECORD 2 ]\nid   | 22\nname | \n(2 rows)\n"},
+		{"CSV", &printOptions{format: formatCSV},
+			"id,name\n1,alice\n22,\n"},
+		{"TSV", &printOptions{format: formatTSV, null: `\N`},
+			"id\tname\n1\talice\n22\t\\N\n"},
+		{"JSON", &printOptions{format: formatJSON},
+			"[\n  {\"id\":1,\"name\":\"alice\"},\n" +
+				"  {\"id\":22,\"name\":null}\n]\n"},
+		{"NDJSON", &printOptions{format: formatNDJSON},
+			"{\"id\":1,\"name\":\"alice\"}\n{\"id\":22,\"name\":null}\n"},
+		{"Markdown", &printOptions{format: formatMarkdown},
+			"| id | name |\n| --- | --- |\n| 1 | alice |\n| 22 |  |\n"},
+	}
+	for i, tt := range tts {
+		var buf bytes.Buffer
+		printResult(&buf, tt.opts, cols, rows)
+		if buf.String() != tt.expected {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.name, buf.String(), tt.expected)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	var buf bytes.Buffer
+	printResult(&buf, defaultPrintOptions(), cols, nil)
+	expected := "+----+------+\n| id | name |\n+----+------+\n(0 rows)\n"
+	if buf.String() != expected {
+		t.Fatalf("got %q, expect %q", buf.String(), expected)
+	}
+}
+
+func TestPrintAlignedCells(t *testing.T) {
+	tts := []struct {
+		name     string
+		opts     *printOptions
+		rows     [][]any
+		expected string
+	}{
+		{"Multi-line", defaultPrintOptions(),
+			[][]any{{1, "line1\nline2\nl3"}},
+			"+----+-------+\n" +
+				"| id | name  |\n" +
+				"+----+-------+\n" +
+				"|  1 | line1+|\n" +
+				"|    | line2+|\n" +
+				"|    | l3    |\n" +
+				"+----+-------+\n" +
+				"(1 row)\n"},
+		{"Wide", defaultPrintOptions(),
+			[][]any{{1, "日本語"}, {2, "ab"}},
+			"+----+--------+\n" +
+				"| id | name   |\n" +
+				"+----+--------+\n" +
+				"|  1 | 日本語 |\n" +
+				"|  2 | ab     |\n" +
+				"+----+--------+\n" +
+				"(2 rows)\n"},
+		{"Expanded multi-line",
+			&printOptions{format: formatAligned, expanded: true},
+			[][]any{{1, "a\nb"}},
+			"-[ RECORD 1 ]\nid   | 1\nname | a+\n     | b\n(1 row)\n"},
+	}
+	for i, tt := range tts {
+		var buf bytes.Buffer
+		printResult(&buf, tt.opts, []string{"id", "name"}, tt.rows)
+		if buf.String() != tt.expected {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.name, buf.String(), tt.expected)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import "strconv"
+
+// Param is the placeholder of a value bound by the client, e.g., $1.
+type Param int
+
+func (p Param) String() string {
+	return "$" + strconv.Itoa(int(p))
+}
+
+// paramType tells how the type of a parameter is inferred: from the type
+// of the column, mapped by as if not nil, e.g., to the array of it, or as
+// typ if there is no column.
+type paramType struct {
+	column string
+	as     func(*Type) *Type
+	typ    *Type
+}
+
+// temporalOperand maps the type of the date and time column to interval,
+// which is added to or subtracted from it, and keeps the others.
+func temporalOperand(typ *Type) *Type {
+	if typ.temporal() {
+		return intervalType
+	}
+	return typ
+}
+
+// ParamTypes infers the types of the parameters of the statement from
+// the columns they are assigned to or compared with, or the arrays of the
+// columns for "column op ANY ($1)", or the elements of the arrays for
+// "$1 op ANY (column)", or the integers for the subscripts, or the intervals added to or
+// subtracted from the date and time columns, or the keys and the paths of
+// the JSON operators, unless they are cast explicitly, e.g., $1::integer. The parameters whose types cannot be
+// inferred are taken as strings.
+func (db *Database) ParamTypes(sts Statement) ([]*Type, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	var (
+		table string
+		// params maps the parameters to how their types are inferred
+		params = make(map[Param]paramType)
+		// casts are the types the parameters are cast to
+		casts = make(map[Param]*Type)
+		// exprs are the expressions the parameters may appear in
+		exprs []Expr
+	)
+	switch s := sts.(type) {
+	case *InsertStatement:
+		table, exprs = s.table.name, s.values
+		columns := s.columns
+		if t, exist := db.tables[table]; exist && columns == nil {
+			columns = t.columns
+		}
+		for i, v := range s.values {
+			if p, ok := v.(Param); ok && i < len(columns) {
+				params[p] = paramType{column: columns[i]}
+			}
+		}
+	case *SelectStatement:
+		table, exprs = s.table.name, []Expr{s.where}
+		for _, item := range s.items {
+			exprs = append(exprs, item.expr)
+		}
+	case *DeleteStatement:
+		table, exprs = s.table.name, []Expr{s.where}
+	case *UpdateStatement:
+		table, exprs = s.table.name, []Expr{s.where}
+		for _, a := range s.assignments {
+			exprs = append(exprs, a.value)
+			if p, ok := a.value.(Param); ok {
+				params[p] = paramType{column: a.column}
+			}
+		}
+	default:
+		return nil, nil
+	}
+	// the parameters compared with the columns
+	for _, e := range exprs {
+		walkExpr(e, func(e Expr) {
+			if c, ok := e.(*CastExpr); ok {
+				if p, ok := c.expr.(Param); ok {
+					casts[p] = c.typ
+				}
+				return
+			}
+			if q, ok := e.(*QuantifiedExpr); ok {
+				if c, ok := q.left.(*ColumnRef); ok {
+					if p, ok := q.array.(Param); ok {
+						params[p] = paramType{column: c.name, as: newArrayType}
+					}
+				}
+				// the elements of the array of the column
+				if c, ok := q.array.(*ColumnRef); ok {
+					if p, ok := q.left.(Param); ok {
+						params[p] = paramType{column: c.name, as: (*Type).base}
+					}
+				}
+				return
+			}
+			if sub, ok := e.(*Subscript); ok {
+				if p, ok := sub.index.(Param); ok {
+					params[p] = paramType{typ: integerType}
+				}
+				return
+			}
+			b, ok := e.(*BinaryExpr)
+			if !ok {
+				return
+			}
+			switch b.op {
+			case jsonGet, jsonGetText:
+				// the key of the object, which is taken as text
+				if p, ok := b.right.(Param); ok {
+					params[p] = paramType{typ: stringType}
+				}
+				return
+			case jsonPath, jsonPathText:
+				if p, ok := b.right.(Param); ok {
+					params[p] = paramType{typ: textArrayType}
+				}
+				return
+			}
+			if c, ok := b.left.(*ColumnRef); ok {
+				if p, ok := b.right.(Param); ok {
+					params[p] = paramType{column: c.name}
+					if b.op == add || b.op == sub {
+						params[p] = paramType{column: c.name, as: temporalOperand}
+					}
+				}
+			}
+			if c, ok := b.right.(*ColumnRef); ok {
+				if p, ok := b.left.(Param); ok {
+					params[p] = paramType{column: c.name}
+					if b.op == add {
+						params[p] = paramType{column: c.name, as: temporalOperand}
+					}
+				}
+			}
+		})
+	}
+
+	types := []*Type{}
+	for p, pt := range params {
+		for len(types) < int(p) {
+			types = append(types, stringType)
+		}
+		if pt.column == "" {
+			types[p-1] = pt.typ
+			continue
+		}
+		t, exist := db.tables[table]
+		if !exist {
+			return nil, newError(codeUndefinedTable,
+				"relation %s does not exist", table)
+		}
+		typ, exist := t.schema[pt.column]
+		if !exist {
+			return nil, newError(codeUndefinedColumn,
+				"column(%s) not exist", pt.column)
+		}
+		if pt.as != nil {
+			typ = pt.as(typ)
+		}
+		types[p-1] = typ
+	}
+	// the parameters that are not compared with any column
+	for _, e := range exprs {
+		walkExpr(e, func(e Expr) {
+			if p, ok := e.(Param); ok {
+				for len(types) < int(p) {
+					types = append(types, stringType)
+				}
+			}
+		})
+	}
+	for p, typ := range casts {
+		types[p-1] = typ
+	}
+	return types, nil
+}
+
+// bindParams returns a copy of the statement with the parameters replaced
+// by the given values.
+func bindParams(sts Statement, vals []any) (Statement, error) {
+	bindExpr := func(e Expr) (Expr, error) {
+		return rewriteExpr(e, func(e Expr) (Expr, error) {
+			p, ok := e.(Param)
+			if !ok {
+				return e, nil
+			}
+			if int(p) > len(vals) {
+				return nil, newError(codeUndefinedParameter,
+					"there is no parameter %s", p)
+			}
+			return &Literal{val: vals[p-1]}, nil
+		})
+	}
+
+	switch s := sts.(type) {
+	case *InsertStatement:
+		bound := *s
+		bound.values = make([]Expr, len(s.values))
+		for i, v := range s.values {
+			bv, err := bindExpr(v)
+			if err != nil {
+				return nil, err
+			}
+			bound.values[i] = bv
+		}
+		return &bound, nil
+	case *SelectStatement:
+		where, err := bindExpr(s.where)
+		if err != nil {
+			return nil, err
+		}
+		bound := *s
+		bound.where = where
+		if s.items != nil {
+			bound.items = make([]*SelectItem, len(s.items))
+			for i, item := range s.items {
+				e, err := bindExpr(item.expr)
+				if err != nil {
+					return nil, err
+				}
+				bound.items[i] = &SelectItem{expr: e, alias: item.alias}
+			}
+		}
+		return &bound, nil
+	case *DeleteStatement:
+		where, err := bindExpr(s.where)
+		if err != nil {
+			return nil, err
+		}
+		bound := *s
+		bound.where = where
+		return &bound, nil
+	case *UpdateStatement:
+		where, err := bindExpr(s.where)
+		if err != nil {
+			return nil, err
+		}
+		bound := *s
+		bound.where = where
+		bound.assignments = make([]*Assignment, len(s.assignments))
+		for i, a := range s.assignments {
+			v, err := bindExpr(a.value)
+			if err != nil {
+				return nil, err
+			}
+			bound.assignments[i] = &Assignment{column: a.column, value: v}
+		}
+		return &bound, nil
+	default:
+		return sts, nil
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParamTypes(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table p (id integer primary key, ts timestamp, d date, " +
+			"i interval, n integer, j jsonb, a integer[])",
+		"insert into p values (1, '2024-01-31 10:00:00', '2024-01-31', " +
+			"'1 hour', 5, '{\"a\": {\"b\": [1, 2]}}', '{3,4}')",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	tts := []struct {
+		name   string
+		sql    string
+		params []string
+		types  []*Type
+		rows   int
+	}{
+		{"Column", "select * from p where id = $1", []string{"1"},
+			[]*Type{integerType}, 1},
+		{"Timestamp plus", "select ts + $1 from p where ts + $1 > '2024-02-01'",
+			[]string{"1 day"}, []*Type{intervalType}, 1},
+		{"Timestamp minus", "select * from p where ts - $1 = '2024-01-31'",
+			[]string{"10 hours"}, []*Type{intervalType}, 1},
+		{"Interval plus", "select * from p where $1 + d = '2024-02-01'",
+			[]string{"1 day"}, []*Type{intervalType}, 1},
+		{"Interval column", "select * from p where i - $1 = '30 minutes'",
+			[]string{"30 minutes"}, []*Type{intervalType}, 1},
+		{"Integer plus", "select * from p where n + $1 = 6",
+			[]string{"1"}, []*Type{integerType}, 1},
+		{"Compared", "select * from p where ts > $1",
+			[]string{"2024-01-01 00:00:00"}, []*Type{timestampType}, 1},
+		{"JSON key", "select j -> $1 from p where j ->> $1 is not null",
+			[]string{"a"}, []*Type{stringType}, 1},
+		{"JSON path", "select * from p where j #>> $1 = $2",
+			[]string{"{a,b,1}", "2"}, []*Type{textArrayType, stringType}, 1},
+		{"JSON path as JSON", "select * from p where j #> $1 = '2'",
+			[]string{"{a,b,1}"}, []*Type{textArrayType}, 1},
+		{"Any of column", "select * from p where $1 = ANY (a)",
+			[]string{"4"}, []*Type{integerType}, 1},
+		{"Any of parameter", "select * from p where n <> ALL ($1)",
+			[]string{"{3,4}"}, []*Type{newArrayType(integerType)}, 1},
+		{"Subscript", "select a[$1] from p where a[$1] = 4",
+			[]string{"2"}, []*Type{integerType}, 1},
+	}
+	ctx := WithSession(context.Background(), NewSession(defaultSuperuser))
+	for i, tt := range tts {
+		sts, err := parseSQL(tt.sql)
+		if err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+		}
+		types, err := db.ParamTypes(sts)
+		if err != nil || !reflect.DeepEqual(types, tt.types) {
+			t.Fatalf("case %d (%s) failed: got types %v (%v), expect %v",
+				i, tt.name, types, err, tt.types)
+		}
+		vals := make([]any, len(tt.params))
+		for j, param := range tt.params {
+			if vals[j], err = types[j].parse(param); err != nil {
+				t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+			}
+		}
+		bound, err := bindParams(sts, vals)
+		if err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+		}
+		if r := db.Interpret(ctx, bound); r.err != nil || len(r.rows) != tt.rows {
+			t.Fatalf("case %d (%s) failed: got %d rows (%v), expect %d",
+				i, tt.name, len(r.rows), r.err, tt.rows)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
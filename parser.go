@@ -0,0 +1,1687 @@
+package main
+
+import (
+	"strings"
+)
+
+// parser is the recursive-descent parser of a single statement, which
+// looks ahead one token.
+type parser struct {
+	tokens []*Token
+	// i is the index of the lookahead token
+	i int
+	// emptyArrays are the empty ARRAY[] whose types are unknown until they
+	// are cast, e.g., ARRAY[]::integer[], and their positions
+	emptyArrays map[*ArrayExpr]Position
+}
+
+// peek returns the lookahead token, nil at the end of the statement.
+func (p *parser) peek() *Token {
+	if p.i == len(p.tokens) {
+		return nil
+	}
+	return p.tokens[p.i]
+}
+
+// next consumes and returns the lookahead token.
+func (p *parser) next() *Token {
+	tk := p.peek()
+	if tk != nil {
+		p.i++
+	}
+	return tk
+}
+
+// is checks if the lookahead token is the keyword.
+func (p *parser) is(kw KeyWord) bool {
+	tk := p.peek()
+	return tk != nil && tk.Type == KeyWordToken && tk.KeyWordVal == kw
+}
+
+// isWord checks if the lookahead token is the non-reserved word.
+func (p *parser) isWord(word string) bool {
+	tk := p.peek()
+	return tk != nil && isWord(tk, word)
+}
+
+// accept consumes the lookahead token if it is the keyword.
+func (p *parser) accept(kw KeyWord) bool {
+	if !p.is(kw) {
+		return false
+	}
+	p.i++
+	return true
+}
+
+// acceptWord consumes the lookahead token if it is the non-reserved word.
+func (p *parser) acceptWord(word string) bool {
+	if !p.isWord(word) {
+		return false
+	}
+	p.i++
+	return true
+}
+
+// expect consumes the keyword, which must be the lookahead token.
+func (p *parser) expect(kw KeyWord) error {
+	if !p.accept(kw) {
+		return p.unexpected(strings.ToUpper(kw.String()))
+	}
+	return nil
+}
+
+// expectWord consumes the non-reserved word, which must be the lookahead
+// token.
+func (p *parser) expectWord(word string) error {
+	if !p.acceptWord(word) {
+		return p.unexpected(strings.ToUpper(word))
+	}
+	return nil
+}
+
+// acceptNull consumes the lookahead token if it is NULL.
+func (p *parser) acceptNull() bool {
+	tk := p.peek()
+	if tk == nil || tk.Type != NullToken {
+		return false
+	}
+	p.i++
+	return true
+}
+
+// expectNull consumes NULL, which must be the lookahead token.
+func (p *parser) expectNull() error {
+	if !p.acceptNull() {
+		return p.unexpected("NULL")
+	}
+	return nil
+}
+
+// ident consumes the identifier, e.g., the name of a table or a column.
+func (p *parser) ident(what string) (string, error) {
+	tk := p.peek()
+	if tk == nil || !isUnquoteStringToken(tk) {
+		return "", p.unexpected(what)
+	}
+	p.i++
+	return tk.StringVal, nil
+}
+
+// table consumes the table reference.
+func (p *parser) table() (*TableRef, error) {
+	name, err := p.ident("table name")
+	if err != nil {
+		return nil, err
+	}
+	return &TableRef{name: name}, nil
+}
+
+// errorf returns the syntax error at the lookahead token, or at the end
+// of the statement.
+func (p *parser) errorf(format string, args ...any) error {
+	if tk := p.peek(); tk != nil {
+		return newSyntaxError(tk.Pos, format, args...)
+	}
+	return p.errorAtEnd(format, args...)
+}
+
+// errorAtEnd returns the syntax error at the end of the statement, e.g.,
+// the statement is incomplete.
+func (p *parser) errorAtEnd(format string, args ...any) error {
+	pos := Position{Line: 1, Column: 1}
+	if len(p.tokens) != 0 {
+		pos = p.tokens[len(p.tokens)-1].End
+	}
+	return newSyntaxError(pos, format, args...)
+}
+
+// errorAtStart returns the syntax error at the start of the statement,
+// which is not attributed to a single token, e.g., no primary key.
+func (p *parser) errorAtStart(format string, args ...any) error {
+	return newSyntaxError(p.tokens[0].Pos, format, args...)
+}
+
+// unexpected returns the error of the lookahead token not being what is
+// expected.
+func (p *parser) unexpected(expect string) error {
+	tk := p.peek()
+	if tk == nil {
+		return p.errorAtEnd("incomplete statement, expect %s", expect)
+	}
+	return p.errorf("syntax error at or near %q, expect %s",
+		tk.String(), expect)
+}
+
+// end checks that the statement is completely consumed.
+func (p *parser) end() error {
+	if tk := p.peek(); tk != nil {
+		return p.errorf("syntax error at or near %q", tk.String())
+	}
+	return nil
+}
+
+// parseScript parses the statements separated by the semicolons. A
+// statement with a syntax error is skipped, so the errors of all the
+// statements are reported at once.
+func parseScript(tokens []*Token) ([]Statement, []error) {
+	var (
+		stmts []Statement
+		errs  []error
+		start int
+	)
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && !cmpTks(*tokens[i], TokenSemicolon) {
+			continue
+		}
+		// the blank statements are dropped
+		if i > start {
+			stmt, err := parseStatement(tokens[start:i])
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				stmts = append(stmts, stmt)
+			}
+		}
+		start = i + 1
+	}
+	return stmts, errs
+}
+
+// parseStatement parses the statement that is not empty.
+func parseStatement(tokens []*Token) (Statement, error) {
+	p := &parser{tokens: tokens}
+	stmt, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	// the first of the empty arrays never cast
+	var (
+		pos   Position
+		found bool
+	)
+	for _, at := range p.emptyArrays {
+		if !found || at.Offset < pos.Offset {
+			pos, found = at, true
+		}
+	}
+	if found {
+		return nil, atPosition(newError(codeIndeterminateDatatype,
+			"cannot determine type of empty array"), pos)
+	}
+	return stmt, nil
+}
+
+func (p *parser) parseStatement() (Statement, error) {
+	tk := p.next()
+	if tk.Type != KeyWordToken {
+		p.i--
+		return nil, p.errorf("syntax error at or near %q, expect a statement",
+			tk.String())
+	}
+	switch tk.KeyWordVal {
+	case Select:
+		return p.parseSelect()
+	case Insert:
+		return p.parseInsert()
+	case Update:
+		return p.parseUpdate()
+	case Delete:
+		return p.parseDelete()
+	case Create:
+		switch {
+		case p.accept(KeyWordUser):
+			return p.parseCreateUser()
+		case p.acceptWord("policy"):
+			return p.parseCreatePolicy()
+		case p.acceptWord("sequence"):
+			return p.parseCreateSequence()
+		case p.acceptWord("index"):
+			return p.parseCreateIndex()
+		}
+		return p.parseCreateTable()
+	case Drop:
+		switch {
+		case p.accept(KeyWordUser):
+			return p.parseDropUser()
+		case p.acceptWord("policy"):
+			return p.parseDropPolicy()
+		case p.acceptWord("sequence"):
+			name, err := p.ident("sequence name")
+			if err != nil {
+				return nil, err
+			}
+			return &DropSequenceStatement{name: name}, nil
+		case p.acceptWord("index"):
+			name, err := p.ident("index name")
+			if err != nil {
+				return nil, err
+			}
+			return &DropIndexStatement{name: name}, nil
+		}
+		return p.parseDropTable()
+	case Alter:
+		if p.accept(KeyWordTable) {
+			return p.parseAlterTable()
+		}
+		return p.parseAlterUser()
+	case Grant, Revoke:
+		return p.parseGrant(tk.KeyWordVal == Revoke)
+	case Set:
+		return p.parseSet()
+	}
+	p.i--
+	return nil, p.errorf("unsupported statement %s",
+		strings.ToUpper(tk.String()))
+}
+
+// parseWhere parses the optional WHERE clause.
+func (p *parser) parseWhere() (Expr, error) {
+	if !p.accept(Where) {
+		return nil, nil
+	}
+	return p.parseExpr()
+}
+
+// parseSelect parses "SELECT * | item [, ...] FROM table [WHERE expr]",
+// the item is "expr [[AS] alias]".
+func (p *parser) parseSelect() (*SelectStatement, error) {
+	ss := &SelectStatement{}
+	if !p.accept(Star) {
+		for {
+			item, err := p.parseSelectItem()
+			if err != nil {
+				return nil, err
+			}
+			ss.items = append(ss.items, item)
+			if !p.accept(Comma) {
+				break
+			}
+		}
+	}
+	if err := p.expect(From); err != nil {
+		return nil, err
+	}
+	table, err := p.table()
+	if err != nil {
+		return nil, err
+	}
+	ss.table = table
+	if ss.where, err = p.parseWhere(); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+func (p *parser) parseSelectItem() (*SelectItem, error) {
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	item := &SelectItem{expr: e}
+	if p.acceptWord("as") {
+		if item.alias, err = p.ident("alias"); err != nil {
+			return nil, err
+		}
+	} else if tk := p.peek(); tk != nil && isUnquoteStringToken(tk) {
+		item.alias = p.next().StringVal
+	}
+	return item, nil
+}
+
+// parseInsert parses
+// "INSERT INTO table [(column [, ...])] VALUES (expr [, ...])".
+func (p *parser) parseInsert() (*InsertStatement, error) {
+	if err := p.expect(Into); err != nil {
+		return nil, err
+	}
+	table, err := p.table()
+	if err != nil {
+		return nil, err
+	}
+	is := &InsertStatement{table: table}
+	if p.accept(LeftParen) {
+		for {
+			col, err := p.ident("column name")
+			if err != nil {
+				return nil, err
+			}
+			is.columns = append(is.columns, col)
+			if !p.accept(Comma) {
+				break
+			}
+		}
+		if err := p.expect(RightParen); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(Values); err != nil {
+		return nil, err
+	}
+	if err := p.expect(LeftParen); err != nil {
+		return nil, err
+	}
+	start := p.peek()
+	if is.values, err = p.parseExprList(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	if is.columns != nil && len(is.columns) != len(is.values) {
+		return nil, newSyntaxError(start.Pos, "number columns(%d) "+
+			"not equal to number values(%d)", len(is.columns), len(is.values))
+	}
+	return is, nil
+}
+
+// parseExprList parses "expr [, ...]".
+func (p *parser) parseExprList() ([]Expr, error) {
+	var es []Expr
+	for {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+		if !p.accept(Comma) {
+			return es, nil
+		}
+	}
+}
+
+// parseUpdate parses
+// "UPDATE table SET column = expr [, ...] [WHERE expr]".
+func (p *parser) parseUpdate() (*UpdateStatement, error) {
+	table, err := p.table()
+	if err != nil {
+		return nil, err
+	}
+	us := &UpdateStatement{table: table}
+	if err := p.expect(Set); err != nil {
+		return nil, err
+	}
+	for {
+		col, err := p.ident("column name")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(Equal); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		us.assignments = append(us.assignments, &Assignment{
+			column: col,
+			value:  value,
+		})
+		if !p.accept(Comma) {
+			break
+		}
+	}
+	if us.where, err = p.parseWhere(); err != nil {
+		return nil, err
+	}
+	return us, nil
+}
+
+// parseDelete parses "DELETE FROM table [WHERE expr]".
+func (p *parser) parseDelete() (*DeleteStatement, error) {
+	if err := p.expect(From); err != nil {
+		return nil, err
+	}
+	table, err := p.table()
+	if err != nil {
+		return nil, err
+	}
+	ds := &DeleteStatement{table: table}
+	if ds.where, err = p.parseWhere(); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// parseCreateTable parses "CREATE TABLE table (element [, ...])", the
+// element is a column "column type [column constraint ...]", see
+// parseColumnDef, or a table constraint "[CONSTRAINT name] PRIMARY KEY
+// (column, ...) | UNIQUE (column, ...) | CHECK (expr) | FOREIGN KEY
+// (column, ...) REFERENCES ...", see parseConstraint. The primary key is
+// given once, either by a column or by the table constraint.
+func (p *parser) parseCreateTable() (*CreateStatement, error) {
+	if err := p.expect(KeyWordTable); err != nil {
+		return nil, err
+	}
+	table, err := p.table()
+	if err != nil {
+		return nil, err
+	}
+	cs := &CreateStatement{table: table}
+	if err := p.expect(LeftParen); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	pk := ""
+	for {
+		start := p.peek()
+		if p.is(KeyWordConstraint) || p.is(Unique) || p.is(Check) ||
+			p.is(Foreign) || p.is(Primary) {
+			c, err := p.parseConstraint("")
+			if err != nil {
+				return nil, err
+			}
+			if c.kind == primaryKeyConstraint {
+				if pk != "" {
+					return nil, newSyntaxError(start.Pos,
+						"multiple primary keys for table %s are not allowed",
+						table)
+				}
+				pk = strings.Join(c.columns, ", ")
+			}
+			cs.constraints = append(cs.constraints, c)
+			if !p.accept(Comma) {
+				break
+			}
+			continue
+		}
+		col, err := p.parseColumnDef()
+		if err != nil {
+			return nil, err
+		}
+		if seen[col.name] {
+			return nil, newSyntaxError(start.Pos, "duplicate column %s",
+				col.name)
+		}
+		seen[col.name] = true
+		if col.primaryKey {
+			if pk != "" {
+				return nil, newSyntaxError(start.Pos,
+					"multiple primary keys for table %s are not allowed",
+					table)
+			}
+			pk = col.name
+		}
+		cs.columns = append(cs.columns, col)
+		if !p.accept(Comma) {
+			break
+		}
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	if pk == "" {
+		return nil, p.errorAtStart("primary key is not set")
+	}
+	return cs, nil
+}
+
+// parseColumnDef parses the column "column {type | SERIAL} [PRIMARY KEY |
+// DEFAULT expr | GENERATED ... AS IDENTITY | NULL | column constraint]
+// ...".
+func (p *parser) parseColumnDef() (*ColumnDef, error) {
+	name, err := p.ident("column name")
+	if err != nil {
+		return nil, err
+	}
+	col := &ColumnDef{name: name}
+	if p.acceptWord("serial") {
+		col.typ, col.serial = integerType, true
+	} else if col.typ, err = p.parseType(); err != nil {
+		return nil, err
+	}
+	for {
+		start := p.peek()
+		switch {
+		case p.accept(Primary):
+			if err := p.expect(Key); err != nil {
+				return nil, err
+			}
+			if col.primaryKey {
+				return nil, newSyntaxError(start.Pos,
+					"multiple primary keys for column %s", name)
+			}
+			col.primaryKey = true
+		case p.accept(Default):
+			if col.defaultValue != nil || col.serial {
+				return nil, newSyntaxError(start.Pos,
+					"multiple default values for column %s", name)
+			}
+			if col.identity != nil {
+				return nil, newSyntaxError(start.Pos,
+					"both default and identity specified for column %s",
+					name)
+			}
+			if col.defaultValue, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		case p.acceptWord("generated"):
+			if col.identity != nil {
+				return nil, newSyntaxError(start.Pos,
+					"multiple identity specifications for column %s", name)
+			}
+			if col.defaultValue != nil || col.serial {
+				return nil, newSyntaxError(start.Pos,
+					"both default and identity specified for column %s",
+					name)
+			}
+			if col.identity, err = p.parseIdentity(); err != nil {
+				return nil, err
+			}
+		case p.acceptNull():
+			// the column is nullable, which is the default
+		case p.is(KeyWordConstraint) || p.is(Not) || p.is(Unique) ||
+			p.is(Check) || p.is(References):
+			c, err := p.parseConstraint(name)
+			if err != nil {
+				return nil, err
+			}
+			col.constraints = append(col.constraints, c)
+		default:
+			return col, nil
+		}
+	}
+}
+
+// parseIdentity parses the "{ALWAYS | BY DEFAULT} AS IDENTITY
+// [(options)]" following GENERATED.
+func (p *parser) parseIdentity() (*Identity, error) {
+	id := &Identity{}
+	switch {
+	case p.acceptWord("always"):
+		id.always = true
+	case p.acceptWord("by"):
+		if err := p.expect(Default); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.unexpected("ALWAYS or BY DEFAULT")
+	}
+	for _, w := range []string{"as", "identity"} {
+		if err := p.expectWord(w); err != nil {
+			return nil, err
+		}
+	}
+	if p.accept(LeftParen) {
+		if err := p.parseSequenceOptions(&id.options); err != nil {
+			return nil, err
+		}
+		if err := p.expect(RightParen); err != nil {
+			return nil, err
+		}
+	}
+	return id, nil
+}
+
+// parseCreateSequence parses "CREATE SEQUENCE name [options]".
+func (p *parser) parseCreateSequence() (*CreateSequenceStatement, error) {
+	name, err := p.ident("sequence name")
+	if err != nil {
+		return nil, err
+	}
+	cs := &CreateSequenceStatement{name: name}
+	if err := p.parseSequenceOptions(&cs.options); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// parseCreateIndex parses "CREATE INDEX [name] ON table (item [, ...])",
+// the item is a column, a function call or a parenthesized expression.
+func (p *parser) parseCreateIndex() (*CreateIndexStatement, error) {
+	ci := &CreateIndexStatement{}
+	if !p.is(On) {
+		name, err := p.ident("index name")
+		if err != nil {
+			return nil, err
+		}
+		ci.name = name
+	}
+	if err := p.expect(On); err != nil {
+		return nil, err
+	}
+	table, err := p.table()
+	if err != nil {
+		return nil, err
+	}
+	ci.table = table
+	if err := p.expect(LeftParen); err != nil {
+		return nil, err
+	}
+	for {
+		e, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		ci.exprs = append(ci.exprs, e)
+		if !p.accept(Comma) {
+			break
+		}
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	return ci, nil
+}
+
+// parseSequenceOptions parses the options of the sequence in any order,
+// i.e., "INCREMENT [BY] n", "MINVALUE n | NO MINVALUE", "MAXVALUE n |
+// NO MAXVALUE", "START [WITH] n" and "[NO] CYCLE".
+func (p *parser) parseSequenceOptions(opts *SequenceOptions) error {
+	seen := make(map[string]bool)
+	for {
+		start := p.peek()
+		no := p.acceptWord("no")
+		var (
+			option string
+			value  **int
+		)
+		switch {
+		case !no && p.acceptWord("increment"):
+			p.acceptWord("by")
+			option, value = "increment", &opts.increment
+		case p.acceptWord("minvalue"):
+			option, value = "minvalue", &opts.minValue
+		case p.acceptWord("maxvalue"):
+			option, value = "maxvalue", &opts.maxValue
+		case !no && p.acceptWord("start"):
+			p.accept(With)
+			option, value = "start", &opts.start
+		case p.acceptWord("cycle"):
+			option = "cycle"
+			cycle := !no
+			opts.cycle = &cycle
+		case no:
+			return p.unexpected("MINVALUE, MAXVALUE or CYCLE")
+		default:
+			return nil
+		}
+		if seen[option] {
+			return newSyntaxError(start.Pos, "conflicting or redundant "+
+				"options %s", strings.ToUpper(option))
+		}
+		seen[option] = true
+		if value == nil || no {
+			continue
+		}
+		v, err := p.parseInteger()
+		if err != nil {
+			return err
+		}
+		*value = &v
+	}
+}
+
+// parseInteger parses the integer, which may be negative.
+func (p *parser) parseInteger() (int, error) {
+	neg := p.accept(Minus)
+	tk := p.peek()
+	if tk == nil || tk.Type != IntegerToken {
+		return 0, p.unexpected("integer")
+	}
+	p.i++
+	if neg {
+		return -tk.IntegerVal, nil
+	}
+	return tk.IntegerVal, nil
+}
+
+// parseType parses the data type, e.g., "INTEGER", "DECIMAL(10, 2)",
+// "VARCHAR(20)" or "TEXT[]". As in PostgreSQL, the sizes and the number of
+// the dimensions of the arrays are ignored, e.g., INTEGER[3][] is
+// INTEGER[].
+func (p *parser) parseType() (*Type, error) {
+	typ, err := p.parseElemType()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(LeftBracket) {
+		if tk := p.peek(); tk != nil && tk.Type == IntegerToken {
+			p.i++
+		}
+		if err := p.expect(RightBracket); err != nil {
+			return nil, err
+		}
+		if typ.id != arrayID {
+			typ = newArrayType(typ)
+		}
+	}
+	return typ, nil
+}
+
+// parseElemType parses the data type other than the arrays.
+func (p *parser) parseElemType() (*Type, error) {
+	start := p.peek()
+	name, err := p.ident("data type")
+	if err != nil {
+		return nil, err
+	}
+	typ, err := lookupType(name)
+	if err != nil {
+		return nil, atPosition(err, start.Pos)
+	}
+	if typ == timestampType || typ == timeType {
+		return p.parseTimeZone(typ)
+	}
+	if (typ != decimalType && typ != varcharType) || !p.accept(LeftParen) {
+		return typ, nil
+	}
+	n, err := p.parseInteger()
+	if err != nil {
+		return nil, err
+	}
+	scale := 0
+	if typ == decimalType && p.accept(Comma) {
+		if scale, err = p.parseInteger(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	if typ == decimalType {
+		typ, err = newDecimalType(n, scale)
+	} else {
+		typ, err = newVarcharType(n)
+	}
+	if err != nil {
+		return nil, atPosition(err, start.Pos)
+	}
+	return typ, nil
+}
+
+// parseTimeZone parses the optional "WITH | WITHOUT TIME ZONE" following
+// TIMESTAMP or TIME, only the timestamp can be with time zone.
+func (p *parser) parseTimeZone(typ *Type) (*Type, error) {
+	start := p.peek()
+	with := p.accept(With)
+	if !with && !p.acceptWord("without") {
+		return typ, nil
+	}
+	if err := p.expectWord("time"); err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("zone"); err != nil {
+		return nil, err
+	}
+	if !with {
+		return typ, nil
+	}
+	if typ == timeType {
+		return nil, atPosition(newError(codeFeatureNotSupported,
+			"time with time zone is not supported"), start.Pos)
+	}
+	return timestamptzType, nil
+}
+
+// parseConstraint parses the column constraint
+// "[CONSTRAINT name] NOT NULL | UNIQUE | CHECK (expr) | REFERENCES ..." of
+// the column, or the table constraint "[CONSTRAINT name]
+// UNIQUE (column, ...) | CHECK (expr) | FOREIGN KEY (column, ...)
+// REFERENCES ..." if the column is empty.
+func (p *parser) parseConstraint(column string) (*Constraint, error) {
+	c := &Constraint{}
+	if p.accept(KeyWordConstraint) {
+		name, err := p.ident("constraint name")
+		if err != nil {
+			return nil, err
+		}
+		c.name = name
+	}
+	switch {
+	case column != "" && p.accept(Not):
+		if err := p.expectNull(); err != nil {
+			return nil, err
+		}
+		c.kind, c.columns = notNullConstraint, []string{column}
+	case column != "" && p.accept(Unique):
+		c.kind, c.columns = uniqueConstraint, []string{column}
+	case column != "" && p.accept(References):
+		c.kind, c.columns = foreignKeyConstraint, []string{column}
+		if err := p.parseReferences(c); err != nil {
+			return nil, err
+		}
+	case p.accept(Unique):
+		cols, err := p.parseColumnList()
+		if err != nil {
+			return nil, err
+		}
+		c.kind, c.columns = uniqueConstraint, cols
+	case column == "" && p.accept(Primary):
+		if err := p.expect(Key); err != nil {
+			return nil, err
+		}
+		cols, err := p.parseColumnList()
+		if err != nil {
+			return nil, err
+		}
+		c.kind, c.columns = primaryKeyConstraint, cols
+	case column == "" && p.accept(Foreign):
+		if err := p.expect(Key); err != nil {
+			return nil, err
+		}
+		cols, err := p.parseColumnList()
+		if err != nil {
+			return nil, err
+		}
+		c.kind, c.columns = foreignKeyConstraint, cols
+		if err := p.expect(References); err != nil {
+			return nil, err
+		}
+		if err := p.parseReferences(c); err != nil {
+			return nil, err
+		}
+	case p.accept(Check):
+		check, err := p.parseParenExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.kind, c.check = checkConstraint, check
+		if column != "" {
+			c.columns = []string{column}
+		}
+	case column != "":
+		return nil, p.unexpected("NOT NULL, UNIQUE, CHECK or REFERENCES")
+	default:
+		return nil, p.unexpected("PRIMARY KEY, UNIQUE, CHECK or FOREIGN KEY")
+	}
+	return c, nil
+}
+
+// parseColumnList parses the parenthesized column names.
+func (p *parser) parseColumnList() ([]string, error) {
+	if err := p.expect(LeftParen); err != nil {
+		return nil, err
+	}
+	var cols []string
+	for {
+		col, err := p.ident("column name")
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+		if !p.accept(Comma) {
+			break
+		}
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
+
+// parseReferences parses "table [(column, ...)] [ON DELETE action]
+// [ON UPDATE action]" following REFERENCES.
+func (p *parser) parseReferences(c *Constraint) error {
+	table, err := p.table()
+	if err != nil {
+		return err
+	}
+	c.refTable = table.name
+	if p.is(LeftParen) {
+		if c.refColumns, err = p.parseColumnList(); err != nil {
+			return err
+		}
+	}
+	for p.accept(On) {
+		action := &c.onDelete
+		switch {
+		case p.accept(Delete):
+		case p.accept(Update):
+			action = &c.onUpdate
+		default:
+			return p.unexpected("DELETE or UPDATE")
+		}
+		if *action, err = p.parseRefAction(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRefAction parses "CASCADE | RESTRICT | SET NULL | NO ACTION".
+func (p *parser) parseRefAction() (RefAction, error) {
+	switch {
+	case p.acceptWord("cascade"):
+		return cascade, nil
+	case p.acceptWord("restrict"):
+		return restrict, nil
+	case p.accept(Set):
+		return setNull, p.expectNull()
+	case p.acceptWord("no"):
+		return noAction, p.expectWord("action")
+	}
+	return noAction, p.unexpected("CASCADE, RESTRICT, SET NULL or NO ACTION")
+}
+
+// parseDropTable parses "DROP TABLE table [CASCADE | RESTRICT]".
+func (p *parser) parseDropTable() (*DropStatement, error) {
+	if err := p.expect(KeyWordTable); err != nil {
+		return nil, err
+	}
+	table, err := p.table()
+	if err != nil {
+		return nil, err
+	}
+	ds := &DropStatement{table: table}
+	if p.acceptWord("cascade") {
+		ds.cascade = true
+	} else {
+		p.acceptWord("restrict")
+	}
+	return ds, nil
+}
+
+// parseSet parses "SET name = value" and "SET name TO value", the name
+// may be qualified, e.g., app.tenant.
+func (p *parser) parseSet() (*SetStatement, error) {
+	name, err := p.ident("parameter name")
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(Dot) {
+		part, err := p.ident("parameter name")
+		if err != nil {
+			return nil, err
+		}
+		name += "." + part
+	}
+	if !p.accept(Equal) && !p.accept(To) {
+		return nil, p.unexpected("= or TO")
+	}
+	tk := p.peek()
+	if tk == nil {
+		return nil, p.unexpected("value")
+	}
+	ss := &SetStatement{name: name}
+	switch tk.Type {
+	case StringToken, UnquoteStringToken:
+		ss.value = tk.StringVal
+	case IntegerToken, DecimalToken, BoolToken:
+		ss.value = tk.String()
+	default:
+		return nil, p.unexpected("value")
+	}
+	p.i++
+	return ss, nil
+}
+
+// isWord checks if the token is the unquoted non-reserved keyword.
+func isWord(token *Token, word string) bool {
+	return isUnquoteStringToken(token) && !token.Quoted &&
+		strings.EqualFold(token.StringVal, word)
+}
+
+// parseUserOptions parses "[WITH] [PASSWORD 'xxx'] [SUPERUSER|NOSUPERUSER]".
+func (p *parser) parseUserOptions() (*string, *bool, error) {
+	var (
+		password  *string
+		superuser *bool
+	)
+	p.accept(With)
+	for p.peek() != nil {
+		switch {
+		case p.acceptWord("password"):
+			tk := p.peek()
+			if tk == nil || tk.Type != StringToken {
+				return nil, nil, p.unexpected("quoted password")
+			}
+			p.i++
+			password = &tk.StringVal
+		case p.acceptWord("superuser"):
+			su := true
+			superuser = &su
+		case p.acceptWord("nosuperuser"):
+			su := false
+			superuser = &su
+		default:
+			return nil, nil, p.unexpected("PASSWORD, SUPERUSER or NOSUPERUSER")
+		}
+	}
+	return password, superuser, nil
+}
+
+// parseCreateUser parses "CREATE USER name [options]".
+func (p *parser) parseCreateUser() (*CreateUserStatement, error) {
+	name, err := p.ident("user name")
+	if err != nil {
+		return nil, err
+	}
+	password, superuser, err := p.parseUserOptions()
+	if err != nil {
+		return nil, err
+	}
+	return &CreateUserStatement{
+		name:      name,
+		password:  password,
+		superuser: superuser,
+	}, nil
+}
+
+// parseAlterUser parses "ALTER USER name options".
+func (p *parser) parseAlterUser() (*AlterUserStatement, error) {
+	if err := p.expect(KeyWordUser); err != nil {
+		return nil, err
+	}
+	name, err := p.ident("user name")
+	if err != nil {
+		return nil, err
+	}
+	password, superuser, err := p.parseUserOptions()
+	if err != nil {
+		return nil, err
+	}
+	if password == nil && superuser == nil {
+		return nil, p.unexpected("PASSWORD, SUPERUSER or NOSUPERUSER")
+	}
+	return &AlterUserStatement{
+		name:      name,
+		password:  password,
+		superuser: superuser,
+	}, nil
+}
+
+// parseDropUser parses "DROP USER name".
+func (p *parser) parseDropUser() (*DropUserStatement, error) {
+	name, err := p.ident("user name")
+	if err != nil {
+		return nil, err
+	}
+	return &DropUserStatement{name: name}, nil
+}
+
+// parsePrivilege parses the privilege, some of which are not keywords.
+func parsePrivilege(token *Token) (Privilege, bool) {
+	if token.Type == KeyWordToken || token.Type == UnquoteStringToken {
+		return stringToPrivilege(token.String())
+	}
+	return 0, false
+}
+
+// parseGrant parses
+// "GRANT privileges ON [TABLE] table TO user" and
+// "REVOKE privileges ON [TABLE] table FROM user", the privileges are
+// either "ALL [PRIVILEGES]" or a list of privileges, the table is either
+// a table name or "ALL TABLES".
+func (p *parser) parseGrant(revoke bool) (*GrantStatement, error) {
+	gs := &GrantStatement{revoke: revoke}
+	if p.accept(All) {
+		gs.privileges = PrivAll
+		p.acceptWord("privileges")
+	} else {
+		for {
+			tk := p.peek()
+			if tk == nil {
+				return nil, p.unexpected("privilege")
+			}
+			priv, ok := parsePrivilege(tk)
+			if !ok {
+				return nil, p.unexpected("privilege")
+			}
+			p.i++
+			gs.privileges |= priv
+			if !p.accept(Comma) {
+				break
+			}
+		}
+	}
+
+	if err := p.expect(On); err != nil {
+		return nil, err
+	}
+	p.accept(KeyWordTable)
+	if p.accept(All) {
+		if err := p.expectWord("tables"); err != nil {
+			return nil, err
+		}
+		gs.table = allTables
+	} else {
+		table, err := p.ident("table name")
+		if err != nil {
+			return nil, err
+		}
+		gs.table = table
+	}
+
+	expect := To
+	if revoke {
+		expect = From
+	}
+	if err := p.expect(expect); err != nil {
+		return nil, err
+	}
+	user, err := p.ident("user name")
+	if err != nil {
+		return nil, err
+	}
+	gs.user = user
+	return gs, nil
+}
+
+// parseParenExpr parses the parenthesized expression, e.g., the
+// expression of "USING (expr)".
+func (p *parser) parseParenExpr() (Expr, error) {
+	if err := p.expect(LeftParen); err != nil {
+		return nil, err
+	}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseCreatePolicy parses
+// "CREATE POLICY name ON table [FOR command] [USING (expr)]
+// [WITH CHECK (expr)]", the command is one of ALL, SELECT, INSERT, UPDATE
+// and DELETE.
+func (p *parser) parseCreatePolicy() (*CreatePolicyStatement, error) {
+	name, err := p.ident("policy name")
+	if err != nil {
+		return nil, err
+	}
+	cs := &CreatePolicyStatement{
+		name:    name,
+		command: PrivAll,
+	}
+	if err := p.expect(On); err != nil {
+		return nil, err
+	}
+	if cs.table, err = p.table(); err != nil {
+		return nil, err
+	}
+
+	if p.accept(For) {
+		switch {
+		case p.accept(All):
+		case p.accept(Select):
+			cs.command = PrivSelect
+		case p.accept(Insert):
+			cs.command = PrivInsert
+		case p.accept(Update):
+			cs.command = PrivUpdate
+		case p.accept(Delete):
+			cs.command = PrivDelete
+		default:
+			return nil, p.unexpected("ALL, SELECT, INSERT, UPDATE or DELETE")
+		}
+	}
+	if p.accept(Using) {
+		if cs.using, err = p.parseParenExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.accept(With) {
+		if err := p.expect(Check); err != nil {
+			return nil, err
+		}
+		if cs.check, err = p.parseParenExpr(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case cs.using == nil && cs.check == nil:
+		return nil, p.errorAtStart("USING or WITH CHECK is required")
+	case cs.command == PrivInsert && cs.using != nil:
+		return nil, p.errorAtStart("only WITH CHECK expression allowed " +
+			"for INSERT")
+	case (cs.command == PrivSelect || cs.command == PrivDelete) &&
+		cs.check != nil:
+		return nil, p.errorAtStart("WITH CHECK cannot be applied to " +
+			"SELECT or DELETE")
+	}
+	return cs, nil
+}
+
+// parseDropPolicy parses "DROP POLICY name ON table".
+func (p *parser) parseDropPolicy() (*DropPolicyStatement, error) {
+	name, err := p.ident("policy name")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(On); err != nil {
+		return nil, err
+	}
+	table, err := p.table()
+	if err != nil {
+		return nil, err
+	}
+	return &DropPolicyStatement{name: name, table: table}, nil
+}
+
+// parseAlterTable parses
+// "ALTER TABLE table ENABLE|DISABLE ROW LEVEL SECURITY".
+func (p *parser) parseAlterTable() (*AlterTableStatement, error) {
+	table, err := p.table()
+	if err != nil {
+		return nil, err
+	}
+	as := &AlterTableStatement{table: table}
+	switch {
+	case p.acceptWord("enable"):
+		as.rowSecurity = true
+	case p.acceptWord("disable"):
+	default:
+		return nil, p.unexpected("ENABLE or DISABLE")
+	}
+	for _, w := range []string{"row", "level", "security"} {
+		if err := p.expectWord(w); err != nil {
+			return nil, err
+		}
+	}
+	return as, nil
+}
+
+// parseExpr parses the expression by precedence climbing, see
+// Operator.precedence.
+func (p *parser) parseExpr() (Expr, error) {
+	return p.parseBinary(1)
+}
+
+// parseBinary parses the binary operators whose precedence is not lower
+// than the minPrec, all of which are left-associative, as well as the
+// postfix IS [NOT] NULL.
+func (p *parser) parseBinary(minPrec int) (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tk := p.peek()
+		if tk == nil || tk.Type != KeyWordToken {
+			return left, nil
+		}
+		if tk.KeyWordVal == Is {
+			if isNull.precedence() < minPrec {
+				return left, nil
+			}
+			p.i++
+			op := isNull
+			if p.accept(Not) {
+				op = isNotNull
+			}
+			if err := p.expectNull(); err != nil {
+				return nil, err
+			}
+			left = &UnaryExpr{op: op, expr: left}
+			continue
+		}
+		op, ok := binaryOperators[tk.KeyWordVal]
+		if !ok || op.precedence() < minPrec {
+			return left, nil
+		}
+		p.i++
+		if op.comparison() {
+			q, err := p.parseQuantified(op, left)
+			if err != nil {
+				return nil, err
+			}
+			if q != nil {
+				left = q
+				continue
+			}
+		}
+		right, err := p.parseBinary(op.precedence() + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{op: op, left: left, right: right}
+	}
+}
+
+// parseQuantified parses "ANY | SOME | ALL (array)" following the
+// comparison operator, which is nil if there is none.
+func (p *parser) parseQuantified(op Operator, left Expr) (Expr, error) {
+	all := p.is(All)
+	if !all && !p.isWord("any") && !p.isWord("some") {
+		return nil, nil
+	}
+	if next := p.i + 1; next == len(p.tokens) ||
+		p.tokens[next].Type != KeyWordToken ||
+		p.tokens[next].KeyWordVal != LeftParen {
+		return nil, nil
+	}
+	p.i++
+	array, err := p.parseParenExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &QuantifiedExpr{op: op, all: all, left: left, array: array}, nil
+}
+
+// parseCase parses the rest of
+// "CASE [operand] WHEN cond THEN result [...] [ELSE result] END".
+func (p *parser) parseCase() (Expr, error) {
+	c := &CaseExpr{}
+	var err error
+	if !p.isWord("when") {
+		if c.operand, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	for p.acceptWord("when") {
+		w := &CaseWhen{}
+		if w.cond, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if err := p.expectWord("then"); err != nil {
+			return nil, err
+		}
+		if w.result, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		c.whens = append(c.whens, w)
+	}
+	if len(c.whens) == 0 {
+		return nil, p.unexpected("WHEN")
+	}
+	if p.acceptWord("else") {
+		if c.els, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectWord("end"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseCast parses the arguments "(expr AS type)" of CAST.
+func (p *parser) parseCast() (Expr, error) {
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("as"); err != nil {
+		return nil, err
+	}
+	typ, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	return p.cast(e, typ), nil
+}
+
+// cast returns the cast of the expression to the type, which gives the
+// type of the empty array.
+func (p *parser) cast(e Expr, typ *Type) Expr {
+	if a, ok := e.(*ArrayExpr); ok && typ.id == arrayID {
+		delete(p.emptyArrays, a)
+	}
+	return &CastExpr{expr: e, typ: typ}
+}
+
+// parseExtract parses the arguments "(field FROM source)" of extract, the
+// field is taken as the string.
+func (p *parser) parseExtract(fc *FuncCall) (Expr, error) {
+	tk := p.peek()
+	if tk == nil || (tk.Type != StringToken && !isUnquoteStringToken(tk)) {
+		return nil, p.unexpected("field")
+	}
+	p.i++
+	field := tk.StringVal
+	if tk.Type != StringToken {
+		field = strings.ToLower(field)
+	}
+	if err := p.expect(From); err != nil {
+		return nil, err
+	}
+	source, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	fc.args = []Expr{&Literal{val: field}, source}
+	return fc, nil
+}
+
+// parsePosition parses the arguments "(substring IN string)" of position.
+func (p *parser) parsePosition(fc *FuncCall) (Expr, error) {
+	sub, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("in"); err != nil {
+		return nil, err
+	}
+	s, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	fc.args = []Expr{sub, s}
+	return fc, nil
+}
+
+// parseTrim parses the arguments
+// "([BOTH | LEADING | TRAILING] [characters] FROM string)" of trim, or
+// "(string [, characters])", into the call of btrim, ltrim or rtrim.
+func (p *parser) parseTrim() (Expr, error) {
+	fc := &FuncCall{name: "btrim"}
+	switch {
+	case p.acceptWord("leading"):
+		fc.name = "ltrim"
+	case p.acceptWord("trailing"):
+		fc.name = "rtrim"
+	default:
+		p.acceptWord("both")
+	}
+	var chars Expr
+	if !p.accept(From) {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(From) {
+			fc.args = []Expr{e}
+			if p.accept(Comma) {
+				if chars, err = p.parseExpr(); err != nil {
+					return nil, err
+				}
+				fc.args = append(fc.args, chars)
+			}
+			if err := p.expect(RightParen); err != nil {
+				return nil, err
+			}
+			return fc, nil
+		}
+		chars = e
+	}
+	s, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+	fc.args = []Expr{s}
+	if chars != nil {
+		fc.args = append(fc.args, chars)
+	}
+	return fc, nil
+}
+
+// parseUnary parses the prefix NOT, which binds looser than the
+// comparisons, i.e., "NOT a = b" is "NOT (a = b)", and the prefix minus,
+// which binds tighter than any binary operator.
+func (p *parser) parseUnary() (Expr, error) {
+	if p.accept(Minus) {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{op: neg, expr: e}, nil
+	}
+	if !p.accept(Not) {
+		e, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return p.parsePostfix(e)
+	}
+	e, err := p.parseBinary(not.precedence())
+	if err != nil {
+		return nil, err
+	}
+	return &UnaryExpr{op: not, expr: e}, nil
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	tk := p.peek()
+	if tk == nil {
+		return nil, p.unexpected("expression")
+	}
+	switch tk.Type {
+	case StringToken:
+		p.i++
+		return &Literal{val: tk.StringVal}, nil
+	case IntegerToken:
+		p.i++
+		return &Literal{val: tk.IntegerVal}, nil
+	case DecimalToken:
+		p.i++
+		return &Literal{val: tk.DecimalVal}, nil
+	case BoolToken:
+		p.i++
+		return &Literal{val: tk.BoolVal}, nil
+	case NullToken:
+		p.i++
+		return &Literal{val: nil}, nil
+	case ParamToken:
+		p.i++
+		return Param(tk.IntegerVal), nil
+	case UnquoteStringToken:
+		p.i++
+		// the typed literal, e.g., DECIMAL '1.50'
+		if next := p.peek(); next != nil && next.Type == StringToken {
+			if typ, err := lookupType(tk.StringVal); err == nil {
+				p.i++
+				val, err := typ.parse(next.StringVal)
+				if err != nil {
+					return nil, atPosition(err, next.Pos)
+				}
+				return &Literal{val: val}, nil
+			}
+		}
+		if isWord(tk, "array") && p.is(LeftBracket) {
+			return p.parseArray(tk)
+		}
+		if isWord(tk, "case") {
+			return p.parseCase()
+		}
+		if !p.accept(LeftParen) {
+			// current_user is called without parentheses
+			if isWord(tk, "current_user") {
+				return &FuncCall{name: "current_user"}, nil
+			}
+			return &ColumnRef{name: tk.StringVal}, nil
+		}
+		fc := &FuncCall{name: strings.ToLower(tk.StringVal)}
+		switch fc.name {
+		case "extract":
+			return p.parseExtract(fc)
+		case "cast":
+			return p.parseCast()
+		case "position":
+			return p.parsePosition(fc)
+		case "trim":
+			return p.parseTrim()
+		}
+		if !p.accept(RightParen) {
+			args, err := p.parseExprList()
+			if err != nil {
+				return nil, err
+			}
+			fc.args = args
+			if err := p.expect(RightParen); err != nil {
+				return nil, err
+			}
+		}
+		return fc, nil
+	}
+	if p.accept(LeftParen) {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(RightParen); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	return nil, p.unexpected("expression")
+}
+
+// parseArray parses the elements "[elem, ...]" of the array constructor
+// ARRAY[...], which is folded into the literal if all the elements are
+// literals. The empty array must be cast to the array type, see cast.
+func (p *parser) parseArray(start *Token) (Expr, error) {
+	if err := p.expect(LeftBracket); err != nil {
+		return nil, err
+	}
+	if p.accept(RightBracket) {
+		a := &ArrayExpr{}
+		if p.emptyArrays == nil {
+			p.emptyArrays = make(map[*ArrayExpr]Position)
+		}
+		p.emptyArrays[a] = start.Pos
+		return a, nil
+	}
+	elems, err := p.parseExprList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(RightBracket); err != nil {
+		return nil, err
+	}
+	a := &ArrayExpr{elems: elems}
+	for _, e := range elems {
+		if _, ok := e.(*Literal); !ok {
+			return a, nil
+		}
+	}
+	v, err := a.Eval(&evalEnv{})
+	if err != nil {
+		return nil, atPosition(err, start.Pos)
+	}
+	return &Literal{val: v}, nil
+}
+
+// parsePostfix parses the subscripts "[index]" and the casts "::type"
+// following the expression, e.g., tags[1]::integer.
+func (p *parser) parsePostfix(e Expr) (Expr, error) {
+	for {
+		switch {
+		case p.accept(LeftBracket):
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(RightBracket); err != nil {
+				return nil, err
+			}
+			e = &Subscript{expr: e, index: index}
+		case p.accept(DoubleColon):
+			typ, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			e = p.cast(e, typ)
+		default:
+			return e, nil
+		}
+	}
+}
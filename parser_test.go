@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mustParse(t *testing.T, sql string) Statement {
+	t.Helper()
+	sts, err := parseSQL(sql)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", sql, err)
+	}
+	return sts
+}
+
+func TestParseStatement(t *testing.T) {
+	tts := []struct {
+		name   string
+		sql    string
+		expect Statement
+	}{
+		{"Create", "create table t (id integer primary key, name string);",
+			&CreateStatement{
+				table: &TableRef{name: "t"},
+				columns: []*ColumnDef{
+					{name: "id", typ: integerType, primaryKey: true},
+					{name: "name", typ: stringType},
+				},
+			}},
+		{"Create with constraints", "create table t (id integer primary key " +
+			"default 1 constraint c not null, unique (id), check (id > 0))",
+			&CreateStatement{
+				table: &TableRef{name: "t"},
+				columns: []*ColumnDef{
+					{name: "id", typ: integerType, primaryKey: true,
+						defaultValue: &Literal{val: 1},
+						constraints: []*Constraint{{name: "c",
+							kind: notNullConstraint, columns: []string{"id"}}}},
+				},
+				constraints: []*Constraint{
+					{kind: uniqueConstraint, columns: []string{"id"}},
+					{kind: checkConstraint, check: &BinaryExpr{op: greater,
+						left: &ColumnRef{name: "id"}, right: &Literal{val: 0}}},
+				},
+			}},
+		{"Select items", "select id, name as n, lower(name) l from t",
+			&SelectStatement{
+				items: []*SelectItem{
+					{expr: &ColumnRef{name: "id"}},
+					{expr: &ColumnRef{name: "name"}, alias: "n"},
+					{expr: &FuncCall{name: "lower",
+						args: []Expr{&ColumnRef{name: "name"}}}, alias: "l"},
+				},
+				table: &TableRef{name: "t"},
+			}},
+		{"Insert without columns", "insert into t values (1, $1)",
+			&InsertStatement{
+				table:  &TableRef{name: "t"},
+				values: []Expr{&Literal{val: 1}, Param(1)},
+			}},
+		{"Update", "update t set a = 1, b = a where id = 2",
+			&UpdateStatement{
+				table: &TableRef{name: "t"},
+				assignments: []*Assignment{
+					{column: "a", value: &Literal{val: 1}},
+					{column: "b", value: &ColumnRef{name: "a"}},
+				},
+				where: &BinaryExpr{op: equal,
+					left: &ColumnRef{name: "id"}, right: &Literal{val: 2}},
+			}},
+	}
+	for i, tt := range tts {
+		got := mustParse(t, tt.sql)
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Fatalf("case %d (%s) failed: got %#v, expect %#v",
+				i, tt.name, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
+
+func TestParseExprPrecedence(t *testing.T) {
+	tts := []struct {
+		where  string
+		expect string
+	}{
+		{"a = 1 or b = 2 and c = 3", "((a = 1) OR ((b = 2) AND (c = 3)))"},
+		{"a or b or c", "((a OR b) OR c)"},
+		{"not a = 1 and b", "((NOT (a = 1)) AND b)"},
+		{"(a or b) and c", "((a OR b) AND c)"},
+		{"f(a, b = 1) <> $1", "(f(a, (b = 1)) <> $1)"},
+		{"not a = b is not null", "(NOT ((a = b) IS NOT NULL))"},
+		{"a is null = null", "((a IS NULL) = NULL)"},
+	}
+	for i, tt := range tts {
+		ss := mustParse(t, "select * from t where "+tt.where).(*SelectStatement)
+		if got := ss.where.String(); got != tt.expect {
+			t.Fatalf("case %d failed: got %s, expect %s", i, got, tt.expect)
+		}
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	tks, err := Tokenize([]rune("select * form t;\n" +
+		"create table t (id integer primary key);\n" +
+		"insert into t (id) values (1,;\n" +
+		"update t set id = 2"))
+	if err != nil {
+		t.Fatalf("failed to tokenize: %v", err)
+	}
+	stmts, errs := parseScript(tks)
+	if len(stmts) != 2 || len(errs) != 2 {
+		t.Fatalf("got %d statements and %d errors, expect 2 and 2",
+			len(stmts), len(errs))
+	}
+	for i, expect := range []Position{
+		{Offset: 9, Line: 1, Column: 10},
+		{Offset: 87, Line: 3, Column: 30},
+	} {
+		pos := errorPosition(errs[i])
+		if sqlState(errs[i]) != codeSyntaxError || pos == nil || *pos != expect {
+			t.Fatalf("error %d: got %v at %v, expect at %v",
+				i, errs[i], pos, expect)
+		}
+	}
+}
@@ -0,0 +1,1368 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Codes of the startup packets.
+const (
+	pgProtocolVersion = 196608
+	pgSSLRequest      = 80877103
+	pgCancelRequest   = 80877102
+	pgGSSENCRequest   = 80877104
+)
+
+// Object identifiers of the types used in RowDescription and
+// ParameterDescription.
+const (
+	oidBool        = 16
+	oidBytea       = 17
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidText        = 25
+	oidJSON        = 114
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidVarchar     = 1043
+	oidDate        = 1082
+	oidTime        = 1083
+	oidTimestamp   = 1114
+	oidTimestampTZ = 1184
+	oidInterval    = 1186
+	oidNumeric     = 1700
+	oidUUID        = 2950
+	oidJSONB       = 3802
+)
+
+// arrayOIDs maps the types of the elements to the array types, e.g.,
+// int4 to _int4.
+var arrayOIDs = map[uint32]uint32{
+	oidBool:        1000,
+	oidBytea:       1001,
+	oidInt2:        1005,
+	oidInt4:        1007,
+	oidText:        1009,
+	oidVarchar:     1015,
+	oidInt8:        1016,
+	oidFloat8:      1022,
+	oidTimestamp:   1115,
+	oidDate:        1182,
+	oidTime:        1183,
+	oidTimestampTZ: 1185,
+	oidInterval:    1187,
+	oidNumeric:     1231,
+	oidJSON:        199,
+	oidUUID:        2951,
+	oidJSONB:       3807,
+}
+
+const (
+	formatText   = 0
+	formatBinary = 1
+)
+
+// typeToOID maps the column type to the PostgreSQL type.
+func typeToOID(typ *Type) (oid uint32, size int16) {
+	switch typ.id {
+	case smallintID:
+		return oidInt2, 2
+	case integerID:
+		return oidInt4, 4
+	case bigintID:
+		return oidInt8, 8
+	case floatID:
+		return oidFloat8, 8
+	case decimalID:
+		return oidNumeric, -1
+	case booleanID:
+		return oidBool, 1
+	case byteaID:
+		return oidBytea, -1
+	case varcharID:
+		return oidVarchar, -1
+	case dateID:
+		return oidDate, 4
+	case timeID:
+		return oidTime, 8
+	case timestampID:
+		return oidTimestamp, 8
+	case timestamptzID:
+		return oidTimestampTZ, 8
+	case intervalID:
+		return oidInterval, 16
+	case jsonID:
+		return oidJSON, -1
+	case jsonbID:
+		return oidJSONB, -1
+	case uuidID:
+		return oidUUID, 16
+	case arrayID:
+		elem, _ := typeToOID(typ.elem)
+		return arrayOIDs[elem], -1
+	default:
+		return oidText, -1
+	}
+}
+
+// oidToType maps the PostgreSQL type to the column type, unknown types
+// are taken as strings.
+func oidToType(oid uint32) *Type {
+	switch oid {
+	case oidInt2:
+		return smallintType
+	case oidInt4:
+		return integerType
+	case oidInt8:
+		return bigintType
+	case oidFloat4, oidFloat8:
+		return floatType
+	case oidNumeric:
+		return decimalType
+	case oidBool:
+		return booleanType
+	case oidBytea:
+		return byteaType
+	case oidVarchar:
+		return varcharType
+	case oidDate:
+		return dateType
+	case oidTime:
+		return timeType
+	case oidTimestamp:
+		return timestampType
+	case oidTimestampTZ:
+		return timestamptzType
+	case oidInterval:
+		return intervalType
+	case oidJSON:
+		return jsonType
+	case oidJSONB:
+		return jsonbType
+	case oidUUID:
+		return uuidType
+	}
+	for elem, array := range arrayOIDs {
+		if array == oid {
+			return newArrayType(oidToType(elem))
+		}
+	}
+	return stringType
+}
+
+// PGServer serves the database over the PostgreSQL v3 frontend/backend
+// protocol.
+type PGServer struct {
+	db *Database
+
+	mu sync.Mutex
+	// running keeps the cancel functions of the running statements keyed
+	// by the backend key data, which is used by the CancelRequest.
+	running map[pgBackendKey]context.CancelFunc
+}
+
+type pgBackendKey struct {
+	pid    uint32
+	secret uint32
+}
+
+func NewPGServer(db *Database) *PGServer {
+	return &PGServer{
+		db:      db,
+		running: make(map[pgBackendKey]context.CancelFunc),
+	}
+}
+
+// Listen listens on the addr, which is taken as a Unix socket path if it
+// contains a '/', otherwise a TCP address.
+func Listen(addr string) (net.Listener, error) {
+	if strings.Contains(addr, "/") {
+		return net.Listen("unix", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Serve accepts the connections on the listener and serves each of them
+// in a goroutine.
+func (s *PGServer) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := s.serveConn(conn); err != nil &&
+				!errors.Is(err, io.EOF) {
+				log.Printf("[ERROR] connection %s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// pgConn is the state of a client connection.
+type pgConn struct {
+	srv *PGServer
+	r   *bufio.Reader
+	w   *bufio.Writer
+	key pgBackendKey
+	ctx context.Context
+
+	stmts   map[string]*pgStatement
+	portals map[string]*pgPortal
+	// failed is set once an error occurs in the extended query protocol,
+	// the messages are discarded until the Sync.
+	failed bool
+}
+
+// pgStatement is a statement prepared by the Parse message.
+type pgStatement struct {
+	sts   Statement
+	types []*Type
+}
+
+// pgPortal is a statement bound with the parameters by the Bind message.
+type pgPortal struct {
+	sts     Statement
+	formats []int16
+}
+
+func (s *PGServer) serveConn(conn net.Conn) error {
+	c := &pgConn{
+		srv:     s,
+		r:       bufio.NewReader(conn),
+		w:       bufio.NewWriter(conn),
+		stmts:   make(map[string]*pgStatement),
+		portals: make(map[string]*pgPortal),
+	}
+	params, err := c.startup()
+	if err != nil || params == nil {
+		return err
+	}
+	user := params["user"]
+	if err := c.authenticate(user); err != nil {
+		c.errorResponse(err)
+		return c.w.Flush()
+	}
+
+	c.ctx = WithSession(context.Background(), NewSession(user))
+	for _, v := range []struct{ name, val string }{
+		{"server_version", "14.0"},
+		{"server_encoding", "UTF8"},
+		{"client_encoding", "UTF8"},
+		{"DateStyle", "ISO, MDY"},
+		{"integer_datetimes", "on"},
+		{"standard_conforming_strings", "on"},
+		{"TimeZone", "UTC"},
+		{"application_name", params["application_name"]},
+	} {
+		c.msg('S').str(v.name).str(v.val).send()
+	}
+	var secret [8]byte
+	if _, err := rand.Read(secret[:]); err != nil {
+		return err
+	}
+	c.key = pgBackendKey{
+		pid:    binary.BigEndian.Uint32(secret[:4]) & math.MaxInt32,
+		secret: binary.BigEndian.Uint32(secret[4:]),
+	}
+	c.msg('K').int32(int32(c.key.pid)).int32(int32(c.key.secret)).send()
+	c.readyForQuery()
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		typ, body, err := c.readMessage(pgMaxMessageLength)
+		if err != nil {
+			if _, ok := err.(*Error); ok {
+				c.errorResponse(err)
+				c.w.Flush()
+			}
+			return err
+		}
+		if c.failed && typ != 'S' && typ != 'X' {
+			continue
+		}
+		switch typ {
+		case 'Q':
+			c.simpleQuery(readString(&body))
+			c.failed = false
+		case 'P':
+			c.parseMessage(body)
+		case 'B':
+			c.bindMessage(body)
+		case 'D':
+			c.describeMessage(body)
+		case 'E':
+			c.executeMessage(body)
+		case 'C':
+			c.closeMessage(body)
+		case 'S':
+			c.failed = false
+			c.readyForQuery()
+		case 'H':
+		case 'X':
+			return c.w.Flush()
+		default:
+			c.errorResponse(newError(codeProtocolViolation,
+				"unsupported frontend message type %q", typ))
+		}
+		// the responses of the extended query protocol are buffered until
+		// the Sync or Flush
+		if typ == 'Q' || typ == 'S' || typ == 'H' || c.failed {
+			if err := c.w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// startup handles the startup packets, returns nil parameters if the
+// connection should be closed without an error, e.g., a CancelRequest.
+func (c *pgConn) startup() (map[string]string, error) {
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint32(hdr[:4]))
+		if length < 8 || length > 10000 {
+			return nil, errors.Errorf("invalid startup packet length %d", length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(c.r, body); err != nil {
+			return nil, err
+		}
+
+		switch code := binary.BigEndian.Uint32(hdr[4:]); code {
+		case pgSSLRequest, pgGSSENCRequest:
+			// encryption is not supported
+			if err := c.w.WriteByte('N'); err != nil {
+				return nil, err
+			}
+			if err := c.w.Flush(); err != nil {
+				return nil, err
+			}
+		case pgCancelRequest:
+			if len(body) == 8 {
+				c.srv.cancel(pgBackendKey{
+					pid:    binary.BigEndian.Uint32(body[:4]),
+					secret: binary.BigEndian.Uint32(body[4:]),
+				})
+			}
+			return nil, nil
+		case pgProtocolVersion:
+			params := make(map[string]string)
+			for len(body) > 1 {
+				name := readString(&body)
+				params[name] = readString(&body)
+			}
+			return params, nil
+		default:
+			c.errorResponse(newError(codeProtocolViolation,
+				"unsupported frontend protocol %d.%d",
+				code>>16, code&0xffff))
+			return nil, c.w.Flush()
+		}
+	}
+}
+
+// authenticate asks the client for the password of the user.
+func (c *pgConn) authenticate(user string) error {
+	// AuthenticationCleartextPassword
+	c.msg('R').int32(3).send()
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	typ, body, err := c.readMessage(pgMaxAuthMessageLength)
+	if err != nil {
+		return err
+	}
+	if typ != 'p' {
+		return newError(codeProtocolViolation,
+			"expected password response, got message type %q", typ)
+	}
+	if err := c.srv.db.Authenticate(user, readString(&body)); err != nil {
+		return err
+	}
+	// AuthenticationOk
+	c.msg('R').int32(0).send()
+	return nil
+}
+
+func (s *PGServer) cancel(key pgBackendKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, exist := s.running[key]; exist {
+		cancel()
+	}
+}
+
+// interpret executes the statement, the statement can be canceled by a
+// CancelRequest carrying the backend key data of the connection.
+func (c *pgConn) interpret(sts Statement) *Result {
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	c.srv.mu.Lock()
+	c.srv.running[c.key] = cancel
+	c.srv.mu.Unlock()
+	defer func() {
+		c.srv.mu.Lock()
+		delete(c.srv.running, c.key)
+		c.srv.mu.Unlock()
+	}()
+	return c.srv.db.Interpret(ctx, sts)
+}
+
+// The limits of the lengths of the messages, the messages before the
+// authentication, i.e., the password, are limited to be small, so that
+// no client can make the server allocate much before it logs in.
+const (
+	pgMaxAuthMessageLength = 10000
+	pgMaxMessageLength     = 1 << 26
+)
+
+// readMessage reads the message of the length up to the limit, the longer
+// one is a protocol violation.
+func (c *pgConn) readMessage(limit int) (byte, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	length := int(binary.BigEndian.Uint32(hdr[1:]))
+	if length < 4 || length > limit {
+		return 0, nil, newError(codeProtocolViolation,
+			"invalid message length %d", length)
+	}
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return hdr[0], body, nil
+}
+
+// parseSQL tokenizes and parses a single statement, returns nil if the
+// sql is empty.
+func parseSQL(sql string) (Statement, error) {
+	stmts, err := parseScriptSQL(sql)
+	if err != nil {
+		return nil, err
+	}
+	switch len(stmts) {
+	case 0:
+		return nil, nil
+	case 1:
+		return stmts[0], nil
+	}
+	return nil, newSyntaxError(positionAt(sql, len(sql)),
+		"expect a single statement, got %d", len(stmts))
+}
+
+// parseScriptSQL tokenizes and parses the statements separated by the
+// semicolons, only the first syntax error is returned.
+func parseScriptSQL(sql string) ([]Statement, error) {
+	tks, err := Tokenize([]rune(sql))
+	if err != nil {
+		return nil, syntaxError(err)
+	}
+	stmts, errs := parseScript(tks)
+	if len(errs) != 0 {
+		return nil, syntaxError(errs[0])
+	}
+	return stmts, nil
+}
+
+// syntaxError returns the err as a syntax error unless it is coded.
+func syntaxError(err error) error {
+	if errorPosition(err) != nil {
+		return err
+	}
+	return newError(codeSyntaxError, "%v", err)
+}
+
+// simpleQuery executes the statements of the query in order, none of
+// them is executed if any has a syntax error.
+func (c *pgConn) simpleQuery(query string) {
+	defer c.readyForQuery()
+	stmts, err := parseScriptSQL(query)
+	if err != nil {
+		c.errorResponseAt(err, query)
+		return
+	}
+	if len(stmts) == 0 {
+		c.msg('I').send()
+		return
+	}
+	for _, sts := range stmts {
+		result := c.interpret(sts)
+		if result.err != nil {
+			c.errorResponse(result.err)
+			return
+		}
+		if len(result.cols) != 0 {
+			c.rowDescription(result.cols, result.types, nil)
+		}
+		c.sendResult(sts, result, nil)
+	}
+}
+
+func (c *pgConn) parseMessage(body []byte) {
+	name := readString(&body)
+	query := readString(&body)
+	n, err := readCount(&body)
+	if err != nil {
+		c.errorResponse(err)
+		return
+	}
+	oids := make([]uint32, n)
+	for i := range oids {
+		oids[i] = uint32(readInt32(&body))
+	}
+
+	stmts, err := parseScriptSQL(query)
+	if err != nil {
+		c.errorResponseAt(err, query)
+		return
+	}
+	if len(stmts) > 1 {
+		c.errorResponse(newError(codeSyntaxError,
+			"cannot insert multiple commands into a prepared statement"))
+		return
+	}
+	var sts Statement
+	if len(stmts) == 1 {
+		sts = stmts[0]
+	}
+	types, err := c.srv.db.ParamTypes(sts)
+	if err != nil {
+		c.errorResponse(err)
+		return
+	}
+	// the types specified by the client take precedence
+	for i, oid := range oids {
+		for len(types) <= i {
+			types = append(types, stringType)
+		}
+		if oid != 0 {
+			types[i] = oidToType(oid)
+		}
+	}
+	c.stmts[name] = &pgStatement{
+		sts:   sts,
+		types: types,
+	}
+	c.msg('1').send()
+}
+
+func (c *pgConn) bindMessage(body []byte) {
+	portal := readString(&body)
+	name := readString(&body)
+	stmt, exist := c.stmts[name]
+	if !exist {
+		c.errorResponse(newError(codeInvalidStatementName,
+			"prepared statement %q does not exist", name))
+		return
+	}
+
+	paramFormats, err := readFormats(&body)
+	if err != nil {
+		c.errorResponse(err)
+		return
+	}
+	n, err := readCount(&body)
+	if err != nil {
+		c.errorResponse(err)
+		return
+	}
+	if n != len(stmt.types) {
+		c.errorResponse(newError(codeProtocolViolation,
+			"bind message supplies %d parameters, "+
+				"but prepared statement requires %d",
+			n, len(stmt.types)))
+		return
+	}
+	if len(paramFormats) > 1 && len(paramFormats) != n {
+		c.errorResponse(newError(codeProtocolViolation,
+			"bind message has %d parameter formats but %d parameters",
+			len(paramFormats), n))
+		return
+	}
+	vals := make([]any, n)
+	for i := range vals {
+		length := readInt32(&body)
+		if length < 0 {
+			// NULL
+			continue
+		}
+		if int(length) > len(body) {
+			c.errorResponse(newError(codeProtocolViolation,
+				"invalid parameter length %d", length))
+			return
+		}
+		raw := body[:length]
+		body = body[length:]
+		val, err := decodeParam(raw, stmt.types[i],
+			formatCode(paramFormats, i))
+		if err != nil {
+			c.errorResponse(errors.Wrapf(err,
+				"invalid parameter %s", Param(i+1)))
+			return
+		}
+		vals[i] = val
+	}
+	resultFormats, err := readFormats(&body)
+	if err != nil {
+		c.errorResponse(err)
+		return
+	}
+
+	sts, err := bindParams(stmt.sts, vals)
+	if err != nil {
+		c.errorResponse(err)
+		return
+	}
+	if len(resultFormats) > 1 {
+		cols := 0
+		if ss, ok := sts.(*SelectStatement); ok {
+			names, _, err := c.srv.db.Columns(ss)
+			if err != nil {
+				c.errorResponse(err)
+				return
+			}
+			cols = len(names)
+		}
+		if len(resultFormats) != cols {
+			c.errorResponse(newError(codeProtocolViolation,
+				"bind message has %d result formats but query has %d columns",
+				len(resultFormats), cols))
+			return
+		}
+	}
+	c.portals[portal] = &pgPortal{
+		sts:     sts,
+		formats: resultFormats,
+	}
+	c.msg('2').send()
+}
+
+func (c *pgConn) describeMessage(body []byte) {
+	if len(body) == 0 {
+		c.errorResponse(newError(codeProtocolViolation,
+			"invalid describe message"))
+		return
+	}
+	typ := body[0]
+	body = body[1:]
+	name := readString(&body)
+
+	var (
+		sts     Statement
+		formats []int16
+	)
+	switch typ {
+	case 'S':
+		stmt, exist := c.stmts[name]
+		if !exist {
+			c.errorResponse(newError(codeInvalidStatementName,
+				"prepared statement %q does not exist", name))
+			return
+		}
+		m := c.msg('t').int16(int16(len(stmt.types)))
+		for _, typ := range stmt.types {
+			oid, _ := typeToOID(typ)
+			m.int32(int32(oid))
+		}
+		m.send()
+		sts = stmt.sts
+	case 'P':
+		portal, exist := c.portals[name]
+		if !exist {
+			c.errorResponse(newError(codeInvalidCursorName,
+				"portal %q does not exist", name))
+			return
+		}
+		sts, formats = portal.sts, portal.formats
+	default:
+		c.errorResponse(newError(codeProtocolViolation,
+			"invalid describe message subtype %q", typ))
+		return
+	}
+
+	ss, ok := sts.(*SelectStatement)
+	if !ok {
+		// NoData
+		c.msg('n').send()
+		return
+	}
+	cols, types, err := c.srv.db.Columns(ss)
+	if err != nil {
+		c.errorResponse(err)
+		return
+	}
+	c.rowDescription(cols, types, formats)
+}
+
+func (c *pgConn) executeMessage(body []byte) {
+	name := readString(&body)
+	portal, exist := c.portals[name]
+	if !exist {
+		c.errorResponse(newError(codeInvalidCursorName,
+			"portal %q does not exist", name))
+		return
+	}
+	if portal.sts == nil {
+		c.msg('I').send()
+		return
+	}
+	result := c.interpret(portal.sts)
+	if result.err != nil {
+		c.errorResponse(result.err)
+		return
+	}
+	c.sendResult(portal.sts, result, portal.formats)
+}
+
+func (c *pgConn) closeMessage(body []byte) {
+	if len(body) == 0 {
+		c.errorResponse(newError(codeProtocolViolation,
+			"invalid close message"))
+		return
+	}
+	typ := body[0]
+	body = body[1:]
+	name := readString(&body)
+	if typ == 'S' {
+		delete(c.stmts, name)
+	} else {
+		delete(c.portals, name)
+	}
+	c.msg('3').send()
+}
+
+func (c *pgConn) rowDescription(cols []string,
+	types []*Type, formats []int16) {
+	m := c.msg('T').int16(int16(len(cols)))
+	for i, col := range cols {
+		oid, size := typeToOID(types[i])
+		m.str(col).
+			int32(0).          // table OID
+			int16(0).          // column attribute number
+			int32(int32(oid)). // type OID
+			int16(size).       // type size
+			int32(-1).         // type modifier
+			int16(formatCode(formats, i))
+	}
+	m.send()
+}
+
+// sendResult sends the DataRows and the CommandComplete.
+func (c *pgConn) sendResult(sts Statement, result *Result, formats []int16) {
+	for _, r := range result.rows {
+		m := c.msg('D').int16(int16(len(result.cols)))
+		for i, val := range r.values {
+			if val == nil {
+				m.int32(-1)
+				continue
+			}
+			raw, err := encodeValue(val, result.types[i], formatCode(formats, i))
+			if err != nil {
+				// the row is dropped, and the portal fails
+				c.errorResponse(err)
+				return
+			}
+			m.int32(int32(len(raw))).bytes(raw)
+		}
+		m.send()
+	}
+	c.msg('C').str(commandTag(sts, result)).send()
+}
+
+func commandTag(sts Statement, result *Result) string {
+	switch s := sts.(type) {
+	case *SelectStatement:
+		return "SELECT " + strconv.Itoa(len(result.rows))
+	case *InsertStatement:
+		return "INSERT 0 " + strconv.Itoa(result.affected)
+	case *DeleteStatement:
+		return "DELETE " + strconv.Itoa(result.affected)
+	case *UpdateStatement:
+		return "UPDATE " + strconv.Itoa(result.affected)
+	case *CreateStatement:
+		return "CREATE TABLE"
+	case *DropStatement:
+		return "DROP TABLE"
+	case *SetStatement:
+		return "SET"
+	case *CreateUserStatement:
+		return "CREATE ROLE"
+	case *AlterUserStatement:
+		return "ALTER ROLE"
+	case *DropUserStatement:
+		return "DROP ROLE"
+	case *GrantStatement:
+		if s.revoke {
+			return "REVOKE"
+		}
+		return "GRANT"
+	case *CreatePolicyStatement:
+		return "CREATE POLICY"
+	case *DropPolicyStatement:
+		return "DROP POLICY"
+	case *AlterTableStatement:
+		return "ALTER TABLE"
+	case *CreateSequenceStatement:
+		return "CREATE SEQUENCE"
+	case *DropSequenceStatement:
+		return "DROP SEQUENCE"
+	case *CreateIndexStatement:
+		return "CREATE INDEX"
+	case *DropIndexStatement:
+		return "DROP INDEX"
+	default:
+		return strings.ToUpper(reflect.TypeOf(s).Elem().Name())
+	}
+}
+
+func (c *pgConn) readyForQuery() {
+	// the transaction status is always idle
+	c.msg('Z').byte('I').send()
+}
+
+func (c *pgConn) errorResponse(err error) {
+	c.errorResponseAt(err, "")
+}
+
+// errorResponseAt reports the err of the query, the position of the
+// syntax error is reported as the 1-based character position in the
+// query.
+func (c *pgConn) errorResponseAt(err error, query string) {
+	m := c.msg('E').
+		byte('S').str("ERROR").
+		byte('V').str("ERROR").
+		byte('C').str(sqlState(err)).
+		byte('M').str(err.Error())
+	if pos := errorPosition(err); pos != nil && query != "" {
+		end := pos.Offset
+		if end > len(query) {
+			end = len(query)
+		}
+		m.byte('P').str(strconv.Itoa(utf8.RuneCountInString(query[:end]) + 1))
+	}
+	m.byte(0).send()
+	c.failed = true
+}
+
+// formatCode returns the format code of the i-th column or parameter,
+// a single code applies to all of them. The codes are checked against the
+// columns by Bind, while the columns missing them, e.g., added to the
+// table since, are in text.
+func formatCode(formats []int16, i int) int16 {
+	switch {
+	case len(formats) == 1:
+		return formats[0]
+	case i < len(formats):
+		return formats[i]
+	}
+	return formatText
+}
+
+// encodeValue encodes the non-NULL value of the column type, the size
+// of the binary integers is of the type. In the binary format, the values
+// of the other types are converted to the type, or else sent as the text
+// if the binary format of the type is the text, i.e., the strings. The
+// values of the other types cannot be sent in the binary format, which
+// the column is described in.
+func encodeValue(val any, typ *Type, format int16) ([]byte, error) {
+	if format == formatBinary && !typeOf(val).compatible(typ) {
+		// e.g., the values of the types unknown until evaluated are
+		// described as the strings, see Table.selectList
+		v, ok, err := typ.assign(val)
+		switch {
+		case err != nil:
+			return nil, err
+		case ok:
+			val = v
+		case typ.id == stringID || typ.id == varcharID:
+			return encodeValue(val, typ, formatText)
+		default:
+			return nil, newError(codeDatatypeMismatch,
+				"cannot send the value of type %s in the binary format of "+
+					"type %s", valueType(val), typ)
+		}
+	}
+	if format == formatBinary {
+		switch v := val.(type) {
+		case int:
+			_, size := typeToOID(typ)
+			raw := make([]byte, 8)
+			binary.BigEndian.PutUint64(raw, uint64(v))
+			switch size {
+			case 2:
+				binary.BigEndian.PutUint16(raw, uint16(v))
+			case 4:
+				binary.BigEndian.PutUint32(raw, uint32(v))
+			default:
+				size = 8
+			}
+			return raw[:size], nil
+		case float64:
+			raw := make([]byte, 8)
+			binary.BigEndian.PutUint64(raw, math.Float64bits(v))
+			return raw, nil
+		case Decimal:
+			return encodeNumeric(v), nil
+		case Bytes:
+			return v, nil
+		case Date, TimeOfDay, Timestamp, TimestampTZ, Interval:
+			return encodeTemporal(v), nil
+		case JSON:
+			// the binary JSONB is the text following the version 1
+			if v.binary {
+				return append([]byte{1}, v.String()...), nil
+			}
+		case UUID:
+			return v[:], nil
+		case Array:
+			return encodeArray(v, typ.elem)
+		case bool:
+			if v {
+				return []byte{1}, nil
+			}
+			return []byte{0}, nil
+		}
+	}
+	return encodeText(val, typ), nil
+}
+
+// encodeText encodes the non-NULL value of the column type in the text
+// format.
+func encodeText(val any, typ *Type) []byte {
+	switch v := val.(type) {
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10)
+	case float64:
+		return strconv.AppendFloat(nil, v, 'g', -1, 64)
+	case bool:
+		if v {
+			return []byte{'t'}
+		}
+		return []byte{'f'}
+	case string:
+		return []byte(v)
+	case Timestamp:
+		return []byte(v.Format("2006-01-02 15:04:05.999999"))
+	case TimestampTZ:
+		return []byte(v.Format("2006-01-02 15:04:05.999999-07"))
+	case Interval:
+		return []byte(v.pgString())
+	case Array:
+		return []byte(formatArray(v.elems, func(e any) string {
+			return string(encodeText(e, typ.elem))
+		}))
+	case fmt.Stringer:
+		return []byte(v.String())
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
+
+// numericNegative is the sign of the negative numerics in the binary
+// format, whose digits are in base 10000.
+const numericNegative = 0x4000
+
+// encodeNumeric encodes the decimal in the binary format of NUMERIC, i.e.,
+// the number of digits, the weight of the first digit, the sign, the
+// display scale and the digits.
+func encodeNumeric(d Decimal) []byte {
+	// pad the fraction to the whole digits of base 10000
+	frac := (d.scale + 3) / 4
+	digits := new(big.Int).Abs(d.rescale(frac * 4).coef).String()
+	if pad := len(digits) % 4; pad != 0 {
+		digits = strings.Repeat("0", 4-pad) + digits
+	}
+	var groups []uint16
+	for i := 0; i < len(digits); i += 4 {
+		g, _ := strconv.Atoi(digits[i : i+4])
+		groups = append(groups, uint16(g))
+	}
+	weight := len(groups) - frac - 1
+	for len(groups) > 0 && groups[0] == 0 {
+		groups, weight = groups[1:], weight-1
+	}
+	for len(groups) > 0 && groups[len(groups)-1] == 0 {
+		groups = groups[:len(groups)-1]
+	}
+	if len(groups) == 0 {
+		weight = 0
+	}
+	sign := 0
+	if d.coef.Sign() < 0 {
+		sign = numericNegative
+	}
+	raw := make([]byte, 8, 8+2*len(groups))
+	binary.BigEndian.PutUint16(raw, uint16(len(groups)))
+	binary.BigEndian.PutUint16(raw[2:], uint16(int16(weight)))
+	binary.BigEndian.PutUint16(raw[4:], uint16(sign))
+	binary.BigEndian.PutUint16(raw[6:], uint16(d.scale))
+	for _, g := range groups {
+		raw = append(raw, byte(g>>8), byte(g))
+	}
+	return raw
+}
+
+// decodeNumeric decodes the NUMERIC in the binary format.
+func decodeNumeric(raw []byte) (Decimal, bool) {
+	if len(raw) < 8 {
+		return Decimal{}, false
+	}
+	n := int(binary.BigEndian.Uint16(raw))
+	weight := int(int16(binary.BigEndian.Uint16(raw[2:])))
+	sign := binary.BigEndian.Uint16(raw[4:])
+	scale := int(binary.BigEndian.Uint16(raw[6:]))
+	if len(raw) != 8+2*n || (sign != 0 && sign != numericNegative) {
+		return Decimal{}, false
+	}
+	coef := new(big.Int)
+	for i := 0; i < n; i++ {
+		coef.Mul(coef, big.NewInt(10000))
+		coef.Add(coef, big.NewInt(int64(binary.BigEndian.Uint16(raw[8+2*i:]))))
+	}
+	if sign == numericNegative {
+		coef.Neg(coef)
+	}
+	// the last digit is of the weight 10000^(weight - n + 1)
+	d := Decimal{coef: coef}
+	if exp := 4 * (weight - n + 1); exp >= 0 {
+		d.coef.Mul(coef, pow10(exp))
+	} else {
+		d.scale = -exp
+	}
+	return d.rescale(scale), true
+}
+
+// pgEpoch is the epoch of the dates and the timestamps in the binary
+// format.
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// encodeTemporal encodes the date and time value in the binary format,
+// i.e., the days of the date and the microseconds of the timestamps since
+// 2000-01-01, the microseconds of the time, and the microseconds, the days
+// and the months of the interval.
+func encodeTemporal(v any) []byte {
+	var raw []byte
+	switch x := v.(type) {
+	case Date:
+		raw = make([]byte, 4)
+		days := (x.Unix() - pgEpoch.Unix()) / 86400
+		binary.BigEndian.PutUint32(raw, uint32(days))
+	case TimeOfDay:
+		raw = make([]byte, 8)
+		binary.BigEndian.PutUint64(raw, uint64(x))
+	case Interval:
+		raw = make([]byte, 16)
+		binary.BigEndian.PutUint64(raw, uint64(x.micros))
+		binary.BigEndian.PutUint32(raw[8:], uint32(x.days))
+		binary.BigEndian.PutUint32(raw[12:], uint32(x.months))
+	default:
+		t, _ := instant(v)
+		raw = make([]byte, 8)
+		binary.BigEndian.PutUint64(raw, uint64(t.UnixMicro()-pgEpoch.UnixMicro()))
+	}
+	return raw
+}
+
+// decodeTemporal decodes the date and time value of the type in the
+// binary format, see encodeTemporal.
+func decodeTemporal(raw []byte, typ *Type) (any, bool) {
+	switch {
+	case typ.id == dateID && len(raw) == 4:
+		days := int(int32(binary.BigEndian.Uint32(raw)))
+		return Date{pgEpoch.AddDate(0, 0, days)}, true
+	case typ.id == intervalID && len(raw) == 16:
+		return Interval{
+			micros: int64(binary.BigEndian.Uint64(raw)),
+			days:   int(int32(binary.BigEndian.Uint32(raw[8:]))),
+			months: int(int32(binary.BigEndian.Uint32(raw[12:]))),
+		}, true
+	case typ.id != dateID && typ.id != intervalID && len(raw) == 8:
+		micros := int64(binary.BigEndian.Uint64(raw))
+		t := time.UnixMicro(pgEpoch.UnixMicro() + micros).UTC()
+		switch typ.id {
+		case timeID:
+			return TimeOfDay(micros), true
+		case timestampID:
+			return Timestamp{t}, true
+		}
+		return TimestampTZ{t}, true
+	}
+	return nil, false
+}
+
+// decodeParam decodes the parameter of the type, which is checked as
+// stored in the column of the type.
+func decodeParam(raw []byte, typ *Type, format int16) (any, error) {
+	if format != formatBinary {
+		return typ.parse(string(raw))
+	}
+	var (
+		val any
+		ok  bool
+	)
+	switch typ.id {
+	case smallintID, integerID, bigintID:
+		switch len(raw) {
+		case 2:
+			val, ok = int(int16(binary.BigEndian.Uint16(raw))), true
+		case 4:
+			val, ok = int(int32(binary.BigEndian.Uint32(raw))), true
+		case 8:
+			val, ok = int(int64(binary.BigEndian.Uint64(raw))), true
+		}
+	case floatID:
+		switch len(raw) {
+		case 4:
+			val, ok = float64(math.Float32frombits(
+				binary.BigEndian.Uint32(raw))), true
+		case 8:
+			val, ok = math.Float64frombits(binary.BigEndian.Uint64(raw)), true
+		}
+	case decimalID:
+		val, ok = decodeNumeric(raw)
+	case booleanID:
+		val, ok = len(raw) == 1 && raw[0] != 0, len(raw) == 1
+	case byteaID:
+		val, ok = Bytes(append([]byte(nil), raw...)), true
+	case dateID, timeID, timestampID, timestamptzID, intervalID:
+		val, ok = decodeTemporal(raw, typ)
+	case jsonbID:
+		if ok = len(raw) > 0 && raw[0] == 1; ok {
+			val = string(raw[1:])
+		}
+	case uuidID:
+		var u UUID
+		if ok = len(raw) == len(u); ok {
+			copy(u[:], raw)
+			val = u
+		}
+	case arrayID:
+		return decodeArray(raw, typ)
+	default:
+		val, ok = string(raw), true
+	}
+	if !ok {
+		return nil, newError(codeInvalidTextRepresent,
+			"invalid binary representation of %s", typ)
+	}
+	val, _, err := typ.assign(val)
+	return val, err
+}
+
+// encodeArray encodes the array in the binary format, i.e., the number of
+// the dimensions, which is 0 for the empty arrays, whether there is NULL,
+// the type of the elements, the length and the lower bound 1 of the
+// dimension, and the elements preceded by their lengths, -1 for NULL.
+func encodeArray(a Array, elem *Type) ([]byte, error) {
+	oid, _ := typeToOID(elem)
+	raw := make([]byte, 12, 20)
+	hasNull := 0
+	for _, e := range a.elems {
+		if e == nil {
+			hasNull = 1
+		}
+	}
+	binary.BigEndian.PutUint32(raw[4:], uint32(hasNull))
+	binary.BigEndian.PutUint32(raw[8:], oid)
+	if len(a.elems) == 0 {
+		return raw, nil
+	}
+	binary.BigEndian.PutUint32(raw, 1)
+	raw = appendUint32(raw, uint32(len(a.elems)))
+	raw = appendUint32(raw, 1)
+	for _, e := range a.elems {
+		if e == nil {
+			raw = appendUint32(raw, math.MaxUint32)
+			continue
+		}
+		b, err := encodeValue(e, elem, formatBinary)
+		if err != nil {
+			return nil, err
+		}
+		raw = appendUint32(raw, uint32(len(b)))
+		raw = append(raw, b...)
+	}
+	return raw, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// decodeArray decodes the one-dimensional array of the type in the binary
+// format, see encodeArray.
+func decodeArray(raw []byte, typ *Type) (any, error) {
+	invalid := newError(codeInvalidTextRepresent,
+		"invalid binary representation of %s", typ)
+	if len(raw) < 12 {
+		return nil, invalid
+	}
+	ndim := readInt32(&raw)
+	readInt32(&raw)
+	readInt32(&raw)
+	a := Array{elem: typ.elem, elems: []any{}}
+	switch {
+	case ndim == 0:
+		return a, nil
+	case ndim != 1:
+		return nil, newError(codeFeatureNotSupported,
+			"multidimensional arrays are not supported")
+	case len(raw) < 8:
+		return nil, invalid
+	}
+	n := int(readInt32(&raw))
+	readInt32(&raw)
+	for i := 0; i < n; i++ {
+		if len(raw) < 4 {
+			return nil, invalid
+		}
+		size := int(readInt32(&raw))
+		if size < 0 {
+			a.elems = append(a.elems, nil)
+			continue
+		}
+		if size > len(raw) {
+			return nil, invalid
+		}
+		v, err := decodeParam(raw[:size], typ.elem, formatBinary)
+		if err != nil {
+			return nil, err
+		}
+		a.elems, raw = append(a.elems, v), raw[size:]
+	}
+	return a, nil
+}
+
+// pgMessage builds a backend message.
+type pgMessage struct {
+	c   *pgConn
+	typ byte
+	buf []byte
+}
+
+func (c *pgConn) msg(typ byte) *pgMessage {
+	return &pgMessage{c: c, typ: typ}
+}
+
+func (m *pgMessage) byte(b byte) *pgMessage {
+	m.buf = append(m.buf, b)
+	return m
+}
+
+func (m *pgMessage) bytes(b []byte) *pgMessage {
+	m.buf = append(m.buf, b...)
+	return m
+}
+
+func (m *pgMessage) str(s string) *pgMessage {
+	m.buf = append(append(m.buf, s...), 0)
+	return m
+}
+
+func (m *pgMessage) int16(n int16) *pgMessage {
+	m.buf = append(m.buf, byte(n>>8), byte(n))
+	return m
+}
+
+func (m *pgMessage) int32(n int32) *pgMessage {
+	m.buf = append(m.buf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	return m
+}
+
+// send writes the message to the buffer of the connection, the write
+// error is reported by the following flush.
+func (m *pgMessage) send() {
+	w := m.c.w
+	_ = w.WriteByte(m.typ)
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(m.buf)+4))
+	_, _ = w.Write(length[:])
+	_, _ = w.Write(m.buf)
+}
+
+func readString(body *[]byte) string {
+	b := *body
+	for i, c := range b {
+		if c == 0 {
+			*body = b[i+1:]
+			return string(b[:i])
+		}
+	}
+	*body = nil
+	return string(b)
+}
+
+// readCount reads the number of the fields following it in the message,
+// which must not be negative.
+func readCount(body *[]byte) (int, error) {
+	n := readInt16(body)
+	if n < 0 {
+		return 0, newError(codeProtocolViolation,
+			"invalid field count %d in message", n)
+	}
+	return int(n), nil
+}
+
+// readFormats reads the format codes of the parameters or the columns,
+// each of which is either text or binary.
+func readFormats(body *[]byte) ([]int16, error) {
+	n, err := readCount(body)
+	if err != nil {
+		return nil, err
+	}
+	formats := make([]int16, n)
+	for i := range formats {
+		formats[i] = readInt16(body)
+		if formats[i] != formatText && formats[i] != formatBinary {
+			return nil, newError(codeProtocolViolation,
+				"unsupported format code: %d", formats[i])
+		}
+	}
+	return formats, nil
+}
+
+func readInt16(body *[]byte) int16 {
+	b := *body
+	if len(b) < 2 {
+		*body = nil
+		return 0
+	}
+	*body = b[2:]
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func readInt32(body *[]byte) int32 {
+	b := *body
+	if len(b) < 4 {
+		*body = nil
+		return 0
+	}
+	*body = b[4:]
+	return int32(binary.BigEndian.Uint32(b))
+}
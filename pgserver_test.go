@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// pgClient is a minimal frontend of the PostgreSQL protocol.
+type pgClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+type pgReply struct {
+	typ  byte
+	body []byte
+}
+
+const testPassword = "secret"
+
+// dialPG connects to the server as the superuser, the caller should
+// wait for the ReadyForQuery if the password is correct.
+func dialPG(t *testing.T, addr, password string) *pgClient {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", addr, err)
+	}
+	c := &pgClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+
+	// StartupMessage
+	body := be32(pgProtocolVersion)
+	body = append(body, "user\x00"+defaultSuperuser+"\x00\x00"...)
+	c.write(be32(uint32(len(body)+4)), body)
+	// AuthenticationCleartextPassword
+	c.until('R')
+	c.send('p', []byte(password+"\x00"))
+	return c
+}
+
+func be32(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, n)
+	return b
+}
+
+func (c *pgClient) write(bs ...[]byte) {
+	for _, b := range bs {
+		if _, err := c.conn.Write(b); err != nil {
+			c.t.Fatalf("failed to write: %v", err)
+		}
+	}
+}
+
+func (c *pgClient) send(typ byte, body []byte) {
+	c.write([]byte{typ},
+		be32(uint32(len(body)+4)), body)
+}
+
+// until reads the replies until the message of type typ.
+func (c *pgClient) until(typ byte) []pgReply {
+	var replies []pgReply
+	for {
+		var hdr [5]byte
+		if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+			c.t.Fatalf("failed to read: %v", err)
+		}
+		body := make([]byte, binary.BigEndian.Uint32(hdr[1:])-4)
+		if _, err := io.ReadFull(c.r, body); err != nil {
+			c.t.Fatalf("failed to read: %v", err)
+		}
+		replies = append(replies, pgReply{typ: hdr[0], body: body})
+		if hdr[0] == typ {
+			return replies
+		}
+	}
+}
+
+func replyTypes(replies []pgReply) string {
+	var types []byte
+	for _, r := range replies {
+		types = append(types, r.typ)
+	}
+	return string(types)
+}
+
+// dataRows returns the text values of the DataRows, NULL is empty.
+func dataRows(replies []pgReply) [][]string {
+	var rows [][]string
+	for _, r := range replies {
+		if r.typ != 'D' {
+			continue
+		}
+		body := r.body
+		row := make([]string, readInt16(&body))
+		for i := range row {
+			n := readInt32(&body)
+			if n < 0 {
+				continue
+			}
+			row[i] = string(body[:n])
+			body = body[n:]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// errorCode returns the SQLSTATE of the ErrorResponse.
+func errorCode(replies []pgReply) string {
+	return errorField(replies, 'C')
+}
+
+// errorField returns the field of the first error response.
+func errorField(replies []pgReply, typ byte) string {
+	for _, r := range replies {
+		if r.typ != 'E' {
+			continue
+		}
+		body := r.body
+		for len(body) > 1 {
+			field := body[0]
+			body = body[1:]
+			val := readString(&body)
+			if field == typ {
+				return val
+			}
+		}
+	}
+	return ""
+}
+
+func startPGServer(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	db := NewDatabase()
+	if err := db.SetPassword(defaultSuperuser, testPassword); err != nil {
+		t.Fatalf("failed to set the password: %v", err)
+	}
+	go NewPGServer(db).Serve(ln)
+	return ln.Addr().String()
+}
+
+func TestPGAuthentication(t *testing.T) {
+	addr := startPGServer(t)
+	c := dialPG(t, addr, "wrong")
+	defer c.conn.Close()
+	if got := errorCode(c.until('E')); got != codeInvalidPassword {
+		t.Fatalf("got SQLSTATE(%s), expect(%s)", got, codeInvalidPassword)
+	}
+
+	c = dialPG(t, addr, testPassword)
+	defer c.conn.Close()
+	if got := replyTypes(c.until('Z')); got[0] != 'R' {
+		t.Fatalf("got replies(%s), expect AuthenticationOk", got)
+	}
+}
+
+func TestPGSimpleQuery(t *testing.T) {
+	c := dialPG(t, startPGServer(t), testPassword)
+	defer c.conn.Close()
+	c.until('Z')
+
+	c.send('Q', []byte("create table t (id integer primary key, "+
+		"name string); insert into t (id, name) values (1, 'a');\x00"))
+	if got := replyTypes(c.until('Z')); got != "CCZ" {
+		t.Fatalf("got replies(%s), expect(CCZ)", got)
+	}
+
+	c.send('Q', []byte("select * from t\x00"))
+	replies := c.until('Z')
+	if got := replyTypes(replies); got != "TDCZ" {
+		t.Fatalf("got replies(%s), expect(TDCZ)", got)
+	}
+	if got, expect := dataRows(replies),
+		[][]string{{"1", "a"}}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got rows(%v), expect(%v)", got, expect)
+	}
+
+	c.send('Q', []byte("select * from missing\x00"))
+	replies = c.until('Z')
+	if got := errorCode(replies); got != codeUndefinedTable {
+		t.Fatalf("got SQLSTATE(%s), expect(%s)", got, codeUndefinedTable)
+	}
+
+	// the position of the syntax error is counted from the query
+	c.send('Q', []byte("select * from t; select * form t\x00"))
+	replies = c.until('Z')
+	if got := errorCode(replies); got != codeSyntaxError {
+		t.Fatalf("got SQLSTATE(%s), expect(%s)", got, codeSyntaxError)
+	}
+	if got := errorField(replies, 'P'); got != "27" {
+		t.Fatalf("got position(%s), expect(27)", got)
+	}
+}
+
+func TestPGExtendedQuery(t *testing.T) {
+	c := dialPG(t, startPGServer(t), testPassword)
+	defer c.conn.Close()
+	c.until('Z')
+
+	c.send('Q', []byte("create table t (id integer primary key, "+
+		"name string)\x00"))
+	c.until('Z')
+
+	// Parse
+	c.send('P', []byte("ins\x00insert into t (id, name) "+
+		"values ($1, $2)\x00\x00\x00"))
+	// Describe the statement
+	c.send('D', []byte("Sins\x00"))
+	// Bind, the id is in binary and the name in text
+	bind := []byte("\x00ins\x00")
+	bind = append(bind, 0, 2, 0, 1, 0, 0)
+	bind = append(bind, 0, 2)
+	bind = append(bind, 0, 0, 0, 8)
+	bind = append(bind, 0, 0, 0, 0, 0, 0, 0, 42)
+	bind = append(bind, 0, 0, 0, 1, 'b')
+	bind = append(bind, 0, 0)
+	c.send('B', bind)
+	// Execute and Sync
+	c.send('E', []byte("\x00\x00\x00\x00\x00"))
+	c.send('S', nil)
+	replies := c.until('Z')
+	if got := replyTypes(replies); got != "1tn2CZ" {
+		t.Fatalf("got replies(%s), expect(1tn2CZ)", got)
+	}
+	body := replies[1].body
+	if n := readInt16(&body); n != 2 {
+		t.Fatalf("got %d parameters, expect 2", n)
+	}
+	if oid := readInt32(&body); oid != oidInt4 {
+		t.Fatalf("got parameter type %d, expect %d", oid, oidInt8)
+	}
+
+	c.send('P', []byte("\x00select * from t\x00\x00\x00"))
+	c.send('B', []byte("\x00\x00\x00\x00\x00\x00\x00\x00"))
+	c.send('D', []byte("P\x00"))
+	c.send('E', []byte("\x00\x00\x00\x00\x00"))
+	c.send('S', nil)
+	replies = c.until('Z')
+	if got := replyTypes(replies); got != "12TDCZ" {
+		t.Fatalf("got replies(%s), expect(12TDCZ)", got)
+	}
+	if got, expect := dataRows(replies),
+		[][]string{{"42", "b"}}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got rows(%v), expect(%v)", got, expect)
+	}
+
+	// the NULL parameter is bound by the length -1
+	c.send('P', []byte("\x00insert into t (id, name) values ($1, $2)\x00\x00\x00"))
+	c.send('B', []byte("\x00\x00\x00\x00\x00\x02\x00\x00\x00\x0243"+
+		"\xff\xff\xff\xff\x00\x00"))
+	c.send('E', []byte("\x00\x00\x00\x00\x00"))
+	c.send('Q', []byte("select * from t where name is null\x00"))
+	replies = c.until('Z')
+	if got, expect := dataRows(replies),
+		[][]string{{"43", ""}}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got rows(%v), expect(%v)", got, expect)
+	}
+
+	// the messages following an error are discarded until the Sync
+	c.send('P', []byte("\x00select from\x00\x00\x00"))
+	c.send('B', []byte("\x00\x00\x00\x00\x00\x00\x00\x00"))
+	c.send('S', nil)
+	replies = c.until('Z')
+	if got := replyTypes(replies); got != "EZ" {
+		t.Fatalf("got replies(%s), expect(EZ)", got)
+	}
+	if got := errorCode(replies); got != codeSyntaxError {
+		t.Fatalf("got SQLSTATE(%s), expect(%s)", got, codeSyntaxError)
+	}
+}
+
+func TestPGBinaryResults(t *testing.T) {
+	c := dialPG(t, startPGServer(t), testPassword)
+	defer c.conn.Close()
+	c.until('Z')
+	c.send('Q', []byte("create table t (id integer primary key, at timestamp);"+
+		"insert into t values (1, '2026-01-31 12:00:00')\x00"))
+	c.until('Z')
+
+	c.send('P', []byte("\x00select at + interval '1 day', "+
+		"CASE WHEN id = 1 THEN 1 ELSE 2.5 END from t\x00\x00\x00"))
+	// Bind, all the results are in binary
+	c.send('B', []byte("\x00\x00\x00\x00\x00\x00\x00\x01\x00\x01"))
+	c.send('D', []byte("P\x00"))
+	c.send('E', []byte("\x00\x00\x00\x00\x00"))
+	c.send('S', nil)
+	replies := c.until('Z')
+	if got := replyTypes(replies); got != "12TDCZ" {
+		t.Fatalf("got replies(%s), expect(12TDCZ)", got)
+	}
+	body := replies[2].body
+	var oids []int32
+	for n := readInt16(&body); n > 0; n-- {
+		readString(&body)
+		readInt32(&body)
+		readInt16(&body)
+		oids = append(oids, readInt32(&body))
+		readInt16(&body)
+		readInt32(&body)
+		readInt16(&body)
+	}
+	if expect := []int32{oidTimestamp, oidNumeric}; !reflect.DeepEqual(oids, expect) {
+		t.Fatalf("got types %v, expect %v", oids, expect)
+	}
+	at := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC).Sub(pgEpoch).Microseconds()
+	// the numeric 1 is of one digit, the weight 0, the sign 0 and the scale 0
+	expect := [][]string{{string(be64(uint64(at))),
+		"\x00\x01\x00\x00\x00\x00\x00\x00\x00\x01"}}
+	if got := dataRows(replies); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got rows(%q), expect(%q)", got, expect)
+	}
+
+	// the values unknown until evaluated are sent as the described types
+	tts := []struct {
+		name   string
+		val    any
+		typ    *Type
+		expect []byte
+	}{
+		{"Integer as float", 1, floatType, be64(math.Float64bits(1))},
+		{"Timestamp as string", Timestamp{time.Date(2026, 2, 1, 12, 0, 0, 0,
+			time.UTC)}, stringType, []byte("2026-02-01 12:00:00")},
+		{"Decimal as float", dec(t, "2.5"), floatType, be64(math.Float64bits(2.5))},
+	}
+	for i, tt := range tts {
+		if got, err := encodeValue(tt.val, tt.typ, formatBinary); err != nil ||
+			!bytes.Equal(got, tt.expect) {
+			t.Fatalf("case %d (%s) failed: got %q (%v), expect %q", i, tt.name,
+				got, err, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+	// the values of the other types are never sent as the text in binary
+	if got, err := encodeValue(true, integerType, formatBinary); sqlState(err) !=
+		codeDatatypeMismatch {
+		t.Fatalf("got %q (%v), expect error(%s)", got, err, codeDatatypeMismatch)
+	}
+}
+
+func be64(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
+
+func TestPGMalformedMessages(t *testing.T) {
+	addr := startPGServer(t)
+	c := dialPG(t, addr, testPassword)
+	defer c.conn.Close()
+	c.until('Z')
+	c.send('Q', []byte("create table t (id integer primary key, "+
+		"name string, n integer)\x00"))
+	c.until('Z')
+
+	c.send('P', []byte("s\x00select * from t where id = $1\x00\x00\x00"))
+	tts := []struct {
+		name string
+		bind []byte
+	}{
+		{"Negative parameter formats", []byte("\x00s\x00\xff\xff")},
+		{"Negative parameters", []byte("\x00s\x00\x00\x00\xff\xff")},
+		{"Parameter formats mismatch", []byte("\x00s\x00\x00\x02\x00\x00\x00\x00" +
+			"\x00\x01\x00\x00\x00\x011\x00\x00")},
+		{"Negative result formats", []byte("\x00s\x00\x00\x00" +
+			"\x00\x01\x00\x00\x00\x011\xff\xff")},
+		{"Result formats mismatch", []byte("\x00s\x00\x00\x00" +
+			"\x00\x01\x00\x00\x00\x011\x00\x02\x00\x01\x00\x01")},
+		{"Unsupported parameter format", []byte("\x00s\x00\x00\x01\x00\x02" +
+			"\x00\x01\x00\x00\x00\x011\x00\x00")},
+		{"Unsupported result format", []byte("\x00s\x00\x00\x00" +
+			"\x00\x01\x00\x00\x00\x011\x00\x01\x00\x02")},
+	}
+	for i, tt := range tts {
+		c.send('B', tt.bind)
+		c.send('S', nil)
+		replies := c.until('Z')
+		if got := errorCode(replies); got != codeProtocolViolation {
+			t.Fatalf("case %d (%s) failed: got SQLSTATE(%s), expect(%s)",
+				i, tt.name, got, codeProtocolViolation)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	// the connection is still served
+	c.send('Q', []byte("select * from t\x00"))
+	if got := replyTypes(c.until('Z')); got != "TCZ" {
+		t.Fatalf("got replies(%s), expect(TCZ)", got)
+	}
+
+	// the long messages are rejected before they are read
+	c.send('Q', []byte("insert into t values (1, 'a', 2)\x00"))
+	c.until('Z')
+	c.write([]byte{'Q'}, be32(pgMaxMessageLength+1))
+	if got := errorCode(c.until('E')); got != codeProtocolViolation {
+		t.Fatalf("got SQLSTATE(%s), expect(%s)", got, codeProtocolViolation)
+	}
+
+	// and so are the long passwords
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", addr, err)
+	}
+	defer conn.Close()
+	c = &pgClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	body := be32(pgProtocolVersion)
+	body = append(body, "user\x00"+defaultSuperuser+"\x00\x00"...)
+	c.write(be32(uint32(len(body)+4)), body)
+	c.until('R')
+	c.write([]byte{'p'}, be32(0xfffffff0))
+	if got := errorCode(c.until('E')); got != codeProtocolViolation {
+		t.Fatalf("got SQLSTATE(%s), expect(%s)", got, codeProtocolViolation)
+	}
+}
+
+func TestPGCommandTag(t *testing.T) {
+	tts := []struct {
+		sql    string
+		expect string
+	}{
+		{"create table t (id integer primary key)", "CREATE TABLE"},
+		{"create user u password 'p'", "CREATE ROLE"},
+		{"alter user u superuser", "ALTER ROLE"},
+		{"drop user u", "DROP ROLE"},
+		{"grant select on t to u", "GRANT"},
+		{"revoke select on t from u", "REVOKE"},
+		{"create policy p on t using (id > 0)", "CREATE POLICY"},
+		{"drop policy p on t", "DROP POLICY"},
+		{"alter table t enable row level security", "ALTER TABLE"},
+		{"create sequence s", "CREATE SEQUENCE"},
+		{"drop sequence s", "DROP SEQUENCE"},
+		{"create index i on t (id)", "CREATE INDEX"},
+		{"drop index i", "DROP INDEX"},
+	}
+	for i, tt := range tts {
+		sts, err := parseSQL(tt.sql)
+		if err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.sql, err)
+		}
+		if got := commandTag(sts, &Result{}); got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.sql, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.sql)
+	}
+}
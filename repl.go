@@ -0,0 +1,543 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const replHelp = `General
+  \q                     quit
+  \?                     show this help
+  \timing [on|off]       toggle the timing of the statements
+
+Informational
+  \d [table]             describe the table, or list the tables
+  \dt                    list the tables
+  \di                    list the indexes
+
+Formatting
+  \pset [name [value]]   set the output option, i.e., format or null
+  \x [on|off]            toggle the expanded output
+  \format [sql]          format the SQL, or the last statement
+
+Input/Output
+  \i file                execute the statements from the file
+  \o [file]              send the query results to the file, or stdout
+`
+
+// the values of -on-error
+const (
+	onErrorStop     = "stop"
+	onErrorContinue = "continue"
+)
+
+// repl reads the statements and the meta-commands, i.e., the lines
+// starting with a backslash, line by line and executes them.
+type repl struct {
+	ctx context.Context
+	db  *Database
+	it  *interrupter
+	// stdout receives the messages of the meta-commands, out receives the
+	// query results and can be redirected by \o
+	stdout io.Writer
+	stderr io.Writer
+	out    io.Writer
+	// file is the file opened by \o
+	file   *os.File
+	opts   *printOptions
+	timing bool
+	quit   bool
+	// failed tells if any statement or meta-command failed, which quits
+	// the REPL if stopOnError is set
+	failed      bool
+	stopOnError bool
+	// buf is the incomplete statement, quote is the quote of the string
+	// or the identifier it ends within, and comment is the depth of the
+	// block comments it ends within
+	buf     []rune
+	quote   rune
+	comment int
+	// last is the last executed statement
+	last string
+}
+
+func newREPL(ctx context.Context, db *Database, it *interrupter,
+	stdout, stderr io.Writer) *repl {
+	return &repl{
+		ctx:    ctx,
+		db:     db,
+		it:     it,
+		stdout: stdout,
+		stderr: stderr,
+		out:    stdout,
+		opts:   defaultPrintOptions(),
+	}
+}
+
+func (r *repl) prompt() string {
+	switch {
+	case len(r.buf) == 0:
+		return "@simple-db=> "
+	case r.comment > 0:
+		return "@simple-db*> "
+	case r.quote != 0:
+		return "@simple-db" + string(r.quote) + "> "
+	default:
+		return "@simple-db-> "
+	}
+}
+
+// run reads the input until EOF or \q.
+func (r *repl) run(in lineReader) error {
+	// lines are the lines of the statements in the buffer, which are
+	// recorded in the history as a single entry once they are executed
+	var lines []string
+	for !r.quit {
+		line, err := in.ReadLine(r.prompt())
+		if err == errAborted {
+			// discard the incomplete statement
+			r.reset()
+			lines = nil
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// the line comments are dropped from the history, which joins the
+		// lines into one
+		code := strings.TrimRight(r.feed(line), " \t")
+		if strings.TrimSpace(code) != "" {
+			lines = append(lines, code)
+		}
+		if len(r.buf) == 0 && len(lines) != 0 {
+			in.AppendHistory(strings.Join(lines, " "))
+			lines = nil
+		}
+	}
+	return nil
+}
+
+// feed handles a line of the input. The meta-commands are only
+// recognized at the beginning of a statement, the statements are
+// executed once they are terminated by a semicolon. It returns the line
+// without its line comment, if any.
+func (r *repl) feed(line string) string {
+	if len(r.buf) == 0 && strings.HasPrefix(strings.TrimSpace(line), `\`) {
+		r.metaCommand(strings.TrimSpace(line))
+		return line
+	}
+	rs := []rune(line)
+	// code is the end of the line before the line comment
+	code := len(rs)
+	for i := 0; i < len(rs); i++ {
+		rn, next := rs[i], rune(0)
+		if i+1 < len(rs) {
+			next = rs[i+1]
+		}
+		switch {
+		case r.comment > 0:
+			if rn == '*' && next == '/' {
+				r.comment--
+				r.buf, i = append(r.buf, rn), i+1
+				rn = next
+			} else if rn == '/' && next == '*' {
+				r.comment++
+				r.buf, i = append(r.buf, rn), i+1
+				rn = next
+			}
+		case r.quote != 0:
+			// the doubled quote ends and starts the quotation again
+			if rn == r.quote {
+				r.quote = 0
+			}
+		case rn == '\'' || rn == '"':
+			r.quote = rn
+		case rn == '-' && next == '-':
+			// the rest of the line is a comment
+			code = i
+			r.buf, i = append(r.buf, rs[i:len(rs)-1]...), len(rs)-1
+			rn = rs[i]
+		case rn == '/' && next == '*':
+			r.comment++
+			r.buf, i = append(r.buf, rn), i+1
+			rn = next
+		case rn == ';':
+			if strings.TrimSpace(string(r.buf)) != "" {
+				r.execute(r.buf)
+			}
+			r.buf = nil
+			if r.quit {
+				// the rest of the line is skipped on error
+				return line
+			}
+			continue
+		}
+		r.buf = append(r.buf, rn)
+	}
+	if r.blank() {
+		r.buf = nil
+	} else {
+		r.buf = append(r.buf, '\n')
+	}
+	return string(rs[:code])
+}
+
+func (r *repl) errorf(format string, a ...any) {
+	fmt.Fprintf(r.stderr, "[ERROR] "+format+"\n", a...)
+	r.failed = true
+	if r.stopOnError {
+		r.quit = true
+	}
+}
+
+// statementError reports the err with its SQLSTATE code, and prints the
+// line of the statement with a caret under the position of the error,
+// e.g.,
+//
+//	LINE 1: select * form t
+//	                 ^
+func (r *repl) statementError(stmt []rune, msg string, err error) {
+	r.errorf("%s: %v (SQLSTATE %s)", msg, err, sqlState(err))
+	pos := errorPosition(err)
+	if pos == nil {
+		return
+	}
+	lines := strings.Split(string(stmt), "\n")
+	if pos.Line > len(lines) {
+		return
+	}
+	// the tabs are replaced so that the caret is aligned
+	line := []rune(strings.ReplaceAll(lines[pos.Line-1], "\t", " "))
+	col := pos.Column - 1
+	if col > len(line) {
+		col = len(line)
+	}
+	prefix := fmt.Sprintf("LINE %d: ", pos.Line)
+	fmt.Fprintf(r.stderr, "%s%s\n%s^\n", prefix, string(line),
+		strings.Repeat(" ", width(prefix)+col))
+}
+
+// flush executes the statement that is not terminated by a semicolon at
+// the end of the input.
+func (r *repl) flush() {
+	if !r.quit && strings.TrimSpace(string(r.buf)) != "" {
+		r.execute(r.buf)
+	}
+	r.reset()
+}
+
+// blank checks if the buffer has nothing but spaces and comments.
+func (r *repl) blank() bool {
+	if r.quote != 0 || r.comment > 0 {
+		return false
+	}
+	tks, err := Tokenize(r.buf)
+	return err == nil && len(tks) == 0
+}
+
+// reset discards the incomplete statement.
+func (r *repl) reset() {
+	r.buf, r.quote, r.comment = nil, 0, 0
+}
+
+func (r *repl) execute(stmt []rune) {
+	r.last = string(stmt)
+	start := time.Now()
+	defer func() {
+		if r.timing {
+			fmt.Fprintf(r.out, "Time: %.3f ms\n",
+				float64(time.Since(start).Microseconds())/1000)
+		}
+	}()
+
+	sts, err := parseSQL(string(stmt))
+	if err != nil {
+		r.statementError(stmt, "failed to parse the statement", err)
+		return
+	}
+	// the statement consists of comments only
+	if sts == nil {
+		return
+	}
+
+	result := r.it.run(r.ctx, r.db, sts)
+	if result.err != nil {
+		r.statementError(stmt, "failed to interpret the statement",
+			result.err)
+		return
+	}
+	if len(result.message) != 0 {
+		fmt.Fprintln(r.out, result.message)
+	}
+	// the statements returning no rows have no columns
+	if result.cols != nil {
+		rows := make([][]any, len(result.rows))
+		for i, row := range result.rows {
+			rows[i] = row.values
+		}
+		r.print(result.cols, rows)
+	}
+}
+
+// print prints the rows in the format set by \pset.
+func (r *repl) print(cols []string, rows [][]any) {
+	printResult(r.out, r.opts, cols, rows)
+}
+
+func (r *repl) metaCommand(line string) {
+	args := strings.Fields(line)
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case `\q`:
+		r.quit = true
+	case `\?`:
+		fmt.Fprint(r.stdout, replHelp)
+	case `\d`:
+		if len(args) == 0 {
+			r.listTables()
+			return
+		}
+		r.describeTable(args[0])
+	case `\dt`:
+		r.listTables()
+	case `\di`:
+		r.listIndexes()
+	case `\timing`:
+		r.setTiming(args)
+	case `\i`:
+		if len(args) != 1 {
+			r.errorf(`\i: missing required argument`)
+			return
+		}
+		r.include(args[0])
+	case `\o`:
+		r.redirect(args)
+	case `\pset`:
+		r.pset(args)
+	case `\x`:
+		r.setExpanded(args)
+	case `\format`:
+		r.format(strings.TrimSpace(strings.TrimPrefix(line, cmd)))
+	default:
+		r.errorf(`invalid command %s, try \? for help`, cmd)
+	}
+}
+
+// format prints the formatted sql, or the last executed statement if the
+// sql is empty.
+func (r *repl) format(sql string) {
+	if sql == "" {
+		sql = r.last
+	}
+	if strings.TrimSpace(sql) == "" {
+		r.errorf(`\format: no statement to format`)
+		return
+	}
+	out, errs := formatSQL(sql, defaultFormatOptions())
+	for _, err := range errs {
+		r.statementError([]rune(sql), `\format`, err)
+	}
+	if len(errs) == 0 {
+		fmt.Fprint(r.out, out)
+	}
+}
+
+func (r *repl) listTables() {
+	names := r.db.TableNames()
+	if len(names) == 0 {
+		fmt.Fprintln(r.out, "Did not find any relations.")
+		return
+	}
+	var vals [][]any
+	for _, name := range names {
+		info, err := r.db.DescribeTable(name)
+		if err != nil {
+			// the table is dropped in the meantime
+			continue
+		}
+		vals = append(vals, []any{info.Name, "table", info.Owner})
+	}
+	r.print([]string{"Name", "Type", "Owner"}, vals)
+}
+
+func (r *repl) describeTable(name string) {
+	info, err := r.db.DescribeTable(name)
+	if err != nil {
+		r.errorf("%v", err)
+		return
+	}
+	fmt.Fprintf(r.out, "Table \"%s\"\n", info.Name)
+	pk := make(map[string]bool, len(info.PrimaryKey))
+	for _, col := range info.PrimaryKey {
+		pk[col] = true
+	}
+	vals := make([][]any, len(info.Columns))
+	for i, col := range info.Columns {
+		vals[i] = []any{col, info.Types[i],
+			pk[col], info.NotNull[i], info.Defaults[i]}
+	}
+	r.print([]string{"Column", "Type", "Primary key", "Not null", "Default"},
+		vals)
+	if len(info.Constraints) != 0 {
+		fmt.Fprintln(r.out, "Constraints:")
+		for _, c := range info.Constraints {
+			fmt.Fprintln(r.out, "    "+c)
+		}
+	}
+	if len(info.Indexes) != 0 {
+		fmt.Fprintln(r.out, "Indexes:")
+		for _, idx := range info.Indexes {
+			fmt.Fprintln(r.out, "    "+idx)
+		}
+	}
+}
+
+// listIndexes lists the indexes, i.e., the primary keys and the created
+// indexes, of the tables.
+func (r *repl) listIndexes() {
+	var vals [][]any
+	for _, name := range r.db.TableNames() {
+		info, err := r.db.DescribeTable(name)
+		if err != nil {
+			continue
+		}
+		vals = append(vals, []any{info.PrimaryKeyName, info.Name,
+			strings.Join(info.PrimaryKey, ", ")})
+		for _, idx := range info.Indexes {
+			name, cols, _ := strings.Cut(idx, " ")
+			vals = append(vals, []any{name, info.Name,
+				strings.TrimSuffix(strings.TrimPrefix(cols, "("), ")")})
+		}
+	}
+	if len(vals) == 0 {
+		fmt.Fprintln(r.out, "Did not find any relations.")
+		return
+	}
+	r.print([]string{"Name", "Table", "Column"}, vals)
+}
+
+// toggle sets the boolean option by the optional on or off argument,
+// and toggles it if no argument is given.
+func (r *repl) toggle(cmd string, opt *bool, args []string) bool {
+	switch {
+	case len(args) == 0:
+		*opt = !*opt
+	case strings.EqualFold(args[0], "on"):
+		*opt = true
+	case strings.EqualFold(args[0], "off"):
+		*opt = false
+	default:
+		r.errorf(`%s: unrecognized value %s, expect on or off`,
+			cmd, args[0])
+		return false
+	}
+	return true
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func (r *repl) setTiming(args []string) {
+	if r.toggle(`\timing`, &r.timing, args) {
+		fmt.Fprintf(r.stdout, "Timing is %s.\n", onOff(r.timing))
+	}
+}
+
+func (r *repl) setExpanded(args []string) {
+	if r.toggle(`\x`, &r.opts.expanded, args) {
+		fmt.Fprintf(r.stdout, "Expanded display is %s.\n",
+			onOff(r.opts.expanded))
+	}
+}
+
+// pset sets the output options, i.e., "\pset format aligned|csv|tsv|json|
+// ndjson|markdown", "\pset null marker" and "\pset expanded [on|off]",
+// all options are shown if no option is given.
+func (r *repl) pset(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(r.stdout, "expanded %s\nformat   %s\nnull     '%s'\n",
+			onOff(r.opts.expanded), r.opts.format, r.opts.null)
+		return
+	}
+	switch name, vals := args[0], args[1:]; name {
+	case "format":
+		if len(vals) != 0 {
+			if !isOutputFormat(vals[0]) {
+				r.errorf(`\pset: allowed formats are %s`,
+					strings.Join(outputFormats, ", "))
+				return
+			}
+			r.opts.format = vals[0]
+		}
+		fmt.Fprintf(r.stdout, "Output format is %s.\n", r.opts.format)
+	case "null":
+		if len(vals) != 0 {
+			r.opts.null = strings.Trim(vals[0], "'")
+		}
+		fmt.Fprintf(r.stdout, "Null display is \"%s\".\n", r.opts.null)
+	case "expanded", "x":
+		r.setExpanded(vals)
+	default:
+		r.errorf(`\pset: unknown option %s`, name)
+	}
+}
+
+// include executes the file as if it were typed in. The statement left
+// incomplete at the end of the file is reported and discarded, so that
+// it does not continue on the lines typed after.
+func (r *repl) include(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		r.errorf("%v", err)
+		return
+	}
+	defer f.Close()
+	buf, quote, comment := r.buf, r.quote, r.comment
+	r.reset()
+	defer func() {
+		r.buf, r.quote, r.comment = buf, quote, comment
+	}()
+	if err := r.run(newScanReader(f)); err != nil {
+		r.errorf("failed to read %s: %v", path, err)
+		return
+	}
+	if !r.blank() {
+		r.errorf("%s: incomplete statement at the end of the file is "+
+			"discarded", path)
+	}
+}
+
+// redirect sends the query results to the file, or back to stdout if no
+// file is given.
+func (r *repl) redirect(args []string) {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	r.out = r.stdout
+	if len(args) == 0 {
+		return
+	}
+	f, err := os.Create(args[0])
+	if err != nil {
+		r.errorf("%v", err)
+		return
+	}
+	r.file, r.out = f, f
+}
+
+// close closes the file opened by \o.
+func (r *repl) close() {
+	r.redirect(nil)
+}
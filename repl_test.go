@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestREPL() (*repl, *bytes.Buffer) {
+	var buf bytes.Buffer
+	ctx := WithSession(context.Background(), NewSession(defaultSuperuser))
+	return newREPL(ctx, NewDatabase(), &interrupter{}, &buf, &buf), &buf
+}
+
+func TestREPLMetaCommands(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.sql")
+	if err := os.WriteFile(script, []byte(
+		"create table t (id integer primary key,\n"+
+			"name string default 'x' check (name <> ''));\n"+
+			"insert into t (id, name) values (1, 'a;b');\n"), 0644); err != nil {
+		t.Fatalf("failed to write the script: %v", err)
+	}
+	unterminated := filepath.Join(dir, "unterminated.sql")
+	if err := os.WriteFile(unterminated, []byte(
+		"select * from t;\nselect 'a\n"), 0644); err != nil {
+		t.Fatalf("failed to write the script: %v", err)
+	}
+	output := filepath.Join(dir, "output.txt")
+
+	r, buf := newTestREPL()
+	tts := []struct {
+		name   string
+		input  string
+		expect []string
+	}{
+		{"No relations", `\dt`, []string{"Did not find any relations."}},
+		{"Include", `\i ` + script, []string{"TABLE CREATED", "1 ROW INSERTED"}},
+		{"List tables", `\dt`, []string{"| t    | table | simpledb |"}},
+		{"Describe table", `\d t`, []string{`Table "t"`,
+			"| id     | integer | true        |",
+			"| name   | string  | false       | false    | 'x'     |",
+			"Constraints:\n    t_name_check CHECK ((name <> ''))\n"}},
+		{"Describe missing table", `\d missing`, []string{"[ERROR]"}},
+		{"Include unterminated", `\i ` + unterminated, []string{"| a;b  |",
+			"[ERROR] " + unterminated + ": incomplete statement"}},
+		{"After unterminated include", "select id from t;",
+			[]string{"|  1 |", "(1 row)"}},
+		{"List indexes", `\di`, []string{"| t_pkey | t     | id     |"}},
+		{"Timing", `\timing`, []string{"Timing is on."}},
+		{"Timed query", "select * from t;", []string{"| a;b  |", "(1 row)", "Time: "}},
+		{"Comments", "select * /* ; */ from t; -- ;", []string{"| a;b  |"}},
+		{"Format", `\format select id from t where id=1`,
+			[]string{"SELECT id\nFROM t\nWHERE id = 1;\n"}},
+		{"Format last", `\format`, []string{"SELECT *\nFROM t;\n"}},
+		{"Timing off", `\timing off`, []string{"Timing is off."}},
+		{"Help", `\?`, []string{`\timing [on|off]`}},
+		{"Unknown command", `\foo`, []string{`invalid command \foo`}},
+		{"Syntax error", "select *\n  form t;", []string{"(SQLSTATE 42601)",
+			"LINE 2:   form t\n" +
+				"          ^\n"}},
+	}
+	for i, tt := range tts {
+		buf.Reset()
+		if err := r.run(newScanReader(strings.NewReader(tt.input))); err != nil {
+			t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
+		}
+		for _, e := range tt.expect {
+			if !strings.Contains(buf.String(), e) {
+				t.Fatalf("case %d (%s) failed: expect %q in %q",
+					i, tt.name, e, buf.String())
+			}
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	// the query results are redirected while the messages are not
+	buf.Reset()
+	if err := r.run(newScanReader(strings.NewReader(
+		`\o ` + output + "\nselect * from t;\n\\o\n\\timing\n"))); err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+	if strings.Contains(buf.String(), "a;b") ||
+		!strings.Contains(buf.String(), "Timing is on.") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	b, err := os.ReadFile(output)
+	if err != nil || !strings.Contains(string(b), "| a;b  |") {
+		t.Fatalf("unexpected redirected output %q (%v)", b, err)
+	}
+
+	r.run(newScanReader(strings.NewReader("\\q\nselect * from t;\n")))
+	if !r.quit {
+		t.Fatalf("expect the REPL to quit")
+	}
+}
+
+func TestREPLOnError(t *testing.T) {
+	input := "create table t (id integer primary key);\n" +
+		"select * from missing; insert into t (id) values (1);\n" +
+		"insert into t (id) values (2)"
+	for _, stop := range []bool{false, true} {
+		r, buf := newTestREPL()
+		r.stopOnError = stop
+		if err := r.run(newScanReader(strings.NewReader(input))); err != nil {
+			t.Fatalf("failed to run: %v", err)
+		}
+		r.flush()
+		if !r.failed {
+			t.Fatalf("expect the failure to be recorded")
+		}
+		// the statements after the failed one are skipped on stop,
+		// including the one not terminated by a semicolon
+		inserted := strings.Count(buf.String(), "1 ROW INSERTED")
+		if (stop && inserted != 0) || (!stop && inserted != 2) {
+			t.Fatalf("stop(%v): unexpected output %q", stop, buf.String())
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+)
+
+// Policy is a row-level security policy. The rows are visible to the
+// command if they satisfy the using expression, and the new rows must
+// satisfy the check expression, which falls back to the using
+// expression if absent.
+type Policy struct {
+	name string
+	// command is either PrivAll or one of PrivSelect, PrivInsert,
+	// PrivUpdate and PrivDelete
+	command Privilege
+	using   Expr
+	check   Expr
+}
+
+// rowSecurity is the set of policies applied to a command on a table, a
+// nil *rowSecurity means the row-level security is bypassed.
+type rowSecurity struct {
+	policies []*Policy
+}
+
+// rowSecurity returns the policies applied to the command issued by the
+// session user. The superusers and the owner bypass the row-level
+// security. The caller must hold the lock.
+func (db *Database) rowSecurity(ctx context.Context,
+	t *Table, cmd Privilege) *rowSecurity {
+	if !t.rowSecurity {
+		return nil
+	}
+	name := SessionFromContext(ctx).User()
+	if u, exist := db.users[name]; (exist && u.superuser) || t.owner == name {
+		return nil
+	}
+	rs := &rowSecurity{}
+	for _, p := range t.policies {
+		if p.command&cmd != 0 {
+			rs.policies = append(rs.policies, p)
+		}
+	}
+	return rs
+}
+
+// visible checks if the row satisfies the using expression of any of
+// the policies, no row is visible if there is no policy.
+func (rs *rowSecurity) visible(env *evalEnv) (bool, error) {
+	if rs == nil {
+		return true, nil
+	}
+	for _, p := range rs.policies {
+		if p.using == nil {
+			continue
+		}
+		ok, err := evalBool(p.using, env)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// check checks if the new row satisfies the check expression of any of
+// the policies.
+func (rs *rowSecurity) check(env *evalEnv, table string) error {
+	if rs == nil {
+		return nil
+	}
+	for _, p := range rs.policies {
+		e := p.check
+		if e == nil {
+			e = p.using
+		}
+		if e == nil {
+			continue
+		}
+		ok, err := evalBool(e, env)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return newError(codeInsufficientPrivilege,
+		"new row violates row-level security policy for table %s", table)
+}
+
+func (db *Database) CreatePolicy(ctx context.Context, cs *CreatePolicyStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	t, exist := db.tables[cs.table.name]
+	if !exist {
+		return &Result{
+			err: newError(codeUndefinedTable,
+				"relation %s does not exist", cs.table.name),
+		}
+	}
+	for _, p := range t.policies {
+		if p.name == cs.name {
+			return &Result{
+				err: newError(codeDuplicateObject,
+					"policy %s for table %s already exists",
+					cs.name, cs.table.name),
+			}
+		}
+	}
+	t.policies = append(t.policies, &Policy{
+		name:    cs.name,
+		command: cs.command,
+		using:   cs.using,
+		check:   cs.check,
+	})
+	return &Result{
+		message: "POLICY CREATED",
+	}
+}
+
+func (db *Database) DropPolicy(ctx context.Context, ds *DropPolicyStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	t, exist := db.tables[ds.table.name]
+	if !exist {
+		return &Result{
+			err: newError(codeUndefinedTable,
+				"relation %s does not exist", ds.table.name),
+		}
+	}
+	for i, p := range t.policies {
+		if p.name == ds.name {
+			t.policies = append(t.policies[:i], t.policies[i+1:]...)
+			return &Result{
+				message: "POLICY DROPPED",
+			}
+		}
+	}
+	return &Result{
+		err: newError(codeUndefinedObject,
+			"policy %s for table %s does not exist", ds.name, ds.table.name),
+	}
+}
+
+func (db *Database) AlterTable(ctx context.Context, as *AlterTableStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	t, exist := db.tables[as.table.name]
+	if !exist {
+		return &Result{
+			err: newError(codeUndefinedTable,
+				"relation %s does not exist", as.table.name),
+		}
+	}
+	t.rowSecurity = as.rowSecurity
+	return &Result{
+		message: "TABLE ALTERED",
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// execSession executes the sql in the session.
+func execSession(db *Database, sess *Session, sql string) *Result {
+	sts, err := parseSQL(sql)
+	if err != nil {
+		return &Result{err: err}
+	}
+	return db.Interpret(WithSession(context.Background(), sess), sts)
+}
+
+func TestRowLevelSecurity(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create user alice",
+		"create table docs (id integer primary key, tenant string)",
+		"insert into docs (id, tenant) values (1, 'a')",
+		"insert into docs (id, tenant) values (2, 'b')",
+		"insert into docs (id, tenant) values (3, 'a')",
+		"grant all on docs to alice",
+		"alter table docs enable row level security",
+		"create policy tenant_isolation on docs " +
+			"using (tenant = current_setting('app.tenant'))",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	alice := NewSession("alice")
+	tts := []struct {
+		name     string
+		sql      string
+		code     string
+		affected int
+	}{
+		{"Set tenant", "set app.tenant = 'a'", "", 0},
+		{"Select visible rows", "select * from docs", "", 2},
+		{"Update visible rows", "update docs set id = id where tenant = 'b'", "", 0},
+		{"Insert into own tenant", "insert into docs (id, tenant) values (4, 'a')", "", 1},
+		{"Insert into other tenant", "insert into docs (id, tenant) values (5, 'b')",
+			codeInsufficientPrivilege, 0},
+		{"Move row to other tenant", "update docs set tenant = 'b' where id = 1",
+			codeInsufficientPrivilege, 0},
+		{"Delete visible rows", "delete from docs", "", 3},
+		{"Drop policy by non-owner", "drop policy tenant_isolation on docs",
+			codeInsufficientPrivilege, 0},
+		{"Switch tenant", "set app.tenant = 'b'", "", 0},
+		{"Select other tenant", "select * from docs where id > 1", "", 1},
+	}
+	for i, tt := range tts {
+		r := execSession(db, alice, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		n := r.affected
+		if r.cols != nil {
+			n = len(r.rows)
+		}
+		if r.err == nil && n != tt.affected {
+			t.Fatalf("case %d (%s) failed: got %d rows, expect %d",
+				i, tt.name, n, tt.affected)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	// the owner bypasses the row-level security
+	r := execSQL(db, defaultSuperuser, "select * from docs")
+	if r.err != nil || len(r.rows) != 1 {
+		t.Fatalf("expect the owner to see 1 row, got %d (%v)",
+			len(r.rows), r.err)
+	}
+	// no row is visible without any policy
+	if r := execSQL(db, defaultSuperuser,
+		"drop policy tenant_isolation on docs"); r.err != nil {
+		t.Fatalf("failed to drop the policy: %v", r.err)
+	}
+	r = execSession(db, alice, "select * from docs")
+	if r.err != nil || len(r.rows) != 0 {
+		t.Fatalf("expect no row to be visible, got %d (%v)",
+			len(r.rows), r.err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import "strings"
+
+// outputName returns the name of the output column of the select item,
+// which is the alias if given, or else is figured from the expression as
+// PostgreSQL does, see figureName, e.g., price for price::integer, lower
+// for lower(name), and ?column? for price * qty.
+func (item *SelectItem) outputName() string {
+	if item.alias != "" {
+		return item.alias
+	}
+	if name, _ := figureName(item.expr); name != "" {
+		return name
+	}
+	return "?column?"
+}
+
+// figureName figures the name of the output column from the expression,
+// and returns how strong the name is: 2 for the names of the columns and
+// the functions, 1 for the names of the types and the keywords, and 0 if
+// there is no name.
+func figureName(e Expr) (string, int) {
+	switch v := e.(type) {
+	case *ColumnRef:
+		return v.name, 2
+	case *FuncCall:
+		return v.name, 2
+	case *Subscript:
+		return figureName(v.expr)
+	case *CastExpr:
+		if name, strength := figureName(v.expr); strength > 0 {
+			return name, strength
+		}
+		name, _, _ := strings.Cut(v.typ.base().String(), "(")
+		return name, 1
+	case *CaseExpr:
+		// the name of the ELSE result if it is strong
+		if name, strength := figureName(v.els); strength > 1 {
+			return name, strength
+		}
+		return "case", 1
+	case *ArrayExpr:
+		return "array", 1
+	}
+	return "", 0
+}
+
+// selectList returns the names and the types of the output columns of
+// the select list, all the columns of the table for "SELECT *". The
+// columns referred to by the expressions must exist, and the calls of the
+// functions are checked, see Table.checkExpr. The types unknown until
+// evaluated, e.g., of the parameters, are taken as strings, whose values
+// are sent as the text, see encodeValue.
+func (t *Table) selectList(items []*SelectItem) ([]string, []*Type, error) {
+	if items == nil {
+		return t.selectColumns(nil)
+	}
+	cols := make([]string, len(items))
+	types := make([]*Type, len(items))
+	for i, item := range items {
+		var err error
+		walkExpr(item.expr, func(e Expr) {
+			c, ok := e.(*ColumnRef)
+			if !ok || err != nil {
+				return
+			}
+			if _, exist := t.schema[c.name]; !exist {
+				err = newError(codeUndefinedColumn,
+					"column %s does not exist", c.name)
+			}
+		})
+		if err == nil {
+			err = t.checkExpr(item.expr, true)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		cols[i], types[i] = item.outputName(), t.exprType(item.expr)
+		if types[i] == nil {
+			types[i] = stringType
+		}
+	}
+	return cols, types, nil
+}
+
+// project evaluates the select list on the row of the environment and
+// returns the output rows, which are more than one, or none, if the
+// select list calls the set-returning functions, e.g., unnest(tags). The
+// sets are returned side by side, and the shorter ones are padded with
+// NULLs, while the other items are repeated on each output row.
+func project(env *evalEnv, items []*SelectItem, cols []string) ([]*Row, error) {
+	if items == nil {
+		vals := make([]any, len(cols))
+		for i, col := range cols {
+			vals[i] = env.row.fields[col]
+		}
+		return []*Row{newResultRow(cols, vals)}, nil
+	}
+	var (
+		vals = make([]any, len(items))
+		sets = make(map[int][]any)
+		// n is the number of the output rows
+		n = 1
+	)
+	for i, item := range items {
+		fc, ok := item.expr.(*FuncCall)
+		if !ok || functions[fc.name] == nil || functions[fc.name].set == nil {
+			v, err := item.expr.Eval(env)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+			continue
+		}
+		fn, args, err := fc.call(env)
+		if err != nil {
+			return nil, err
+		}
+		s, err := fn.set(env, args)
+		if err != nil {
+			return nil, err
+		}
+		if len(sets) == 0 || len(s) > n {
+			n = len(s)
+		}
+		sets[i] = s
+	}
+	rows := make([]*Row, n)
+	for k := range rows {
+		row := make([]any, len(vals))
+		copy(row, vals)
+		for i, s := range sets {
+			if k < len(s) {
+				row[i] = s[k]
+			}
+		}
+		rows[k] = newResultRow(cols, row)
+	}
+	return rows, nil
+}
+
+// newResultRow returns the output row of the values of the columns.
+func newResultRow(cols []string, vals []any) *Row {
+	r := &Row{fields: make(map[string]any, len(cols)), values: vals}
+	// the first of the columns of the same name is kept in the fields
+	for i := len(cols) - 1; i >= 0; i-- {
+		r.fields[cols[i]] = vals[i]
+	}
+	return r
+}
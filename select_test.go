@@ -0,0 +1,156 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCaseExpressions(t *testing.T) {
+	tts := []struct {
+		expr   string
+		code   string
+		expect string
+	}{
+		{"CASE WHEN 1 > 2 THEN 'a' WHEN 2 > 1 THEN 'b' ELSE 'c' END", "", "b"},
+		{"CASE WHEN NULL THEN 'a' ELSE 'c' END", "", "c"},
+		{"CASE WHEN false THEN 'a' END is null", "", "true"},
+		{"case 2 when 1 then 'one' when 2 then 'two' end", "", "two"},
+		{"CASE NULL WHEN NULL THEN 'x' ELSE 'y' END", "", "y"},
+		{"CASE 1 WHEN 1 THEN 10 END + 1", "", "11"},
+		{"CASE WHEN true THEN 1 ELSE 1 / 0 END", "", "1"},
+		{"CASE WHEN 1 THEN 'a' END", codeDatatypeMismatch, ""},
+		{"CASE ELSE 1 END", codeSyntaxError, ""},
+		{"CASE WHEN true THEN 1", codeSyntaxError, ""},
+	}
+	env := &evalEnv{sess: NewSession(defaultSuperuser)}
+	for i, tt := range tts {
+		got, err := evalText(env, tt.expr)
+		if sqlState(err) != tt.code && (err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.expr, err, tt.code)
+		}
+		if got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.expr, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.expr)
+	}
+}
+
+func TestSelectExpressions(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table items (id integer primary key, name string, " +
+			"price decimal(6, 2), qty integer, tags text[])",
+		"insert into items values (1, 'Apple', 1.50, 4, '{red,green}')",
+		"insert into items values (2, 'Pear', 2.25, NULL, '{}')",
+		"create table events (id integer primary key, at timestamp)",
+		"insert into events values (1, '2026-01-31 12:00:00')",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		cols   []string
+		types  []*Type
+		expect [][]any
+	}{
+		{"Columns", "select name, id from items where id = 1", "",
+			[]string{"name", "id"}, []*Type{stringType, integerType},
+			[][]any{{"Apple", 1}}},
+		{"Arithmetic", "select id, price * qty as total, qty + 1 from items", "",
+			[]string{"id", "total", "?column?"},
+			[]*Type{integerType, decimalType, integerType},
+			[][]any{{1, dec(t, "6.00"), 5}, {2, nil, nil}}},
+		{"Concat and functions", "select coalesce(name, 'x') || '!' shout, " +
+			"nullif(qty, 4), name::varchar(2), 'x', current_user from items " +
+			"where id = 1", "",
+			[]string{"shout", "nullif", "name", "?column?", "current_user"},
+			[]*Type{stringType, integerType, newVarchar(t, 2), stringType,
+				stringType},
+			[][]any{{"Apple!", nil, "Ap", "x", defaultSuperuser}}},
+		{"Case", "select CASE WHEN qty > 2 THEN 'many' ELSE 'few' END, " +
+			"CASE id WHEN 1 THEN name END AS n, CASE WHEN true THEN 1 ELSE id END " +
+			"from items", "",
+			[]string{"case", "n", "id"},
+			[]*Type{stringType, stringType, integerType},
+			[][]any{{"many", "Apple", 1}, {"few", nil, 1}}},
+		{"Promoted", "select CASE WHEN qty > 2 THEN qty ELSE price END, " +
+			"ARRAY[id, price], coalesce(price, qty) from items", "",
+			[]string{"price", "array", "coalesce"},
+			[]*Type{decimalType, newArrayType(decimalType), decimalType},
+			[][]any{{dec(t, "4"), Array{elem: decimalType,
+				elems: []any{dec(t, "1"), dec(t, "1.50")}}, dec(t, "1.50")},
+				{dec(t, "2.25"), Array{elem: decimalType,
+					elems: []any{dec(t, "2"), dec(t, "2.25")}}, dec(t, "2.25")}}},
+		{"Temporal", "select at + interval '1 day', at - '2026-01-01', " +
+			"at::date - date '2026-01-01', CASE WHEN id = 1 THEN 1 ELSE id * 0.5 END " +
+			"from events", "",
+			[]string{"?column?", "?column?", "?column?", "case"},
+			[]*Type{timestampType, intervalType, integerType, decimalType},
+			[][]any{{Timestamp{time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)},
+				Interval{days: 30, micros: 12 * 3600 * 1000000}, 30, dec(t, "1")}}},
+		{"Typed names", "select '1'::integer, tags[1], ARRAY[id], id > 1 " +
+			"from items where id = 1", "",
+			[]string{"integer", "tags", "array", "?column?"},
+			[]*Type{integerType, stringType, newArrayType(integerType), booleanType},
+			[][]any{{1, "red", Array{elem: integerType, elems: []any{1}}, false}}},
+		{"Same names", "select id, id, 1, 2 from items where id = 1", "",
+			[]string{"id", "id", "?column?", "?column?"},
+			[]*Type{integerType, integerType, integerType, integerType},
+			[][]any{{1, 1, 1, 2}}},
+		{"Set-returning", "select id, unnest(tags), unnest('{a,b,c}'::text[]) " +
+			"from items", "",
+			[]string{"id", "unnest", "unnest"},
+			[]*Type{integerType, stringType, stringType},
+			[][]any{{1, "red", "a"}, {1, "green", "b"}, {1, nil, "c"},
+				{2, nil, "a"}, {2, nil, "b"}, {2, nil, "c"}}},
+		{"Empty set", "select id, unnest(tags) from items", "",
+			[]string{"id", "unnest"}, []*Type{integerType, stringType},
+			[][]any{{1, "red"}, {1, "green"}}},
+		{"Nested set", "select unnest(tags) || 'x' from items",
+			codeFeatureNotSupported, nil, nil, nil},
+		{"Unknown column", "select price * amount from items",
+			codeUndefinedColumn, nil, nil, nil},
+		{"Unknown column no rows", "select x from items where id = 3",
+			codeUndefinedColumn, nil, nil, nil},
+		{"Error", "select 1 / (qty - 4) from items", codeDivisionByZero, nil,
+			nil, nil},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		if !reflect.DeepEqual(r.cols, tt.cols) ||
+			!reflect.DeepEqual(r.types, tt.types) {
+			t.Fatalf("case %d (%s) failed: got columns %v %v, expect %v %v",
+				i, tt.name, r.cols, r.types, tt.cols, tt.types)
+		}
+		if tt.expect != nil {
+			var got [][]any
+			for _, row := range r.rows {
+				got = append(got, row.values)
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Fatalf("case %d (%s) failed: got %v, expect %v",
+					i, tt.name, got, tt.expect)
+			}
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
+
+func newVarchar(t *testing.T, n int) *Type {
+	typ, err := newVarcharType(n)
+	if err != nil {
+		t.Fatalf("failed to create varchar(%d): %v", n, err)
+	}
+	return typ
+}
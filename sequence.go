@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Sequence generates the integers for nextval, e.g., of the SERIAL and
+// the identity columns. Its own lock makes nextval safe under the read
+// lock of the database.
+type Sequence struct {
+	sync.Mutex
+	increment int
+	minValue  int
+	maxValue  int
+	start     int
+	cycle     bool
+	// last is the value last returned by nextval or given by setval, the
+	// next value is last itself if called is false
+	last   int
+	called bool
+	// table and column name the column owning the sequence, which is
+	// dropped with the table, both are empty if there is none
+	table  string
+	column string
+}
+
+// newSequence creates the sequence with the options, the omitted ones
+// default as in PostgreSQL, e.g., an ascending sequence starts with its
+// MINVALUE 1.
+func newSequence(opts *SequenceOptions) (*Sequence, error) {
+	s := &Sequence{increment: 1}
+	if opts.increment != nil {
+		s.increment = *opts.increment
+	}
+	if s.increment == 0 {
+		return nil, newError(codeInvalidParameterValue,
+			"INCREMENT must not be zero")
+	}
+	s.minValue, s.maxValue = 1, math.MaxInt64
+	if s.increment < 0 {
+		s.minValue, s.maxValue = math.MinInt64, -1
+	}
+	if opts.minValue != nil {
+		s.minValue = *opts.minValue
+	}
+	if opts.maxValue != nil {
+		s.maxValue = *opts.maxValue
+	}
+	if s.minValue >= s.maxValue {
+		return nil, newError(codeInvalidParameterValue,
+			"MINVALUE (%d) must be less than MAXVALUE (%d)",
+			s.minValue, s.maxValue)
+	}
+	s.start = s.minValue
+	if s.increment < 0 {
+		s.start = s.maxValue
+	}
+	if opts.start != nil {
+		s.start = *opts.start
+	}
+	if s.start < s.minValue {
+		return nil, newError(codeInvalidParameterValue,
+			"START value (%d) cannot be less than MINVALUE (%d)",
+			s.start, s.minValue)
+	}
+	if s.start > s.maxValue {
+		return nil, newError(codeInvalidParameterValue,
+			"START value (%d) cannot be greater than MAXVALUE (%d)",
+			s.start, s.maxValue)
+	}
+	s.cycle = opts.cycle != nil && *opts.cycle
+	s.last = s.start
+	return s, nil
+}
+
+// next advances the sequence and returns the new value, which wraps
+// around to the other bound if the sequence cycles.
+func (s *Sequence) next(name string) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.called {
+		s.called = true
+		return s.last, nil
+	}
+	v := s.last + s.increment
+	switch {
+	case s.increment > 0 && (v < s.last || v > s.maxValue):
+		if !s.cycle {
+			return 0, newError(codeSequenceLimitExceeded,
+				"nextval: reached maximum value of sequence %s (%d)",
+				name, s.maxValue)
+		}
+		v = s.minValue
+	case s.increment < 0 && (v > s.last || v < s.minValue):
+		if !s.cycle {
+			return 0, newError(codeSequenceLimitExceeded,
+				"nextval: reached minimum value of sequence %s (%d)",
+				name, s.minValue)
+		}
+		v = s.maxValue
+	}
+	s.last = v
+	return v, nil
+}
+
+// set sets the last value of the sequence, the next value is the value
+// itself if called is false.
+func (s *Sequence) set(name string, v int, called bool) error {
+	s.Lock()
+	defer s.Unlock()
+	if v < s.minValue || v > s.maxValue {
+		return newError(codeNumericOutOfRange,
+			"setval: value %d is out of bounds for sequence %s (%d..%d)",
+			v, name, s.minValue, s.maxValue)
+	}
+	s.last, s.called = v, called
+	return nil
+}
+
+// relationExists checks if a table, a sequence or an index has the name,
+// including the primary keys. The caller must hold the lock.
+func (db *Database) relationExists(name string) bool {
+	_, table := db.tables[name]
+	_, seq := db.sequences[name]
+	idx, _ := db.lookupIndex(name)
+	if table || seq || idx != nil {
+		return true
+	}
+	for _, t := range db.tables {
+		if t.pkeyName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ownedSequenceName returns the name of the sequence owned by the column
+// as PostgreSQL does, e.g., t_id_seq, a number is appended if the name is
+// taken. The caller must hold the lock.
+func (db *Database) ownedSequenceName(table, column string) string {
+	base := table + "_" + column + "_seq"
+	name := base
+	for i := 1; db.relationExists(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	return name
+}
+
+func (db *Database) CreateSequence(ctx context.Context, cs *CreateSequenceStatement) *Result {
+	seq, err := newSequence(&cs.options)
+	if err != nil {
+		return &Result{
+			err: err,
+		}
+	}
+	db.Lock()
+	defer db.Unlock()
+	if db.relationExists(cs.name) {
+		return &Result{
+			err: newError(codeDuplicateTable,
+				"relation %s already exists", cs.name),
+		}
+	}
+	db.sequences[cs.name] = seq
+	return &Result{
+		message: "SEQUENCE CREATED",
+	}
+}
+
+func (db *Database) DropSequence(ctx context.Context, ds *DropSequenceStatement) *Result {
+	db.Lock()
+	defer db.Unlock()
+	seq, exist := db.sequences[ds.name]
+	if !exist {
+		return &Result{
+			err: newError(codeUndefinedTable,
+				"sequence %s does not exist", ds.name),
+		}
+	}
+	if seq.table != "" {
+		return &Result{
+			err: newError(codeDependentObjects,
+				"cannot drop sequence %s because column %s of table %s "+
+					"requires it", ds.name, seq.column, seq.table),
+		}
+	}
+	delete(db.sequences, ds.name)
+	return &Result{
+		message: "SEQUENCE DROPPED",
+	}
+}
+
+// sequence returns the sequence named by the argument of the sequence
+// functions, which is folded to lower case unless double-quoted. The
+// caller must hold the lock.
+func (env *evalEnv) sequence(fn string, arg any) (string, *Sequence, error) {
+	name, ok := arg.(string)
+	if !ok {
+		return "", nil, newError(codeDatatypeMismatch,
+			"function %s takes the name of the sequence", fn)
+	}
+	if len(name) > 1 && strings.HasPrefix(name, `"`) &&
+		strings.HasSuffix(name, `"`) {
+		name = strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	} else {
+		name = strings.ToLower(name)
+	}
+	var seq *Sequence
+	if env.db != nil {
+		seq = env.db.sequences[name]
+	}
+	if seq == nil {
+		return "", nil, newError(codeUndefinedTable,
+			"relation %s does not exist", name)
+	}
+	return name, seq, nil
+}
+
+// checkSequence checks if the session user may change the sequence, which
+// is allowed by the privileges on the table owning it: nextval needs
+// INSERT or UPDATE on the table, and setval, for which priv is 0, the
+// ownership of it. Only the superusers change the sequences owned by no
+// table. The caller must hold the lock.
+func (env *evalEnv) checkSequence(name string, seq *Sequence,
+	priv Privilege) error {
+	u := env.db.users[env.sess.User()]
+	if u != nil && u.superuser {
+		return nil
+	}
+	if t, exist := env.db.tables[seq.table]; u != nil && exist {
+		granted := u.privileges[seq.table] | u.privileges[allTables]
+		if t.owner == u.name || granted&priv != 0 {
+			return nil
+		}
+	}
+	return newError(codeInsufficientPrivilege,
+		"permission denied for sequence %s", name)
+}
+
+// nextVal advances the sequence and returns the new value, which is
+// remembered by the session for currval.
+func nextVal(env *evalEnv, args []any) (any, error) {
+	name, seq, err := env.sequence("nextval", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := env.checkSequence(name, seq, PrivInsert|PrivUpdate); err != nil {
+		return nil, err
+	}
+	v, err := seq.next(name)
+	if err != nil {
+		return nil, err
+	}
+	env.sess.setCurrval(name, v)
+	return v, nil
+}
+
+// currVal returns the value last returned by nextval of the sequence in
+// the session.
+func currVal(env *evalEnv, args []any) (any, error) {
+	name, _, err := env.sequence("currval", args[0])
+	if err != nil {
+		return nil, err
+	}
+	v, ok := env.sess.currval(name)
+	if !ok {
+		return nil, newError(codeObjectNotInPrerequisiteState,
+			"currval of sequence %s is not yet defined in this session",
+			name)
+	}
+	return v, nil
+}
+
+// setVal sets the last value of the sequence, the optional third argument
+// tells whether the value has been used, i.e., nextval returns the value
+// following it, which is the default.
+func setVal(env *evalEnv, args []any) (any, error) {
+	name, seq, err := env.sequence("setval", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := env.checkSequence(name, seq, 0); err != nil {
+		return nil, err
+	}
+	v, ok := args[1].(int)
+	if !ok {
+		return nil, newError(codeDatatypeMismatch,
+			"the value of setval must be an integer")
+	}
+	called := true
+	if len(args) == 3 {
+		if called, ok = args[2].(bool); !ok {
+			return nil, newError(codeDatatypeMismatch,
+				"the third argument of setval must be a boolean")
+		}
+	}
+	if err := seq.set(name, v, called); err != nil {
+		return nil, err
+	}
+	if called {
+		env.sess.setCurrval(name, v)
+	}
+	return v, nil
+}
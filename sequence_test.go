@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSequences(t *testing.T) {
+	db := NewDatabase()
+	sess := NewSession(defaultSuperuser)
+	for _, sql := range []string{
+		"create table t (id serial primary key, name string)",
+		"create table g (id integer generated always as identity " +
+			"(start with 10 increment by 10) primary key, name string)",
+		"create table u (id integer primary key)",
+		"create sequence s increment by -2 minvalue 1 maxvalue 5 cycle",
+		"create sequence r maxvalue 3",
+	} {
+		if r := execSession(db, sess, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	count := func(sql string) int {
+		r := execSession(db, sess, sql)
+		if r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+		return len(r.rows)
+	}
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		check  string
+		expect int
+	}{
+		{"Serial", "insert into t (name) values ('a')", "",
+			"select * from t where id = 1", 1},
+		{"Explicit value", "insert into t values (10, 'b')", "",
+			"select * from t where id = 10", 1},
+		{"Not advanced", "insert into t (name) values ('c')", "",
+			"select * from t where id = 2 and name = 'c'", 1},
+		{"Currval", "insert into t (name) values ('d')", "",
+			"select * from t where id = currval('t_id_seq')", 1},
+		{"Generated always", "insert into g values (1, 'x')",
+			codeGeneratedAlways, "select * from g", 0},
+		{"Identity", "insert into g (name) values ('x')", "",
+			"select * from g where id = 10", 1},
+		{"Identity increment", "insert into g (name) values ('y')", "",
+			"select * from g where id = 20", 1},
+		{"Update generated always", "update g set id = 1",
+			codeGeneratedAlways, "", 0},
+		{"Nextval", "insert into u values (nextval('s'))", "",
+			"select * from u where id = 5", 1},
+		{"Descending", "insert into u values (nextval('S'))", "",
+			"select * from u where id = 3", 1},
+		{"Minimum", "insert into u values (nextval('s'))", "",
+			"select * from u where id = 1", 1},
+		{"Cycle", "insert into u values (nextval('s'))",
+			codeUniqueViolation, "select * from u", 3},
+		{"Setval", "delete from u where id = setval('r', 3)", "",
+			"select * from u where id = currval('r')", 0},
+		{"Limit exceeded", "insert into u values (nextval('r'))",
+			codeSequenceLimitExceeded, "", 0},
+		{"Out of bounds", "insert into u values (setval('r', 4))",
+			codeNumericOutOfRange, "", 0},
+		{"Setval not called", "insert into u values (setval('s', 4, false))",
+			"", "select * from u where id = 4", 1},
+		{"Next after setval", "insert into u values (nextval('s'))",
+			codeUniqueViolation, "", 0},
+		{"Missing", "insert into u values (nextval('missing'))",
+			codeUndefinedTable, "", 0},
+		{"Duplicate", "create sequence t", codeDuplicateTable, "", 0},
+		{"Zero increment", "create sequence x increment 0",
+			codeInvalidParameterValue, "", 0},
+		{"Start out of bounds", "create sequence x start with 0",
+			codeInvalidParameterValue, "", 0},
+		{"Conflicting options", "create sequence x start 1 start 2",
+			codeSyntaxError, "", 0},
+		{"Default and identity", "create table x (id integer primary key " +
+			"default 1 generated by default as identity)", codeSyntaxError, "", 0},
+		{"Drop owned", "drop sequence t_id_seq", codeDependentObjects, "", 0},
+		{"Drop table", "drop table t", "", "", 0},
+		{"Dropped with table", "create sequence t_id_seq", "", "", 0},
+		{"Name taken", "create table t (id serial primary key)", "", "", 0},
+		{"Drop", "drop sequence r", "", "", 0},
+		{"Dropped", "insert into u values (nextval('r'))",
+			codeUndefinedTable, "", 0},
+	}
+	for i, tt := range tts {
+		r := execSession(db, sess, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		if tt.check != "" {
+			if n := count(tt.check); n != tt.expect {
+				t.Fatalf("case %d (%s) failed: got %d rows, expect %d",
+					i, tt.name, n, tt.expect)
+			}
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	// currval is local to the session
+	r := execSQL(db, defaultSuperuser, "select * from g where id = currval('s')")
+	if sqlState(r.err) != codeObjectNotInPrerequisiteState {
+		t.Fatalf("expect currval to be undefined, got %v", r.err)
+	}
+	info, err := db.DescribeTable("t")
+	if err != nil || info.Defaults[0] != "nextval('t_id_seq1')" {
+		t.Fatalf("unexpected table info %+v (%v)", info, err)
+	}
+	info, err = db.DescribeTable("g")
+	if err != nil || info.Defaults[0] != "generated always as identity" {
+		t.Fatalf("unexpected table info %+v (%v)", info, err)
+	}
+}
+
+func TestSequencePrivileges(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create user alice with password 'pw'",
+		"create user bob with password 'pw'",
+		"create table secret (id serial primary key, name string)",
+		"create table pub (id integer primary key)",
+		"create sequence s",
+		"insert into pub values (1)",
+		"grant select on pub to alice",
+		"grant insert on secret to bob",
+		"grant create on all tables to bob",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+
+	tts := []struct {
+		name    string
+		user    string
+		sql     string
+		allowed bool
+	}{
+		{"Setval without ownership", "alice",
+			"select setval('secret_id_seq', 1, false) from pub", false},
+		{"Nextval without privilege", "alice",
+			"select nextval('secret_id_seq') from pub", false},
+		{"Unowned sequence", "alice", "select nextval('s') from pub", false},
+		{"Insert by superuser", defaultSuperuser,
+			"insert into secret (name) values ('a')", true},
+		{"Serial with insert privilege", "bob",
+			"insert into secret (name) values ('b')", true},
+		{"Setval with insert privilege", "bob",
+			"insert into secret values (setval('secret_id_seq', 1), 'c')", false},
+		{"Create owned sequence", "bob", "create table own (id serial primary key)", true},
+		{"Setval owned", "bob",
+			"insert into own values (setval('own_id_seq', 5))", true},
+		{"Setval by superuser", defaultSuperuser,
+			"select setval('secret_id_seq', 2) from pub", true},
+		{"Insert after setval", defaultSuperuser,
+			"insert into secret (name) values ('d')", true},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, tt.user, tt.sql)
+		if (r.err == nil) != tt.allowed {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect allowed(%v)",
+				i, tt.name, r.err, tt.allowed)
+		}
+		if r.err != nil && sqlState(r.err) != codeInsufficientPrivilege {
+			t.Fatalf("case %d (%s) failed: unexpected error %v",
+				i, tt.name, r.err)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
+
+func TestSequenceConcurrency(t *testing.T) {
+	db := NewDatabase()
+	if r := execSQL(db, defaultSuperuser,
+		"create table t (id serial primary key, worker integer)"); r.err != nil {
+		t.Fatalf("failed to create the table: %v", r.err)
+	}
+	const workers, inserts = 8, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < inserts; i++ {
+				r := execSQL(db, defaultSuperuser,
+					fmt.Sprintf("insert into t (worker) values (%d)", w))
+				if r.err != nil {
+					errs <- r.err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("failed to insert: %v", err)
+	}
+	r := execSQL(db, defaultSuperuser,
+		fmt.Sprintf("select * from t where id >= 1 and id <= %d",
+			workers*inserts))
+	if r.err != nil || len(r.rows) != workers*inserts {
+		t.Fatalf("got %d rows (%v), expect %d", len(r.rows), r.err,
+			workers*inserts)
+	}
+}
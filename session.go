@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const statementTimeout = "statement_timeout"
+
+// Session holds the state of a client connection, i.e., the user and the
+// run-time parameters changed by the SET statement.
+type Session struct {
+	sync.RWMutex
+	user string
+	vars map[string]string
+	// currvals are the values last returned by nextval keyed by the
+	// sequences
+	currvals map[string]int
+}
+
+func NewSession(user string) *Session {
+	return &Session{
+		user:     user,
+		vars:     make(map[string]string),
+		currvals: make(map[string]int),
+	}
+}
+
+// User returns the name of the user the session belongs to.
+func (s *Session) User() string {
+	if s == nil {
+		return ""
+	}
+	return s.user
+}
+
+// setCurrval remembers the value returned by nextval of the sequence.
+func (s *Session) setCurrval(name string, v int) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.currvals[name] = v
+}
+
+// currval returns the value last returned by nextval of the sequence in
+// the session, false if nextval has not been called.
+func (s *Session) currval(name string) (int, bool) {
+	if s == nil {
+		return 0, false
+	}
+	s.RLock()
+	defer s.RUnlock()
+	v, exist := s.currvals[name]
+	return v, exist
+}
+
+type sessionKey struct{}
+
+// WithSession returns a copy of the ctx that carries the session.
+func WithSession(ctx context.Context, sess *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sess)
+}
+
+// SessionFromContext returns the session carried by the ctx, or nil if
+// there is none.
+func SessionFromContext(ctx context.Context) *Session {
+	sess, _ := ctx.Value(sessionKey{}).(*Session)
+	return sess
+}
+
+// Set changes the run-time parameter named by the statement.
+func (s *Session) Set(ss *SetStatement) *Result {
+	if s == nil {
+		return &Result{
+			err: newError(codeFeatureNotSupported,
+				"SET is not allowed without a session"),
+		}
+	}
+	name := strings.ToLower(ss.name)
+	if name == statementTimeout {
+		if _, err := parseTimeout(ss.value); err != nil {
+			return &Result{
+				err: newError(codeInvalidParameterValue,
+					"invalid value for %s: %v", name, err),
+			}
+		}
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.vars[name] = ss.value
+	return &Result{
+		message: "SET",
+	}
+}
+
+// Get returns the value of the run-time parameter.
+func (s *Session) Get(name string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.RLock()
+	defer s.RUnlock()
+	val, exist := s.vars[strings.ToLower(name)]
+	return val, exist
+}
+
+// StatementTimeout returns the statement_timeout of the session, zero
+// means no timeout.
+func (s *Session) StatementTimeout() time.Duration {
+	val, exist := s.Get(statementTimeout)
+	if !exist {
+		return 0
+	}
+	// the value has been validated by Set
+	timeout, _ := parseTimeout(val)
+	return timeout
+}
+
+// parseTimeout parses the timeout in the format accepted by PostgreSQL,
+// a bare integer is taken as milliseconds.
+func parseTimeout(val string) (time.Duration, error) {
+	val = strings.TrimSpace(strings.ToLower(val))
+	if ms, err := strconv.Atoi(val); err == nil {
+		if ms < 0 {
+			return 0, errors.Errorf("negative timeout %d", ms)
+		}
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	// PostgreSQL spells minutes as "min"
+	if strings.HasSuffix(val, "min") {
+		val = strings.TrimSuffix(val, "in")
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.Errorf("negative timeout %s", d)
+	}
+	return d, nil
+}
+
+// ctxError converts the error of a done context to the error reported
+// to the client.
+func ctxError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return newError(codeQueryCanceled,
+			"canceling statement due to statement timeout")
+	}
+	return newError(codeQueryCanceled,
+		"canceling statement due to user request")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseTimeout(t *testing.T) {
+	tts := []struct {
+		name   string
+		input  string
+		expect time.Duration
+		valid  bool
+	}{
+		{"Bare integer in milliseconds", "1500", 1500 * time.Millisecond, true},
+		{"Seconds", "5s", 5 * time.Second, true},
+		{"PostgreSQL minutes", "2min", 2 * time.Minute, true},
+		{"Disabled", "0", 0, true},
+		{"Negative", "-1", 0, false},
+		{"Invalid unit", "5 apples", 0, false},
+	}
+
+	for i, tt := range tts {
+		tt := tt
+		i := i
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseTimeout(tt.input)
+			if (err == nil) != tt.valid {
+				t.Fatalf("case %d (%s) failed: got error(%v), expect valid(%v)",
+					i, tt.name, err, tt.valid)
+			}
+			if got != tt.expect {
+				t.Fatalf("case %d (%s) failed: got(%s), expect(%s)", i,
+					tt.name, got, tt.expect)
+			}
+			t.Logf("case %d (%s) succeed", i, tt.name)
+		})
+	}
+}
+
+// expiringContext is the context which is done with the err after its
+// Err is checked a number of times, e.g., in the middle of a scan.
+type expiringContext struct {
+	context.Context
+	checks int
+	err    error
+}
+
+func (c *expiringContext) Err() error {
+	if c.checks <= 0 {
+		return c.err
+	}
+	c.checks--
+	return c.Context.Err()
+}
+
+func newScanTestDatabase(t *testing.T, sess *Session) *Database {
+	db := NewDatabase()
+	if r := execSession(db, sess,
+		"create table test (id integer primary key, name string)"); r.err != nil {
+		t.Fatalf("failed to create the table: %v", r.err)
+	}
+	for i := 0; i < 100; i++ {
+		sql := fmt.Sprintf("insert into test values (%d, 'name %d')", i, i)
+		if r := execSession(db, sess, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+	return db
+}
+
+func TestInterpretCanceled(t *testing.T) {
+	sess := NewSession(defaultSuperuser)
+	db := newScanTestDatabase(t, sess)
+	sts, err := parseSQL("select * from test where lower(name) = 'x'")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if r := db.Interpret(WithSession(context.Background(), sess),
+		sts); r.err != nil {
+		t.Fatalf("failed to scan the table: %v", r.err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(),
+		time.Now().Add(-time.Second))
+	defer cancel()
+	tts := []struct {
+		name   string
+		ctx    context.Context
+		expect string
+	}{
+		{"Canceled", canceled, "canceling statement due to user request"},
+		{"Deadline exceeded", expired,
+			"canceling statement due to statement timeout"},
+		{"Canceled during scan", &expiringContext{Context: context.Background(),
+			checks: 10, err: context.Canceled},
+			"canceling statement due to user request"},
+		{"Deadline exceeded during scan", &expiringContext{
+			Context: context.Background(), checks: 10,
+			err: context.DeadlineExceeded},
+			"canceling statement due to statement timeout"},
+	}
+	for i, tt := range tts {
+		r := db.Interpret(WithSession(tt.ctx, sess), sts)
+		if sqlState(r.err) != codeQueryCanceled ||
+			r.err.(*Error).Msg != tt.expect {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	sess := NewSession(defaultSuperuser)
+	db := newScanTestDatabase(t, sess)
+	if r := execSession(db, sess, "set statement_timeout = '1'"); r.err != nil {
+		t.Fatalf("failed to set the timeout: %v", r.err)
+	}
+	// the timeout expires in the middle of the scan
+	defer func(f func(context.Context, time.Duration) (
+		context.Context, context.CancelFunc)) {
+		withTimeout = f
+	}(withTimeout)
+	var got time.Duration
+	withTimeout = func(ctx context.Context, d time.Duration) (
+		context.Context, context.CancelFunc) {
+		got = d
+		ctx, cancel := context.WithCancel(ctx)
+		return &expiringContext{Context: ctx, checks: 10,
+			err: context.DeadlineExceeded}, cancel
+	}
+
+	r := execSession(db, sess, "select * from test where lower(name) = 'x'")
+	if got != time.Millisecond {
+		t.Fatalf("got timeout %s, expect 1ms", got)
+	}
+	if sqlState(r.err) != codeQueryCanceled || r.err.(*Error).Msg !=
+		"canceling statement due to statement timeout" {
+		t.Fatalf("got error(%v), expect the statement timeout", r.err)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// textArgs returns the string arguments of the string function, which is
+// false if any of them is NULL. The types are checked by checkCall.
+func textArgs(args []any) ([]string, bool) {
+	ss := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, false
+		}
+		ss[i] = s
+	}
+	return ss, true
+}
+
+func lower(env *evalEnv, args []any) (any, error) {
+	ss, ok := textArgs(args)
+	if !ok {
+		return nil, nil
+	}
+	return strings.ToLower(ss[0]), nil
+}
+
+func upper(env *evalEnv, args []any) (any, error) {
+	ss, ok := textArgs(args)
+	if !ok {
+		return nil, nil
+	}
+	return strings.ToUpper(ss[0]), nil
+}
+
+// length returns the number of the characters of the string.
+func length(env *evalEnv, args []any) (any, error) {
+	ss, ok := textArgs(args)
+	if !ok {
+		return nil, nil
+	}
+	return utf8.RuneCountInString(ss[0]), nil
+}
+
+// substr returns the characters of the string from the 1-based start, up
+// to the count if given, e.g., substr('hello', 2, 3) is "ell". The
+// characters before the first one are counted but not returned as in
+// PostgreSQL, e.g., substr('hello', 0, 2) is "h".
+func substr(env *evalEnv, args []any) (any, error) {
+	for _, arg := range args {
+		if arg == nil {
+			return nil, nil
+		}
+	}
+	rs := []rune(args[0].(string))
+	start := args[1].(int)
+	end := len(rs) + 1
+	if len(args) == 3 {
+		count := args[2].(int)
+		if count < 0 {
+			return nil, newError(codeSubstringError,
+				"negative substring length not allowed")
+		}
+		// start + count is not to overflow
+		if start < end-count {
+			end = start + count
+		}
+	}
+	if start < 1 {
+		start = 1
+	}
+	if start >= end {
+		return "", nil
+	}
+	return string(rs[start-1 : end-1]), nil
+}
+
+// trim returns btrim, ltrim or rtrim, which remove the longest string of
+// the characters, the spaces by default, from both ends, the start or the
+// end of the string respectively. They are called by
+// "trim([BOTH | LEADING | TRAILING] [characters] FROM string)" as well.
+func trim(fn string) func(env *evalEnv, args []any) (any, error) {
+	return func(env *evalEnv, args []any) (any, error) {
+		ss, ok := textArgs(args)
+		if !ok {
+			return nil, nil
+		}
+		cutset := " "
+		if len(ss) == 2 {
+			cutset = ss[1]
+		}
+		switch fn {
+		case "ltrim":
+			return strings.TrimLeft(ss[0], cutset), nil
+		case "rtrim":
+			return strings.TrimRight(ss[0], cutset), nil
+		}
+		return strings.Trim(ss[0], cutset), nil
+	}
+}
+
+// replace replaces all the occurrences of the second argument in the
+// string with the third.
+func replace(env *evalEnv, args []any) (any, error) {
+	ss, ok := textArgs(args)
+	if !ok {
+		return nil, nil
+	}
+	if ss[1] == "" {
+		return ss[0], nil
+	}
+	return strings.ReplaceAll(ss[0], ss[1], ss[2]), nil
+}
+
+// concatValues concatenates the text representations of the arguments,
+// the NULLs are ignored unlike ||.
+func concatValues(env *evalEnv, args []any) (any, error) {
+	var b strings.Builder
+	for _, arg := range args {
+		if arg != nil {
+			b.WriteString(toText(arg))
+		}
+	}
+	return b.String(), nil
+}
+
+// position returns the 1-based position of the first occurrence of the
+// substring in the string, 0 if there is none, which is called by
+// "position(substring IN string)".
+func position(env *evalEnv, args []any) (any, error) {
+	ss, ok := textArgs(args)
+	if !ok {
+		return nil, nil
+	}
+	i := strings.Index(ss[1], ss[0])
+	if i < 0 {
+		return 0, nil
+	}
+	return utf8.RuneCountInString(ss[1][:i]) + 1, nil
+}
+
+// splitPart splits the string by the delimiter and returns the field of
+// the 1-based position, which counts from the end if negative, or the
+// empty string if there is no such field.
+func splitPart(env *evalEnv, args []any) (any, error) {
+	if args[0] == nil || args[1] == nil || args[2] == nil {
+		return nil, nil
+	}
+	s, delim, n := args[0].(string), args[1].(string), args[2].(int)
+	if n == 0 {
+		return nil, newError(codeInvalidParameterValue,
+			"field position must not be zero")
+	}
+	fields := []string{s}
+	if delim != "" && s != "" {
+		fields = strings.Split(s, delim)
+	} else if s == "" {
+		fields = nil
+	}
+	if n < 0 {
+		n += len(fields) + 1
+	}
+	if n < 1 || n > len(fields) {
+		return "", nil
+	}
+	return fields[n-1], nil
+}
@@ -1,43 +1,141 @@
 package main
 
-import (
-	"reflect"
-	"strings"
-
-	"github.com/pkg/errors"
-)
-
-func stringToKind(kindStr string) (reflect.Kind, error) {
-	kindStr = strings.ToLower(kindStr)
-	switch kindStr {
-	case "integer":
-		return reflect.Int, nil
-	case "float":
-		return reflect.Float64, nil
-	case "string":
-		return reflect.String, nil
-	case "boolean":
-		return reflect.Bool, nil
-	default:
-		return reflect.Kind(^uint(0)),
-			errors.Errorf("unsupported kind %s", kindStr)
-	}
-}
-
 type Table struct {
-	primaryKey string
-	schema     map[string]reflect.Kind
-	rows       map[any]*Row
+	// owner is the name of the user who created the table
+	owner string
+	// primaryKey are the columns of the primary key, whose constraint is
+	// named pkeyName
+	primaryKey []string
+	pkeyName   string
+	schema     map[string]*Type
+	// columns keeps the column names in the order they are defined
+	columns []string
+	// rows are keyed by the encoded primary keys, see appendKey, which
+	// are kept in order by the keys for the range scans
+	rows map[string]*Row
+	keys []string
+	// defaults are the DEFAULT expressions keyed by the column
+	defaults map[string]Expr
+	// identities tell whether the identity columns are GENERATED ALWAYS
+	identities map[string]bool
+	// constraints are enforced on every insert and update
+	constraints []*Constraint
+	// uniques map the keys of the rows on the UNIQUE constraints, see
+	// uniqueKey, to the primary keys of the rows, which are more than one
+	// only while the undo log is rolled back
+	uniques map[*Constraint]map[string][]string
+	// rowSecurity tells whether the policies are applied
+	rowSecurity bool
+	policies    []*Policy
+	// indexes are updated as the rows are put and removed
+	indexes []*Index
 }
 
 type Row struct {
 	fields map[string]any
+	// values are the values of the output row in the order of the output
+	// columns, whose names may repeat, e.g., ?column?, which is nil for
+	// the stored rows
+	values []any
 }
 
-func NewTable(pk string, schema map[string]reflect.Kind) *Table {
+func NewTable(pk []string,
+	schema map[string]*Type, columns []string) *Table {
 	return &Table{
 		primaryKey: pk,
 		schema:     schema,
-		rows:       make(map[any]*Row),
+		columns:    columns,
+		rows:       make(map[string]*Row),
+		defaults:   make(map[string]Expr),
+		identities: make(map[string]bool),
+		uniques:    make(map[*Constraint]map[string][]string),
+	}
+}
+
+// selectColumns returns the types of the desired columns, all columns are
+// selected if no column is given.
+func (t *Table) selectColumns(fields []string) (
+	[]string, []*Type, error) {
+	if len(fields) == 0 {
+		fields = t.columns
+	}
+	types := make([]*Type, 0, len(fields))
+	for _, f := range fields {
+		typ, exist := t.schema[f]
+		if !exist {
+			return nil, nil, newError(codeUndefinedColumn,
+				"column %s not exist", f)
+		}
+		types = append(types, typ)
+	}
+	return fields, types, nil
+}
+
+// coerce returns the value to be stored in the column, see Type.assign.
+func (t *Table) coerce(col string, val any) (any, error) {
+	typ, exist := t.schema[col]
+	// column is not defined in the schema
+	if !exist {
+		return nil, newError(codeUndefinedColumn, "column(%s) not exist", col)
 	}
+	if val == nil {
+		return nil, nil
+	}
+	v, ok, err := typ.assign(val)
+	if err != nil {
+		return nil, err
+	}
+	// the given value is not of the type of the column, nor converted
+	// implicitly
+	if !ok {
+		return nil, newError(codeDatatypeMismatch,
+			"column %s is of type %s but expression is of type %s, "+
+				"you will need to rewrite or cast the expression",
+			col, typ, valueType(val))
+	}
+	return v, nil
+}
+
+// replaceRows replaces the rows of the keys with the new rows, whose
+// primary keys may be changed. The table is untouched if any of the new
+// rows violates the primary key or the UNIQUE constraints, otherwise the
+// changed rows are recorded in the undo log if it is not nil.
+func (t *Table) replaceRows(table string, keys []string, rows []*Row,
+	undo *undoLog) error {
+	removed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		removed[key] = true
+	}
+	added := make([]string, len(rows))
+	seen := make(map[string]bool, len(rows))
+	for i, row := range rows {
+		key, err := t.rowKey(table, row)
+		if err != nil {
+			return err
+		}
+		if _, exist := t.rows[key]; (exist && !removed[key]) || seen[key] {
+			return t.duplicateKey(table, row)
+		}
+		seen[key] = true
+		added[i] = key
+	}
+	if err := t.checkUnique(rows, removed); err != nil {
+		return err
+	}
+	undo.record(t, keys...)
+	undo.record(t, added...)
+	for _, key := range keys {
+		t.remove(key)
+	}
+	for i, row := range rows {
+		t.put(added[i], row)
+	}
+	return nil
+}
+
+// duplicateKey returns the error of the row violating the primary key.
+func (t *Table) duplicateKey(table string, r *Row) error {
+	return newError(codeUniqueViolation,
+		"duplicate key value violates unique constraint %s: %s already "+
+			"exists", t.pkeyName, t.pkeyString(r))
 }
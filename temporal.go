@@ -0,0 +1,527 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The date and time values are kept in UTC with the precision of
+// microseconds as in PostgreSQL, and printed in ISO 8601.
+const (
+	microsPerSecond = int64(time.Second / time.Microsecond)
+	microsPerDay    = 86400 * microsPerSecond
+)
+
+// Date is the value of DATE, which is the midnight of the day in UTC.
+type Date struct{ time.Time }
+
+// TimeOfDay is the value of TIME, which is the microseconds since the
+// midnight.
+type TimeOfDay int64
+
+// Timestamp is the value of TIMESTAMP, which is the wall time without
+// time zone kept as in UTC.
+type Timestamp struct{ time.Time }
+
+// TimestampTZ is the value of TIMESTAMPTZ, which is the instant kept in
+// UTC.
+type TimestampTZ struct{ time.Time }
+
+func newDate(y int, m time.Month, d int) Date {
+	return Date{time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) String() string {
+	return d.Format("2006-01-02")
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (t TimeOfDay) String() string {
+	return formatClock(int64(t))
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (ts Timestamp) String() string {
+	return ts.Format("2006-01-02T15:04:05.999999")
+}
+
+func (ts Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ts.String())
+}
+
+func (ts TimestampTZ) String() string {
+	return ts.Format("2006-01-02T15:04:05.999999Z07:00")
+}
+
+func (ts TimestampTZ) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ts.String())
+}
+
+// formatClock formats the microseconds as "15:04:05", the fraction of the
+// second is appended without the trailing zeros if any.
+func formatClock(micros int64) string {
+	sec := micros / microsPerSecond
+	s := pad2(sec/3600) + ":" + pad2(sec/60%60) + ":" + pad2(sec%60)
+	if frac := micros % microsPerSecond; frac != 0 {
+		s += "." + strings.TrimRight(
+			strconv.FormatInt(frac+microsPerSecond, 10)[1:], "0")
+	}
+	return s
+}
+
+func pad2(n int64) string {
+	if n < 10 {
+		return "0" + strconv.FormatInt(n, 10)
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// parseDate parses the date "2006-01-02", the time following it, if any,
+// is ignored.
+func parseDate(s string) (Date, error) {
+	t, _, _, err := parseDateTime("date", s)
+	if err != nil {
+		return Date{}, err
+	}
+	return Date{t}, nil
+}
+
+// parseTimeOfDay parses the time "15:04[:05[.999999]]".
+func parseTimeOfDay(s string) (TimeOfDay, error) {
+	clock, _, _ := splitZone(strings.TrimSpace(s))
+	micros, ok := parseClock(clock)
+	if !ok {
+		return 0, invalidInput("time", s)
+	}
+	if micros > microsPerDay {
+		return 0, fieldOverflow(s)
+	}
+	return TimeOfDay(micros), nil
+}
+
+// parseTimestamp parses the timestamp "2006-01-02[( |T)15:04:05.999999]",
+// the zone offset, if any, is ignored.
+func parseTimestamp(s string) (Timestamp, error) {
+	t, clock, _, err := parseDateTime("timestamp", s)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	return Timestamp{t.Add(time.Duration(clock) * time.Microsecond)}, nil
+}
+
+// parseTimestampTZ parses the timestamp with the zone offset, e.g.,
+// "2006-01-02 15:04:05+08:00", the timestamp without offset is in UTC.
+func parseTimestampTZ(s string) (TimestampTZ, error) {
+	t, clock, offset, err := parseDateTime("timestamptz", s)
+	if err != nil {
+		return TimestampTZ{}, err
+	}
+	micros := clock - int64(offset)*microsPerSecond
+	return TimestampTZ{t.Add(time.Duration(micros) * time.Microsecond)}, nil
+}
+
+func invalidInput(typ, s string) error {
+	return newError(codeInvalidDatetimeFormat,
+		"invalid input syntax for type %s: %q", typ, s)
+}
+
+func fieldOverflow(s string) error {
+	return newError(codeDatetimeFieldOverflow,
+		"date/time field value out of range: %q", s)
+}
+
+// parseDateTime parses the date at the midnight in UTC, the microseconds
+// of the time following it, and the offset of its zone in seconds.
+func parseDateTime(typ, s string) (time.Time, int64, int, error) {
+	invalid := invalidInput(typ, s)
+	s = strings.TrimSpace(s)
+	date, clock := s, ""
+	if i := strings.IndexAny(s, " Tt"); i >= 0 {
+		date, clock = s[:i], strings.TrimSpace(s[i+1:])
+	}
+	parts := strings.Split(date, "-")
+	if len(parts) != 3 || len(parts[0]) < 4 {
+		return time.Time{}, 0, 0, invalid
+	}
+	var ymd [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return time.Time{}, 0, 0, invalid
+		}
+		ymd[i] = n
+	}
+	t := time.Date(ymd[0], time.Month(ymd[1]), ymd[2], 0, 0, 0, 0, time.UTC)
+	// reject the overflowing month and day, e.g., 2026-02-30
+	if t.Month() != time.Month(ymd[1]) || t.Day() != ymd[2] {
+		return time.Time{}, 0, 0, fieldOverflow(s)
+	}
+	if clock == "" {
+		return t, 0, 0, nil
+	}
+	clock, offset, ok := splitZone(clock)
+	if !ok {
+		return time.Time{}, 0, 0, invalid
+	}
+	micros := int64(0)
+	if clock != "" {
+		if micros, ok = parseClock(clock); !ok {
+			return time.Time{}, 0, 0, invalid
+		}
+		if micros > microsPerDay {
+			return time.Time{}, 0, 0, fieldOverflow(s)
+		}
+	}
+	return t, micros, offset, nil
+}
+
+// splitZone splits the trailing zone of the time, i.e., "Z", "UTC" or the
+// offset "+08", "+08:00" or "+0800", and returns the offset in seconds.
+func splitZone(s string) (string, int, bool) {
+	upper := strings.ToUpper(s)
+	for _, z := range []string{"UTC", "Z"} {
+		if strings.HasSuffix(upper, z) {
+			return strings.TrimSpace(s[:len(s)-len(z)]), 0, true
+		}
+	}
+	i := strings.LastIndexAny(s, "+-")
+	if i < 0 {
+		return s, 0, true
+	}
+	zone := strings.ReplaceAll(s[i+1:], ":", "")
+	if len(zone) != 2 && len(zone) != 4 {
+		return "", 0, false
+	}
+	hh, err1 := strconv.Atoi(zone[:2])
+	mm, err2 := strconv.Atoi("0" + zone[2:])
+	if err1 != nil || err2 != nil || hh > 15 || mm > 59 {
+		return "", 0, false
+	}
+	offset := hh*3600 + mm*60
+	if s[i] == '-' {
+		offset = -offset
+	}
+	return strings.TrimSpace(s[:i]), offset, true
+}
+
+// parseClock parses the time "15:04[:05[.999999]]" as the microseconds,
+// the fraction is rounded to microseconds. The hours are unbounded for
+// the intervals, and the times are up to 24:00:00, the end of the day.
+func parseClock(s string) (int64, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	hh, err1 := strconv.Atoi(parts[0])
+	mm, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hh < 0 || mm < 0 || mm > 59 {
+		return 0, false
+	}
+	micros := (int64(hh)*3600 + int64(mm)*60) * microsPerSecond
+	if len(parts) == 3 {
+		sec, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil || sec < 0 || sec >= 61 || strings.ContainsAny(parts[2], "eE+-") {
+			return 0, false
+		}
+		micros += int64(math.Round(sec * float64(microsPerSecond)))
+	}
+	return micros, true
+}
+
+// instant returns the time of the date or the timestamps in UTC, which is
+// false for the other values.
+func instant(v any) (time.Time, bool) {
+	switch x := v.(type) {
+	case Date:
+		return x.Time, true
+	case Timestamp:
+		return x.Time, true
+	case TimestampTZ:
+		return x.Time, true
+	}
+	return time.Time{}, false
+}
+
+// isTemporal tells whether the value is of the date and time types.
+func isTemporal(v any) bool {
+	switch v.(type) {
+	case Date, TimeOfDay, Timestamp, TimestampTZ, Interval:
+		return true
+	}
+	return false
+}
+
+// temporalOperands converts the string compared with the date and time
+// value to the type of the value, as the untyped literals are, e.g.,
+// "ts > '2026-01-01'".
+func temporalOperands(a, b any) (any, any, error) {
+	if s, ok := a.(string); ok && isTemporal(b) {
+		v, err := typeOf(b).parse(s)
+		return v, b, err
+	}
+	if s, ok := b.(string); ok && isTemporal(a) {
+		v, err := typeOf(a).parse(s)
+		return a, v, err
+	}
+	return a, b, nil
+}
+
+// compareTemporal compares the date and time values, the dates and the
+// timestamps compare as the instants in UTC, which is false if they are
+// not comparable.
+func compareTemporal(a, b any) (int, bool) {
+	if x, ok := instant(a); ok {
+		if y, ok := instant(b); ok {
+			return compareOrdered(int(x.UnixMicro()), int(y.UnixMicro())), true
+		}
+		return 0, false
+	}
+	switch x := a.(type) {
+	case TimeOfDay:
+		if y, ok := b.(TimeOfDay); ok {
+			return compareOrdered(int(x), int(y)), true
+		}
+	case Interval:
+		if y, ok := b.(Interval); ok {
+			return compareOrdered(x.total(), y.total()), true
+		}
+	}
+	return 0, false
+}
+
+// temporalArithmetic applies the arithmetic operator to the date and time
+// values as PostgreSQL does, e.g., the timestamp plus the interval is a
+// timestamp, and the date minus the date is the number of days. It is
+// false if neither operand is a date or time value.
+func temporalArithmetic(op Operator, a, b any) (any, bool, error) {
+	if !isTemporal(a) && !isTemporal(b) {
+		return nil, false, nil
+	}
+	a, b, err := temporalOperands(a, b)
+	if err != nil {
+		return nil, true, err
+	}
+	// the additions and the multiplications are commutative, so that only
+	// the ones of the higher rank on the left are considered below
+	if (op == add || op == mul) && temporalRank(a) < temporalRank(b) {
+		a, b = b, a
+	}
+	switch x := a.(type) {
+	case Date:
+		switch y := b.(type) {
+		case int:
+			if op == add {
+				return Date{x.AddDate(0, 0, y)}, true, nil
+			}
+			if op == sub {
+				return Date{x.AddDate(0, 0, -y)}, true, nil
+			}
+		case Date:
+			if op == sub {
+				return int((x.Unix() - y.Unix()) / 86400), true, nil
+			}
+		case Interval:
+			if op == add || op == sub {
+				return Timestamp{addInterval(x.Time, y, op == sub)}, true, nil
+			}
+		case TimeOfDay:
+			if op == add {
+				return Timestamp{x.Add(time.Duration(y) * time.Microsecond)},
+					true, nil
+			}
+		}
+	case Timestamp:
+		switch y := b.(type) {
+		case Interval:
+			if op == add || op == sub {
+				return Timestamp{addInterval(x.Time, y, op == sub)}, true, nil
+			}
+		case Timestamp, Date:
+			if op == sub {
+				t, _ := instant(y)
+				return intervalBetween(x.Time, t), true, nil
+			}
+		}
+	case TimestampTZ:
+		switch y := b.(type) {
+		case Interval:
+			if op == add || op == sub {
+				return TimestampTZ{addInterval(x.Time, y, op == sub)}, true, nil
+			}
+		case TimestampTZ:
+			if op == sub {
+				return intervalBetween(x.Time, y.Time), true, nil
+			}
+		}
+	case TimeOfDay:
+		switch y := b.(type) {
+		case Interval:
+			micros := y.micros
+			if op == sub {
+				micros = -micros
+			}
+			if op == add || op == sub {
+				t := (int64(x) + micros%microsPerDay + microsPerDay) % microsPerDay
+				return TimeOfDay(t), true, nil
+			}
+		case TimeOfDay:
+			if op == sub {
+				return Interval{micros: int64(x - y)}, true, nil
+			}
+		}
+	case Interval:
+		switch y := b.(type) {
+		case Interval:
+			if op == add {
+				return x.add(y), true, nil
+			}
+			if op == sub {
+				return x.add(y.neg()), true, nil
+			}
+		case int:
+			if op == mul {
+				return Interval{x.months * y, x.days * y, x.micros * int64(y)},
+					true, nil
+			}
+		}
+		if f, ok := toFloat(b); ok && (op == mul || op == div) {
+			if op == div {
+				if f == 0 {
+					return nil, true, errDivisionByZero
+				}
+				f = 1 / f
+			}
+			return x.scale(f), true, nil
+		}
+	}
+	return nil, true, newError(codeUndefinedFunction,
+		"operator does not exist: %s %s %s", valueType(a), op, valueType(b))
+}
+
+// temporalType returns the type of the results of temporalArithmetic on
+// the values of the types, which is nil if unknown or if neither is a date
+// or time type. The strings are taken as the type of the other operand,
+// as temporalOperands converts them.
+func temporalType(op Operator, l, r *Type) *Type {
+	if l == nil || r == nil || (!l.temporal() && !r.temporal()) {
+		return nil
+	}
+	textual := func(t *Type) bool { return t.id == stringID || t.id == varcharID }
+	if textual(l) {
+		l = r
+	} else if textual(r) {
+		r = l
+	}
+	rank := func(t *Type) int {
+		switch t.id {
+		case dateID, timestampID, timestamptzID:
+			return 3
+		case timeID:
+			return 2
+		case intervalID:
+			return 1
+		}
+		return 0
+	}
+	if (op == add || op == mul) && rank(l) < rank(r) {
+		l, r = r, l
+	}
+	shift := op == add || op == sub
+	switch l.id {
+	case dateID:
+		switch {
+		case r.integral() && shift:
+			return dateType
+		case r.id == dateID && op == sub:
+			return integerType
+		case (r.id == intervalID && shift) || (r.id == timeID && op == add):
+			return timestampType
+		}
+	case timestampID:
+		switch {
+		case r.id == intervalID && shift:
+			return timestampType
+		case (r.id == timestampID || r.id == dateID) && op == sub:
+			return intervalType
+		}
+	case timestamptzID:
+		switch {
+		case r.id == intervalID && shift:
+			return timestamptzType
+		case r.id == timestamptzID && op == sub:
+			return intervalType
+		}
+	case timeID:
+		switch {
+		case r.id == intervalID && shift:
+			return timeType
+		case r.id == timeID && op == sub:
+			return intervalType
+		}
+	case intervalID:
+		if (r.id == intervalID && shift) ||
+			(r.numeric() && (op == mul || op == div)) {
+			return intervalType
+		}
+	}
+	return nil
+}
+
+// temporalRank ranks the operands of the date and time arithmetic, e.g.,
+// the timestamp is added with the time, which is added with the interval.
+func temporalRank(v any) int {
+	switch v.(type) {
+	case Date, Timestamp, TimestampTZ:
+		return 3
+	case TimeOfDay:
+		return 2
+	case Interval:
+		return 1
+	}
+	return 0
+}
+
+// addInterval adds the interval to the time, or subtracts it if sub is
+// true. The months are added first, and the day is clamped to the end of
+// the month, e.g., 2026-01-31 plus 1 month is 2026-02-28.
+func addInterval(t time.Time, iv Interval, sub bool) time.Time {
+	if sub {
+		iv = iv.neg()
+	}
+	if iv.months != 0 {
+		y, m, d := t.Date()
+		months := int(m) - 1 + iv.months
+		y += floorDiv(months, 12)
+		m = time.Month(months - floorDiv(months, 12)*12 + 1)
+		if last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day(); d > last {
+			d = last
+		}
+		hh, mm, ss := t.Clock()
+		t = time.Date(y, m, d, hh, mm, ss, t.Nanosecond(), time.UTC)
+	}
+	return t.AddDate(0, 0, iv.days).Add(time.Duration(iv.micros) * time.Microsecond)
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// intervalBetween returns the interval from u to t in days and
+// microseconds.
+func intervalBetween(t, u time.Time) Interval {
+	micros := t.UnixMicro() - u.UnixMicro()
+	return Interval{days: int(micros / microsPerDay), micros: micros % microsPerDay}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// evalText evaluates the expression and returns its value as text.
+func evalText(env *evalEnv, expr string) (string, error) {
+	ss, err := parseSQL("select * from t where " + expr)
+	if err != nil {
+		return "", err
+	}
+	v, err := ss.(*SelectStatement).where.Eval(env)
+	if err != nil || v == nil {
+		return "", err
+	}
+	return toText(v), nil
+}
+
+func TestTemporalExpressions(t *testing.T) {
+	tts := []struct {
+		expr   string
+		code   string
+		expect string
+	}{
+		{"date '2024-02-29'", "", "2024-02-29"},
+		{"time '13:04:05.25'", "", "13:04:05.25"},
+		{"timestamp '2024-01-02 03:04:05'", "", "2024-01-02T03:04:05"},
+		{"timestamptz '2024-01-02 03:04:05+08'", "", "2024-01-01T19:04:05Z"},
+		{"timestamp '2024-01-02T03:04:05.5Z'", "", "2024-01-02T03:04:05.5"},
+		{"interval '1 year 2 mons 3 days 04:05:06'", "", "P1Y2M3DT4H5M6S"},
+		{"interval '1.5 days'", "", "P1DT12H"},
+		{"interval '1 week ago'", "", "P-7D"},
+		{"interval 'P1DT2H30M'", "", "P1DT2H30M"},
+		{"interval '90'", "", "PT1M30S"},
+		{"date '2024-01-31' < '2024-02-01'", "", "true"},
+		{"timestamp '2024-01-01 10:00' = '2024-01-01T10:00:00'", "", "true"},
+		{"timestamptz '2024-01-01 08:00+08' = timestamptz '2024-01-01 00:00Z'",
+			"", "true"},
+		{"interval '1 day' = interval '24 hours'", "", "true"},
+		{"interval '1 mon' > interval '29 days'", "", "true"},
+		{"date '2024-01-31' + interval '1 month'", "", "2024-02-29T00:00:00"},
+		{"date '2024-02-29' + 1", "", "2024-03-01"},
+		{"1 + date '2024-02-29'", "", "2024-03-01"},
+		{"date '2024-03-01' - date '2024-01-01'", "", "60"},
+		{"date '2024-01-01' + time '10:30'", "", "2024-01-01T10:30:00"},
+		{"timestamp '2024-01-01 00:00' + interval '1 day 2 hours'", "",
+			"2024-01-02T02:00:00"},
+		{"timestamp '2024-03-31' - interval '1 mon'", "", "2024-02-29T00:00:00"},
+		{"timestamp '2024-01-02 03:00' - timestamp '2024-01-01'", "", "P1DT3H"},
+		{"time '23:00' + interval '2 hours'", "", "01:00:00"},
+		{"time '10:00' - time '08:30'", "", "PT1H30M"},
+		{"interval '1 hour' * 2.5 + interval '1 day'", "", "P1DT2H30M"},
+		{"interval '1 day' / 4", "", "PT6H"},
+		{"-interval '1 day'", "", "P-1D"},
+		{"date_trunc('month', timestamp '2024-02-15 10:20:30')", "",
+			"2024-02-01T00:00:00"},
+		{"date_trunc('hour', timestamp '2024-02-15 10:20:30')", "",
+			"2024-02-15T10:00:00"},
+		{"date_trunc('week', date '2024-02-15')", "", "2024-02-12T00:00:00"},
+		{"extract(year from date '2024-02-15')", "", "2024"},
+		{"extract(dow from date '2024-02-18')", "", "0"},
+		{"extract(epoch from timestamp '1970-01-02')", "", "86400.000000"},
+		{"extract(hour from interval '1 day 30 hours')", "", "30"},
+		{"date_part('month', date '2024-02-15')", "", "2.0"},
+		{"to_char(timestamp '2024-02-05 13:04:05', 'YYYY-MM-DD HH12:MI:SS PM')",
+			"", "2024-02-05 01:04:05 PM"},
+		{`to_char(date '2024-02-05', 'FMDay, FMDD "of" FMMonth')`, "",
+			"Monday, 5 of February"},
+		{"to_timestamp('05/02/2024 13:04', 'DD/MM/YYYY HH24:MI')", "",
+			"2024-02-05T13:04:00Z"},
+		{"to_timestamp(86400)", "", "1970-01-02T00:00:00Z"},
+		{"to_date('20240205', 'YYYYMMDD')", "", "2024-02-05"},
+		{"date '2024-02-30' is null", codeDatetimeFieldOverflow, ""},
+		{"date 'tomorrow' is null", codeInvalidDatetimeFormat, ""},
+		{"interval '1 fortnight' is null", codeInvalidDatetimeFormat, ""},
+		{"date '2024-01-01' + date '2024-01-01' is null", codeUndefinedFunction, ""},
+		{"date_trunc('fortnight', now()) is null", codeInvalidParameterValue, ""},
+		{"timestamp '2024-01-01' + null is null", "", "true"},
+	}
+	env := &evalEnv{sess: NewSession(defaultSuperuser)}
+	for i, tt := range tts {
+		got, err := evalText(env, tt.expr)
+		if sqlState(err) != tt.code && (err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.expr, err, tt.code)
+		}
+		if got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.expr, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.expr)
+	}
+}
+
+func TestNow(t *testing.T) {
+	env := &evalEnv{sess: NewSession(defaultSuperuser)}
+	before := time.Now()
+	got, err := evalText(env, "now() = now() and now() - interval '1 second' < now()")
+	if err != nil || got != "true" {
+		t.Fatalf("got %q (%v), expect true", got, err)
+	}
+	if env.now.Before(before) || env.now.After(time.Now()) {
+		t.Fatalf("got now %v, expect after %v", env.now, before)
+	}
+}
+
+func TestTemporalColumns(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table e (at timestamp primary key, d date, t time, " +
+			"z timestamp with time zone, i interval)",
+		"insert into e values ('2024-01-03 09:00', '2024-01-03', '09:00', " +
+			"'2024-01-03 09:00+01', '1 day')",
+		"insert into e values (timestamp '2024-01-01 12:30', date '2024-01-01', " +
+			"time '12:30', now(), interval '2 hours')",
+		"insert into e (at, d) values ('2023-12-31 23:59:59.999999', " +
+			"timestamp '2023-12-31 23:00')",
+		"insert into e (at) values (date '2024-01-02')",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		expect []string
+	}{
+		{"Ordered scan", "select at from e", "", []string{
+			"2023-12-31T23:59:59.999999", "2024-01-01T12:30:00",
+			"2024-01-02T00:00:00", "2024-01-03T09:00:00"}},
+		{"Range", "select at from e where at >= '2024-01-01' and " +
+			"at < timestamp '2024-01-03'", "", []string{
+			"2024-01-01T12:30:00", "2024-01-02T00:00:00"}},
+		{"Date column", "select d from e where d < date '2024-01-02'", "",
+			[]string{"2023-12-31", "2024-01-01"}},
+		{"Interval arithmetic", "select at from e where at + i > " +
+			"timestamp '2024-01-04'", "", []string{"2024-01-03T09:00:00"}},
+		{"Time zone", "select z from e where z = '2024-01-03 08:00Z'", "",
+			[]string{"2024-01-03T08:00:00Z"}},
+		{"Update", "update e set i = i * 2 where t = '12:30'", "", nil},
+		{"Updated", "select i from e where i > interval '3 hours'", "",
+			[]string{"PT4H", "P1D"}},
+		{"Duplicate", "insert into e (at) values ('2024-01-02T00:00:00')",
+			codeUniqueViolation, nil},
+		{"Type mismatch", "insert into e (at, t) values ('2025-01-01', 1)",
+			codeDatatypeMismatch, nil},
+		{"Invalid time", "insert into e (at, t) values ('2025-01-01', '25:00')",
+			codeDatetimeFieldOverflow, nil},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		var got []string
+		for _, row := range r.rows {
+			got = append(got, toText(row.fields[r.cols[0]]))
+		}
+		if len(got) != len(tt.expect) {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.name, got, tt.expect)
+		}
+		for j := range got {
+			if got[j] != tt.expect[j] {
+				t.Fatalf("case %d (%s) failed: got %q, expect %q",
+					i, tt.name, got, tt.expect)
+			}
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	r := execSQL(db, defaultSuperuser, "select at, d, t, i from e where at = '2024-01-03 09:00'")
+	if r.err != nil || len(r.rows) != 1 {
+		t.Fatalf("got %d rows (%v), expect 1", len(r.rows), r.err)
+	}
+	b, err := json.Marshal(r.rows[0].fields)
+	if expect := `{"at":"2024-01-03T09:00:00","d":"2024-01-03","i":"P1D",` +
+		`"t":"09:00:00"}`; err != nil || string(b) != expect {
+		t.Fatalf("got %s (%v), expect %s", b, err, expect)
+	}
+}
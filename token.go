@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
@@ -29,6 +30,55 @@ const (
 	Equal
 	Star
 	Values
+	Set
+	Alter
+	Grant
+	Revoke
+	On
+	To
+	KeyWordUser
+	All
+	With
+	NotEqual
+	Less
+	LessEqual
+	Greater
+	GreaterEqual
+	And
+	Or
+	Not
+	Update
+	Using
+	Check
+	For
+	Semicolon
+	Plus
+	Minus
+	Slash
+	Percent
+	Concat
+	Dot
+	Is
+	Unique
+	Default
+	KeyWordConstraint
+	References
+	Foreign
+	// Arrow, DoubleArrow, HashArrow, HashDoubleArrow, AtGreater and
+	// LessAt are the JSON operators ->, ->>, #>, #>>, @> and <@
+	Arrow
+	DoubleArrow
+	HashArrow
+	HashDoubleArrow
+	AtGreater
+	LessAt
+	// LeftBracket and RightBracket enclose the array subscripts and the
+	// elements of ARRAY[...], and DoubleAmpersand is the array operator &&
+	LeftBracket
+	RightBracket
+	DoubleAmpersand
+	// DoubleColon is the cast operator ::, e.g., '1'::integer
+	DoubleColon
 )
 
 var (
@@ -91,6 +141,176 @@ var (
 		Type:       KeyWordToken,
 		KeyWordVal: Star,
 	}
+
+	TokenSet = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Set,
+	}
+
+	TokenAlter = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Alter,
+	}
+
+	TokenGrant = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Grant,
+	}
+
+	TokenRevoke = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Revoke,
+	}
+
+	TokenOn = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: On,
+	}
+
+	TokenTo = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: To,
+	}
+
+	TokenUser = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: KeyWordUser,
+	}
+
+	TokenAll = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: All,
+	}
+
+	TokenWith = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: With,
+	}
+
+	TokenNotEqual = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: NotEqual,
+	}
+
+	TokenLess = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Less,
+	}
+
+	TokenLessEqual = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: LessEqual,
+	}
+
+	TokenGreater = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Greater,
+	}
+
+	TokenGreaterEqual = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: GreaterEqual,
+	}
+
+	TokenAnd = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: And,
+	}
+
+	TokenOr = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Or,
+	}
+
+	TokenNot = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Not,
+	}
+
+	TokenUpdate = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Update,
+	}
+
+	TokenUsing = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Using,
+	}
+
+	TokenCheck = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Check,
+	}
+
+	TokenFor = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: For,
+	}
+
+	TokenSemicolon = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Semicolon,
+	}
+
+	TokenPlus = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Plus,
+	}
+
+	TokenMinus = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Minus,
+	}
+
+	TokenSlash = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Slash,
+	}
+
+	TokenPercent = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Percent,
+	}
+
+	TokenConcat = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Concat,
+	}
+
+	TokenDot = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Dot,
+	}
+
+	TokenIs = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Is,
+	}
+
+	TokenUnique = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Unique,
+	}
+
+	TokenDefault = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Default,
+	}
+
+	TokenConstraint = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: KeyWordConstraint,
+	}
+
+	TokenReferences = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: References,
+	}
+
+	TokenForeign = Token{
+		Type:       KeyWordToken,
+		KeyWordVal: Foreign,
+	}
 )
 
 func isUnquoteStringToken(token *Token) bool {
@@ -108,12 +328,16 @@ func cmpTks(tk1, tk2 Token) bool {
 		return tk1.StringVal == tk2.StringVal
 	case IntegerToken:
 		return tk1.IntegerVal == tk2.IntegerVal
-	case FloatToken:
-		return tk1.FloatVal == tk2.FloatVal
+	case DecimalToken:
+		return tk1.DecimalVal.Cmp(tk2.DecimalVal) == 0
 	case BoolToken:
 		return tk1.BoolVal == tk2.BoolVal
+	case NullToken:
+		return true
 	case StringToken:
 		return tk1.StringVal == tk2.StringVal
+	case ParamToken:
+		return tk1.IntegerVal == tk2.IntegerVal
 	default:
 		// TODO(charleszheng44): print error message
 		return false
@@ -160,33 +384,172 @@ func (kw KeyWord) String() string {
 		return "*"
 	case Values:
 		return "values"
+	case Set:
+		return "set"
+	case Alter:
+		return "alter"
+	case Grant:
+		return "grant"
+	case Revoke:
+		return "revoke"
+	case On:
+		return "on"
+	case To:
+		return "to"
+	case KeyWordUser:
+		return "user"
+	case All:
+		return "all"
+	case With:
+		return "with"
+	case NotEqual:
+		return "<>"
+	case Less:
+		return "<"
+	case LessEqual:
+		return "<="
+	case Greater:
+		return ">"
+	case GreaterEqual:
+		return ">="
+	case And:
+		return "and"
+	case Or:
+		return "or"
+	case Not:
+		return "not"
+	case Update:
+		return "update"
+	case Using:
+		return "using"
+	case Check:
+		return "check"
+	case For:
+		return "for"
+	case Semicolon:
+		return ";"
+	case Plus:
+		return "+"
+	case Minus:
+		return "-"
+	case Slash:
+		return "/"
+	case Percent:
+		return "%"
+	case Concat:
+		return "||"
+	case Dot:
+		return "."
+	case Is:
+		return "is"
+	case Unique:
+		return "unique"
+	case Default:
+		return "default"
+	case KeyWordConstraint:
+		return "constraint"
+	case References:
+		return "references"
+	case Foreign:
+		return "foreign"
+	case Arrow:
+		return "->"
+	case DoubleArrow:
+		return "->>"
+	case HashArrow:
+		return "#>"
+	case HashDoubleArrow:
+		return "#>>"
+	case AtGreater:
+		return "@>"
+	case LessAt:
+		return "<@"
+	case LeftBracket:
+		return "["
+	case RightBracket:
+		return "]"
+	case DoubleAmpersand:
+		return "&&"
+	case DoubleColon:
+		return "::"
 	}
 	return "invalid"
 }
 
+// operators are the operators and the punctuations, the longer ones
+// are matched first.
+var operators = []string{"->>", "#>>", "<=", ">=", "<>", "!=", "||", "->",
+	"#>", "@>", "<@", "&&", "::", "=", "<", ">", "+", "-", "*", "/", "%", "(",
+	")", "[", "]", ",", ";", "."}
+
 type empty struct{}
 
 var null = struct{}{}
 
 var keyWords map[string]empty = map[string]empty{
-	Create.String():       null,
-	Select.String():       null,
-	Insert.String():       null,
-	Delete.String():       null,
-	Drop.String():         null,
-	Into.String():         null,
-	From.String():         null,
-	Primary.String():      null,
-	Key.String():          null,
-	KeyWordTable.String(): null,
-	Where.String():        null,
-	LeftParen.String():    null,
-	RightParen.String():   null,
-	SingleQuote.String():  null,
-	Comma.String():        null,
-	Equal.String():        null,
-	Star.String():         null,
-	Values.String():       null,
+	Create.String():            null,
+	Select.String():            null,
+	Insert.String():            null,
+	Delete.String():            null,
+	Drop.String():              null,
+	Into.String():              null,
+	From.String():              null,
+	Primary.String():           null,
+	Key.String():               null,
+	KeyWordTable.String():      null,
+	Where.String():             null,
+	LeftParen.String():         null,
+	RightParen.String():        null,
+	SingleQuote.String():       null,
+	Comma.String():             null,
+	Equal.String():             null,
+	Star.String():              null,
+	Values.String():            null,
+	Set.String():               null,
+	Alter.String():             null,
+	Grant.String():             null,
+	Revoke.String():            null,
+	On.String():                null,
+	To.String():                null,
+	KeyWordUser.String():       null,
+	All.String():               null,
+	With.String():              null,
+	NotEqual.String():          null,
+	"!=":                       null,
+	Less.String():              null,
+	LessEqual.String():         null,
+	Greater.String():           null,
+	GreaterEqual.String():      null,
+	And.String():               null,
+	Or.String():                null,
+	Not.String():               null,
+	Update.String():            null,
+	Using.String():             null,
+	Check.String():             null,
+	For.String():               null,
+	Semicolon.String():         null,
+	Plus.String():              null,
+	Minus.String():             null,
+	Slash.String():             null,
+	Percent.String():           null,
+	Concat.String():            null,
+	Dot.String():               null,
+	Is.String():                null,
+	Unique.String():            null,
+	Default.String():           null,
+	KeyWordConstraint.String(): null,
+	References.String():        null,
+	Foreign.String():           null,
+	Arrow.String():             null,
+	DoubleArrow.String():       null,
+	HashArrow.String():         null,
+	HashDoubleArrow.String():   null,
+	AtGreater.String():         null,
+	LessAt.String():            null,
+	LeftBracket.String():       null,
+	RightBracket.String():      null,
+	DoubleAmpersand.String():   null,
+	DoubleColon.String():       null,
 }
 
 func StringToKeyWord(str string) (KeyWord, error) {
@@ -227,6 +590,94 @@ func StringToKeyWord(str string) (KeyWord, error) {
 		return Star, nil
 	case "values":
 		return Values, nil
+	case "set":
+		return Set, nil
+	case "alter":
+		return Alter, nil
+	case "grant":
+		return Grant, nil
+	case "revoke":
+		return Revoke, nil
+	case "on":
+		return On, nil
+	case "to":
+		return To, nil
+	case "user":
+		return KeyWordUser, nil
+	case "all":
+		return All, nil
+	case "with":
+		return With, nil
+	case "<>", "!=":
+		return NotEqual, nil
+	case "<":
+		return Less, nil
+	case "<=":
+		return LessEqual, nil
+	case ">":
+		return Greater, nil
+	case ">=":
+		return GreaterEqual, nil
+	case "and":
+		return And, nil
+	case "or":
+		return Or, nil
+	case "not":
+		return Not, nil
+	case "update":
+		return Update, nil
+	case "using":
+		return Using, nil
+	case "check":
+		return Check, nil
+	case "for":
+		return For, nil
+	case ";":
+		return Semicolon, nil
+	case "+":
+		return Plus, nil
+	case "-":
+		return Minus, nil
+	case "/":
+		return Slash, nil
+	case "%":
+		return Percent, nil
+	case "||":
+		return Concat, nil
+	case ".":
+		return Dot, nil
+	case "is":
+		return Is, nil
+	case "unique":
+		return Unique, nil
+	case "default":
+		return Default, nil
+	case "constraint":
+		return KeyWordConstraint, nil
+	case "references":
+		return References, nil
+	case "foreign":
+		return Foreign, nil
+	case "->":
+		return Arrow, nil
+	case "->>":
+		return DoubleArrow, nil
+	case "#>":
+		return HashArrow, nil
+	case "#>>":
+		return HashDoubleArrow, nil
+	case "@>":
+		return AtGreater, nil
+	case "<@":
+		return LessAt, nil
+	case "[":
+		return LeftBracket, nil
+	case "]":
+		return RightBracket, nil
+	case "&&":
+		return DoubleAmpersand, nil
+	case "::":
+		return DoubleColon, nil
 	}
 	return Invalid, errors.New("unknown keywrds")
 }
@@ -237,9 +688,13 @@ const (
 	KeyWordToken TokenType = iota
 	UnquoteStringToken
 	IntegerToken
-	FloatToken
+	DecimalToken
 	BoolToken
 	StringToken
+	// ParamToken is the placeholder of a bound parameter, e.g., $1
+	ParamToken
+	// NullToken is the NULL literal
+	NullToken
 )
 
 func (tt TokenType) String() string {
@@ -250,24 +705,43 @@ func (tt TokenType) String() string {
 		return "UnquoteString"
 	case IntegerToken:
 		return "Integer"
-	case FloatToken:
-		return "Float"
+	case DecimalToken:
+		return "Decimal"
 	case BoolToken:
 		return "Bool"
 	case StringToken:
 		return "String"
+	case ParamToken:
+		return "Param"
+	case NullToken:
+		return "Null"
 	default:
 		return "invalid"
 	}
 }
 
+// Position is the location in the input, the line and the column, which
+// counts the runes, start from 1.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
 type Token struct {
 	Type       TokenType
 	KeyWordVal KeyWord
 	IntegerVal int
-	FloatVal   float64
+	DecimalVal Decimal
 	BoolVal    bool
 	StringVal  string
+	// Quoted is true for the double-quoted identifier, whose case is
+	// preserved
+	Quoted bool
+	// Pos is the position of the first rune of the token, and End is the
+	// position right after the token
+	Pos Position
+	End Position
 }
 
 func (tk Token) String() string {
@@ -275,15 +749,22 @@ func (tk Token) String() string {
 	case KeyWordToken:
 		return tk.KeyWordVal.String()
 	case UnquoteStringToken:
+		if tk.Quoted {
+			return `"` + strings.ReplaceAll(tk.StringVal, `"`, `""`) + `"`
+		}
 		return tk.StringVal
 	case IntegerToken:
 		return strconv.FormatInt(int64(tk.IntegerVal), 10)
-	case FloatToken:
-		return strconv.FormatFloat(tk.FloatVal, 'f', 8, 64)
+	case DecimalToken:
+		return tk.DecimalVal.String()
 	case BoolToken:
 		return strconv.FormatBool(tk.BoolVal)
 	case StringToken:
-		return "'" + tk.StringVal + "'"
+		return "'" + strings.ReplaceAll(tk.StringVal, "'", "''") + "'"
+	case ParamToken:
+		return "$" + strconv.Itoa(tk.IntegerVal)
+	case NullToken:
+		return "null"
 	}
 	return "invalid"
 }
@@ -293,7 +774,7 @@ func isKeyWord(word string) (*Token, bool) {
 	if !exist {
 		return nil, false
 	}
-	kw, err := StringToKeyWord(string(word))
+	kw, err := StringToKeyWord(strings.ToLower(word))
 	if err != nil {
 		// TODO(charleszheng44): print the error message
 		return nil, false
@@ -305,40 +786,45 @@ func isKeyWord(word string) (*Token, bool) {
 	}, true
 }
 
-func isNumber(word string) (*Token, bool) {
-	var floatNum bool
-	for _, r := range word {
-		if !unicode.IsDigit(r) {
-			if r == '.' && !floatNum {
-				// meet the '.' for the first time
-				floatNum = true
-				continue
-			}
-			return nil, false
+// isNumber checks if the input `word` is a numeric literal, e.g., 1, -5,
+// 1.5, .5 and 1e10. The literals with the point or the exponent, and the
+// integers out of range, are exact decimals, whose error is returned if
+// they are out of range, see parseDecimal.
+func isNumber(word string) (*Token, bool, error) {
+	digits := strings.TrimPrefix(word, "-")
+	if digits == "" || digits[0] != '.' && !isDigit(rune(digits[0])) {
+		return nil, false, nil
+	}
+	integer := true
+	for _, r := range digits {
+		switch {
+		case isDigit(r):
+		case strings.ContainsRune(".eE+-", r):
+			integer = false
+		default:
+			return nil, false, nil
 		}
 	}
 
-	if floatNum {
-		fn, err := strconv.ParseFloat(word, 64)
-		if err != nil {
-			// TODO(charleszheng44): print the error message
-			return nil, false
+	if integer {
+		if n, err := strconv.Atoi(word); err == nil {
+			return &Token{
+				Type:       IntegerToken,
+				IntegerVal: n,
+			}, true, nil
 		}
-		return &Token{
-			Type:     FloatToken,
-			FloatVal: fn,
-		}, true
 	}
-
-	n, err := strconv.Atoi(word)
+	d, err := parseDecimal(word)
+	if sqlState(err) == codeNumericOutOfRange {
+		return nil, true, err
+	}
 	if err != nil {
-		// TODO(charleszheng44): print the error message
-		return nil, false
+		return nil, false, nil
 	}
 	return &Token{
-		Type:       IntegerToken,
-		IntegerVal: n,
-	}, true
+		Type:       DecimalToken,
+		DecimalVal: d,
+	}, true, nil
 }
 
 func isBool(word string) (*Token, bool) {
@@ -358,142 +844,326 @@ func isBool(word string) (*Token, bool) {
 	return nil, false
 }
 
-// isString checks if the input `word` is a quoted string, i.e., 'xxxx'.
+// isString checks if the input `word` is a quoted string, i.e., 'xxxx',
+// in which a quote is escaped by doubling it, e.g.,
+//
+//	'it''s'
 func isString(word string) (*Token, bool) {
+	val, ok := unquote(word, '\'')
+	if !ok {
+		return nil, false
+	}
+	return &Token{
+		Type:      StringToken,
+		StringVal: val,
+	}, true
+}
+
+// unquote removes the surrounding quotes of the word and unescapes the
+// doubled quotes inside.
+func unquote(word string, quote rune) (string, bool) {
 	rs := []rune(word)
-	if rs[0] == '\'' && rs[len(rs)-1] == '\'' {
-		return &Token{
-			Type:      StringToken,
-			StringVal: string(rs[1 : len(rs)-1]),
-		}, true
+	if len(rs) < 2 || rs[0] != quote || rs[len(rs)-1] != quote {
+		return "", false
 	}
-	return nil, false
+	var b strings.Builder
+	for i := 1; i < len(rs)-1; i++ {
+		if rs[i] == quote {
+			// must be doubled
+			if i+1 == len(rs)-1 || rs[i+1] != quote {
+				return "", false
+			}
+			i++
+		}
+		b.WriteRune(rs[i])
+	}
+	return b.String(), true
 }
 
-func tokenize(word string) (*Token, error) {
-	if tk, ok := isKeyWord(word); ok {
-		return tk, nil
+// isParam checks if the input `word` is a parameter placeholder, i.e., $n.
+func isParam(word string) (*Token, bool) {
+	if len(word) < 2 || word[0] != '$' {
+		return nil, false
+	}
+	n, err := strconv.Atoi(word[1:])
+	if err != nil || n < 1 {
+		return nil, false
 	}
+	return &Token{
+		Type:       ParamToken,
+		IntegerVal: n,
+	}, true
+}
 
-	if tk, ok := isBool(word); ok {
-		return tk, nil
+// tokenize returns the token of the unquoted word, which is either a
+// keyword, a boolean or an identifier folded to lower case.
+func tokenize(word string) *Token {
+	word = strings.ToLower(word)
+	if tk, ok := isKeyWord(word); ok {
+		return tk
 	}
 
-	if tk, ok := isNumber(word); ok {
-		return tk, nil
+	if tk, ok := isBool(word); ok {
+		return tk
 	}
 
-	if tk, ok := isString(word); ok {
-		return tk, nil
+	if word == "null" {
+		return &Token{Type: NullToken}
 	}
 
 	return &Token{
 		Type:      UnquoteStringToken,
 		StringVal: word,
-	}, nil
+	}
+}
+
+// advance returns the position after the rune.
+func (p Position) advance(rn rune) Position {
+	p.Offset += utf8.RuneLen(rn)
+	if rn == '\n' {
+		p.Line++
+		p.Column = 1
+	} else {
+		p.Column++
+	}
+	return p
+}
+
+// positionAt returns the position of the byte offset in the input.
+func positionAt(inp string, offset int) Position {
+	if offset > len(inp) {
+		offset = len(inp)
+	}
+	pos := Position{Line: 1, Column: 1}
+	for _, rn := range inp[:offset] {
+		pos = pos.advance(rn)
+	}
+	return pos
+}
+
+// lexer scans the tokens of the input.
+type lexer struct {
+	inp []rune
+	// i is the index of the next rune, which is at the pos
+	i   int
+	pos Position
+	tks []*Token
 }
 
+// Tokenize splits the input into tokens, the spaces and the comments,
+// i.e., "-- ..." to the end of the line and "/* ... */", are skipped.
 func Tokenize(inp []rune) ([]*Token, error) {
-	var tks []*Token
-	// process the keywords first
-	var currWord []rune
-	var isStr bool
-	for _, rn := range inp {
-		// ignore the space
-		if unicode.IsSpace(rn) {
-			if len(currWord) == 0 {
-				continue
-			}
-			tk, err := tokenize(string(currWord))
-			if err != nil {
-				return tks, err
-			}
-			tks = append(tks, tk)
-			// reset for the next word
-			currWord = []rune{}
-			continue
+	l := &lexer{
+		inp: inp,
+		pos: Position{Offset: 0, Line: 1, Column: 1},
+	}
+	for l.i < len(l.inp) {
+		if err := l.scan(); err != nil {
+			return l.tks, err
 		}
+	}
+	return l.tks, nil
+}
+
+// peek returns the n-th rune after the next one, 0 at the end.
+func (l *lexer) peek(n int) rune {
+	if l.i+n >= len(l.inp) {
+		return 0
+	}
+	return l.inp[l.i+n]
+}
+
+func (l *lexer) advance() {
+	l.pos = l.pos.advance(l.inp[l.i])
+	l.i++
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isIdentRune(r rune) bool {
+	return isIdentStart(r) || isDigit(r) || r == '$'
+}
+
+// afterOperand checks if the last token ends an operand, after which a
+// '-' is the operator rather than the sign of a number.
+func (l *lexer) afterOperand() bool {
+	if len(l.tks) == 0 {
+		return false
+	}
+	last := l.tks[len(l.tks)-1]
+	if last.Type == KeyWordToken {
+		return last.KeyWordVal == RightParen || last.KeyWordVal == RightBracket
+	}
+	return true
+}
 
-		if rn == '(' && !isStr {
-			if len(currWord) == 0 {
-				tks = append(tks, &Token{
-					Type:       KeyWordToken,
-					KeyWordVal: LeftParen,
-				})
+// scan scans the next token, or skips the space or the comment.
+func (l *lexer) scan() error {
+	start, begin := l.pos, l.i
+	rn := l.peek(0)
+	var tk *Token
+	switch {
+	case unicode.IsSpace(rn):
+		l.advance()
+		return nil
+	case rn == '-' && l.peek(1) == '-':
+		for l.i < len(l.inp) && l.peek(0) != '\n' {
+			l.advance()
+		}
+		return nil
+	case rn == '/' && l.peek(1) == '*':
+		return l.skipBlockComment()
+	case rn == '\'':
+		if err := l.skipQuoted(rn, "quoted string"); err != nil {
+			return err
+		}
+		tk, _ = isString(string(l.inp[begin:l.i]))
+	case rn == '"':
+		if err := l.skipQuoted(rn, "quoted identifier"); err != nil {
+			return err
+		}
+		name, _ := unquote(string(l.inp[begin:l.i]), rn)
+		if name == "" {
+			return newSyntaxError(start, "zero-length delimited identifier")
+		}
+		tk = &Token{
+			Type:      UnquoteStringToken,
+			StringVal: name,
+			Quoted:    true,
+		}
+	case isDigit(rn), rn == '.' && isDigit(l.peek(1)),
+		rn == '-' && !l.afterOperand() &&
+			(isDigit(l.peek(1)) || l.peek(1) == '.' && isDigit(l.peek(2))):
+		l.scanNumber()
+		var (
+			ok  bool
+			err error
+		)
+		if tk, ok, err = isNumber(string(l.inp[begin:l.i])); err != nil {
+			return atPosition(err, start)
+		}
+		if !ok {
+			return newSyntaxError(start, "invalid number %q",
+				string(l.inp[begin:l.i]))
+		}
+		if isIdentRune(l.peek(0)) {
+			return newSyntaxError(start, "trailing junk after numeric "+
+				"literal at or near %q", string(l.inp[begin:l.i+1]))
+		}
+	case rn == '$':
+		l.advance()
+		for isDigit(l.peek(0)) {
+			l.advance()
+		}
+		var ok bool
+		if tk, ok = isParam(string(l.inp[begin:l.i])); !ok {
+			return newSyntaxError(start, "invalid parameter %q",
+				string(l.inp[begin:l.i]))
+		}
+	case isIdentStart(rn):
+		for l.i < len(l.inp) && isIdentRune(l.peek(0)) {
+			l.advance()
+		}
+		tk = tokenize(string(l.inp[begin:l.i]))
+	default:
+		for _, op := range operators {
+			// the operators are ASCII
+			if l.i+len(op) > len(l.inp) || string(l.inp[l.i:l.i+len(op)]) != op {
 				continue
 			}
-			tk, err := tokenize(string(currWord))
-			if err != nil {
-				return tks, err
-			}
-			tks = append(tks, tk)
-			// reset for the next word
-			currWord = []rune{}
-			tks = append(tks, &Token{
+			kw, _ := StringToKeyWord(op)
+			tk = &Token{
 				Type:       KeyWordToken,
-				KeyWordVal: LeftParen,
-			})
-			continue
-		}
-
-		if rn == ')' && !isStr {
-			if len(currWord) == 0 {
-				tks = append(tks, &Token{
-					Type:       KeyWordToken,
-					KeyWordVal: RightParen,
-				})
-				continue
+				KeyWordVal: kw,
 			}
-			tk, err := tokenize(string(currWord))
-			if err != nil {
-				return tks, err
+			for range op {
+				l.advance()
 			}
-			tks = append(tks, tk)
-			// reset for the next word
-			currWord = []rune{}
-			tks = append(tks, &Token{
-				Type:       KeyWordToken,
-				KeyWordVal: RightParen,
-			})
-			continue
+			break
+		}
+		if tk == nil {
+			return newSyntaxError(start, "syntax error at or near %q",
+				string(rn))
 		}
+	}
+	tk.Pos, tk.End = start, l.pos
+	l.tks = append(l.tks, tk)
+	return nil
+}
 
-		if rn == ',' && !isStr {
-			if len(currWord) == 0 {
-				tks = append(tks, &Token{
-					Type:       KeyWordToken,
-					KeyWordVal: Comma,
-				})
-				continue
+// scanNumber scans "[-]digits[.digits][e[+-]digits]", the exponent is
+// only scanned if it has digits.
+func (l *lexer) scanNumber() {
+	if l.peek(0) == '-' {
+		l.advance()
+	}
+	for isDigit(l.peek(0)) {
+		l.advance()
+	}
+	if l.peek(0) == '.' {
+		l.advance()
+		for isDigit(l.peek(0)) {
+			l.advance()
+		}
+	}
+	if r := l.peek(0); r == 'e' || r == 'E' {
+		n := 1
+		if s := l.peek(1); s == '+' || s == '-' {
+			n = 2
+		}
+		if isDigit(l.peek(n)) {
+			for ; n > 0; n-- {
+				l.advance()
 			}
-			tk, err := tokenize(string(currWord))
-			if err != nil {
-				return tks, err
+			for isDigit(l.peek(0)) {
+				l.advance()
 			}
-			tks = append(tks, tk)
-			// reset for the next word
-			currWord = []rune{}
-			tks = append(tks, &Token{
-				Type:       KeyWordToken,
-				KeyWordVal: Comma,
-			})
-			continue
 		}
+	}
+}
 
-		if rn == '\'' {
-			// content within two single quotes is a string
-			isStr = !isStr
+// skipQuoted skips the string or the identifier enclosed by the quotes,
+// in which the doubled quote is an escaped one.
+func (l *lexer) skipQuoted(quote rune, what string) error {
+	start := l.pos
+	l.advance()
+	for l.i < len(l.inp) {
+		if l.peek(0) != quote {
+			l.advance()
+			continue
 		}
-
-		currWord = append(currWord, rn)
-	}
-	if len(currWord) != 0 {
-		tk, err := tokenize(string(currWord))
-		if err != nil {
-			return tks, err
+		l.advance()
+		if l.peek(0) != quote {
+			return nil
 		}
-		tks = append(tks, tk)
+		l.advance()
 	}
+	return newSyntaxError(start, "unterminated %s", what)
+}
 
-	return tks, nil
+// skipBlockComment skips the "/* ... */" comment, which may be nested.
+func (l *lexer) skipBlockComment() error {
+	start := l.pos
+	depth := 0
+	for l.i < len(l.inp) {
+		switch {
+		case l.peek(0) == '/' && l.peek(1) == '*':
+			depth++
+			l.advance()
+		case l.peek(0) == '*' && l.peek(1) == '/':
+			depth--
+			l.advance()
+		}
+		l.advance()
+		if depth == 0 {
+			return nil
+		}
+	}
+	return newSyntaxError(start, "unterminated /* comment")
 }
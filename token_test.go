@@ -53,6 +53,35 @@ func intTk(inp int) *Token {
 	}
 }
 
+func decimalTk(inp string) *Token {
+	d, _ := parseDecimal(inp)
+	return &Token{
+		Type:       DecimalToken,
+		DecimalVal: d,
+	}
+}
+
+func keyWordTk(kw KeyWord) *Token {
+	return &Token{
+		Type:       KeyWordToken,
+		KeyWordVal: kw,
+	}
+}
+
+// equalTokens compares the values of the tokens, the positions are
+// ignored.
+func equalTokens(got, expect []*Token) bool {
+	if len(got) != len(expect) {
+		return false
+	}
+	for i := range got {
+		if !cmpTks(*got[i], *expect[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func TestTokenize(t *testing.T) {
 	tts := []struct {
 		name   string
@@ -97,6 +126,50 @@ func TestTokenize(t *testing.T) {
 				stringTk("test-name"),
 			},
 		},
+		{
+			"Operators without spaces",
+			"a=1 and b<=-5 or c<>2.5 or d!=1e10 or e||'x'>.5",
+			[]*Token{
+				unQuoteStrTk("a"), EqualTk, intTk(1), keyWordTk(And),
+				unQuoteStrTk("b"), keyWordTk(LessEqual), intTk(-5),
+				keyWordTk(Or),
+				unQuoteStrTk("c"), keyWordTk(NotEqual), decimalTk("2.5"),
+				keyWordTk(Or),
+				unQuoteStrTk("d"), keyWordTk(NotEqual), decimalTk("1e10"),
+				keyWordTk(Or),
+				unQuoteStrTk("e"), keyWordTk(Concat), stringTk("x"),
+				keyWordTk(Greater), decimalTk(".5"),
+			},
+		},
+		{
+			"Minus after an operand",
+			"(a)-1 - -2",
+			[]*Token{
+				keyWordTk(LeftParen), unQuoteStrTk("a"), keyWordTk(RightParen),
+				keyWordTk(Minus), intTk(1), keyWordTk(Minus), intTk(-2),
+			},
+		},
+		{
+			"Comments",
+			"select -- a comment; 'x'\n* /* a /* nested */ comment */ from t",
+			[]*Token{SelectTk, StarTk, FromTk, unQuoteStrTk("t")},
+		},
+		{
+			"Quoted identifiers and escapes",
+			`SELECT "Name", "a""b" FROM "select" WHERE Name = 'it''s;'`,
+			[]*Token{
+				SelectTk,
+				{Type: UnquoteStringToken, StringVal: "Name", Quoted: true},
+				keyWordTk(Comma),
+				{Type: UnquoteStringToken, StringVal: `a"b`, Quoted: true},
+				FromTk,
+				{Type: UnquoteStringToken, StringVal: "select", Quoted: true},
+				WhereTk,
+				unQuoteStrTk("name"),
+				EqualTk,
+				stringTk("it's;"),
+			},
+		},
 	}
 
 	for i, tt := range tts {
@@ -109,7 +182,7 @@ func TestTokenize(t *testing.T) {
 				t.Fatalf("case %d (%s) failed: %v", i, tt.name, err)
 			}
 
-			if !reflect.DeepEqual(got, tt.expect) {
+			if !equalTokens(got, tt.expect) {
 				t.Fatalf("case %d (%s) failed: got(%s), expect(%s)", i,
 					tt.name, got, tt.expect)
 			}
@@ -118,6 +191,36 @@ func TestTokenize(t *testing.T) {
 	}
 }
 
+func TestTokenPosition(t *testing.T) {
+	got, err := Tokenize([]rune("select *\n  from tést,\tt2 where s = 'a b'"))
+	if err != nil {
+		t.Fatalf("failed to tokenize: %v", err)
+	}
+	expect := []Position{
+		{0, 1, 1}, {7, 1, 8}, {11, 2, 3}, {16, 2, 8}, {21, 2, 12},
+		{23, 2, 14}, {26, 2, 17}, {32, 2, 23}, {34, 2, 25}, {36, 2, 27},
+	}
+	if len(got) != len(expect) {
+		t.Fatalf("got %d tokens %s, expect %d", len(got), got, len(expect))
+	}
+	for i, tk := range got {
+		if tk.Pos != expect[i] {
+			t.Fatalf("token %d (%s): got position %+v, expect %+v",
+				i, tk, tk.Pos, expect[i])
+		}
+	}
+	if end := got[len(got)-1].End; end != (Position{41, 2, 32}) {
+		t.Fatalf("got end %+v", end)
+	}
+
+	_, err = parseSQL("select *\nform t")
+	if pos := errorPosition(err); sqlState(err) != codeSyntaxError ||
+		pos == nil || pos.Line != 2 || pos.Column != 1 {
+		t.Fatalf("got error %v at %+v, expect a syntax error at 2:1",
+			err, pos)
+	}
+}
+
 func TestIsString(t *testing.T) {
 	tts := []struct {
 		name     string
@@ -140,6 +243,18 @@ func TestIsString(t *testing.T) {
 			nil,
 			false,
 		},
+		{
+			"Escaped quote",
+			"'it''s'",
+			&Token{
+				Type:      StringToken,
+				StringVal: "it's",
+			},
+			true,
+		},
+		{"Empty", "", nil, false},
+		{"Single quote", "'", nil, false},
+		{"Unescaped quote", "'a'b'", nil, false},
 	}
 
 	for i, tt := range tts {
@@ -162,3 +277,58 @@ func TestIsString(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenizeErrors(t *testing.T) {
+	tts := []struct {
+		input  string
+		column int
+	}{
+		{"select 'abc", 8},
+		{`select "abc`, 8},
+		{`select ""`, 8},
+		{"select /* abc", 8},
+		{"select 12abc", 8},
+		{"select $a", 8},
+		{"select #", 8},
+	}
+	for i, tt := range tts {
+		_, err := Tokenize([]rune(tt.input))
+		pos := errorPosition(err)
+		if sqlState(err) != codeSyntaxError || pos == nil ||
+			pos.Column != tt.column {
+			t.Fatalf("case %d (%q): got error %v at %+v, expect a syntax "+
+				"error at column %d", i, tt.input, err, pos, tt.column)
+		}
+	}
+}
+
+func FuzzTokenize(f *testing.F) {
+	for _, seed := range []string{
+		"select * from t where a = 'x' and b <= -1.5e3",
+		`select "A""b" from t -- comment`,
+		"/* a /* b */ c */ insert into t values ($1, 'it''s')",
+		"'", `"`, "-", "1e", ".", "$", "/*",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, inp string) {
+		tks, err := Tokenize([]rune(inp))
+		if err != nil {
+			if errorPosition(err) == nil {
+				t.Fatalf("error without position: %v", err)
+			}
+			return
+		}
+		// the tokens are ordered and not empty
+		prev := Position{Line: 1, Column: 1}
+		for _, tk := range tks {
+			if tk.Pos.Offset < prev.Offset || tk.End.Offset <= tk.Pos.Offset {
+				t.Fatalf("token %s at %+v-%+v after %+v",
+					tk, tk.Pos, tk.End, prev)
+			}
+			prev = tk.End
+		}
+		// the parser must not panic either
+		parseScript(tks)
+	})
+}
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// typeID identifies the SQL type regardless of its modifiers.
+type typeID int
+
+const (
+	booleanID typeID = iota
+	smallintID
+	integerID
+	bigintID
+	floatID
+	decimalID
+	stringID
+	varcharID
+	byteaID
+	dateID
+	timeID
+	timestampID
+	timestamptzID
+	intervalID
+	jsonID
+	jsonbID
+	uuidID
+	arrayID
+)
+
+// Type is the SQL type of a column, e.g., VARCHAR(20). The values of the
+// integer types are int, FLOAT float64, DECIMAL Decimal, STRING and
+// VARCHAR string, BOOLEAN bool, BYTEA Bytes, DATE Date, TIME TimeOfDay,
+// TIMESTAMP Timestamp, TIMESTAMPTZ TimestampTZ, INTERVAL Interval, JSON
+// and JSONB JSON, UUID UUID, and the arrays Array.
+type Type struct {
+	id typeID
+	// elem is the type of the elements of the array
+	elem *Type
+	// precision and scale are the digits of DECIMAL, the precision is 0
+	// if unconstrained
+	precision int
+	scale     int
+	// length is the maximal length of VARCHAR, 0 if unlimited
+	length int
+}
+
+var (
+	booleanType     = &Type{id: booleanID}
+	smallintType    = &Type{id: smallintID}
+	integerType     = &Type{id: integerID}
+	bigintType      = &Type{id: bigintID}
+	floatType       = &Type{id: floatID}
+	decimalType     = &Type{id: decimalID}
+	stringType      = &Type{id: stringID}
+	varcharType     = &Type{id: varcharID}
+	byteaType       = &Type{id: byteaID}
+	dateType        = &Type{id: dateID}
+	timeType        = &Type{id: timeID}
+	timestampType   = &Type{id: timestampID}
+	timestamptzType = &Type{id: timestamptzID}
+	intervalType    = &Type{id: intervalID}
+	jsonType        = &Type{id: jsonID}
+	jsonbType       = &Type{id: jsonbID}
+	uuidType        = &Type{id: uuidID}
+	// textArrayType is TEXT[], e.g., of the JSON paths
+	textArrayType = &Type{id: arrayID, elem: stringType}
+)
+
+// typeNames maps the names of the types, including the aliases, to the
+// types without modifiers.
+var typeNames = map[string]*Type{
+	"boolean":     booleanType,
+	"bool":        booleanType,
+	"smallint":    smallintType,
+	"int2":        smallintType,
+	"integer":     integerType,
+	"int":         integerType,
+	"int4":        integerType,
+	"bigint":      bigintType,
+	"int8":        bigintType,
+	"float":       floatType,
+	"float8":      floatType,
+	"decimal":     decimalType,
+	"numeric":     decimalType,
+	"string":      stringType,
+	"text":        stringType,
+	"varchar":     varcharType,
+	"bytea":       byteaType,
+	"date":        dateType,
+	"time":        timeType,
+	"timestamp":   timestampType,
+	"timestamptz": timestamptzType,
+	"interval":    intervalType,
+	"json":        jsonType,
+	"jsonb":       jsonbType,
+	"uuid":        uuidType,
+}
+
+// lookupType returns the type of the name without modifiers.
+func lookupType(name string) (*Type, error) {
+	t, exist := typeNames[strings.ToLower(name)]
+	if !exist {
+		return nil, newError(codeUndefinedObject,
+			"type %s does not exist", strings.ToLower(name))
+	}
+	return t, nil
+}
+
+// newDecimalType returns DECIMAL(precision, scale).
+func newDecimalType(precision, scale int) (*Type, error) {
+	if precision < 1 || precision > maxDigits {
+		return nil, newError(codeInvalidParameterValue,
+			"DECIMAL precision %d must be between 1 and %d", precision,
+			maxDigits)
+	}
+	if scale < 0 || scale > precision {
+		return nil, newError(codeInvalidParameterValue,
+			"DECIMAL scale %d must be between 0 and precision %d",
+			scale, precision)
+	}
+	return &Type{id: decimalID, precision: precision, scale: scale}, nil
+}
+
+// newArrayType returns the array type of the elements, e.g., INTEGER[].
+func newArrayType(elem *Type) *Type {
+	return &Type{id: arrayID, elem: elem}
+}
+
+// newVarcharType returns VARCHAR(length).
+func newVarcharType(length int) (*Type, error) {
+	if length < 1 {
+		return nil, newError(codeInvalidParameterValue,
+			"length for type varchar must be at least 1")
+	}
+	return &Type{id: varcharID, length: length}, nil
+}
+
+func (t *Type) String() string {
+	switch t.id {
+	case booleanID:
+		return "boolean"
+	case smallintID:
+		return "smallint"
+	case integerID:
+		return "integer"
+	case bigintID:
+		return "bigint"
+	case floatID:
+		return "float"
+	case decimalID:
+		if t.precision == 0 {
+			return "decimal"
+		}
+		return "decimal(" + strconv.Itoa(t.precision) + "," +
+			strconv.Itoa(t.scale) + ")"
+	case varcharID:
+		if t.length == 0 {
+			return "varchar"
+		}
+		return "varchar(" + strconv.Itoa(t.length) + ")"
+	case byteaID:
+		return "bytea"
+	case dateID:
+		return "date"
+	case timeID:
+		return "time"
+	case timestampID:
+		return "timestamp"
+	case timestamptzID:
+		return "timestamptz"
+	case intervalID:
+		return "interval"
+	case jsonID:
+		return "json"
+	case jsonbID:
+		return "jsonb"
+	case uuidID:
+		return "uuid"
+	case arrayID:
+		return t.elem.String() + "[]"
+	default:
+		return "string"
+	}
+}
+
+// integral tells whether the type is one of the integer types.
+func (t *Type) integral() bool {
+	return t.id == smallintID || t.id == integerID || t.id == bigintID
+}
+
+// numeric tells whether the type is one of the number types.
+func (t *Type) numeric() bool {
+	return t.integral() || t.id == floatID || t.id == decimalID
+}
+
+// compatible tells whether the values of the types are comparable as
+// they are, e.g., INTEGER and BIGINT, STRING and VARCHAR, or the arrays
+// of such types.
+func (t *Type) compatible(o *Type) bool {
+	if t.id == arrayID && o.id == arrayID {
+		return t.elem.compatible(o.elem)
+	}
+	textual := func(t *Type) bool { return t.id == stringID || t.id == varcharID }
+	return t.id == o.id || (t.integral() && o.integral()) ||
+		(textual(t) && textual(o))
+}
+
+// base returns the element type of the array type, or the type itself.
+func (t *Type) base() *Type {
+	if t.id == arrayID {
+		return t.elem
+	}
+	return t
+}
+
+// temporal tells whether the type is one of the date and time types.
+func (t *Type) temporal() bool {
+	return t.id >= dateID && t.id <= intervalID
+}
+
+// intRange returns the bounds of the integer type.
+func (t *Type) intRange() (int, int) {
+	switch t.id {
+	case smallintID:
+		return math.MinInt16, math.MaxInt16
+	case integerID:
+		return math.MinInt32, math.MaxInt32
+	default:
+		return math.MinInt64, math.MaxInt64
+	}
+}
+
+// assign converts the non-NULL value to be stored as the type, which is
+// false if the value is not of the type. The numbers are widened
+// implicitly, i.e., the integers to FLOAT and DECIMAL, and the floats and
+// the decimals to each other, but never narrowed to the integers, which
+// requires the explicit cast, see castValue. The integers are range
+// checked, the decimals are rounded to the scale, the strings are checked
+// against the length, and the strings in the bytea hex format, e.g.,
+// '\x0a0b', are decoded for BYTEA. The strings are parsed for the numeric,
+// the date and time types, and the dates and the timestamps are converted
+// to each other.
+// The strings are validated for JSON and JSONB, which are converted to
+// each other, and parsed for UUID and the arrays, the elements of which
+// are assigned as the element type.
+func (t *Type) assign(v any) (any, bool, error) {
+	switch t.id {
+	case smallintID, integerID, bigintID:
+		if s, ok := v.(string); ok {
+			v, err := t.parse(s)
+			return v, true, err
+		}
+		i, ok := v.(int)
+		if !ok {
+			return nil, false, nil
+		}
+		if min, max := t.intRange(); i < min || i > max {
+			return nil, true, newError(codeNumericOutOfRange,
+				"%s out of range", t)
+		}
+		return i, true, nil
+	case floatID:
+		switch x := v.(type) {
+		case string:
+			f, err := t.parse(x)
+			return f, true, err
+		case float64:
+			return x, true, nil
+		case int:
+			return float64(x), true, nil
+		case Decimal:
+			f := x.Float64()
+			if math.IsInf(f, 0) {
+				return nil, true, newError(codeNumericOutOfRange,
+					"value out of range: overflow")
+			}
+			return f, true, nil
+		}
+		return nil, false, nil
+	case decimalID:
+		var (
+			d   Decimal
+			err error
+		)
+		switch x := v.(type) {
+		case string:
+			d, err := t.parse(x)
+			return d, true, err
+		case Decimal:
+			d = x
+		case int:
+			d = decimalFromInt(x)
+		case float64:
+			d, err = decimalFromFloat(x)
+		default:
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, true, err
+		}
+		d, err = t.fitDecimal(d)
+		return d, true, err
+	case stringID, varcharID:
+		s, ok := v.(string)
+		if !ok {
+			return nil, false, nil
+		}
+		if t.length != 0 && utf8.RuneCountInString(s) > t.length {
+			return nil, true, newError(codeStringDataRightTruncation,
+				"value too long for type %s", t)
+		}
+		return s, true, nil
+	case byteaID:
+		switch x := v.(type) {
+		case Bytes:
+			return x, true, nil
+		case string:
+			b, err := parseBytea(x)
+			return b, true, err
+		}
+		return nil, false, nil
+	case dateID, timestampID, timestamptzID:
+		if s, ok := v.(string); ok {
+			v, err := t.parse(s)
+			return v, true, err
+		}
+		i, ok := instant(v)
+		if !ok {
+			return nil, false, nil
+		}
+		switch t.id {
+		case dateID:
+			return Date{i.Truncate(24 * time.Hour)}, true, nil
+		case timestampID:
+			return Timestamp{i}, true, nil
+		}
+		return TimestampTZ{i}, true, nil
+	case timeID, intervalID:
+		if s, ok := v.(string); ok {
+			v, err := t.parse(s)
+			return v, true, err
+		}
+		if t.id == timeID {
+			x, ok := v.(TimeOfDay)
+			return x, ok, nil
+		}
+		x, ok := v.(Interval)
+		return x, ok, nil
+	case jsonID, jsonbID:
+		binary := t.id == jsonbID
+		switch x := v.(type) {
+		case string:
+			j, err := parseJSON(x, binary)
+			return j, true, err
+		case JSON:
+			if x.binary != binary {
+				x = newJSON(x.doc, binary)
+			}
+			return x, true, nil
+		}
+		return nil, false, nil
+	case uuidID:
+		switch x := v.(type) {
+		case string:
+			u, err := parseUUID(x)
+			return u, true, err
+		case UUID:
+			return x, true, nil
+		}
+		return nil, false, nil
+	case arrayID:
+		switch x := v.(type) {
+		case string:
+			a, err := t.parse(x)
+			return a, true, err
+		case Array:
+			a := Array{elem: t.elem, elems: make([]any, len(x.elems))}
+			for i, e := range x.elems {
+				if e == nil {
+					continue
+				}
+				v, ok, err := t.elem.assign(e)
+				if !ok || err != nil {
+					return nil, ok, err
+				}
+				a.elems[i] = v
+			}
+			return a, true, nil
+		}
+		return nil, false, nil
+	default:
+		b, ok := v.(bool)
+		return b, ok, nil
+	}
+}
+
+// fitDecimal rounds the decimal to the scale of the type, and checks if
+// it fits in the precision.
+func (t *Type) fitDecimal(d Decimal) (Decimal, error) {
+	if t.precision == 0 {
+		return d, nil
+	}
+	d = d.rescale(t.scale)
+	if d.integerDigits() > t.precision-t.scale {
+		return Decimal{}, newError(codeNumericOutOfRange,
+			"numeric field overflow: a field with precision %d, scale %d "+
+				"must round to an absolute value less than 10^%d",
+			t.precision, t.scale, t.precision-t.scale)
+	}
+	return d, nil
+}
+
+// parse parses the text representation of the value of the type, e.g.,
+// of the typed literal "DECIMAL '1.50'" or of the parameters.
+func (t *Type) parse(s string) (any, error) {
+	invalid := newError(codeInvalidTextRepresent,
+		"invalid input syntax for type %s: %q", t, s)
+	var v any
+	switch t.id {
+	case smallintID, integerID, bigintID:
+		i, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, invalid
+		}
+		v = i
+	case floatID:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, newError(codeNumericOutOfRange,
+				"%q is out of range for type %s", s, t)
+		}
+		if err != nil {
+			return nil, invalid
+		}
+		v = f
+	case decimalID:
+		d, err := parseDecimal(s)
+		if err != nil {
+			return nil, err
+		}
+		v = d
+	case dateID:
+		return parseDate(s)
+	case timeID:
+		return parseTimeOfDay(s)
+	case timestampID:
+		return parseTimestamp(s)
+	case timestamptzID:
+		return parseTimestampTZ(s)
+	case intervalID:
+		return parseInterval(s)
+	case jsonID, jsonbID:
+		return parseJSON(s, t.id == jsonbID)
+	case uuidID:
+		return parseUUID(s)
+	case arrayID:
+		texts, err := parseTextArray(s)
+		if err != nil {
+			return nil, err
+		}
+		a := Array{elem: t.elem, elems: texts}
+		for i, e := range texts {
+			if e == nil {
+				continue
+			}
+			if a.elems[i], err = t.elem.parse(e.(string)); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case booleanID:
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "t", "true", "on", "yes", "1":
+			v = true
+		case "f", "false", "off", "no", "0":
+			v = false
+		default:
+			return nil, invalid
+		}
+	default:
+		v = s
+	}
+	v, _, err := t.assign(v)
+	return v, err
+}
+
+// Bytes is the value of BYTEA, which is printed in the hex format, e.g.,
+// \x0a0b.
+type Bytes []byte
+
+func (b Bytes) String() string {
+	return `\x` + hex.EncodeToString(b)
+}
+
+// MarshalJSON encodes the bytes as the JSON string in the hex format.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// parseBytea parses the bytea in the hex format, e.g., '\x0a0b', other
+// strings without backslashes are taken as their bytes.
+func parseBytea(s string) (Bytes, error) {
+	if !strings.HasPrefix(s, `\x`) {
+		if strings.Contains(s, `\`) {
+			return nil, newError(codeInvalidTextRepresent,
+				"invalid input syntax for type bytea")
+		}
+		return Bytes(s), nil
+	}
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, newError(codeInvalidTextRepresent,
+			"invalid hexadecimal data for type bytea: %q", s)
+	}
+	return Bytes(b), nil
+}
+
+// typeOf returns the type of the non-NULL value without modifiers.
+func typeOf(v any) *Type {
+	switch x := v.(type) {
+	case int:
+		return integerType
+	case float64:
+		return floatType
+	case Decimal:
+		return decimalType
+	case bool:
+		return booleanType
+	case Bytes:
+		return byteaType
+	case Date:
+		return dateType
+	case TimeOfDay:
+		return timeType
+	case Timestamp:
+		return timestampType
+	case TimestampTZ:
+		return timestamptzType
+	case Interval:
+		return intervalType
+	case JSON:
+		if x.binary {
+			return jsonbType
+		}
+		return jsonType
+	case UUID:
+		return uuidType
+	case Array:
+		return newArrayType(x.elem)
+	default:
+		return stringType
+	}
+}
+
+// valueType returns the name of the type of the non-NULL value for the
+// error messages.
+func valueType(v any) string {
+	return typeOf(v).String()
+}
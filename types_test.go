@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// dec parses the decimal for the tests.
+func dec(t *testing.T, s string) Decimal {
+	t.Helper()
+	d, err := parseDecimal(s)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", s, err)
+	}
+	return d
+}
+
+func TestArithmetic(t *testing.T) {
+	tts := []struct {
+		expr   string
+		code   string
+		expect any
+	}{
+		{"1 + 2 * 3 = 7", "", true},
+		{"(1 + 2) * 3 - -1 = 10", "", true},
+		{"7 / 2 = 3 and -7 % 3 = -1 and 7 % -3 = 1", "", true},
+		{"-(1 - 3) = 2 and - 2 * 3 = -6", "", true},
+		{"1 + 2.5 = 3.5", "", true},
+		{"0.1 + 0.2 = 0.3", "", true},
+		{"0.1::float + 0.2 = 0.3::float", "", false},
+		{"decimal '0.1' + decimal '0.2' = decimal '0.3'", "", true},
+		{"decimal '0.1' + 0.2 = 0.3", "", true},
+		{"decimal '0.1' + 0.2::float = 0.30000000000000004::float", "", true},
+		{"99999999999999999999.99 + 0.01 = 100000000000000000000", "", true},
+		{"1e400 > 1e399", "", true},
+		{"1e1001 > 0", codeNumericOutOfRange, nil},
+		{"1e400::float > 0", codeNumericOutOfRange, nil},
+		{"'1e400'::float > 0", codeNumericOutOfRange, nil},
+		{"decimal '1.50' * 2 = 3", "", true},
+		{"decimal '1' / 3 = decimal '0.3333333333333333'", "", true},
+		{"decimal '2' / decimal '3' = decimal '0.6666666666666667'", "", true},
+		{"decimal '-7.5' % 2 = decimal '-1.5'", "", true},
+		{"- decimal '1.5' < -1", "", true},
+		{"1 + null is null", "", true},
+		{"9223372036854775807 + 1 > 0", codeNumericOutOfRange, nil},
+		{"-9223372036854775807 - 2 < 0", codeNumericOutOfRange, nil},
+		{"4611686018427387904 * 2 > 0", codeNumericOutOfRange, nil},
+		{"2147483647 + 1 > 0", codeNumericOutOfRange, nil},
+		{"-(-2147483647 - 1) > 0", codeNumericOutOfRange, nil},
+		{"2147483648 + 1 > 0", "", true},
+		{"2147483647 + 1::bigint > 0", "", true},
+		{"1 / 0 = 1", codeDivisionByZero, nil},
+		{"1.5 % 0 = 1", codeDivisionByZero, nil},
+		{"decimal '1' / 0 = 1", codeDivisionByZero, nil},
+		{"'a' + 1 = 1", codeUndefinedFunction, nil},
+		{"-'a' = 1", codeUndefinedFunction, nil},
+		{"decimal 'x' = 1", codeInvalidTextRepresent, nil},
+	}
+	for i, tt := range tts {
+		var (
+			got any
+			err error
+		)
+		ss, err := parseSQL("select * from t where " + tt.expr)
+		if err == nil {
+			got, err = ss.(*SelectStatement).where.Eval(
+				&evalEnv{sess: NewSession(defaultSuperuser)})
+		}
+		if sqlState(err) != tt.code && (err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.expr, err, tt.code)
+		}
+		if got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %v, expect %v",
+				i, tt.expr, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.expr)
+	}
+}
+
+func TestColumnTypes(t *testing.T) {
+	db := NewDatabase()
+	if r := execSQL(db, defaultSuperuser, "create table t (id bigint "+
+		"primary key, s smallint, i int, d decimal(5, 2), v varchar(3), "+
+		"b bytea, w decimal(30, 2))"); r.err != nil {
+		t.Fatalf("failed to create the table: %v", r.err)
+	}
+	tts := []struct {
+		name  string
+		sql   string
+		code  string
+		check string
+	}{
+		{"Bigint", "insert into t (id) values (9223372036854775807)", "",
+			"select * from t where id = 9223372036854775807"},
+		{"Smallint", "insert into t (id, s) values (1, 32767)", "",
+			"select * from t where s = 32767"},
+		{"Smallint out of range", "insert into t (id, s) values (2, 32768)",
+			codeNumericOutOfRange, ""},
+		{"Integer out of range", "insert into t (id, i) values (2, -2147483649)",
+			codeNumericOutOfRange, ""},
+		{"Update out of range", "update t set s = s + 1 where id = 1",
+			codeNumericOutOfRange, ""},
+		{"Smallint arithmetic", "select s * s from t where id = 1",
+			codeNumericOutOfRange, ""},
+		{"Smallint widened", "select s * 2 from t where id = 1", "",
+			"select * from t where s * 2 = 65534"},
+		{"Integer", "insert into t (id, s, i) values (9, 1, 2147483647)", "",
+			"select * from t where i + 1::bigint = 2147483648"},
+		{"Integer arithmetic", "select i + 1 from t where id = 9",
+			codeNumericOutOfRange, ""},
+		{"Integer in where", "delete from t where i + s > 0",
+			codeNumericOutOfRange, ""},
+		{"Decimal rounded", "insert into t (id, d) values (2, 1.005)", "",
+			"select * from t where d = decimal '1.01'"},
+		{"Decimal from integer", "insert into t (id, d) values (3, 999)", "",
+			"select * from t where d = 999 and id = 3"},
+		{"Decimal overflow", "insert into t (id, d) values (4, 1000)",
+			codeNumericOutOfRange, ""},
+		{"Decimal rounded overflow",
+			"insert into t (id, d) values (4, decimal '999.995')",
+			codeNumericOutOfRange, ""},
+		{"Decimal arithmetic", "update t set d = d * 2 - decimal '0.035' " +
+			"where id = 2", "", "select * from t where d = decimal '1.99'"},
+		{"Exact decimal literal",
+			"insert into t (id, w) values (10, 99999999999999999999.99)", "",
+			"select * from t where w::string = '99999999999999999999.99'"},
+		{"Decimal with literal", "insert into t (id, w) values (11, 0.1)", "",
+			"select * from t where (w + 0.2)::string = '0.30'"},
+		{"Decimal literal out of range",
+			"insert into t (id, w) values (12, 1e1001)", codeNumericOutOfRange, ""},
+		{"Varchar", "insert into t (id, v) values (5, 'abc')", "",
+			"select * from t where v = 'abc'"},
+		{"Varchar too long", "insert into t (id, v) values (6, 'abcd')",
+			codeStringDataRightTruncation, ""},
+		{"Bytea", `insert into t (id, b) values (6, '\x0aFF')`, "",
+			`select * from t where b = bytea '\x0aff'`},
+		{"Bytea literal", `insert into t (id, b) values (7, bytea '\x')`, "",
+			`select * from t where b = bytea '' and id = 7`},
+		{"Invalid bytea", `insert into t (id, b) values (8, '\x0g')`,
+			codeInvalidTextRepresent, ""},
+		{"Integer string", "insert into t (id, i) values (13, ' 12')", "",
+			"select * from t where i = 12"},
+		{"Decimal string", "insert into t (id, d) values (14, '1.005')", "",
+			"select * from t where d = decimal '1.01' and id = 14"},
+		{"Invalid integer string", "insert into t (id, i) values (8, 'a')",
+			codeInvalidTextRepresent, ""},
+		{"Type mismatch", "insert into t (id, i) values (8, true)",
+			codeDatatypeMismatch, ""},
+		{"Unknown type", "create table x (id money primary key)",
+			codeUndefinedObject, ""},
+		{"Invalid scale", "create table x (id decimal(2, 3) primary key)",
+			codeInvalidParameterValue, ""},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		if tt.check != "" {
+			if r := execSQL(db, defaultSuperuser, tt.check); r.err != nil ||
+				len(r.rows) != 1 {
+				t.Fatalf("case %d (%s) failed: got %d rows (%v), expect 1",
+					i, tt.name, len(r.rows), r.err)
+			}
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+
+	r := execSQL(db, defaultSuperuser, "select -s - s from t where id = 1")
+	if r.err == nil || r.err.(*Error).Msg != "smallint out of range" {
+		t.Fatalf("got error %v, expect smallint out of range", r.err)
+	}
+	r = execSQL(db, defaultSuperuser, "select d, b from t where id = 6 or id = 2")
+	if r.err != nil || len(r.rows) != 2 {
+		t.Fatalf("got %d rows (%v), expect 2", len(r.rows), r.err)
+	}
+	if d, ok := r.rows[0].fields["d"].(Decimal); !ok || d.String() != "1.99" {
+		t.Fatalf("got decimal %v, expect 1.99", r.rows[0].fields["d"])
+	}
+	if b, ok := r.rows[1].fields["b"].(Bytes); !ok ||
+		!bytes.Equal(b, []byte{0x0a, 0xff}) || b.String() != `\x0aff` {
+		t.Fatalf(`got bytea %v, expect \x0aff`, r.rows[1].fields["b"])
+	}
+	info, err := db.DescribeTable("t")
+	if err != nil || info.Types[3].String() != "decimal(5,2)" ||
+		info.Types[4].String() != "varchar(3)" {
+		t.Fatalf("unexpected table info %+v (%v)", info, err)
+	}
+}
+
+func TestDecimalPrimaryKey(t *testing.T) {
+	db := NewDatabase()
+	for _, sql := range []string{
+		"create table t (k decimal primary key, name string)",
+		"insert into t values (10, 'f')",
+		"insert into t values (decimal '-0.5', 'b')",
+		"insert into t values (1.5, 'e')",
+		"insert into t values (0, 'c')",
+		"insert into t values (decimal '-2', 'a')",
+		"insert into t values (decimal '0.25', 'd')",
+	} {
+		if r := execSQL(db, defaultSuperuser, sql); r.err != nil {
+			t.Fatalf("failed to execute %q: %v", sql, r.err)
+		}
+	}
+	tts := []struct {
+		name   string
+		sql    string
+		code   string
+		expect string
+	}{
+		{"Ordered scan", "select name from t", "", "abcdef"},
+		{"Equal scale", "select name from t where k = decimal '1.50'", "", "e"},
+		{"Integer", "select name from t where k = 10", "", "f"},
+		{"Range", "select name from t where k > -1 and k <= 1.5", "", "bcde"},
+		{"Negative range", "select name from t where k < 0", "", "ab"},
+		{"Duplicate", "insert into t values (decimal '10.000', 'x')",
+			codeUniqueViolation, ""},
+	}
+	for i, tt := range tts {
+		r := execSQL(db, defaultSuperuser, tt.sql)
+		if sqlState(r.err) != tt.code && (r.err != nil || tt.code != "") {
+			t.Fatalf("case %d (%s) failed: got error(%v), expect(%s)",
+				i, tt.name, r.err, tt.code)
+		}
+		got := ""
+		for _, row := range r.rows {
+			got += row.fields["name"].(string)
+		}
+		if got != tt.expect {
+			t.Fatalf("case %d (%s) failed: got %q, expect %q",
+				i, tt.name, got, tt.expect)
+		}
+		t.Logf("case %d (%s) succeed", i, tt.name)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// UUID is the value of UUID, which is stored in its 16 bytes and printed
+// in the canonical form, e.g., a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11.
+type UUID [16]byte
+
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[:8], u[:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
+
+// MarshalJSON encodes the UUID as the JSON string in the canonical form.
+func (u UUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
+// parseUUID parses the UUID in the forms PostgreSQL accepts, i.e., the 32
+// hexadecimal digits in either case, optionally enclosed by braces, with
+// the hyphens after any group of 4 digits.
+func parseUUID(s string) (UUID, error) {
+	var u UUID
+	invalid := newError(codeInvalidTextRepresent,
+		"invalid input syntax for type uuid: %q", s)
+	t := strings.TrimSpace(s)
+	if strings.HasPrefix(t, "{") {
+		if !strings.HasSuffix(t, "}") {
+			return u, invalid
+		}
+		t = t[1 : len(t)-1]
+	}
+	digits := make([]byte, 0, 32)
+	for i := 0; i < len(t); i++ {
+		if t[i] == '-' {
+			// a hyphen follows a group of 4 digits but not another one
+			if len(digits) == 0 || len(digits)%4 != 0 || len(digits) == 32 ||
+				t[i-1] == '-' {
+				return u, invalid
+			}
+			continue
+		}
+		digits = append(digits, t[i])
+	}
+	if len(digits) != 32 || strings.HasSuffix(t, "-") {
+		return u, invalid
+	}
+	if _, err := hex.Decode(u[:], digits); err != nil {
+		return u, invalid
+	}
+	return u, nil
+}
+
+// uuidOperands converts the string operand to the UUID if the other one
+// is, as the untyped literal compared with the UUID.
+func uuidOperands(a, b any) (any, any, error) {
+	var err error
+	if s, ok := a.(string); ok {
+		if _, ok := b.(UUID); ok {
+			a, err = parseUUID(s)
+		}
+	}
+	if s, ok := b.(string); ok {
+		if _, ok := a.(UUID); ok {
+			b, err = parseUUID(s)
+		}
+	}
+	return a, b, err
+}
+
+// genRandomUUID returns the random UUID of version 4.
+func genRandomUUID(env *evalEnv, args []any) (any, error) {
+	var u UUID
+	if _, err := rand.Read(u[:]); err != nil {
+		return nil, newError(codeInternalError,
+			"could not generate random values: %v", err)
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return u, nil
+}